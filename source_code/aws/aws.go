@@ -6,34 +6,98 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"os"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
-	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/ec2metadata"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 )
 
-// NewSession : creates a new EC2 service client
+var (
+	// ec2ClientsMu guards ec2Clients, so volumes can be checked concurrently.
+	ec2ClientsMu sync.Mutex
+	// ec2Clients caches one EC2 service client per region and assumed role.
+	ec2Clients = make(map[clientKey]EC2API)
+	// ec2Override replaces every EC2 service client when set, see SetEC2Client.
+	ec2Override EC2API
+)
+
+// clientKey : identifies a cached client by its region and the role it assumes
+type clientKey struct {
+	region  string
+	roleARN string
+}
+
+// NewSession : returns the EC2 service client for a region, creating it on first use
+// The client assumes the role set by SetAssumeRoleARN, if any.
+// Panics if the session can't be created, as session.Must does.
 // region : string : AWS region for the client
-// returns : *ec2.EC2 : returns an EC2 service client
-func NewSession(region string) *ec2.EC2 {
+// returns : EC2API : returns an EC2 service client
+func NewSession(region string) EC2API {
+	return sessionFor(region, "")
+}
+
+// sessionFor : returns the EC2 service client for a region and role, creating it on first use
+// Panics if the session can't be created, as session.Must does.
+// region : string : AWS region for the client
+// roleARN : string : role to assume, empty uses the role set by SetAssumeRoleARN
+// returns : EC2API : returns an EC2 service client
+func sessionFor(region, roleARN string) EC2API {
+	svc, err := ec2Client(region, roleOrDefault(roleARN))
+	if err != nil {
+		panic(err)
+	}
+	return svc
+}
+
+// ec2Client : returns the cached EC2 service client for a region and role, creating it on first use
+// Clients are safe for concurrent use, so one is shared by every call for the region and role.
+// region : string : AWS region for the client
+// roleARN : string : role to assume, empty uses the default credential chain
+// returns : EC2API : returns an EC2 service client, the one set by SetEC2Client if any
+// returns : error : returns an error if the session can't be created
+func ec2Client(region, roleARN string) (EC2API, error) {
+	ec2ClientsMu.Lock()
+	defer ec2ClientsMu.Unlock()
+
+	if ec2Override != nil {
+		return ec2Override, nil
+	}
+
+	key := clientKey{region: region, roleARN: roleARN}
+	if svc, ok := ec2Clients[key]; ok {
+		return svc, nil
+	}
+
 	// Create a new session
-	sess := session.Must(session.NewSession(&aws.Config{
-		Region: aws.String(region),
-	}))
+	// The SDK's own retries are turned off, as withRetry retries EC2 calls up to maxRetries
+	sess, err := session.NewSession(&aws.Config{
+		Region:     aws.String(region),
+		MaxRetries: aws.Int(0),
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	// Create an EC2 service client
-	return ec2.New(sess)
+	// Create an EC2 service client, using the assumed role's credentials when set
+	var svc EC2API
+	if roleARN != "" {
+		svc = ec2.New(sess, &aws.Config{Credentials: assumeRoleCredentials(sess, roleARN)})
+	} else {
+		svc = ec2.New(sess)
+	}
+	ec2Clients[key] = svc
+	return svc, nil
 }
 
 // GetVolume : retrieves an EBS volume using the provided runtime.EBSVolumeConfig
@@ -42,7 +106,7 @@ func NewSession(region string) *ec2.EC2 {
 // returns : error : returns an error if any occur during the process
 func GetVolume(config runtime.EBSVolumeConfig) (*ec2.Volume, error) {
 	// Create a new session
-	svc := NewSession(config.AWSRegion)
+	svc := sessionFor(config.AWSRegion, config.AssumeRoleARN)
 
 	// Define input for DescribeVolumes call
 	input := &ec2.DescribeVolumesInput{
@@ -51,26 +115,30 @@ func GetVolume(config runtime.EBSVolumeConfig) (*ec2.Volume, error) {
 		},
 	}
 
-	// Call DescribeVolumes API
-	result, err := svc.DescribeVolumes(input)
+	// Call DescribeVolumes API, retrying on throttling
+	var result *ec2.DescribeVolumesOutput
+	err := withRetry(func() (err error) {
+		result, err = svc.DescribeVolumes(input)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get volume information from aws. error: %w", err)
+		return nil, fmt.Errorf("failed to get volume information from aws. error: %w", wrapError(err))
 	}
 
 	// Check if volume was found
 	if len(result.Volumes) == 0 {
-		return nil, fmt.Errorf("failed to find volume information. error: %w", err)
+		return nil, fmt.Errorf("failed to find volume information for %v. error: %w", config.AWSVolumeID, ErrVolumeNotFound)
 	}
 
 	// Return the found volume
 	return result.Volumes[0], nil
 }
 
-// GetAWSDeviceSizeGB : retrieves the size of the EBS volume specified in the runtime.EBSVolumeConfig in GiB
+// GetAWSDeviceSizeGiB : retrieves the size of the EBS volume specified in the runtime.EBSVolumeConfig in GiB
 // config : runtime.EBSVolumeConfig : configuration of the EBS volume
 // returns : int64 : returns the size of the volume in GiB
 // returns : error : returns an error if any occur during the process
-func GetAWSDeviceSizeGB(config runtime.EBSVolumeConfig) (int64, error) {
+func GetAWSDeviceSizeGiB(config runtime.EBSVolumeConfig) (int64, error) {
 	// Retrieve the volume
 	volume, err := GetVolume(config)
 	if err != nil {
@@ -96,7 +164,54 @@ func GetVolumeState(config runtime.EBSVolumeConfig) (string, error) {
 	return *volume.State, nil
 }
 
-// GetAllRegions : retrieves all AWS regions
+// GetVolumeType : retrieves the type of the EBS volume specified in the runtime.EBSVolumeConfig
+// config : runtime.EBSVolumeConfig : configuration of the EBS volume
+// returns : string : returns the volume type, e.g. "gp3"
+// returns : error : returns an error if any occur during the process
+func GetVolumeType(config runtime.EBSVolumeConfig) (string, error) {
+	// Retrieve the volume
+	volume, err := GetVolume(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to get volume type. error: %w", err)
+	}
+
+	// Return the type of the volume
+	return aws.StringValue(volume.VolumeType), nil
+}
+
+// CheckVolumeAttached : checks that the EBS volume is in use and attached to the local instance
+// config : runtime.EBSVolumeConfig : configuration of the EBS volume
+// returns : error : returns an error if the volume is not attached here, or if any occur during the process
+func CheckVolumeAttached(config runtime.EBSVolumeConfig) error {
+	// Retrieve the volume
+	volume, err := GetVolume(config)
+	if err != nil {
+		return fmt.Errorf("failed to get volume attachment. error: %w", err)
+	}
+
+	if aws.StringValue(volume.State) != ec2.VolumeStateInUse {
+		return fmt.Errorf("volume %v is %v, not in-use", config.AWSVolumeID, aws.StringValue(volume.State))
+	}
+
+	instanceID, err := getInstanceID()
+	if err != nil {
+		return fmt.Errorf("failed to get instance ID: %w", err)
+	}
+
+	// Check the volume is attached to this instance
+	for _, attachment := range volume.Attachments {
+		if aws.StringValue(attachment.InstanceId) == instanceID {
+			if aws.StringValue(attachment.State) != ec2.VolumeAttachmentStateAttached {
+				return fmt.Errorf("volume %v is %v to instance %v", config.AWSVolumeID, aws.StringValue(attachment.State), instanceID)
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("volume %v is not attached to instance %v", config.AWSVolumeID, instanceID)
+}
+
+// GetAllRegions : retrieves all AWS regions, using the role set by SetAssumeRoleARN
 // returns : []string : slice of all AWS region names
 // returns : error : returns an error if any occur during the process
 func GetAllRegions() ([]string, error) {
@@ -118,6 +233,152 @@ func GetAllRegions() ([]string, error) {
 	return regions, nil
 }
 
+// GetVolumesByTags : discovers the EBS volumes attached to the local instance that carry all of the given tags
+// filters : map[string]string : tag key to value, every tag must match
+// region : string : AWS region of the local instance
+// roleARN : string : role to assume, empty uses the role set by SetAssumeRoleARN
+// returns : []runtime.EBSVolumeConfig : the matching volumes' ID, device name and region, ordered by volume ID
+// returns : error : returns an error if any occur during the process
+func GetVolumesByTags(filters map[string]string, region, roleARN string) ([]runtime.EBSVolumeConfig, error) {
+	instanceID, err := getInstanceID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance ID: %w", err)
+	}
+
+	// Create a new session
+	svc := sessionFor(region, roleARN)
+
+	// Call DescribeVolumes API for each page, retrying on throttling
+	var found []*ec2.Volume
+	input := &ec2.DescribeVolumesInput{Filters: buildTagFilters(filters, instanceID)}
+	err = withRetry(func() error {
+		found = nil
+		return svc.DescribeVolumesPages(input, func(page *ec2.DescribeVolumesOutput, lastPage bool) bool {
+			found = append(found, page.Volumes...)
+			return true
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover volumes by tag. error: %w", wrapError(err))
+	}
+
+	return volumesAttachedTo(found, instanceID, region), nil
+}
+
+// buildTagFilters : builds DescribeVolumes filters matching every tag and an attachment to the instance
+// filters : map[string]string : tag key to value
+// instanceID : string : ID of the instance the volumes must be attached to
+// returns : []*ec2.Filter : the filters, with tags ordered by key
+func buildTagFilters(filters map[string]string, instanceID string) []*ec2.Filter {
+	keys := make([]string, 0, len(filters))
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ec2Filters := make([]*ec2.Filter, 0, len(keys)+1)
+	for _, key := range keys {
+		ec2Filters = append(ec2Filters, &ec2.Filter{
+			Name:   aws.String("tag:" + key),
+			Values: []*string{aws.String(filters[key])},
+		})
+	}
+	return append(ec2Filters, &ec2.Filter{
+		Name:   aws.String("attachment.instance-id"),
+		Values: []*string{aws.String(instanceID)},
+	})
+}
+
+// volumesAttachedTo : converts described volumes into volume configs, using each one's device name on the instance
+// volumes : []*ec2.Volume : volumes returned by DescribeVolumes
+// instanceID : string : ID of the instance the volumes are attached to
+// region : string : AWS region of the volumes
+// returns : []runtime.EBSVolumeConfig : volume configs ordered by volume ID, skipping volumes not attached to the instance
+func volumesAttachedTo(volumes []*ec2.Volume, instanceID, region string) []runtime.EBSVolumeConfig {
+	configs := make([]runtime.EBSVolumeConfig, 0, len(volumes))
+	for _, volume := range volumes {
+		for _, attachment := range volume.Attachments {
+			if aws.StringValue(attachment.InstanceId) != instanceID {
+				continue
+			}
+			configs = append(configs, runtime.EBSVolumeConfig{
+				AWSVolumeID:   aws.StringValue(volume.VolumeId),
+				AWSDeviceName: aws.StringValue(attachment.Device),
+				AWSRegion:     region,
+			})
+			break
+		}
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].AWSVolumeID < configs[j].AWSVolumeID
+	})
+	return configs
+}
+
+// AttachedVolume : an EBS volume attached to the local instance, as listed by GetAttachedVolumes
+type AttachedVolume struct {
+	AWSVolumeID   string // Identifier for the EBS volume.
+	AWSDeviceName string // Name the volume is attached to the instance as.
+	AWSRegion     string // AWS region of the volume.
+	SizeGiB       int64  // Size of the volume, in GiB.
+	VolumeType    string // EBS volume type, e.g. gp3.
+}
+
+// GetAttachedVolumes : lists the EBS volumes attached to the local instance
+// region : string : AWS region of the local instance
+// returns : []AttachedVolume : the attached volumes, ordered by volume ID
+// returns : error : returns an error if any occur during the process
+func GetAttachedVolumes(region string) ([]AttachedVolume, error) {
+	instanceID, err := getInstanceID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance ID: %w", err)
+	}
+
+	// Create a new session
+	svc := NewSession(region)
+
+	// Call DescribeVolumes API for each page, retrying on throttling
+	var found []*ec2.Volume
+	input := &ec2.DescribeVolumesInput{Filters: buildTagFilters(nil, instanceID)}
+	err = withRetry(func() error {
+		found = nil
+		return svc.DescribeVolumesPages(input, func(page *ec2.DescribeVolumesOutput, lastPage bool) bool {
+			found = append(found, page.Volumes...)
+			return true
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attached volumes. error: %w", wrapError(err))
+	}
+
+	return attachedVolumes(found, instanceID, region), nil
+}
+
+// attachedVolumes : converts described volumes into attached volumes, using each one's device name on the instance
+// volumes : []*ec2.Volume : volumes returned by DescribeVolumes
+// instanceID : string : ID of the instance the volumes are attached to
+// region : string : AWS region of the volumes
+// returns : []AttachedVolume : attached volumes ordered by volume ID, skipping volumes not attached to the instance
+func attachedVolumes(volumes []*ec2.Volume, instanceID, region string) []AttachedVolume {
+	byID := make(map[string]*ec2.Volume, len(volumes))
+	for _, volume := range volumes {
+		byID[aws.StringValue(volume.VolumeId)] = volume
+	}
+	configs := volumesAttachedTo(volumes, instanceID, region)
+	attached := make([]AttachedVolume, 0, len(configs))
+	for _, config := range configs {
+		volume := byID[config.AWSVolumeID]
+		attached = append(attached, AttachedVolume{
+			AWSVolumeID:   config.AWSVolumeID,
+			AWSDeviceName: config.AWSDeviceName,
+			AWSRegion:     config.AWSRegion,
+			SizeGiB:       aws.Int64Value(volume.Size),
+			VolumeType:    aws.StringValue(volume.VolumeType),
+		})
+	}
+	return attached
+}
+
 // getCurrentRegion fetches the current region from EC2 instance metadata using the AWS SDK for Go V2.
 // returns : string : AWS region where the instance is located
 // returns : error : return an error if any occur during the process
@@ -144,11 +405,12 @@ func getCurrentRegion() (string, error) {
 // ValidateVolumeID : checks if the provided Volume ID is valid
 // volumeID : string : AWS EBS volume ID to validate
 // region : string : AWS region where the volume is located
+// roleARN : string : role to assume, empty uses the role set by SetAssumeRoleARN
 // returns : bool : returns true if the Volume ID is valid, false otherwise
 // returns : error : returns an error if any occur during the process
-func ValidateVolumeID(volumeID, region string) (bool, error) {
+func ValidateVolumeID(volumeID, region, roleARN string) (bool, error) {
 	// Create a new session
-	svc := NewSession(region)
+	svc := sessionFor(region, roleARN)
 
 	// Define input for DescribeVolumes call
 	input := &ec2.DescribeVolumesInput{
@@ -160,36 +422,26 @@ func ValidateVolumeID(volumeID, region string) (bool, error) {
 	// Call DescribeVolumes API
 	_, err := svc.DescribeVolumes(input)
 	if err != nil {
-		return false, fmt.Errorf("failed to call DescribeVolumes API to validate volume ID. error: %w", err)
+		return false, fmt.Errorf("failed to call DescribeVolumes API to validate volume ID. error: %w", wrapError(err))
 	}
 
 	return true, nil
 }
 
-// getInstanceID : Fetches the instance ID of the current instance using AWS SDK's IMDS client
+// getInstanceID : Fetches the instance ID of the current instance from the instance metadata
 // Returns: string : The instance ID of the current instance
 // error : error : An error that occurred while getting the instance ID, or nil if no error occurred
 func getInstanceID() (string, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO())
-	if err != nil {
-		return "", err
-	}
-
-	client := imds.NewFromConfig(cfg)
-	resp, err := client.GetInstanceIdentityDocument(context.TODO(), &imds.GetInstanceIdentityDocumentInput{})
-	if err != nil {
-		return "", err
-	}
-
-	return resp.InstanceID, nil
+	return metadata().InstanceID()
 }
 
 // GetVolumeIDByDeviceName : Fetches the volume ID attached to a specific device name of the current instance
 // deviceName : string : Device name attached to the volume
 // region : string : AWS region name
+// roleARN : string : role to assume, empty uses the role set by SetAssumeRoleARN
 // Returns: string : The volume ID attached to the device name in the current instance
 // error : error : An error that occurred while getting the volume ID, or nil if no error occurred
-func GetVolumeIDByDeviceName(deviceName, region string) (string, error) {
+func GetVolumeIDByDeviceName(deviceName, region, roleARN string) (string, error) {
 	// Get the instance ID from metadata service
 	instanceID, err := getInstanceID()
 	if err != nil {
@@ -197,7 +449,7 @@ func GetVolumeIDByDeviceName(deviceName, region string) (string, error) {
 	}
 
 	// Create a new session
-	svc := NewSession(region)
+	svc := sessionFor(region, roleARN)
 
 	// Create input configuration
 	input := &ec2.DescribeInstancesInput{
@@ -209,7 +461,7 @@ func GetVolumeIDByDeviceName(deviceName, region string) (string, error) {
 	// Call DescribeInstances API
 	resp, err := svc.DescribeInstances(input)
 	if err != nil {
-		return "", fmt.Errorf("failed to get instance information from AWS: %w", err)
+		return "", fmt.Errorf("failed to get instance information from AWS: %w", wrapError(err))
 	}
 
 	// Loop over reservations and instances
@@ -228,45 +480,113 @@ func GetVolumeIDByDeviceName(deviceName, region string) (string, error) {
 	return "", fmt.Errorf("no volume found with device name %v", deviceName)
 }
 
-// GetDeviceNameByVolumeID : retrieves the device name of the EBS volume attached to an EC2 instance
+// GetDeviceNameByVolumeID : retrieves the device name of the EBS volume attached to the local EC2 instance
+// DescribeInstances is filtered to the local instance and every page is scanned.
 // volumeID : string : AWS EBS volume ID
 // region : string : AWS region where the volume is located
+// roleARN : string : role to assume, empty uses the role set by SetAssumeRoleARN
 // returns : string : returns the device name
+// returns : error : returns an error if any occur during the process, or if the volume isn't attached to the local instance
+func GetDeviceNameByVolumeID(volumeID, region, roleARN string) (string, error) {
+	instanceID, instances, err := describeLocalInstance(region, roleARN)
+	if err != nil {
+		return "", err
+	}
+
+	deviceName, found := findDeviceName(instances, volumeID)
+	if !found {
+		return "", fmt.Errorf("volume ID %v is not attached to the local instance %v", volumeID, instanceID)
+	}
+	return deviceName, nil
+}
+
+// CountAttachedVolumes : counts the EBS volumes attached to the local EC2 instance, from its block device mapping
+// region : string : AWS region of the local instance
+// roleARN : string : role to assume, empty uses the role set by SetAssumeRoleARN
+// returns : int : the number of attached EBS volumes, including the root volume
 // returns : error : returns an error if any occur during the process
-func GetDeviceNameByVolumeID(volumeID, region string) (string, error) {
+func CountAttachedVolumes(region, roleARN string) (int, error) {
+	_, instances, err := describeLocalInstance(region, roleARN)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, inst := range instances {
+		for _, bd := range inst.BlockDeviceMappings {
+			if bd.Ebs != nil {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// describeLocalInstance : describes the local EC2 instance
+// DescribeInstances is filtered to the local instance and every page is scanned.
+// region : string : AWS region of the local instance
+// roleARN : string : role to assume, empty uses the role set by SetAssumeRoleARN
+// returns : string : the local instance ID
+// returns : []*ec2.Instance : the instances returned by DescribeInstances
+// returns : error : returns an error if any occur during the process
+func describeLocalInstance(region, roleARN string) (string, []*ec2.Instance, error) {
+	// Get the instance ID from metadata service
+	instanceID, err := getInstanceID()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get instance ID: %w", err)
+	}
+
 	// Create a new session
-	svc := NewSession(region)
+	svc := sessionFor(region, roleARN)
 
-	// Call DescribeInstances API
-	resp, err := svc.DescribeInstances(nil)
+	// Call DescribeInstances API for each page, retrying on throttling
+	var instances []*ec2.Instance
+	input := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("instance-id"), Values: []*string{aws.String(instanceID)}},
+		},
+	}
+	err = withRetry(func() error {
+		instances = nil
+		return svc.DescribeInstancesPages(input, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, res := range page.Reservations {
+				instances = append(instances, res.Instances...)
+			}
+			return true
+		})
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to get instance information from AWS. error: %w", err)
+		return "", nil, fmt.Errorf("failed to get instance information from AWS. error: %w", wrapError(err))
 	}
+	return instanceID, instances, nil
+}
 
-	// Loop over reservations and instances
-	for _, res := range resp.Reservations {
-		for _, inst := range res.Instances {
-			// Loop over instance block device mappings
-			for _, bd := range inst.BlockDeviceMappings {
-				if *bd.Ebs.VolumeId == volumeID {
-					return *bd.DeviceName, nil
-				}
+// findDeviceName : finds the device name a volume is mapped to on a set of instances
+// instances : []*ec2.Instance : instances returned by DescribeInstances
+// volumeID : string : AWS EBS volume ID
+// returns : string : the device name
+// returns : bool : false if no instance maps the volume
+func findDeviceName(instances []*ec2.Instance, volumeID string) (string, bool) {
+	for _, inst := range instances {
+		// Loop over instance block device mappings, skipping those that aren't EBS volumes
+		for _, bd := range inst.BlockDeviceMappings {
+			if bd.Ebs != nil && aws.StringValue(bd.Ebs.VolumeId) == volumeID {
+				return aws.StringValue(bd.DeviceName), true
 			}
 		}
 	}
-
-	// Return error if no device name found
-	return "", fmt.Errorf("no device name found for volume ID %v", volumeID)
+	return "", false
 }
 
 // ValidateDeviceName : checks if the provided Device Name is valid
 // deviceName : string : AWS Device Name to validate
 // region : string : AWS region where the device is located
+// roleARN : string : role to assume, empty uses the role set by SetAssumeRoleARN
 // returns : bool : returns true if the Device Name is valid, false otherwise
 // returns : error : returns an error if any occur during the process
-func ValidateDeviceName(deviceName, region string) (bool, error) {
+func ValidateDeviceName(deviceName, region, roleARN string) (bool, error) {
 	// Create a new session
-	svc := NewSession(region)
+	svc := sessionFor(region, roleARN)
 
 	// Define input for DescribeVolumes call
 	input := &ec2.DescribeInstancesInput{
@@ -283,93 +603,162 @@ func ValidateDeviceName(deviceName, region string) (bool, error) {
 	// Call DescribeInstances API
 	_, err := svc.DescribeInstances(input)
 	if err != nil {
-		return false, fmt.Errorf("failed to get getting instance information from AWS. error: %w", err)
+		return false, fmt.Errorf("failed to get getting instance information from AWS. error: %w", wrapError(err))
 	}
 
 	return true, nil
 }
 
-// ValidateRegion : checks if the provided Region is valid
-// region : string : AWS Region to validate
-// returns : bool : returns true if the Region is valid, false otherwise
-// returns : error : returns an error if any occur during the process
-func ValidateRegion(region string) (bool, error) {
-	// Get all regions
-	regions, err := GetAllRegions()
-	if err != nil {
-		return false, err
-	}
-
-	// Check if the provided region is in the list of regions
-	for _, r := range regions {
-		if r == region {
-			return true, nil
-		}
-	}
-
-	// If the provided region is not found in the list of regions, return false
-	return false, nil
-}
-
 // GetLocalRegion : retrieves the region of the local EC2 instance from its metadata
 // returns : region : string : the region of the local EC2 instance
 // returns : err : error : any error that occurs during the process
 func GetLocalRegion() (string, error) {
-	// Create a new session
-	sess, err := session.NewSession()
-	if err != nil {
-		return "", err
-	}
-
-	// Create a new EC2Metadata client
-	ec2metadataSvc := ec2metadata.New(sess)
-
-	// Retrieve the region of the local EC2 instance
-	region, err := ec2metadataSvc.Region()
-	if err != nil {
-		return "", err
-	}
-
-	return region, nil
+	return metadata().Region()
 }
 
 // ResizeVolume: Resizes an EBS volume.
 // config: runtime.EBSVolumeConfig - Configuration for the EBS volume.
 // newSize: int64 - New size for the EBS volume.
+// bool: bool - Returns false if AWS reported the modification as a no-op (target size equal to the original size).
 // error: error - Returns an error if there was a problem resizing the volume or if the timeout is reached while waiting for the volume to resize.
-func ResizeVolume(config runtime.EBSVolumeConfig, newSize int64) error {
-	// Create a session
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String(config.AWSRegion)},
-	)
+func ResizeVolume(config runtime.EBSVolumeConfig, newSize int64) (bool, error) {
+	// Get the EC2 service client for the region
+	svc, err := ec2Client(config.AWSRegion, roleOrDefault(config.AssumeRoleARN))
+	if err != nil {
+		return false, fmt.Errorf("failed to get region information from AWS. error: %w", err)
+	}
 
+	// Modifying the EBS volume
+	modification, err := modifyVolume(svc, config, newSize)
 	if err != nil {
-		return fmt.Errorf("failed to get region information from AWS. error: %w", err)
+		return false, fmt.Errorf("failed to modify ebs volume in aws. error: %w", wrapError(err))
 	}
 
-	// Create a EC2 service client
-	svc := ec2.New(sess)
+	// A no-op modification never transitions through 'optimizing', so waiting on it would only run into the timeout
+	if isNoopModification(modification) && !config.WaitOnNoopModification {
+		return false, nil
+	}
 
-	// Modifying the EBS volume
-	modifyOutput, err := svc.ModifyVolume(&ec2.ModifyVolumeInput{
-		VolumeId: aws.String(config.AWSVolumeID),
-		Size:     aws.Int64(int64(newSize)),
+	// Waiting for the volume to enter the 'optimizing' state
+	err = svc.WaitUntilVolumeInUse(&ec2.DescribeVolumesInput{
+		VolumeIds: []*string{aws.String(config.AWSVolumeID)},
 	})
 
 	if err != nil {
-		return fmt.Errorf("failed to modify ebs volume in aws. error: %w", err)
+		return true, fmt.Errorf("failed to wait for volume to enter 'in-use' state again. error: %w", err)
 	}
 
-	// Waiting for the volume to enter the 'optimizing' state
-	err = svc.WaitUntilVolumeInUse(&ec2.DescribeVolumesInput{
-		VolumeIds: []*string{modifyOutput.VolumeModification.VolumeId},
+	return !isNoopModification(modification), nil
+}
+
+// modifyVolume : calls ModifyVolume, retrying while it fails with a transient error
+// ModifyVolume isn't idempotent, and a server error or timeout doesn't say whether the modification was made,
+// so before it is re-issued after one the volume's modifications are checked for it. Throttled calls were
+// rejected outright, so they are re-issued straight away.
+// svc : EC2API : the EC2 client for the volume's region
+// config : runtime.EBSVolumeConfig : configuration of the EBS volume
+// newSize : int64 : the new size of the EBS volume in GiB
+// returns : *ec2.VolumeModification : the modification made
+// returns : error : the last error, if the modification wasn't made
+func modifyVolume(svc EC2API, config runtime.EBSVolumeConfig, newSize int64) (*ec2.VolumeModification, error) {
+	var modification *ec2.VolumeModification
+	var lastErr error
+	err := withRetry(func() error {
+		if lastErr != nil && !isThrottled(lastErr) {
+			applied, err := appliedModification(config, newSize)
+			if err != nil {
+				return err
+			}
+			if applied != nil {
+				modification = applied
+				return nil
+			}
+		}
+		output, err := svc.ModifyVolume(buildModifyVolumeInput(config, newSize))
+		lastErr = err
+		if err == nil {
+			modification = output.VolumeModification
+		}
+		return err
 	})
+	return modification, err
+}
+
+// appliedModification : returns the volume's latest modification if it is a resize to a size that hasn't failed,
+// i.e. a ModifyVolume call that failed was applied anyway
+// config : runtime.EBSVolumeConfig : configuration of the EBS volume
+// newSize : int64 : the size the volume was being resized to in GiB
+// returns : *ec2.VolumeModification : the modification, nil if the resize wasn't applied
+// returns : error : returns an error if the modifications can't be described
+func appliedModification(config runtime.EBSVolumeConfig, newSize int64) (*ec2.VolumeModification, error) {
+	modification, err := getLatestModification(config)
+	if err != nil || modification == nil {
+		return nil, err
+	}
+	if aws.Int64Value(modification.TargetSize) != newSize || aws.StringValue(modification.ModificationState) == ec2.VolumeModificationStateFailed {
+		return nil, nil
+	}
+	return modification, nil
+}
+
+// CreateSnapshot : creates a snapshot of an EBS volume, e.g. as a rollback point before a resize
+// The snapshot is point-in-time as of the call, so the volume can be modified while it completes.
+// config : runtime.EBSVolumeConfig : configuration of the EBS volume
+// returns : string : the ID of the new snapshot
+// returns : error : returns an error if any occur during the process
+func CreateSnapshot(config runtime.EBSVolumeConfig) (string, error) {
+	// Create a new session
+	svc := sessionFor(config.AWSRegion, config.AssumeRoleARN)
 
+	snapshot, err := svc.CreateSnapshot(&ec2.CreateSnapshotInput{
+		VolumeId:    aws.String(config.AWSVolumeID),
+		Description: aws.String(fmt.Sprintf("ebs-monitor pre-resize snapshot of %s", config.AWSVolumeID)),
+		TagSpecifications: []*ec2.TagSpecification{
+			{
+				ResourceType: aws.String(ec2.ResourceTypeSnapshot),
+				Tags: []*ec2.Tag{
+					{Key: aws.String("CreatedBy"), Value: aws.String("ebs-monitor")},
+				},
+			},
+		},
+	})
 	if err != nil {
-		return fmt.Errorf("failed to wait for volume to enter 'in-use' state again. error: %w", err)
+		return "", fmt.Errorf("failed to create snapshot of ebs volume in aws. error: %w", wrapError(err))
 	}
 
-	return nil
+	return aws.StringValue(snapshot.SnapshotId), nil
+}
+
+// isNoopModification : checks if a volume modification leaves the volume size, IOPS and throughput unchanged.
+// modification : *ec2.VolumeModification : modification returned by ModifyVolume
+// returns : bool : true if every target value equals the original value
+func isNoopModification(modification *ec2.VolumeModification) bool {
+	if modification == nil || modification.TargetSize == nil || modification.OriginalSize == nil {
+		return false
+	}
+	return *modification.TargetSize == *modification.OriginalSize &&
+		aws.Int64Value(modification.TargetIops) == aws.Int64Value(modification.OriginalIops) &&
+		aws.Int64Value(modification.TargetThroughput) == aws.Int64Value(modification.OriginalThroughput)
+}
+
+// buildModifyVolumeInput : builds the ModifyVolume request for a resize
+// IOPS and throughput are only included when the volume sets targetIOPS or targetThroughput,
+// otherwise the request modifies the size alone.
+// config : runtime.EBSVolumeConfig : configuration of the EBS volume
+// newSize : int64 : the new size of the EBS volume in GiB
+// returns : *ec2.ModifyVolumeInput : the request
+func buildModifyVolumeInput(config runtime.EBSVolumeConfig, newSize int64) *ec2.ModifyVolumeInput {
+	input := &ec2.ModifyVolumeInput{
+		VolumeId: aws.String(config.AWSVolumeID),
+		Size:     aws.Int64(newSize),
+	}
+	if config.TargetIOPS > 0 {
+		input.Iops = aws.Int64(int64(config.TargetIOPS))
+	}
+	if config.TargetThroughput > 0 {
+		input.Throughput = aws.Int64(int64(config.TargetThroughput))
+	}
+	return input
 }
 
 // ChatbotMessage is a struct that reflects the message format for Chatbot to post to Slack
@@ -379,45 +768,56 @@ type ChatbotMessage struct {
 	NextSteps   []string `json:"nextSteps,omitempty"`
 }
 
+// snsPublishTimeout bounds each SNS publish call.
+const snsPublishTimeout = 30 * time.Second
+
+var (
+	// snsConfigsMu guards snsConfigs, as notifications are published from a background worker.
+	snsConfigsMu sync.Mutex
+	// snsConfigs caches the SDK config for each SNS region and assumed role, so credentials aren't resolved on every publish.
+	snsConfigs = make(map[clientKey]awsv2.Config)
+)
+
+// loadSNSConfig returns the SDK config for the SNS region, loading it on first use.
+// The config assumes the role set by SetAssumeRoleARN, if any.
+// snsRegion: string - AWS region of the SNS topic.
+// returns: awsv2.Config - The SDK config for the region.
+// returns: error - Returns an error if the config can't be loaded.
+func loadSNSConfig(snsRegion string) (awsv2.Config, error) {
+	snsConfigsMu.Lock()
+	defer snsConfigsMu.Unlock()
+
+	key := clientKey{region: snsRegion, roleARN: defaultRoleARN()}
+	if cfg, ok := snsConfigs[key]; ok {
+		return cfg, nil
+	}
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(snsRegion))
+	if err != nil {
+		return awsv2.Config{}, fmt.Errorf("unable to load SDK config, %v", err)
+	}
+	cfg = withAssumedRole(cfg, key.roleARN)
+	snsConfigs[key] = cfg
+	return cfg, nil
+}
+
 // PublishToSNS publishes a structured message to an SNS topic.
 // arn: string - ARN of the SNS topic.
 // snsRegion: string - AWS region of the SNS topic.
 // message: ChatbotMessage - The structured message to be published.
 // returns: error - Returns an error if any occur during the process.
 func PublishToSNS(arn string, snsRegion string, messageDescription string) error {
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(snsRegion))
-	if err != nil {
-		return fmt.Errorf("unable to load SDK config, %v", err)
-	}
-
-	// Get AWS account number
-	stsClient := sts.NewFromConfig(cfg)
-	identity, err := stsClient.GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
-	if err != nil {
-		return fmt.Errorf("unable to get AWS account number, %v", err)
-	}
-	accountNumber := awsv2.ToString(identity.Account)
-
-	// Get instance hostname
-	hostname, err := os.Hostname()
-	if err != nil {
-		return fmt.Errorf("unable to get hostname, %v", err)
-	}
-
-	// Get region of EC2 instance running ebs-monitor.service
-	instanceRegion, err := getCurrentRegion()
-
+	cfg, err := loadSNSConfig(snsRegion)
 	if err != nil {
-		return fmt.Errorf("unable to get instance region, %v", err)
+		return err
 	}
 
-	// Fetch the versions of ebs-monitor.service
-	runningVersion, latestVersion, err := GetEBSVersions()
+	// Get the cached account number, hostname, region and versions
+	enrichment, err := snsEnrichmentCache.get(cfg, runtime.Now())
 	if err != nil {
-		// Handle the error or set versions to a default or error value
-		runningVersion, latestVersion = "unknown", "unknown"
-		fmt.Println("Error: ", err)
+		return err
 	}
+	hostname, accountNumber, instanceRegion := enrichment.Hostname, enrichment.AccountNumber, enrichment.InstanceRegion
+	runningVersion, latestVersion := enrichment.RunningVersion, enrichment.LatestVersion
 
 	// Construct enriched message
 	msgContent := ChatbotMessage{
@@ -432,11 +832,13 @@ func PublishToSNS(arn string, snsRegion string, messageDescription string) error
 		},
 	}
 
-	// Check if an update is needed and include a warning message if so
-	if runningVersion < latestVersion {
+	// Check if an update is needed and include a warning message if so.
+	// Versions that can't be parsed, e.g. "unknown", are skipped.
+	comparison, comparable := compareVersions(runningVersion, latestVersion)
+	if comparable && comparison < 0 {
 		msgContent.NextSteps = append(msgContent.NextSteps, fmt.Sprintf(":warning: ebs-monitor needs to be updated from version %s to %s", runningVersion, latestVersion))
 	}
-	if runningVersion > latestVersion {
+	if comparable && comparison > 0 {
 		msgContent.NextSteps = append(msgContent.NextSteps, fmt.Sprintf(":grey_exclamation: ebs-monitor is running a pre-release version... this may lead to issues.\n\t\tRunning: %s\n\t\tAvailable: %s", runningVersion, latestVersion))
 	}
 
@@ -453,8 +855,11 @@ func PublishToSNS(arn string, snsRegion string, messageDescription string) error
 	}
 
 	// Publish the enriched message to SNS
+	// Bound the publish so a hung endpoint can't stall the notification queue
+	ctx, cancel := context.WithTimeout(context.Background(), snsPublishTimeout)
+	defer cancel()
 	client := sns.NewFromConfig(cfg)
-	_, err = client.Publish(context.TODO(), &sns.PublishInput{
+	_, err = client.Publish(ctx, &sns.PublishInput{
 		Message:  aws.String(string(messageJSON)),
 		TopicArn: aws.String(arn),
 	})
@@ -471,8 +876,47 @@ func PublishToSNS(arn string, snsRegion string, messageDescription string) error
 // returns : bool : returns true if the volume is in the 'optimizing' state, false otherwise
 // returns : error : returns an error if any occur during the process
 func CheckVolumeState(config runtime.EBSVolumeConfig) (bool, error) {
+	state, err := GetVolumeModificationState(config)
+	if err != nil {
+		return false, err
+	}
+	return state == ec2.VolumeModificationStateOptimizing, nil
+}
+
+// GetVolumeModificationState returns the state of the latest modification of the specified EBS volume,
+// e.g. 'modifying', 'optimizing' or 'completed'.
+// config : runtime.EBSVolumeConfig : configuration of the EBS volume
+// returns : string : the modification state, or an empty string if the volume has never been modified
+// returns : error : returns an error if any occur during the process
+func GetVolumeModificationState(config runtime.EBSVolumeConfig) (string, error) {
+	modification, err := getLatestModification(config)
+	if err != nil || modification == nil {
+		return "", err
+	}
+	return aws.StringValue(modification.ModificationState), nil
+}
+
+// GetModificationProgress returns the progress and state of the latest modification of the specified EBS volume,
+// e.g. 40 and 'optimizing'.
+// config : runtime.EBSVolumeConfig : configuration of the EBS volume
+// returns : int64 : the modification progress as a percentage, 0 if the volume has never been modified
+// returns : string : the modification state, or an empty string if the volume has never been modified
+// returns : error : returns an error if any occur during the process
+func GetModificationProgress(config runtime.EBSVolumeConfig) (int64, string, error) {
+	modification, err := getLatestModification(config)
+	if err != nil || modification == nil {
+		return 0, "", err
+	}
+	return aws.Int64Value(modification.Progress), aws.StringValue(modification.ModificationState), nil
+}
+
+// getLatestModification returns the latest modification of the specified EBS volume from DescribeVolumesModifications.
+// config : runtime.EBSVolumeConfig : configuration of the EBS volume
+// returns : *ec2.VolumeModification : the modification, or nil if the volume has never been modified
+// returns : error : returns an error if any occur during the process
+func getLatestModification(config runtime.EBSVolumeConfig) (*ec2.VolumeModification, error) {
 	// Create a new session
-	svc := NewSession(config.AWSRegion)
+	svc := sessionFor(config.AWSRegion, config.AssumeRoleARN)
 
 	// Define input for DescribeVolumesModifications call
 	input := &ec2.DescribeVolumesModificationsInput{
@@ -481,33 +925,26 @@ func CheckVolumeState(config runtime.EBSVolumeConfig) (bool, error) {
 		},
 	}
 
-	// Call DescribeVolumesModifications API
-	result, err := svc.DescribeVolumesModifications(input)
+	// Call DescribeVolumesModifications API, retrying on throttling
+	var result *ec2.DescribeVolumesModificationsOutput
+	err := withRetry(func() (err error) {
+		result, err = svc.DescribeVolumesModifications(input)
+		return err
+	})
 	if err != nil {
 		// Check for the specific error of no modifications
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "InvalidVolumeModification.NotFound":
-				return false, nil // No modifications, return false with no error
-			default:
-				return false, fmt.Errorf("failed to get volume modification information from AWS. error: %w", err)
-			}
-		} else {
-			return false, fmt.Errorf("failed to get volume modification information from AWS. error: %w", err)
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "InvalidVolumeModification.NotFound" {
+			return nil, nil // No modifications, return no modification with no error
 		}
+		return nil, fmt.Errorf("failed to get volume modification information from AWS. error: %w", wrapError(err))
 	}
 
 	// Check if volume modification was found
 	if len(result.VolumesModifications) == 0 {
-		return false, fmt.Errorf("failed to find volume modification information. error: %w", err)
-	}
-
-	// Check the modification state of the volume
-	if *result.VolumesModifications[0].ModificationState == ec2.VolumeModificationStateOptimizing {
-		return true, nil
+		return nil, fmt.Errorf("failed to find volume modification information")
 	}
 
-	return false, nil
+	return result.VolumesModifications[0], nil
 }
 
 // -----------------------------------------------------------------
@@ -516,42 +953,75 @@ func CheckVolumeState(config runtime.EBSVolumeConfig) (bool, error) {
 // CIRCULAR DEPENDENCIES.. SO HERE WE ARE
 // -----------------------------------------------------------------
 
-// GetEBSVersions : fetches the running version and the latest available version of ebs-monitor.service.
-// returns : string : Running version of the ebs-monitor.service
-// returns : string : Latest available version for installation
-// returns : error : Potential errors during the operation
-func GetEBSVersions() (string, string, error) {
-	// Get the running version
-	cmd := exec.Command("ebsmon", "--version")
-	runningVersionBytes, err := cmd.Output()
-	if err != nil {
-		return "", "", err
+// UnknownVersion : reported for a version of ebs-monitor that couldn't be determined
+const UnknownVersion = "unknown"
+
+// ebsVersions : the running and latest versions of ebs-monitor, loaded once at startup by LoadEBSVersions
+var ebsVersions = struct {
+	mu      sync.RWMutex
+	running string
+	latest  string
+}{running: UnknownVersion, latest: UnknownVersion}
+
+// LoadEBSVersions : determines the running and latest versions of ebs-monitor and caches them for notifications.
+// It is called once at startup so publishing a notification never shells out. Versions that can't be determined
+// are cached as UnknownVersion, without logging an error.
+// running : string : version the binary was built with, empty if unknown
+// checkApt : bool : whether to ask apt for the installed and candidate versions, only useful on Debian based hosts
+func LoadEBSVersions(running string, checkApt bool) {
+	latest := ""
+	if checkApt {
+		if output, err := exec.Command("apt-cache", "policy", "ebs-monitor").Output(); err == nil {
+			installed, candidate := parseAptPolicy(string(output))
+			if running == "" {
+				running = installed
+			}
+			latest = candidate
+		}
 	}
-	runningVersion := strings.TrimSpace(string(runningVersionBytes))
 
-	// Get the version details using apt-cache policy
-	cmd = exec.Command("apt-cache", "policy", "ebs-monitor")
-	aptOutputBytes, err := cmd.Output()
-	if err != nil {
-		return runningVersion, "", err
-	}
-	aptOutput := string(aptOutputBytes)
+	ebsVersions.mu.Lock()
+	defer ebsVersions.mu.Unlock()
+	ebsVersions.running, ebsVersions.latest = versionOrUnknown(running), versionOrUnknown(latest)
+}
 
-	// Extract the installed version
-	reInstalled := regexp.MustCompile(`Installed: (\d+\.\d+\.\d+)`)
-	matchesInstalled := reInstalled.FindStringSubmatch(aptOutput)
-	if len(matchesInstalled) < 2 {
-		return runningVersion, "", fmt.Errorf("could not extract installed version from apt output")
-	}
-	installedVersion := matchesInstalled[1]
+// EBSVersions : returns the versions cached by LoadEBSVersions
+// returns : string : Running version of ebs-monitor, or UnknownVersion
+// returns : string : Latest available version for installation, or UnknownVersion
+func EBSVersions() (string, string) {
+	ebsVersions.mu.RLock()
+	defer ebsVersions.mu.RUnlock()
+	return ebsVersions.running, ebsVersions.latest
+}
 
-	// Extract the candidate version
-	reCandidate := regexp.MustCompile(`Candidate: (\d+\.\d+\.\d+)`)
-	matchesCandidate := reCandidate.FindStringSubmatch(aptOutput)
-	if len(matchesCandidate) < 2 {
-		return installedVersion, "", fmt.Errorf("could not extract candidate version from apt output")
+// parseAptPolicy : extracts the installed and candidate versions from the output of apt-cache policy
+// output : string : output of apt-cache policy ebs-monitor
+// returns : string : Installed version, empty if not found
+// returns : string : Candidate version, empty if not found
+func parseAptPolicy(output string) (string, string) {
+	installed, candidate := "", ""
+	if matches := aptInstalledRegex.FindStringSubmatch(output); len(matches) == 2 {
+		installed = matches[1]
+	}
+	if matches := aptCandidateRegex.FindStringSubmatch(output); len(matches) == 2 {
+		candidate = matches[1]
 	}
-	candidateVersion := matchesCandidate[1]
+	return installed, candidate
+}
+
+// aptInstalledRegex, aptCandidateRegex : match the versions in the output of apt-cache policy
+var (
+	aptInstalledRegex = regexp.MustCompile(`Installed: (\d+\.\d+\.\d+)`)
+	aptCandidateRegex = regexp.MustCompile(`Candidate: (\d+\.\d+\.\d+)`)
+)
 
-	return installedVersion, candidateVersion, nil
+// versionOrUnknown : returns the version, or UnknownVersion if it is empty
+// version : string : the version
+// returns : string : the version or UnknownVersion
+func versionOrUnknown(version string) string {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return UnknownVersion
+	}
+	return version
 }
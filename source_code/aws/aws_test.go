@@ -0,0 +1,329 @@
+package aws
+
+import (
+	"ebs-monitor/aws/awstest"
+	"ebs-monitor/runtime"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// TestBuildModifyVolumeInput tests that IOPS and throughput are only requested when configured.
+func TestBuildModifyVolumeInput(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         runtime.EBSVolumeConfig
+		wantIOPS       *int64
+		wantThroughput *int64
+	}{
+		{
+			name:   "size only",
+			config: runtime.EBSVolumeConfig{AWSVolumeID: "vol-0abcd1234efgh5678"},
+		},
+		{
+			name:           "gp3 with IOPS and throughput",
+			config:         runtime.EBSVolumeConfig{AWSVolumeID: "vol-0abcd1234efgh5678", TargetIOPS: 6000, TargetThroughput: 250},
+			wantIOPS:       aws.Int64(6000),
+			wantThroughput: aws.Int64(250),
+		},
+		{
+			name:     "IOPS only",
+			config:   runtime.EBSVolumeConfig{AWSVolumeID: "vol-0abcd1234efgh5678", TargetIOPS: 4000},
+			wantIOPS: aws.Int64(4000),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := buildModifyVolumeInput(tt.config, 30)
+			if aws.Int64Value(input.Size) != 30 || aws.StringValue(input.VolumeId) != tt.config.AWSVolumeID {
+				t.Errorf("buildModifyVolumeInput() size/volume = %v/%v, want 30/%v", input.Size, input.VolumeId, tt.config.AWSVolumeID)
+			}
+			if (input.Iops == nil) != (tt.wantIOPS == nil) || aws.Int64Value(input.Iops) != aws.Int64Value(tt.wantIOPS) {
+				t.Errorf("buildModifyVolumeInput() Iops = %v, want %v", input.Iops, tt.wantIOPS)
+			}
+			if (input.Throughput == nil) != (tt.wantThroughput == nil) || aws.Int64Value(input.Throughput) != aws.Int64Value(tt.wantThroughput) {
+				t.Errorf("buildModifyVolumeInput() Throughput = %v, want %v", input.Throughput, tt.wantThroughput)
+			}
+		})
+	}
+}
+
+// TestIsNoopModification tests that a modification changing only IOPS or throughput is not a no-op.
+func TestIsNoopModification(t *testing.T) {
+	tests := []struct {
+		name         string
+		modification *ec2.VolumeModification
+		want         bool
+	}{
+		{"nil modification", nil, false},
+		{"size unchanged", &ec2.VolumeModification{OriginalSize: aws.Int64(20), TargetSize: aws.Int64(20)}, true},
+		{"size grown", &ec2.VolumeModification{OriginalSize: aws.Int64(20), TargetSize: aws.Int64(30)}, false},
+		{
+			"size unchanged, IOPS raised",
+			&ec2.VolumeModification{OriginalSize: aws.Int64(20), TargetSize: aws.Int64(20), OriginalIops: aws.Int64(3000), TargetIops: aws.Int64(6000)},
+			false,
+		},
+		{
+			"size unchanged, throughput raised",
+			&ec2.VolumeModification{OriginalSize: aws.Int64(20), TargetSize: aws.Int64(20), OriginalThroughput: aws.Int64(125), TargetThroughput: aws.Int64(250)},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNoopModification(tt.modification); got != tt.want {
+				t.Errorf("isNoopModification() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEC2ClientCache tests that EC2 clients are reused per region.
+func TestEC2ClientCache(t *testing.T) {
+	first, err := ec2Client("ap-southeast-2", "")
+	if err != nil {
+		t.Fatalf("ec2Client() error = %v", err)
+	}
+	second, _ := ec2Client("ap-southeast-2", "")
+	other, _ := ec2Client("us-east-1", "")
+
+	if first != second {
+		t.Errorf("ec2Client() returned a new client for the same region")
+	}
+	if first == other {
+		t.Errorf("ec2Client() returned the same client for different regions")
+	}
+	if got := first.(*ec2.EC2).Config.MaxRetries; got == nil || *got != 0 {
+		t.Errorf("ec2Client() MaxRetries = %v, want 0 so only withRetry retries", got)
+	}
+	if got := NewSession("ap-southeast-2"); got != first {
+		t.Errorf("NewSession() did not return the cached client")
+	}
+
+	assumed, err := ec2Client("ap-southeast-2", "arn:aws:iam::123456789012:role/ebs-monitor")
+	if err != nil {
+		t.Fatalf("ec2Client() error = %v", err)
+	}
+	if assumed == first {
+		t.Errorf("ec2Client() returned the same client with and without an assumed role")
+	}
+	if assumed.(*ec2.EC2).Config.Credentials == first.(*ec2.EC2).Config.Credentials {
+		t.Errorf("ec2Client() did not use the assumed role's credentials")
+	}
+}
+
+// TestResizeVolumeRetries tests that a ModifyVolume call failing with a server error isn't made again once it
+// has been applied, while a throttled one is.
+func TestResizeVolumeRetries(t *testing.T) {
+	sleep = func(time.Duration) {}
+	SetMaxRetries(2)
+	defer func() {
+		sleep = time.Sleep
+		SetMaxRetries(0)
+		SetEC2Client(nil)
+	}()
+	config := runtime.EBSVolumeConfig{AWSVolumeID: "vol-1", AWSRegion: "us-east-1"}
+	modifyCalls := func(fake *awstest.FakeEC2) int {
+		count := 0
+		for _, call := range fake.Calls {
+			if call == "ModifyVolume" {
+				count++
+			}
+		}
+		return count
+	}
+
+	lost := &awstest.FakeEC2{Volumes: []*ec2.Volume{awstest.NewFakeVolume("vol-1", "i-1", "/dev/sdf", 100)}, LostResponses: 1}
+	SetEC2Client(lost)
+	if modified, err := ResizeVolume(config, 120); err != nil || !modified {
+		t.Fatalf("ResizeVolume() after a lost response = (%v, %v), want (true, nil)", modified, err)
+	}
+	if got := modifyCalls(lost); got != 1 {
+		t.Errorf("ModifyVolume calls after a lost response = %d, want 1", got)
+	}
+
+	throttled := &awstest.FakeEC2{Volumes: []*ec2.Volume{awstest.NewFakeVolume("vol-1", "i-1", "/dev/sdf", 100)}, ModifyErr: awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil)}
+	SetEC2Client(throttled)
+	if _, err := ResizeVolume(config, 120); err == nil {
+		t.Fatal("ResizeVolume() while throttled error = nil, want an error")
+	}
+	if got := modifyCalls(throttled); got != 3 {
+		t.Errorf("ModifyVolume calls while throttled = %d, want 3", got)
+	}
+}
+
+// TestRoleOrDefault tests that a call's role overrides the role set by SetAssumeRoleARN.
+func TestRoleOrDefault(t *testing.T) {
+	defer SetAssumeRoleARN("")
+
+	const (
+		defaultRole = "arn:aws:iam::123456789012:role/default"
+		volumeRole  = "arn:aws:iam::210987654321:role/volume"
+	)
+
+	if got := roleOrDefault(""); got != "" {
+		t.Errorf("roleOrDefault() = %q with no roles set, want empty", got)
+	}
+
+	SetAssumeRoleARN(defaultRole)
+	if got := roleOrDefault(""); got != defaultRole {
+		t.Errorf("roleOrDefault() = %q, want %q", got, defaultRole)
+	}
+	if got := roleOrDefault(volumeRole); got != volumeRole {
+		t.Errorf("roleOrDefault() = %q, want %q", got, volumeRole)
+	}
+}
+
+// TestBuildTagFilters tests that every tag and the instance attachment are filtered on, in a stable order.
+func TestBuildTagFilters(t *testing.T) {
+	filters := buildTagFilters(map[string]string{"Role": "data", "Env": "prod"}, "i-0123")
+
+	want := []struct{ name, value string }{
+		{"tag:Env", "prod"},
+		{"tag:Role", "data"},
+		{"attachment.instance-id", "i-0123"},
+	}
+	if len(filters) != len(want) {
+		t.Fatalf("buildTagFilters() returned %d filters, want %d", len(filters), len(want))
+	}
+	for i, w := range want {
+		if got := aws.StringValue(filters[i].Name); got != w.name {
+			t.Errorf("filter %d Name = %q, want %q", i, got, w.name)
+		}
+		if got := aws.StringValueSlice(filters[i].Values); len(got) != 1 || got[0] != w.value {
+			t.Errorf("filter %d Values = %v, want [%s]", i, got, w.value)
+		}
+	}
+}
+
+// TestVolumesAttachedTo tests that discovered volumes use their device name on the local instance.
+func TestVolumesAttachedTo(t *testing.T) {
+	attachment := func(instanceID, device string) *ec2.VolumeAttachment {
+		return &ec2.VolumeAttachment{InstanceId: aws.String(instanceID), Device: aws.String(device)}
+	}
+	volumes := []*ec2.Volume{
+		{VolumeId: aws.String("vol-b"), Attachments: []*ec2.VolumeAttachment{attachment("i-0123", "/dev/sdg")}},
+		{VolumeId: aws.String("vol-a"), Attachments: []*ec2.VolumeAttachment{
+			attachment("i-other", "/dev/sdz"),
+			attachment("i-0123", "/dev/sdf"),
+		}},
+		{VolumeId: aws.String("vol-c"), Attachments: []*ec2.VolumeAttachment{attachment("i-other", "/dev/sdh")}},
+	}
+
+	got := volumesAttachedTo(volumes, "i-0123", "ap-southeast-2")
+	want := []runtime.EBSVolumeConfig{
+		{AWSVolumeID: "vol-a", AWSDeviceName: "/dev/sdf", AWSRegion: "ap-southeast-2"},
+		{AWSVolumeID: "vol-b", AWSDeviceName: "/dev/sdg", AWSRegion: "ap-southeast-2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("volumesAttachedTo() = %+v, want %+v", got, want)
+	}
+}
+
+// TestFindDeviceName tests that a volume's device name is found on any page's instances, skipping non-EBS mappings.
+func TestFindDeviceName(t *testing.T) {
+	mapping := func(device, volumeID string) *ec2.InstanceBlockDeviceMapping {
+		return &ec2.InstanceBlockDeviceMapping{
+			DeviceName: aws.String(device),
+			Ebs:        &ec2.EbsInstanceBlockDevice{VolumeId: aws.String(volumeID)},
+		}
+	}
+	instances := []*ec2.Instance{
+		{BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{{DeviceName: aws.String("/dev/sdb")}, mapping("/dev/xvda", "vol-root")}},
+		{BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{mapping("/dev/sdf", "vol-data")}},
+	}
+
+	tests := []struct {
+		name      string
+		volumeID  string
+		want      string
+		wantFound bool
+	}{
+		{"first instance", "vol-root", "/dev/xvda", true},
+		{"later instance", "vol-data", "/dev/sdf", true},
+		{"not attached", "vol-missing", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := findDeviceName(instances, tt.volumeID)
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("findDeviceName() = %v, %v, want %v, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+// TestAttachedVolumes tests that attached volumes carry their size and type along with their device name.
+func TestAttachedVolumes(t *testing.T) {
+	volumes := []*ec2.Volume{
+		{
+			VolumeId:    aws.String("vol-b"),
+			Size:        aws.Int64(200),
+			VolumeType:  aws.String("gp3"),
+			Attachments: []*ec2.VolumeAttachment{{InstanceId: aws.String("i-0123"), Device: aws.String("/dev/sdg")}},
+		},
+		{
+			VolumeId:    aws.String("vol-a"),
+			Size:        aws.Int64(8),
+			VolumeType:  aws.String("gp2"),
+			Attachments: []*ec2.VolumeAttachment{{InstanceId: aws.String("i-0123"), Device: aws.String("/dev/xvda")}},
+		},
+		{
+			VolumeId:    aws.String("vol-c"),
+			Size:        aws.Int64(50),
+			Attachments: []*ec2.VolumeAttachment{{InstanceId: aws.String("i-other"), Device: aws.String("/dev/sdh")}},
+		},
+	}
+
+	got := attachedVolumes(volumes, "i-0123", "ap-southeast-2")
+	want := []AttachedVolume{
+		{AWSVolumeID: "vol-a", AWSDeviceName: "/dev/xvda", AWSRegion: "ap-southeast-2", SizeGiB: 8, VolumeType: "gp2"},
+		{AWSVolumeID: "vol-b", AWSDeviceName: "/dev/sdg", AWSRegion: "ap-southeast-2", SizeGiB: 200, VolumeType: "gp3"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("attachedVolumes() = %+v, want %+v", got, want)
+	}
+}
+
+// TestParseAptPolicy tests that the installed and candidate versions are read from apt-cache policy output.
+func TestParseAptPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		output        string
+		wantInstalled string
+		wantCandidate string
+	}{
+		{"installed and candidate", "ebs-monitor:\n  Installed: 1.2.3\n  Candidate: 1.3.0\n  Version table:\n", "1.2.3", "1.3.0"},
+		{"not installed", "ebs-monitor:\n  Installed: (none)\n  Candidate: 1.3.0\n", "", "1.3.0"},
+		{"unknown package", "N: Unable to locate package ebs-monitor\n", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installed, candidate := parseAptPolicy(tt.output)
+			if installed != tt.wantInstalled || candidate != tt.wantCandidate {
+				t.Errorf("parseAptPolicy() = %v, %v, want %v, %v", installed, candidate, tt.wantInstalled, tt.wantCandidate)
+			}
+		})
+	}
+}
+
+// TestLoadEBSVersionsWithoutApt tests that versions degrade to unknown when apt isn't checked.
+func TestLoadEBSVersionsWithoutApt(t *testing.T) {
+	LoadEBSVersions("", false)
+	if running, latest := EBSVersions(); running != UnknownVersion || latest != UnknownVersion {
+		t.Errorf("EBSVersions() = %v, %v, want %v, %v", running, latest, UnknownVersion, UnknownVersion)
+	}
+
+	LoadEBSVersions(" 1.2.3\n", false)
+	if running, latest := EBSVersions(); running != "1.2.3" || latest != UnknownVersion {
+		t.Errorf("EBSVersions() = %v, %v, want 1.2.3, %v", running, latest, UnknownVersion)
+	}
+}
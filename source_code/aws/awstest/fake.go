@@ -0,0 +1,337 @@
+// Package awstest provides in-memory fakes of the AWS APIs the aws package calls, for tests.
+package awstest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// FakeEC2 is an in-memory aws.EC2API for tests, installed with aws.SetEC2Client.
+// It answers from its volumes, instances and modifications, and ModifyVolume updates them as AWS would.
+// Request filters other than volume and instance IDs are ignored.
+type FakeEC2 struct {
+	mu            sync.Mutex
+	Volumes       []*ec2.Volume             // Volumes DescribeVolumes returns and ModifyVolume resizes.
+	Instances     []*ec2.Instance           // Instances DescribeInstances returns.
+	Modifications []*ec2.VolumeModification // Volume modifications, latest first.
+	Regions       []string                  // Regions DescribeRegions returns.
+	Err           error                     // Returned by every call when set.
+	ModifyErr     error                     // Returned by ModifyVolume when set, e.g. an awserr for a rate limited modification.
+	LostResponses int                       // ModifyVolume calls that make the modification but then fail with a server error, as when the response is lost.
+	Calls         []string                  // Names of the calls made, in order.
+	snapshots     int
+}
+
+// dryRunSucceededCode : error code EC2 returns for a DryRun request that would have succeeded
+const dryRunSucceededCode = "DryRunOperation"
+
+// FakeMetadata is an aws.InstanceMetadata for tests, installed with aws.SetInstanceMetadata.
+type FakeMetadata struct {
+	ID       string // Instance ID returned by InstanceID.
+	RegionID string // Region returned by Region.
+	Err      error  // Returned by every call when set.
+}
+
+// InstanceID returns the fake instance ID.
+func (m FakeMetadata) InstanceID() (string, error) {
+	return m.ID, m.Err
+}
+
+// Region returns the fake region.
+func (m FakeMetadata) Region() (string, error) {
+	return m.RegionID, m.Err
+}
+
+// call : records a call and returns the error every call fails with, if set
+// name : string : the name of the call
+// returns : error : the configured Err
+func (f *FakeEC2) call(name string) error {
+	f.Calls = append(f.Calls, name)
+	return f.Err
+}
+
+// findVolume : returns the fake volume with an ID
+// volumeID : string : the volume ID
+// returns : *ec2.Volume : the volume, nil if there is none
+func (f *FakeEC2) findVolume(volumeID string) *ec2.Volume {
+	for _, volume := range f.Volumes {
+		if aws.StringValue(volume.VolumeId) == volumeID {
+			return volume
+		}
+	}
+	return nil
+}
+
+// DescribeVolumes returns the volumes with the requested IDs, or every volume if none are requested.
+func (f *FakeEC2) DescribeVolumes(input *ec2.DescribeVolumesInput) (*ec2.DescribeVolumesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DescribeVolumes"); err != nil {
+		return nil, err
+	}
+	if len(input.VolumeIds) == 0 {
+		return &ec2.DescribeVolumesOutput{Volumes: f.Volumes}, nil
+	}
+	var volumes []*ec2.Volume
+	for _, id := range input.VolumeIds {
+		volume := f.findVolume(aws.StringValue(id))
+		if volume == nil {
+			return nil, awserr.New("InvalidVolume.NotFound", fmt.Sprintf("The volume '%s' does not exist.", aws.StringValue(id)), nil)
+		}
+		volumes = append(volumes, volume)
+	}
+	return &ec2.DescribeVolumesOutput{Volumes: volumes}, nil
+}
+
+// DescribeVolumesPages returns the volumes DescribeVolumes would, as one page.
+func (f *FakeEC2) DescribeVolumesPages(input *ec2.DescribeVolumesInput, fn func(*ec2.DescribeVolumesOutput, bool) bool) error {
+	output, err := f.DescribeVolumes(input)
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
+
+// DescribeVolumesModifications returns the modifications of the requested volumes, latest first.
+func (f *FakeEC2) DescribeVolumesModifications(input *ec2.DescribeVolumesModificationsInput) (*ec2.DescribeVolumesModificationsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DescribeVolumesModifications"); err != nil {
+		return nil, err
+	}
+	requested := make(map[string]bool)
+	for _, id := range input.VolumeIds {
+		requested[aws.StringValue(id)] = true
+	}
+	var modifications []*ec2.VolumeModification
+	for _, modification := range f.Modifications {
+		if len(requested) == 0 || requested[aws.StringValue(modification.VolumeId)] {
+			modifications = append(modifications, modification)
+		}
+	}
+	if len(modifications) == 0 {
+		return nil, awserr.New("InvalidVolumeModification.NotFound", "No modifications found.", nil)
+	}
+	return &ec2.DescribeVolumesModificationsOutput{VolumesModifications: modifications}, nil
+}
+
+// DescribeInstances returns the instances with the requested IDs, by InstanceIds or an instance-id filter,
+// or every instance if none are requested.
+func (f *FakeEC2) DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DescribeInstances"); err != nil {
+		return nil, err
+	}
+	requested := make(map[string]bool)
+	for _, id := range input.InstanceIds {
+		requested[aws.StringValue(id)] = true
+	}
+	for _, filter := range input.Filters {
+		if aws.StringValue(filter.Name) == "instance-id" {
+			for _, id := range filter.Values {
+				requested[aws.StringValue(id)] = true
+			}
+		}
+	}
+	var instances []*ec2.Instance
+	for _, instance := range f.Instances {
+		if len(requested) == 0 || requested[aws.StringValue(instance.InstanceId)] {
+			instances = append(instances, instance)
+		}
+	}
+	return &ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: instances}}}, nil
+}
+
+// DescribeInstancesPages returns the instances DescribeInstances would, as one page.
+func (f *FakeEC2) DescribeInstancesPages(input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+	output, err := f.DescribeInstances(input)
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
+
+// DescribeRegions returns the fake regions.
+func (f *FakeEC2) DescribeRegions(*ec2.DescribeRegionsInput) (*ec2.DescribeRegionsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DescribeRegions"); err != nil {
+		return nil, err
+	}
+	output := &ec2.DescribeRegionsOutput{}
+	for _, region := range f.Regions {
+		output.Regions = append(output.Regions, &ec2.Region{RegionName: aws.String(region)})
+	}
+	return output, nil
+}
+
+// ModifyVolume resizes the fake volume and records the modification, in the 'modifying' state.
+// A DryRun request is answered with DryRunOperation, as AWS answers a permitted one.
+func (f *FakeEC2) ModifyVolume(input *ec2.ModifyVolumeInput) (*ec2.ModifyVolumeOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("ModifyVolume"); err != nil {
+		return nil, err
+	}
+	if aws.BoolValue(input.DryRun) {
+		return nil, awserr.New(dryRunSucceededCode, "Request would have succeeded, but DryRun flag is set.", nil)
+	}
+	if f.ModifyErr != nil {
+		return nil, f.ModifyErr
+	}
+	volume := f.findVolume(aws.StringValue(input.VolumeId))
+	if volume == nil {
+		return nil, awserr.New("InvalidVolume.NotFound", fmt.Sprintf("The volume '%s' does not exist.", aws.StringValue(input.VolumeId)), nil)
+	}
+
+	modification := &ec2.VolumeModification{
+		VolumeId:           volume.VolumeId,
+		ModificationState:  aws.String(ec2.VolumeModificationStateModifying),
+		Progress:           aws.Int64(0),
+		OriginalSize:       volume.Size,
+		TargetSize:         volume.Size,
+		OriginalIops:       volume.Iops,
+		TargetIops:         volume.Iops,
+		OriginalThroughput: volume.Throughput,
+		TargetThroughput:   volume.Throughput,
+	}
+	if input.Size != nil {
+		modification.TargetSize = input.Size
+		volume.Size = aws.Int64(*input.Size)
+	}
+	if input.Iops != nil {
+		modification.TargetIops = input.Iops
+		volume.Iops = aws.Int64(*input.Iops)
+	}
+	if input.Throughput != nil {
+		modification.TargetThroughput = input.Throughput
+		volume.Throughput = aws.Int64(*input.Throughput)
+	}
+	f.Modifications = append([]*ec2.VolumeModification{modification}, f.Modifications...)
+	if f.LostResponses > 0 {
+		f.LostResponses--
+		return nil, awserr.NewRequestFailure(awserr.New("InternalError", "An internal error has occurred.", nil), 500, "request-id")
+	}
+	return &ec2.ModifyVolumeOutput{VolumeModification: modification}, nil
+}
+
+// WaitUntilVolumeInUse returns at once, as fake volumes are always in use.
+func (f *FakeEC2) WaitUntilVolumeInUse(*ec2.DescribeVolumesInput) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.call("WaitUntilVolumeInUse")
+}
+
+// CreateSnapshot returns a new snapshot ID for the volume.
+func (f *FakeEC2) CreateSnapshot(input *ec2.CreateSnapshotInput) (*ec2.Snapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("CreateSnapshot"); err != nil {
+		return nil, err
+	}
+	if f.findVolume(aws.StringValue(input.VolumeId)) == nil {
+		return nil, awserr.New("InvalidVolume.NotFound", fmt.Sprintf("The volume '%s' does not exist.", aws.StringValue(input.VolumeId)), nil)
+	}
+	f.snapshots++
+	return &ec2.Snapshot{
+		SnapshotId: aws.String(fmt.Sprintf("snap-%017d", f.snapshots)),
+		VolumeId:   input.VolumeId,
+	}, nil
+}
+
+// CreateTags sets the tags on the volumes, replacing any with the same key.
+func (f *FakeEC2) CreateTags(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("CreateTags"); err != nil {
+		return nil, err
+	}
+	for _, id := range input.Resources {
+		volume := f.findVolume(aws.StringValue(id))
+		if volume == nil {
+			return nil, awserr.New("InvalidVolume.NotFound", fmt.Sprintf("The volume '%s' does not exist.", aws.StringValue(id)), nil)
+		}
+		for _, tag := range input.Tags {
+			volume.Tags = append(removeTag(volume.Tags, tag.Key, nil), &ec2.Tag{Key: tag.Key, Value: tag.Value})
+		}
+	}
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+// DeleteTags removes the tags from the volumes, only where the value matches when one is given, as AWS does.
+func (f *FakeEC2) DeleteTags(input *ec2.DeleteTagsInput) (*ec2.DeleteTagsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DeleteTags"); err != nil {
+		return nil, err
+	}
+	for _, id := range input.Resources {
+		volume := f.findVolume(aws.StringValue(id))
+		if volume == nil {
+			return nil, awserr.New("InvalidVolume.NotFound", fmt.Sprintf("The volume '%s' does not exist.", aws.StringValue(id)), nil)
+		}
+		for _, tag := range input.Tags {
+			volume.Tags = removeTag(volume.Tags, tag.Key, tag.Value)
+		}
+	}
+	return &ec2.DeleteTagsOutput{}, nil
+}
+
+// removeTag : removes a tag from a list of tags
+// tags : []*ec2.Tag : the tags
+// key : *string : the key of the tag to remove
+// value : *string : the value the tag must have to be removed, nil removes it whatever its value
+// returns : []*ec2.Tag : the remaining tags
+func removeTag(tags []*ec2.Tag, key, value *string) []*ec2.Tag {
+	var kept []*ec2.Tag
+	for _, tag := range tags {
+		if aws.StringValue(tag.Key) == aws.StringValue(key) && (value == nil || aws.StringValue(tag.Value) == aws.StringValue(value)) {
+			continue
+		}
+		kept = append(kept, tag)
+	}
+	return kept
+}
+
+// NewFakeVolume : builds an in-use gp3 volume attached to an instance, for a FakeEC2
+// volumeID : string : the volume ID
+// instanceID : string : the instance the volume is attached to
+// deviceName : string : the device name of the attachment, e.g. /dev/sdf
+// sizeGiB : int64 : the size of the volume in GiB
+// returns : *ec2.Volume : the volume
+func NewFakeVolume(volumeID, instanceID, deviceName string, sizeGiB int64) *ec2.Volume {
+	return &ec2.Volume{
+		VolumeId:   aws.String(volumeID),
+		VolumeType: aws.String(ec2.VolumeTypeGp3),
+		Size:       aws.Int64(sizeGiB),
+		State:      aws.String(ec2.VolumeStateInUse),
+		Attachments: []*ec2.VolumeAttachment{{
+			InstanceId: aws.String(instanceID),
+			Device:     aws.String(deviceName),
+			VolumeId:   aws.String(volumeID),
+			State:      aws.String(ec2.VolumeAttachmentStateAttached),
+		}},
+	}
+}
+
+// NewFakeInstance : builds an instance with EBS volumes mapped to device names, for a FakeEC2
+// instanceID : string : the instance ID
+// devices : map[string]string : volume ID attached at each device name
+// returns : *ec2.Instance : the instance
+func NewFakeInstance(instanceID string, devices map[string]string) *ec2.Instance {
+	instance := &ec2.Instance{InstanceId: aws.String(instanceID)}
+	for deviceName, volumeID := range devices {
+		instance.BlockDeviceMappings = append(instance.BlockDeviceMappings, &ec2.InstanceBlockDeviceMapping{
+			DeviceName: aws.String(deviceName),
+			Ebs:        &ec2.EbsInstanceBlockDevice{VolumeId: aws.String(volumeID)},
+		})
+	}
+	return instance
+}
@@ -0,0 +1,11 @@
+package awstest_test
+
+import (
+	"ebs-monitor/aws"
+	"ebs-monitor/aws/awstest"
+)
+
+var (
+	_ aws.EC2API           = (*awstest.FakeEC2)(nil)
+	_ aws.InstanceMetadata = awstest.FakeMetadata{}
+)
@@ -0,0 +1,104 @@
+package aws
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// EC2API is the subset of the EC2 service client this package calls, so a fake can stand in for AWS in tests.
+// *ec2.EC2 satisfies it.
+type EC2API interface {
+	DescribeVolumes(input *ec2.DescribeVolumesInput) (*ec2.DescribeVolumesOutput, error)
+	DescribeVolumesPages(input *ec2.DescribeVolumesInput, fn func(*ec2.DescribeVolumesOutput, bool) bool) error
+	DescribeVolumesModifications(input *ec2.DescribeVolumesModificationsInput) (*ec2.DescribeVolumesModificationsOutput, error)
+	DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error)
+	DescribeInstancesPages(input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error
+	DescribeRegions(input *ec2.DescribeRegionsInput) (*ec2.DescribeRegionsOutput, error)
+	ModifyVolume(input *ec2.ModifyVolumeInput) (*ec2.ModifyVolumeOutput, error)
+	WaitUntilVolumeInUse(input *ec2.DescribeVolumesInput) error
+	CreateSnapshot(input *ec2.CreateSnapshotInput) (*ec2.Snapshot, error)
+	CreateTags(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error)
+	DeleteTags(input *ec2.DeleteTagsInput) (*ec2.DeleteTagsOutput, error)
+}
+
+var _ EC2API = (*ec2.EC2)(nil)
+
+// SetEC2Client : replaces the EC2 service client of every region and role, e.g. with an awstest.FakeEC2 in tests
+// client : EC2API : the client to use, nil restores the AWS clients
+func SetEC2Client(client EC2API) {
+	ec2ClientsMu.Lock()
+	defer ec2ClientsMu.Unlock()
+	ec2Override = client
+}
+
+// InstanceMetadata is the local instance's identity, read from the instance metadata service.
+type InstanceMetadata interface {
+	InstanceID() (string, error)
+	Region() (string, error)
+}
+
+// imdsMetadata : reads the instance metadata service of the instance ebs-monitor runs on
+type imdsMetadata struct{}
+
+// InstanceID : fetches the instance ID from the instance identity document
+// returns : string : the instance ID
+// returns : error : returns an error if the metadata service can't be reached
+func (imdsMetadata) InstanceID() (string, error) {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		return "", err
+	}
+
+	client := imds.NewFromConfig(cfg)
+	resp, err := client.GetInstanceIdentityDocument(context.TODO(), &imds.GetInstanceIdentityDocumentInput{})
+	if err != nil {
+		return "", err
+	}
+
+	return resp.InstanceID, nil
+}
+
+// Region : fetches the region of the instance
+// returns : string : the region
+// returns : error : returns an error if the metadata service can't be reached
+func (imdsMetadata) Region() (string, error) {
+	// Create a new session
+	sess, err := session.NewSession()
+	if err != nil {
+		return "", err
+	}
+
+	// Retrieve the region of the local EC2 instance
+	return ec2metadata.New(sess).Region()
+}
+
+var (
+	// metadataMu guards instanceMetadata, which tests replace with an awstest.FakeMetadata.
+	metadataMu       sync.Mutex
+	instanceMetadata InstanceMetadata = imdsMetadata{}
+)
+
+// SetInstanceMetadata : replaces where the local instance's ID and region are read from, e.g. with an awstest.FakeMetadata in tests
+// m : InstanceMetadata : the metadata to use, nil restores the instance metadata service
+func SetInstanceMetadata(m InstanceMetadata) {
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+	if m == nil {
+		m = imdsMetadata{}
+	}
+	instanceMetadata = m
+}
+
+// metadata : returns the instance metadata set by SetInstanceMetadata
+// returns : InstanceMetadata : the instance metadata
+func metadata() InstanceMetadata {
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+	return instanceMetadata
+}
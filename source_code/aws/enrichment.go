@@ -0,0 +1,95 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// enrichmentTTL : how long enrichment data is reused before it is looked up again.
+// The account and region lookups can fail transiently, so the data is refreshed rather than kept forever.
+const enrichmentTTL = time.Hour
+
+// snsEnrichment : host and version details added to every SNS message
+type snsEnrichment struct {
+	Hostname       string // Hostname of the instance.
+	AccountNumber  string // AWS account the instance runs in.
+	InstanceRegion string // Region of the instance.
+	RunningVersion string // Installed version of ebs-monitor.
+	LatestVersion  string // Latest version of ebs-monitor available.
+}
+
+// enrichmentCache : caches enrichment data so it isn't looked up on every publish
+type enrichmentCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	load     func(cfg awsv2.Config) (snsEnrichment, error)
+	value    snsEnrichment
+	loadedAt time.Time
+	loaded   bool
+}
+
+// snsEnrichmentCache : the enrichment cache used by PublishToSNS
+var snsEnrichmentCache = &enrichmentCache{ttl: enrichmentTTL, load: loadSNSEnrichment}
+
+// get : returns the cached enrichment data, loading it when missing or older than the TTL
+// Failed loads aren't cached, so the next publish tries again.
+// cfg : awsv2.Config : SDK config used for the STS lookup
+// now : time.Time : the current time
+// returns : snsEnrichment : the enrichment data
+// returns : error : returns an error if the data had to be loaded and the load failed
+func (c *enrichmentCache) get(cfg awsv2.Config, now time.Time) (snsEnrichment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.loaded && now.Sub(c.loadedAt) < c.ttl {
+		return c.value, nil
+	}
+	value, err := c.load(cfg)
+	if err != nil {
+		return snsEnrichment{}, err
+	}
+	c.value, c.loadedAt, c.loaded = value, now, true
+	return value, nil
+}
+
+// loadSNSEnrichment : looks up the account number, hostname, region and versions
+// cfg : awsv2.Config : SDK config used for the STS lookup
+// returns : snsEnrichment : the enrichment data
+// returns : error : returns an error if any lookup fails
+func loadSNSEnrichment(cfg awsv2.Config) (snsEnrichment, error) {
+	// Get AWS account number
+	stsClient := sts.NewFromConfig(cfg)
+	identity, err := stsClient.GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return snsEnrichment{}, fmt.Errorf("unable to get AWS account number, %v", err)
+	}
+
+	// Get instance hostname
+	hostname, err := os.Hostname()
+	if err != nil {
+		return snsEnrichment{}, fmt.Errorf("unable to get hostname, %v", err)
+	}
+
+	// Get region of EC2 instance running ebs-monitor.service
+	instanceRegion, err := getCurrentRegion()
+	if err != nil {
+		return snsEnrichment{}, fmt.Errorf("unable to get instance region, %v", err)
+	}
+
+	// Versions of ebs-monitor.service, cached at startup
+	runningVersion, latestVersion := EBSVersions()
+
+	return snsEnrichment{
+		Hostname:       hostname,
+		AccountNumber:  awsv2.ToString(identity.Account),
+		InstanceRegion: instanceRegion,
+		RunningVersion: runningVersion,
+		LatestVersion:  latestVersion,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package aws
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// TestEnrichmentCacheGet tests that enrichment data is reused within the TTL and reloaded after it.
+// It also checks that failed loads are not cached.
+func TestEnrichmentCacheGet(t *testing.T) {
+	loads := 0
+	fail := false
+	cache := &enrichmentCache{
+		ttl: time.Hour,
+		load: func(cfg awsv2.Config) (snsEnrichment, error) {
+			loads++
+			if fail {
+				return snsEnrichment{}, errors.New("lookup failed")
+			}
+			return snsEnrichment{Hostname: "host", RunningVersion: "1.0.0"}, nil
+		},
+	}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		fail      bool
+		wantErr   bool
+		wantLoads int
+	}{
+		{"first call loads", start, false, false, 1},
+		{"within TTL reuses cached data", start.Add(30 * time.Minute), false, false, 1},
+		{"failed reload after TTL is not cached", start.Add(2 * time.Hour), true, true, 2},
+		{"next call after failure loads again", start.Add(2 * time.Hour), false, false, 3},
+		{"within TTL of the reload reuses cached data", start.Add(150 * time.Minute), false, false, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fail = tt.fail
+			got, err := cache.get(awsv2.Config{}, tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Hostname != "host" {
+				t.Errorf("get() Hostname = %q, want %q", got.Hostname, "host")
+			}
+			if loads != tt.wantLoads {
+				t.Errorf("loads = %d, want %d", loads, tt.wantLoads)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package aws
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// Errors classifying a failed AWS call, matched with errors.Is against the errors this package returns
+var (
+	ErrVolumeNotFound         = errors.New("volume not found")                  // The volume doesn't exist, e.g. it was deleted.
+	ErrThrottled              = errors.New("request throttled")                 // AWS throttled the request, retrying later should succeed.
+	ErrAccessDenied           = errors.New("access denied")                     // The credentials lack a permission the call needs.
+	ErrModificationInProgress = errors.New("volume modification in progress")   // A previous modification of the volume hasn't finished.
+	ErrModificationRateLimit  = errors.New("volume modification limit reached") // The volume was modified too recently to be modified again.
+)
+
+// errorCodes : the AWS error codes that each classifying error matches
+var errorCodes = map[error]map[string]bool{
+	ErrVolumeNotFound: {
+		"InvalidVolume.NotFound": true,
+	},
+	ErrThrottled: {
+		"RequestLimitExceeded":  true,
+		"Throttling":            true,
+		"ThrottlingException":   true,
+		"RequestThrottled":      true,
+		"EC2ThrottledException": true,
+	},
+	ErrAccessDenied: unauthorizedCodes,
+	ErrModificationInProgress: {
+		"IncorrectModificationState": true,
+	},
+	ErrModificationRateLimit: {
+		"VolumeModificationRateExceeded": true,
+	},
+}
+
+// unauthorizedCodes : error codes AWS returns when the caller lacks a permission
+var unauthorizedCodes = map[string]bool{
+	"UnauthorizedOperation": true,
+	"AccessDenied":          true,
+	"AccessDeniedException": true,
+}
+
+// APIError : an error returned by an AWS call, carrying the AWS error code so callers can branch on it
+// with errors.Is and the Err variables, instead of matching on the message.
+type APIError struct {
+	Code string // AWS error code, e.g. InvalidVolume.NotFound.
+	Err  error  // Error returned by AWS.
+}
+
+// Error : returns the error returned by AWS
+// returns : string : the error message
+func (e *APIError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap : returns the error returned by AWS
+// returns : error : the underlying error
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Is : matches the classifying error for the AWS error code, e.g. ErrVolumeNotFound for InvalidVolume.NotFound
+// target : error : the error to match
+// returns : bool : true if the code is one of the target's codes
+func (e *APIError) Is(target error) bool {
+	return errorCodes[target][e.Code]
+}
+
+// wrapError : wraps an error returned by an AWS call in an *APIError carrying its error code
+// err : error : the error returned by the AWS call
+// returns : error : the wrapped error, or err unchanged if it is nil or has no AWS error code
+func wrapError(err error) error {
+	var awsErr awserr.Error
+	if err == nil || !errors.As(err, &awsErr) {
+		return err
+	}
+	return &APIError{Code: awsErr.Code(), Err: err}
+}
@@ -0,0 +1,56 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// TestWrapError tests that wrapped AWS errors match the classifying error for their code, through further wrapping.
+func TestWrapError(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want error
+	}{
+		{"volume not found", "InvalidVolume.NotFound", ErrVolumeNotFound},
+		{"throttled", "RequestLimitExceeded", ErrThrottled},
+		{"unauthorized", "UnauthorizedOperation", ErrAccessDenied},
+		{"modification in progress", "IncorrectModificationState", ErrModificationInProgress},
+		{"modification rate", "VolumeModificationRateExceeded", ErrModificationRateLimit},
+	}
+	classifying := []error{ErrVolumeNotFound, ErrThrottled, ErrAccessDenied, ErrModificationInProgress, ErrModificationRateLimit}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("call failed. error: %w", wrapError(awserr.New(tt.code, "message", nil)))
+			for _, target := range classifying {
+				if got := errors.Is(err, target); got != (target == tt.want) {
+					t.Errorf("errors.Is(%v, %v) = %v, want %v", tt.code, target, got, target == tt.want)
+				}
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.Code != tt.code {
+				t.Errorf("errors.As() = %v, want an *APIError with code %v", apiErr, tt.code)
+			}
+		})
+	}
+
+	if wrapError(nil) != nil {
+		t.Errorf("wrapError(nil) != nil")
+	}
+	plain := errors.New("plain")
+	if wrapError(plain) != plain {
+		t.Errorf("wrapError() of an error without a code changed it")
+	}
+}
+
+// TestMissingPermissionErrorIsAccessDenied tests that a missing permission matches ErrAccessDenied.
+func TestMissingPermissionErrorIsAccessDenied(t *testing.T) {
+	err := &MissingPermissionError{Action: "sts:AssumeRole", Resource: "role", Err: errors.New("denied")}
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("errors.Is(MissingPermissionError, ErrAccessDenied) = false, want true")
+	}
+}
@@ -0,0 +1,156 @@
+package aws
+
+import (
+	"ebs-monitor/runtime"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// ResizeLockTag : the volume tag an instance holds while it resizes a Multi-Attach volume,
+// valued "<instance ID> <expiry, RFC 3339>"
+const ResizeLockTag = "ebs-monitor-resizing"
+
+// resizeLock : the holder of a volume's resize lock and when the lock expires
+type resizeLock struct {
+	instanceID string
+	expires    time.Time
+}
+
+// String : formats the lock as the resize lock tag's value
+// returns : string : the tag value
+func (lock resizeLock) String() string {
+	return lock.instanceID + " " + lock.expires.UTC().Format(time.RFC3339)
+}
+
+// parseResizeLock : parses the value of a resize lock tag
+// value : string : the tag value
+// returns : resizeLock : the lock
+// returns : bool : false if the value isn't a lock, which is then treated as expired
+func parseResizeLock(value string) (resizeLock, bool) {
+	instanceID, expiry, found := strings.Cut(value, " ")
+	if !found || instanceID == "" {
+		return resizeLock{}, false
+	}
+	expires, err := time.Parse(time.RFC3339, expiry)
+	if err != nil {
+		return resizeLock{}, false
+	}
+	return resizeLock{instanceID: instanceID, expires: expires}, true
+}
+
+// resizeLockTag : returns the value of a volume's resize lock tag
+// volume : *ec2.Volume : the volume
+// returns : string : the tag value, empty if the volume isn't locked
+func resizeLockTag(volume *ec2.Volume) string {
+	for _, tag := range volume.Tags {
+		if aws.StringValue(tag.Key) == ResizeLockTag {
+			return aws.StringValue(tag.Value)
+		}
+	}
+	return ""
+}
+
+// AcquireResizeLock : takes a volume's resize lock, so ebs-monitor on another instance sharing a Multi-Attach volume
+// doesn't resize it at the same time. A lock held by another instance is honoured until it expires.
+// Tags can't be set conditionally, so the tag is read back after it is set and the last instance to set it wins.
+// The lock is best-effort: tags are eventually consistent, so two instances setting it at once can both read back
+// their own value. Callers should wait for the tags to settle and confirm the lock with HoldsResizeLock before resizing.
+// config : runtime.EBSVolumeConfig : configuration of the EBS volume
+// ttl : time.Duration : how long the lock is held for if it isn't released, e.g. after a crash
+// returns : bool : true if this instance now holds the lock, false if another instance does
+// returns : error : returns an error if any occur during the process
+func AcquireResizeLock(config runtime.EBSVolumeConfig, ttl time.Duration) (bool, error) {
+	instanceID, err := getInstanceID()
+	if err != nil {
+		return false, fmt.Errorf("failed to get instance ID: %w", err)
+	}
+
+	volume, err := GetVolume(config)
+	if err != nil {
+		return false, fmt.Errorf("failed to get the resize lock. error: %w", err)
+	}
+	now := runtime.Now()
+	if held, ok := parseResizeLock(resizeLockTag(volume)); ok && held.instanceID != instanceID && now.Before(held.expires) {
+		return false, nil
+	}
+
+	// Create a new session
+	svc := sessionFor(config.AWSRegion, config.AssumeRoleARN)
+
+	lock := resizeLock{instanceID: instanceID, expires: now.Add(ttl)}
+	err = withRetry(func() error {
+		_, err := svc.CreateTags(&ec2.CreateTagsInput{
+			Resources: []*string{aws.String(config.AWSVolumeID)},
+			Tags:      []*ec2.Tag{{Key: aws.String(ResizeLockTag), Value: aws.String(lock.String())}},
+		})
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to tag the volume with the resize lock. error: %w", wrapError(err))
+	}
+
+	// Another instance may have set the tag at the same time, so check which one won
+	volume, err = GetVolume(config)
+	if err != nil {
+		return false, fmt.Errorf("failed to confirm the resize lock. error: %w", err)
+	}
+	return resizeLockTag(volume) == lock.String(), nil
+}
+
+// HoldsResizeLock : re-reads a volume's resize lock tag, confirming this instance still holds an unexpired lock
+// An instance that set the tag at the same time may have overwritten it once the tags settled.
+// config : runtime.EBSVolumeConfig : configuration of the EBS volume
+// returns : bool : true if this instance holds the lock, false if another instance does or it has expired
+// returns : error : returns an error if any occur during the process
+func HoldsResizeLock(config runtime.EBSVolumeConfig) (bool, error) {
+	instanceID, err := getInstanceID()
+	if err != nil {
+		return false, fmt.Errorf("failed to get instance ID: %w", err)
+	}
+
+	volume, err := GetVolume(config)
+	if err != nil {
+		return false, fmt.Errorf("failed to confirm the resize lock. error: %w", err)
+	}
+	held, ok := parseResizeLock(resizeLockTag(volume))
+	return ok && held.instanceID == instanceID && runtime.Now().Before(held.expires), nil
+}
+
+// ReleaseResizeLock : releases a volume's resize lock, if this instance holds it
+// The tag is only deleted if it still has the value read, so a lock another instance has since taken is kept.
+// config : runtime.EBSVolumeConfig : configuration of the EBS volume
+// returns : error : returns an error if any occur during the process
+func ReleaseResizeLock(config runtime.EBSVolumeConfig) error {
+	instanceID, err := getInstanceID()
+	if err != nil {
+		return fmt.Errorf("failed to get instance ID: %w", err)
+	}
+
+	volume, err := GetVolume(config)
+	if err != nil {
+		return fmt.Errorf("failed to get the resize lock. error: %w", err)
+	}
+	value := resizeLockTag(volume)
+	if held, ok := parseResizeLock(value); !ok || held.instanceID != instanceID {
+		return nil
+	}
+
+	// Create a new session
+	svc := sessionFor(config.AWSRegion, config.AssumeRoleARN)
+
+	err = withRetry(func() error {
+		_, err := svc.DeleteTags(&ec2.DeleteTagsInput{
+			Resources: []*string{aws.String(config.AWSVolumeID)},
+			Tags:      []*ec2.Tag{{Key: aws.String(ResizeLockTag), Value: aws.String(value)}},
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove the resize lock tag from the volume. error: %w", wrapError(err))
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package aws
+
+import (
+	"ebs-monitor/aws/awstest"
+	"ebs-monitor/runtime"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// TestParseResizeLock tests that lock tag values round trip, and that malformed values aren't locks.
+func TestParseResizeLock(t *testing.T) {
+	lock := resizeLock{instanceID: "i-1", expires: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	if got, ok := parseResizeLock(lock.String()); !ok || got != lock {
+		t.Errorf("parseResizeLock(%q) = (%v, %v), want (%v, true)", lock.String(), got, ok, lock)
+	}
+	for _, value := range []string{"", "i-1", "i-1 tomorrow", " 2024-01-01T12:00:00Z"} {
+		if _, ok := parseResizeLock(value); ok {
+			t.Errorf("parseResizeLock(%q) ok = true, want false", value)
+		}
+	}
+}
+
+// TestResizeLock tests acquiring and releasing a volume's resize lock from two instances.
+func TestResizeLock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := runtime.NewMockClock(start)
+	runtime.SetClock(clock)
+	fake := &awstest.FakeEC2{Volumes: []*ec2.Volume{awstest.NewFakeVolume("vol-1", "i-1", "/dev/sdf", 100)}}
+	SetEC2Client(fake)
+	defer func() {
+		runtime.SetClock(nil)
+		SetEC2Client(nil)
+		SetInstanceMetadata(nil)
+	}()
+	config := runtime.EBSVolumeConfig{AWSVolumeID: "vol-1", AWSRegion: "us-east-1"}
+
+	SetInstanceMetadata(awstest.FakeMetadata{ID: "i-1"})
+	if acquired, err := AcquireResizeLock(config, time.Hour); err != nil || !acquired {
+		t.Fatalf("AcquireResizeLock() on i-1 = (%v, %v), want (true, nil)", acquired, err)
+	}
+
+	if held, err := HoldsResizeLock(config); err != nil || !held {
+		t.Errorf("HoldsResizeLock() on i-1 = (%v, %v), want (true, nil)", held, err)
+	}
+
+	SetInstanceMetadata(awstest.FakeMetadata{ID: "i-2"})
+	if acquired, err := AcquireResizeLock(config, time.Hour); err != nil || acquired {
+		t.Errorf("AcquireResizeLock() on i-2 while i-1 holds it = (%v, %v), want (false, nil)", acquired, err)
+	}
+	if held, err := HoldsResizeLock(config); err != nil || held {
+		t.Errorf("HoldsResizeLock() on i-2 while i-1 holds it = (%v, %v), want (false, nil)", held, err)
+	}
+	if err := ReleaseResizeLock(config); err != nil || resizeLockTag(fake.Volumes[0]) == "" {
+		t.Errorf("ReleaseResizeLock() on i-2 = %v, want the lock held by i-1 kept", err)
+	}
+
+	clock.Advance(time.Hour + time.Minute)
+	SetInstanceMetadata(awstest.FakeMetadata{ID: "i-1"})
+	if held, err := HoldsResizeLock(config); err != nil || held {
+		t.Errorf("HoldsResizeLock() on i-1 after its lock expired = (%v, %v), want (false, nil)", held, err)
+	}
+	SetInstanceMetadata(awstest.FakeMetadata{ID: "i-2"})
+	if acquired, err := AcquireResizeLock(config, time.Hour); err != nil || !acquired {
+		t.Errorf("AcquireResizeLock() on i-2 after i-1's lock expired = (%v, %v), want (true, nil)", acquired, err)
+	}
+	if err := ReleaseResizeLock(config); err != nil {
+		t.Fatalf("ReleaseResizeLock() error = %v", err)
+	}
+	for _, tag := range fake.Volumes[0].Tags {
+		if aws.StringValue(tag.Key) == ResizeLockTag {
+			t.Errorf("ReleaseResizeLock() left the tag %q", aws.StringValue(tag.Value))
+		}
+	}
+}
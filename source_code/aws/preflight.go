@@ -0,0 +1,102 @@
+package aws
+
+import (
+	"context"
+	"ebs-monitor/runtime"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// dryRunSucceededCode : error code EC2 returns for a DryRun request that would have succeeded
+const dryRunSucceededCode = "DryRunOperation"
+
+// MissingPermissionError : reports an IAM permission the credentials lack
+type MissingPermissionError struct {
+	Action   string // IAM action that was denied, e.g. ec2:ModifyVolume.
+	Resource string // Resource the action was denied on, e.g. a volume ID.
+	Err      error  // Error returned by AWS.
+}
+
+// Error : returns the missing permission and the resource it is needed on
+// returns : string : the error message
+func (e *MissingPermissionError) Error() string {
+	return fmt.Sprintf("missing IAM permission %s on %s. error: %v", e.Action, e.Resource, e.Err)
+}
+
+// Unwrap : returns the error returned by AWS
+// returns : error : the underlying error
+func (e *MissingPermissionError) Unwrap() error {
+	return e.Err
+}
+
+// Is : matches ErrAccessDenied, including for a role that couldn't be assumed
+// target : error : the error to match
+// returns : bool : true if target is ErrAccessDenied
+func (e *MissingPermissionError) Is(target error) bool {
+	return target == ErrAccessDenied
+}
+
+// Preflight : checks the credentials and the IAM permissions the monitor needs, before monitoring starts
+// The caller identity is looked up with STS, then DescribeVolumes and ModifyVolume are called with DryRun
+// on the volume, so no volume is changed.
+// volume : runtime.EBSVolumeConfig : a configured volume to check the EC2 permissions on
+// returns : string : the ARN of the caller
+// returns : error : a *MissingPermissionError if a permission is missing, or an error if the credentials can't be used
+func Preflight(volume runtime.EBSVolumeConfig) (string, error) {
+	roleARN := roleOrDefault(volume.AssumeRoleARN)
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(volume.AWSRegion))
+	if err != nil {
+		return "", fmt.Errorf("unable to load SDK config, %v", err)
+	}
+	identity, err := sts.NewFromConfig(withAssumedRole(cfg, roleARN)).GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
+	if err != nil {
+		if roleARN != "" {
+			return "", &MissingPermissionError{Action: "sts:AssumeRole", Resource: roleARN, Err: err}
+		}
+		return "", fmt.Errorf("no usable AWS credentials, check the instance profile or credential environment. error: %w", err)
+	}
+
+	svc, err := ec2Client(volume.AWSRegion, roleARN)
+	if err != nil {
+		return "", fmt.Errorf("failed to get region information from AWS. error: %w", err)
+	}
+	_, err = svc.DescribeVolumes(&ec2.DescribeVolumesInput{
+		DryRun:    aws.Bool(true),
+		VolumeIds: []*string{aws.String(volume.AWSVolumeID)},
+	})
+	if err = dryRunResult("ec2:DescribeVolumes", volume.AWSVolumeID, err); err != nil {
+		return "", err
+	}
+	_, err = svc.ModifyVolume(&ec2.ModifyVolumeInput{
+		DryRun:   aws.Bool(true),
+		VolumeId: aws.String(volume.AWSVolumeID),
+	})
+	if err = dryRunResult("ec2:ModifyVolume", volume.AWSVolumeID, err); err != nil {
+		return "", err
+	}
+	return aws.StringValue(identity.Arn), nil
+}
+
+// dryRunResult : interprets the error returned by a DryRun request
+// EC2 answers a permitted DryRun request with a DryRunOperation error, and a denied one with UnauthorizedOperation.
+// action : string : IAM action the request needs
+// resource : string : resource the request was made on
+// err : error : error returned by the request
+// returns : error : nil if the request was permitted, a *MissingPermissionError if it was denied, otherwise the error
+func dryRunResult(action, resource string, err error) error {
+	var awsErr awserr.Error
+	if err == nil || (errors.As(err, &awsErr) && awsErr.Code() == dryRunSucceededCode) {
+		return nil
+	}
+	err = wrapError(err)
+	if errors.Is(err, ErrAccessDenied) {
+		return &MissingPermissionError{Action: action, Resource: resource, Err: err}
+	}
+	return fmt.Errorf("failed to check %s on %s. error: %w", action, resource, err)
+}
@@ -0,0 +1,41 @@
+package aws
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// TestDryRunResult tests that DryRun responses are read as permitted, denied or failed.
+func TestDryRunResult(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantErr     bool
+		wantMissing bool
+	}{
+		{"permitted", awserr.New("DryRunOperation", "Request would have succeeded, but DryRun flag is set.", nil), false, false},
+		{"no error", nil, false, false},
+		{"unauthorized", awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil), true, true},
+		{"access denied", awserr.New("AccessDenied", "Access denied.", nil), true, true},
+		{"volume not found", awserr.New("InvalidVolume.NotFound", "The volume does not exist.", nil), true, false},
+		{"plain error", errors.New("connection refused"), true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := dryRunResult("ec2:ModifyVolume", "vol-1", tt.err)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dryRunResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var missing *MissingPermissionError
+			if got := errors.As(err, &missing); got != tt.wantMissing {
+				t.Errorf("dryRunResult() missing permission = %v, want %v", got, tt.wantMissing)
+			}
+			if tt.wantMissing && missing.Action != "ec2:ModifyVolume" {
+				t.Errorf("dryRunResult() action = %v, want ec2:ModifyVolume", missing.Action)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package aws
+
+import (
+	"regexp"
+	"sync"
+)
+
+// knownRegions : AWS regions that exist, so validating them needs no DescribeRegions call
+var knownRegions = map[string]bool{
+	"af-south-1":     true,
+	"ap-east-1":      true,
+	"ap-east-2":      true,
+	"ap-northeast-1": true,
+	"ap-northeast-2": true,
+	"ap-northeast-3": true,
+	"ap-south-1":     true,
+	"ap-south-2":     true,
+	"ap-southeast-1": true,
+	"ap-southeast-2": true,
+	"ap-southeast-3": true,
+	"ap-southeast-4": true,
+	"ap-southeast-5": true,
+	"ap-southeast-7": true,
+	"ca-central-1":   true,
+	"ca-west-1":      true,
+	"cn-north-1":     true,
+	"cn-northwest-1": true,
+	"eu-central-1":   true,
+	"eu-central-2":   true,
+	"eu-north-1":     true,
+	"eu-south-1":     true,
+	"eu-south-2":     true,
+	"eu-west-1":      true,
+	"eu-west-2":      true,
+	"eu-west-3":      true,
+	"il-central-1":   true,
+	"me-central-1":   true,
+	"me-south-1":     true,
+	"mx-central-1":   true,
+	"sa-east-1":      true,
+	"us-east-1":      true,
+	"us-east-2":      true,
+	"us-gov-east-1":  true,
+	"us-gov-west-1":  true,
+	"us-west-1":      true,
+	"us-west-2":      true,
+}
+
+// regionPattern : matches the format of a commercial AWS region name, e.g. ap-southeast-2
+var regionPattern = regexp.MustCompile(`^[a-z]{2}-[a-z]+-\d$`)
+
+var (
+	// regionMu guards offlineRegions, which is set once flags are parsed.
+	regionMu       sync.Mutex
+	offlineRegions bool
+	// getAllRegions : lists the regions from DescribeRegions, replaced in tests
+	getAllRegions = GetAllRegions
+)
+
+// SetOfflineRegionValidation : sets whether regions are validated without calling DescribeRegions
+// offline : bool : true to accept any well-formed region not in the static list, rather than asking AWS
+func SetOfflineRegionValidation(offline bool) {
+	regionMu.Lock()
+	defer regionMu.Unlock()
+	offlineRegions = offline
+}
+
+// ValidateRegion : checks if the provided Region is valid
+// Known regions and malformed names are decided without calling AWS. A well-formed region missing from the
+// static list, e.g. one launched since, is checked with DescribeRegions, or accepted in offline mode.
+// region : string : AWS Region to validate
+// returns : bool : returns true if the Region is valid, false otherwise
+// returns : error : returns an error if any occur during the process
+func ValidateRegion(region string) (bool, error) {
+	if knownRegions[region] {
+		return true, nil
+	}
+	if !regionPattern.MatchString(region) {
+		return false, nil
+	}
+
+	regionMu.Lock()
+	offline := offlineRegions
+	regionMu.Unlock()
+	if offline {
+		return true, nil
+	}
+
+	// Get all regions
+	regions, err := getAllRegions()
+	if err != nil {
+		return false, err
+	}
+
+	// Check if the provided region is in the list of regions
+	for _, r := range regions {
+		if r == region {
+			return true, nil
+		}
+	}
+
+	// If the provided region is not found in the list of regions, return false
+	return false, nil
+}
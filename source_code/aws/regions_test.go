@@ -0,0 +1,57 @@
+package aws
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestValidateRegion tests that known and malformed regions are decided without calling DescribeRegions,
+// and that other well-formed regions are looked up unless validation is offline.
+func TestValidateRegion(t *testing.T) {
+	defer func() {
+		getAllRegions = GetAllRegions
+		SetOfflineRegionValidation(false)
+	}()
+
+	tests := []struct {
+		name      string
+		region    string
+		offline   bool
+		regions   []string
+		err       error
+		want      bool
+		wantErr   bool
+		wantCalls int
+	}{
+		{name: "known region", region: "ap-southeast-2", want: true},
+		{name: "known gov region", region: "us-gov-west-1", want: true},
+		{name: "malformed region", region: "sydney", want: false},
+		{name: "new region found", region: "ap-southeast-9", regions: []string{"ap-southeast-9"}, want: true, wantCalls: 1},
+		{name: "new region not found", region: "ap-southeast-9", regions: []string{"ap-southeast-2"}, want: false, wantCalls: 1},
+		{name: "lookup fails", region: "ap-southeast-9", err: errors.New("UnauthorizedOperation"), wantErr: true, wantCalls: 1},
+		{name: "offline accepts new region", region: "ap-southeast-9", offline: true, want: true},
+		{name: "offline rejects malformed region", region: "sydney", offline: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			getAllRegions = func() ([]string, error) {
+				calls++
+				return tt.regions, tt.err
+			}
+			SetOfflineRegionValidation(tt.offline)
+
+			got, err := ValidateRegion(tt.region)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateRegion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ValidateRegion() = %v, want %v", got, tt.want)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("DescribeRegions calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
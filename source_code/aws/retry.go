@@ -0,0 +1,165 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// DefaultMaxRetries : retries made for a throttled or failed EC2 call when maxRetries is not configured
+const DefaultMaxRetries = 3
+
+// Backoff between retries, doubled on each attempt up to the maximum, with full jitter
+const (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 20 * time.Second
+)
+
+// throttlingCodes : AWS error codes for throttled calls, which are rejected without being carried out
+var throttlingCodes = map[string]bool{
+	"RequestLimitExceeded":  true,
+	"Throttling":            true,
+	"ThrottlingException":   true,
+	"RequestThrottled":      true,
+	"EC2ThrottledException": true,
+}
+
+// serverErrorCodes : AWS error codes for server-side failures, which like throttling are worth retrying
+var serverErrorCodes = map[string]bool{
+	"InternalError":           true,
+	"InternalFailure":         true,
+	"ServiceUnavailable":      true,
+	"Unavailable":             true,
+	"RequestTimeout":          true,
+	"PriorRequestNotComplete": true,
+}
+
+var (
+	// retryMu guards maxRetries, which is set once config is loaded.
+	retryMu    sync.Mutex
+	maxRetries = DefaultMaxRetries
+	// sleep : waits between retries, replaced in tests
+	sleep = time.Sleep
+
+	// failureMu guards consecutiveFailures, updated by every retried EC2 call
+	failureMu           sync.Mutex
+	consecutiveFailures int
+)
+
+// SetMaxRetries : sets how many times a throttled or failed EC2 call is retried
+// retries : int : number of retries after the first attempt, 0 uses DefaultMaxRetries and a negative number disables retries
+func SetMaxRetries(retries int) {
+	if retries == 0 {
+		retries = DefaultMaxRetries
+	}
+	if retries < 0 {
+		retries = 0
+	}
+	retryMu.Lock()
+	defer retryMu.Unlock()
+	maxRetries = retries
+}
+
+// withRetry : runs an AWS call, retrying with exponential backoff and jitter while it fails with a transient error,
+// i.e. throttling, a server-side failure or a network error. Other errors, e.g. InvalidVolume.NotFound, are returned
+// immediately. The SDK's own retries are turned off, so this is the only retrying done.
+// fn : func() error : the AWS call
+// returns : error : the last error, or nil once the call succeeds
+func withRetry(fn func() error) error {
+	retryMu.Lock()
+	retries := maxRetries
+	retryMu.Unlock()
+
+	err := fn()
+	for attempt := 0; attempt < retries && IsTransient(err); attempt++ {
+		sleep(retryDelay(attempt, rand.Float64()))
+		err = fn()
+	}
+	recordOutcome(err)
+	return err
+}
+
+// recordOutcome : counts consecutive failed EC2 calls, resetting the count when a call succeeds
+// err : error : the final error of a call, after retries
+func recordOutcome(err error) {
+	failureMu.Lock()
+	defer failureMu.Unlock()
+	if err != nil {
+		consecutiveFailures++
+	} else {
+		consecutiveFailures = 0
+	}
+}
+
+// ConsecutiveFailures : returns how many EC2 calls in a row have failed, after retries, since the last success
+// returns : int : the number of failed calls, 0 if the last call succeeded
+func ConsecutiveFailures() int {
+	failureMu.Lock()
+	defer failureMu.Unlock()
+	return consecutiveFailures
+}
+
+// isRetryable : checks if an AWS error is due to throttling or a server-side failure
+// err : error : the error returned by an AWS call
+// returns : bool : true if the call should be retried
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var requestFailure awserr.RequestFailure
+	if errors.As(err, &requestFailure) && requestFailure.StatusCode() >= 500 {
+		return true
+	}
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return throttlingCodes[awsErr.Code()] || serverErrorCodes[awsErr.Code()]
+	}
+	return false
+}
+
+// isThrottled : checks if an AWS error is due to throttling, in which case the call wasn't carried out
+// err : error : the error returned by an AWS call
+// returns : bool : true if the call was throttled
+func isThrottled(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && throttlingCodes[awsErr.Code()]
+}
+
+// IsTransient : checks if an AWS error is likely to clear by itself, i.e. throttling, a server-side failure,
+// a network error or a timeout, even once retries are exhausted. Other errors, e.g. InvalidVolume.NotFound, are permanent.
+// err : error : the error returned by an AWS call, possibly wrapped
+// returns : bool : true if the error is transient
+func IsTransient(err error) bool {
+	if isRetryable(err) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == request.ErrCodeRequestError {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryDelay : returns how long to wait before a retry
+// The backoff doubles with each attempt up to retryMaxDelay, and a random fraction of it is used (full jitter)
+// so throttled callers don't retry in lockstep.
+// attempt : int : number of retries already made
+// jitter : float64 : random value in [0, 1)
+// returns : time.Duration : the delay
+func retryDelay(attempt int, jitter float64) time.Duration {
+	backoff := retryBaseDelay
+	for i := 0; i < attempt && backoff < retryMaxDelay; i++ {
+		backoff *= 2
+	}
+	if backoff > retryMaxDelay {
+		backoff = retryMaxDelay
+	}
+	return time.Duration(jitter * float64(backoff))
+}
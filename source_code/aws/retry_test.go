@@ -0,0 +1,147 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// TestWithRetry tests that only throttling, server and network errors are retried, up to the configured retries.
+func TestWithRetry(t *testing.T) {
+	sleep = func(time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+	SetMaxRetries(2)
+	defer SetMaxRetries(0)
+
+	throttled := awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil)
+	serverError := awserr.NewRequestFailure(awserr.New("Unknown", "server error", nil), 503, "request-id")
+	notFound := awserr.New("InvalidVolume.NotFound", "The volume does not exist.", nil)
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   bool
+	}{
+		{"success", []error{nil}, 1, false},
+		{"throttled then success", []error{throttled, nil}, 2, false},
+		{"server error then success", []error{serverError, nil}, 2, false},
+		{"network error then success", []error{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, nil}, 2, false},
+		{"not found returns immediately", []error{notFound, nil}, 1, true},
+		{"gives up after max retries", []error{throttled, throttled, throttled, nil}, 3, true},
+		{"plain error returns immediately", []error{errors.New("boom"), nil}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := withRetry(func() error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("withRetry() calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("withRetry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestSetMaxRetriesDisabled tests that a negative maxRetries makes a single attempt.
+func TestSetMaxRetriesDisabled(t *testing.T) {
+	sleep = func(time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+	SetMaxRetries(-1)
+	defer SetMaxRetries(0)
+
+	calls := 0
+	withRetry(func() error {
+		calls++
+		return awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil)
+	})
+	if calls != 1 {
+		t.Errorf("withRetry() calls = %d, want 1", calls)
+	}
+}
+
+// TestRetryDelay tests that the backoff doubles per attempt, is capped, and is scaled by the jitter.
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		jitter  float64
+		want    time.Duration
+	}{
+		{0, 1, retryBaseDelay},
+		{1, 1, 2 * retryBaseDelay},
+		{3, 1, 8 * retryBaseDelay},
+		{20, 1, retryMaxDelay},
+		{2, 0.5, 2 * retryBaseDelay},
+		{2, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempt, tt.jitter); got != tt.want {
+			t.Errorf("retryDelay(%d, %v) = %v, want %v", tt.attempt, tt.jitter, got, tt.want)
+		}
+	}
+}
+
+// TestIsTransient tests that throttling, server, network and timeout errors are transient, even when wrapped,
+// and that other errors are permanent.
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"throttled", awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil), true},
+		{"server error", awserr.NewRequestFailure(awserr.New("Unknown", "server error", nil), 503, "request-id"), true},
+		{"network error", awserr.New("RequestError", "send request failed", nil), true},
+		{"timeout", fmt.Errorf("failed to get volume. error: %w", context.DeadlineExceeded), true},
+		{"wrapped throttle", fmt.Errorf("failed to get volume. error: %w", awserr.New("Throttling", "Rate exceeded", nil)), true},
+		{"volume not found", awserr.New("InvalidVolume.NotFound", "The volume does not exist.", nil), false},
+		{"access denied", awserr.NewRequestFailure(awserr.New("UnauthorizedOperation", "denied", nil), 403, "request-id"), false},
+		{"plain error", errors.New("unsupported filesystem"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestConsecutiveFailures tests that failed calls are counted until a call succeeds.
+func TestConsecutiveFailures(t *testing.T) {
+	sleep = func(time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+	SetMaxRetries(1)
+	defer SetMaxRetries(0)
+	defer recordOutcome(nil)
+
+	notFound := awserr.New("InvalidVolume.NotFound", "The volume does not exist.", nil)
+	throttled := awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil)
+
+	withRetry(func() error { return nil })
+	withRetry(func() error { return notFound })
+	withRetry(func() error { return throttled })
+	if got := ConsecutiveFailures(); got != 2 {
+		t.Errorf("ConsecutiveFailures() = %d, want 2", got)
+	}
+
+	withRetry(func() error { return nil })
+	if got := ConsecutiveFailures(); got != 0 {
+		t.Errorf("ConsecutiveFailures() after a success = %d, want 0", got)
+	}
+}
@@ -0,0 +1,75 @@
+package aws
+
+import (
+	"sync"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	stscredsv2 "github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// roleSessionName : session name recorded in CloudTrail for calls made with an assumed role
+const roleSessionName = "ebs-monitor"
+
+var (
+	// assumeRoleMu guards assumeRoleARN, which is set once config is loaded.
+	assumeRoleMu sync.Mutex
+	// assumeRoleARN : role assumed for calls not tied to a volume, e.g. GetAllRegions and SNS
+	assumeRoleARN string
+)
+
+// SetAssumeRoleARN : sets the role assumed for AWS calls, unless a volume overrides it
+// roleARN : string : ARN of the IAM role, empty uses the default credential chain
+func SetAssumeRoleARN(roleARN string) {
+	assumeRoleMu.Lock()
+	defer assumeRoleMu.Unlock()
+	assumeRoleARN = roleARN
+}
+
+// defaultRoleARN : returns the role set by SetAssumeRoleARN
+// returns : string : ARN of the IAM role, empty when using the default credential chain
+func defaultRoleARN() string {
+	assumeRoleMu.Lock()
+	defer assumeRoleMu.Unlock()
+	return assumeRoleARN
+}
+
+// roleOrDefault : returns the role to assume for a call
+// roleARN : string : role configured for the call, e.g. a volume's AssumeRoleARN
+// returns : string : roleARN, falling back to the role set by SetAssumeRoleARN when empty
+func roleOrDefault(roleARN string) string {
+	if roleARN != "" {
+		return roleARN
+	}
+	return defaultRoleARN()
+}
+
+// assumeRoleCredentials : returns SDK v1 credentials that assume a role using the session's credentials
+// Credentials are fetched from STS on first use and refreshed before they expire.
+// sess : *session.Session : session providing the credentials used to call STS
+// roleARN : string : ARN of the IAM role to assume
+// returns : *credentials.Credentials : the assumed role's credentials
+func assumeRoleCredentials(sess *session.Session, roleARN string) *credentials.Credentials {
+	return stscreds.NewCredentials(sess, roleARN, func(p *stscreds.AssumeRoleProvider) {
+		p.RoleSessionName = roleSessionName
+	})
+}
+
+// withAssumedRole : returns a copy of an SDK v2 config whose credentials assume a role
+// Credentials are fetched from STS on first use and cached until they expire.
+// cfg : awsv2.Config : config providing the credentials used to call STS
+// roleARN : string : ARN of the IAM role to assume, empty returns cfg unchanged
+// returns : awsv2.Config : the config using the assumed role's credentials
+func withAssumedRole(cfg awsv2.Config, roleARN string) awsv2.Config {
+	if roleARN == "" {
+		return cfg
+	}
+	provider := stscredsv2.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN, func(o *stscredsv2.AssumeRoleOptions) {
+		o.RoleSessionName = roleSessionName
+	})
+	cfg.Credentials = awsv2.NewCredentialsCache(provider)
+	return cfg
+}
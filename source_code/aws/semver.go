@@ -0,0 +1,117 @@
+package aws
+
+import (
+	"strconv"
+	"strings"
+)
+
+// semanticVersion : a parsed semantic version, e.g. 1.10.0-rc.1
+type semanticVersion struct {
+	core       [3]int   // Major, minor and patch numbers.
+	preRelease []string // Dot separated pre-release identifiers, empty for a release.
+}
+
+// parseSemanticVersion : parses a semantic version such as 1.10.0, v1.2.3 or 1.3.0-rc.1+build.5
+// A leading "v" and build metadata are ignored, and missing minor or patch numbers are treated as 0.
+// version : string : the version to parse
+// returns : semanticVersion : the parsed version
+// returns : bool : false if the version isn't a semantic version, e.g. "unknown"
+func parseSemanticVersion(version string) (semanticVersion, bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.Index(version, "+"); i >= 0 {
+		version = version[:i]
+	}
+
+	var parsed semanticVersion
+	if i := strings.Index(version, "-"); i >= 0 {
+		parsed.preRelease = strings.Split(version[i+1:], ".")
+		version = version[:i]
+	}
+
+	parts := strings.Split(version, ".")
+	if len(parts) > len(parsed.core) {
+		return semanticVersion{}, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return semanticVersion{}, false
+		}
+		parsed.core[i] = n
+	}
+	return parsed, true
+}
+
+// compareVersions : compares two semantic versions numerically, so 1.10.0 is newer than 1.9.0
+// A pre-release sorts before its release, so 1.3.0-rc.1 is older than 1.3.0.
+// a : string : the first version
+// b : string : the second version
+// returns : int : -1 if a is older than b, 0 if they are equal, 1 if a is newer than b
+// returns : bool : false if either version can't be parsed, in which case the result is 0
+func compareVersions(a, b string) (int, bool) {
+	va, ok := parseSemanticVersion(a)
+	if !ok {
+		return 0, false
+	}
+	vb, ok := parseSemanticVersion(b)
+	if !ok {
+		return 0, false
+	}
+
+	for i := range va.core {
+		if c := compareInts(va.core[i], vb.core[i]); c != 0 {
+			return c, true
+		}
+	}
+
+	// A release is newer than any of its pre-releases
+	switch {
+	case len(va.preRelease) == 0 && len(vb.preRelease) == 0:
+		return 0, true
+	case len(va.preRelease) == 0:
+		return 1, true
+	case len(vb.preRelease) == 0:
+		return -1, true
+	}
+
+	for i := 0; i < len(va.preRelease) && i < len(vb.preRelease); i++ {
+		if c := comparePreReleaseIdentifiers(va.preRelease[i], vb.preRelease[i]); c != 0 {
+			return c, true
+		}
+	}
+	// When every shared identifier is equal, the longer pre-release is newer
+	return compareInts(len(va.preRelease), len(vb.preRelease)), true
+}
+
+// comparePreReleaseIdentifiers : compares a single pre-release identifier
+// Numeric identifiers compare numerically and sort before alphanumeric ones, which compare lexically.
+// a : string : the first identifier
+// b : string : the second identifier
+// returns : int : -1, 0 or 1 as a is older than, equal to or newer than b
+func comparePreReleaseIdentifiers(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return compareInts(na, nb)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// compareInts : compares two integers
+// a : int : the first integer
+// b : int : the second integer
+// returns : int : -1, 0 or 1 as a is less than, equal to or greater than b
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,41 @@
+package aws
+
+import "testing"
+
+// TestCompareVersions tests that versions are compared numerically, with pre-releases before their release.
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		name   string
+		a      string
+		b      string
+		want   int
+		wantOK bool
+	}{
+		{"equal", "1.2.3", "1.2.3", 0, true},
+		{"multi-digit minor", "1.10.0", "1.9.0", 1, true},
+		{"multi-digit patch", "1.2.9", "1.2.10", -1, true},
+		{"major wins", "2.0.0", "1.99.99", 1, true},
+		{"leading v", "v1.2.3", "1.2.3", 0, true},
+		{"missing patch", "1.2", "1.2.0", 0, true},
+		{"build metadata ignored", "1.2.3+build.5", "1.2.3", 0, true},
+		{"pre-release before release", "1.3.0-rc.1", "1.3.0", -1, true},
+		{"release after pre-release", "1.3.0", "1.3.0-beta", 1, true},
+		{"numeric pre-release identifiers", "1.3.0-rc.10", "1.3.0-rc.9", 1, true},
+		{"numeric before alphanumeric", "1.3.0-1", "1.3.0-alpha", -1, true},
+		{"alphanumeric lexical", "1.3.0-alpha", "1.3.0-beta", -1, true},
+		{"longer pre-release newer", "1.3.0-alpha.1", "1.3.0-alpha", 1, true},
+		{"pre-release of newer version", "1.10.0-rc.1", "1.9.0", 1, true},
+		{"unknown", "unknown", "1.2.3", 0, false},
+		{"non-numeric core", "1.x.0", "1.2.3", 0, false},
+		{"too many parts", "1.2.3.4", "1.2.3", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := compareVersions(tt.a, tt.b)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("compareVersions(%q, %q) = %v, %v, want %v, %v", tt.a, tt.b, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
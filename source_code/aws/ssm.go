@@ -0,0 +1,57 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// ssmTimeout bounds each SSM Parameter Store call.
+const ssmTimeout = 30 * time.Second
+
+// GetSSMParameter : reads a parameter from SSM Parameter Store, decrypting SecureString parameters.
+// Uses the default credential chain, as it is used to load the config that may set an IAM role to assume.
+// name : string : Name of the parameter, e.g. /ebs-monitor/config.
+// region : string : AWS region of the parameter. Empty uses the instance's region.
+// returns : string : The parameter's value.
+// returns : error : An error wrapping fs.ErrNotExist if the parameter doesn't exist, or any other error.
+func GetSSMParameter(name, region string) (string, error) {
+	if region == "" {
+		localRegion, err := GetLocalRegion()
+		if err != nil {
+			return "", fmt.Errorf("failed to get local region to read SSM parameter '%v'. error: %w", name, err)
+		}
+		region = localRegion
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ssmTimeout)
+	defer cancel()
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return "", fmt.Errorf("unable to load SDK config, %v", err)
+	}
+
+	output, err := ssm.NewFromConfig(cfg).GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           awsv2.String(name),
+		WithDecryption: awsv2.Bool(true),
+	})
+	var notFound *types.ParameterNotFound
+	if errors.As(err, &notFound) {
+		return "", fmt.Errorf("SSM parameter '%v' not found in %v: %w", name, region, fs.ErrNotExist)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get SSM parameter '%v'. error: %w", name, err)
+	}
+	if output.Parameter == nil || output.Parameter.Value == nil {
+		return "", fmt.Errorf("SSM parameter '%v' has no value", name)
+	}
+	return *output.Parameter.Value, nil
+}
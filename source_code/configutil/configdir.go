@@ -0,0 +1,127 @@
+package configutil
+
+import (
+	"ebs-monitor/runtime"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// sourceDir prefixes a directory of config fragments, e.g. dir:///etc/ebs-monitor/conf.d.
+const sourceDir = "dir://"
+
+// configDirPattern matches the config fragments read from a config directory.
+const configDirPattern = "*.yaml"
+
+// volumesKey is the config key holding the volume list, which is merged across fragments rather than set once.
+const volumesKey = "volumes"
+
+// DirSource : returns the config source that LoadConfig reads a directory of config fragments from.
+// dir : string directory holding the fragments
+// returns : string the config source
+func DirSource(dir string) string {
+	return sourceDir + dir
+}
+
+// LoadConfigFromDir : reads every *.yaml file in a directory, merges them and returns the complete, validated configuration.
+// The volume lists of the files are joined. Any other setting may be set in more than one file only with the
+// same value, so e.g. a single checkIntervalSeconds applies to every volume.
+// dir : string directory holding the config fragments
+// returns : *runtime.Config validated configuration
+// returns : error potential errors, wrapping fs.ErrNotExist if the directory doesn't exist or has no fragments
+func LoadConfigFromDir(dir string) (*runtime.Config, error) {
+	files, err := configDirFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	v, err := mergeConfigFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	var cfg runtime.Config
+	if err := unmarshalConfig(v, &cfg); err != nil {
+		return nil, err
+	}
+	if err := finaliseConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// configDirFiles : lists the config fragments in a directory, in name order.
+// dir : string directory holding the config fragments
+// returns : []string paths of the fragments
+// returns : error potential errors, wrapping fs.ErrNotExist if the directory doesn't exist or has no fragments
+func configDirFiles(dir string) ([]string, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("failed to read the configuration directory: %v. error: %w", dir, err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, configDirPattern))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the configuration directory: %v. error: %w", dir, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no %s files in the configuration directory: %v: %w", configDirPattern, dir, fs.ErrNotExist)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// mergeConfigFiles : reads config fragments and merges them into one configuration.
+// Each fragment is checked for unknown keys on its own, so errors name the file they are in.
+// files : []string paths of the fragments
+// returns : *viper.Viper viper instance holding the merged configuration
+// returns : error potential errors, naming both files when a setting conflicts or a volume is configured twice
+func mergeConfigFiles(files []string) (*viper.Viper, error) {
+	merged := viper.New()
+	settingFiles := make(map[string]string) // setting key -> file that set it
+	volumeFiles := make(map[string]string)  // volume ID, or device name when no ID is set -> file that configured it
+	volumes := make([]interface{}, 0)
+
+	for _, file := range files {
+		v := viper.New()
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read the configuration file: %v. error: %w", file, err)
+		}
+		var fragment runtime.Config
+		if err := unmarshalConfig(v, &fragment); err != nil {
+			return nil, fmt.Errorf("invalid configuration file: %v. error: %w", file, err)
+		}
+
+		for _, volume := range fragment.Volumes {
+			key := volume.AWSVolumeID
+			if key == "" {
+				key = volume.AWSDeviceName
+			}
+			if other, ok := volumeFiles[key]; ok {
+				return nil, fmt.Errorf("volume %v is configured in both %v and %v", key, other, file)
+			}
+			volumeFiles[key] = file
+		}
+		if fileVolumes, ok := v.Get(volumesKey).([]interface{}); ok {
+			volumes = append(volumes, fileVolumes...)
+		}
+
+		for _, key := range v.AllKeys() {
+			if key == volumesKey {
+				continue
+			}
+			value := v.Get(key)
+			if other, ok := settingFiles[key]; ok && !reflect.DeepEqual(merged.Get(key), value) {
+				return nil, fmt.Errorf("%v is set to %v in %v and %v in %v, it must be the same in every file", key, merged.Get(key), other, value, file)
+			}
+			settingFiles[key] = file
+			merged.Set(key, value)
+		}
+	}
+	merged.Set(volumesKey, volumes)
+
+	return merged, nil
+}
@@ -0,0 +1,121 @@
+package configutil
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFragments writes config fragments to a new directory and returns their paths in name order.
+func writeFragments(t *testing.T, fragments map[string]string) (string, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range fragments {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := configDirFiles(dir)
+	if err != nil {
+		t.Fatalf("configDirFiles() error = %v", err)
+	}
+	return dir, files
+}
+
+// TestMergeConfigFiles tests that fragments' volumes are joined, and that conflicting settings and
+// duplicate volumes are rejected naming both files.
+func TestMergeConfigFiles(t *testing.T) {
+	appVolume := "volumes:\n  - awsVolumeID: vol-app\n    awsDeviceName: /dev/sdf\n"
+	dbVolume := "volumes:\n  - awsVolumeID: vol-db\n    awsDeviceName: /dev/sdg\n"
+
+	tests := []struct {
+		name        string
+		fragments   map[string]string
+		wantVolumes int
+		wantErr     []string
+	}{
+		{
+			name: "volumes joined",
+			fragments: map[string]string{
+				"10-app.yaml": "checkIntervalSeconds: 60\n" + appVolume,
+				"20-db.yaml":  dbVolume,
+			},
+			wantVolumes: 2,
+		},
+		{
+			name: "same interval in both",
+			fragments: map[string]string{
+				"10-app.yaml": "checkIntervalSeconds: 60\n" + appVolume,
+				"20-db.yaml":  "checkIntervalSeconds: 60\n" + dbVolume,
+			},
+			wantVolumes: 2,
+		},
+		{
+			name: "conflicting interval",
+			fragments: map[string]string{
+				"10-app.yaml": "checkIntervalSeconds: 60\n" + appVolume,
+				"20-db.yaml":  "checkIntervalSeconds: 30\n" + dbVolume,
+			},
+			wantErr: []string{"checkintervalseconds", "10-app.yaml", "20-db.yaml"},
+		},
+		{
+			name: "duplicate volume",
+			fragments: map[string]string{
+				"10-app.yaml": appVolume,
+				"20-db.yaml":  appVolume,
+			},
+			wantErr: []string{"vol-app", "10-app.yaml", "20-db.yaml"},
+		},
+		{
+			name: "unknown key names the file",
+			fragments: map[string]string{
+				"10-app.yaml": appVolume,
+				"20-db.yaml":  "checkIntervalSecs: 60\n" + dbVolume,
+			},
+			wantErr: []string{"20-db.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, files := writeFragments(t, tt.fragments)
+			v, err := mergeConfigFiles(files)
+			if len(tt.wantErr) > 0 {
+				if err == nil {
+					t.Fatalf("mergeConfigFiles() error = nil, want an error naming %v", tt.wantErr)
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("mergeConfigFiles() error = %v, want it to mention %v", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mergeConfigFiles() error = %v", err)
+			}
+			if volumes, _ := v.Get(volumesKey).([]interface{}); len(volumes) != tt.wantVolumes {
+				t.Errorf("merged volumes = %d, want %d", len(volumes), tt.wantVolumes)
+			}
+			if got := v.GetInt("checkIntervalSeconds"); got != 60 {
+				t.Errorf("merged checkIntervalSeconds = %d, want 60", got)
+			}
+		})
+	}
+}
+
+// TestConfigDirFilesNotFound tests that a missing or empty directory is reported as not found, so startup can wait for it.
+func TestConfigDirFilesNotFound(t *testing.T) {
+	empty := t.TempDir()
+	if err := os.WriteFile(filepath.Join(empty, "README"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{filepath.Join(t.TempDir(), "missing"), empty} {
+		if _, err := configDirFiles(dir); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("configDirFiles(%v) error = %v, want fs.ErrNotExist", dir, err)
+		}
+	}
+}
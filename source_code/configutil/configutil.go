@@ -1,14 +1,28 @@
 package configutil
 
 import (
+	"bytes"
 	"ebs-monitor/aws"
+	"ebs-monitor/filesystem"
+	"ebs-monitor/logger"
 	"ebs-monitor/runtime"
 	"errors"
 	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 )
 
+// Initialise logger
+var l = logger.NewLogger()
 
 // GetConfigFromFile : reads a configuration file, parses its content, and returns runtime components.
 // Includes configuration validation for each volume and lookups for missing, important data.
@@ -18,25 +32,288 @@ import (
 // returns : time.Duration check interval
 // returns : error potential errors
 func GetConfigFromFile(filename string) ([]runtime.EBSVolumeConfig, int, error) {
+	cfg, err := LoadConfigFromFile(filename)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return cfg.Volumes, cfg.CheckIntervalSeconds, nil
+}
+
+// LoadConfigFromFile : reads a configuration file and returns the complete, validated configuration.
+// filename : string name of the file to read
+// returns : *runtime.Config validated configuration
+// returns : error potential errors
+func LoadConfigFromFile(filename string) (*runtime.Config, error) {
 	viper.SetConfigFile(filename)
 	if err := viper.ReadInConfig(); err != nil {
-		return nil, 0, fmt.Errorf("failed to read the configuration file: %v. error: %w", filename, err)
+		return nil, fmt.Errorf("failed to read the configuration file: %v. error: %w", filename, err)
 	}
 	var cfg runtime.Config
-	if err := viper.Unmarshal(&cfg); err != nil {
-		return nil, 0, fmt.Errorf("failed to unmarshal the configuration. error: %w", err)
+	if err := unmarshalConfig(viper.GetViper(), &cfg); err != nil {
+		return nil, err
+	}
+	if err := finaliseConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// IsConfigNotFound : reports whether a load failed because the configuration source does not exist (yet),
+// as opposed to existing but being invalid.
+// err : error error returned by LoadConfig or LoadConfigFromFile
+// returns : bool true if the source was not found
+func IsConfigNotFound(err error) bool {
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// Config source prefixes accepted by LoadConfig. Sources without a prefix are file paths.
+const (
+	sourceSSM  = "ssm://"  // An SSM Parameter Store parameter, e.g. ssm:///ebs-monitor/config?region=ap-southeast-2.
+	sourceEnv  = "env://"  // An environment variable, e.g. env://EBS_MONITOR_CONFIG.
+	sourceFile = "file://" // A local file, e.g. file:///etc/ebs-monitor/config.yaml.
+)
+
+// LoadConfig : loads the complete, validated configuration from a config source.
+// Every source is parsed and validated the same way, so the same YAML gives the same configuration.
+// source : string "ssm://<parameter name>[?region=<region>]", "env://<variable>", "dir://<directory>", or a file path (optionally "file://")
+// returns : *runtime.Config validated configuration
+// returns : error potential errors, wrapping fs.ErrNotExist if the source doesn't exist
+func LoadConfig(source string) (*runtime.Config, error) {
+	switch {
+	case strings.HasPrefix(source, sourceSSM):
+		name, region, err := parseSSMSource(source)
+		if err != nil {
+			return nil, err
+		}
+		return LoadConfigFromSSM(name, region)
+	case strings.HasPrefix(source, sourceEnv):
+		return LoadConfigFromEnv(strings.TrimPrefix(source, sourceEnv))
+	case strings.HasPrefix(source, sourceDir):
+		return LoadConfigFromDir(strings.TrimPrefix(source, sourceDir))
+	default:
+		return LoadConfigFromFile(strings.TrimPrefix(source, sourceFile))
+	}
+}
+
+// parseSSMSource : splits an ssm:// config source into its parameter name and region.
+// source : string e.g. "ssm:///ebs-monitor/config?region=ap-southeast-2" or "ssm://ebs-monitor-config"
+// returns : string parameter name, e.g. "/ebs-monitor/config"
+// returns : string region, empty when not given
+// returns : error potential errors
+func parseSSMSource(source string) (string, string, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid SSM config source: %v. error: %w", source, err)
+	}
+	name := u.Host + u.Path
+	if name == "" {
+		return "", "", fmt.Errorf("invalid SSM config source: %v, expected ssm://<parameter name>", source)
+	}
+	return name, u.Query().Get("region"), nil
+}
+
+// GetConfigFromSSM : reads a configuration from an SSM Parameter Store parameter, parses it, and returns runtime components.
+// paramName : string name of the parameter holding the YAML configuration
+// region : string region of the parameter, empty uses the instance's region
+// returns : []runtime.EBSVolumeConfig volume configurations
+// returns : int check interval
+// returns : error potential errors
+func GetConfigFromSSM(paramName, region string) ([]runtime.EBSVolumeConfig, int, error) {
+	cfg, err := LoadConfigFromSSM(paramName, region)
+	if err != nil {
+		return nil, 0, err
 	}
-	if err := ValidateConfig(&cfg); err != nil {
-		return nil, 0, fmt.Errorf("failed to validate the application configuration. error: %w", err)
+
+	return cfg.Volumes, cfg.CheckIntervalSeconds, nil
+}
+
+// LoadConfigFromSSM : reads a configuration from an SSM Parameter Store parameter and returns the complete, validated configuration.
+// paramName : string name of the parameter holding the YAML configuration
+// region : string region of the parameter, empty uses the instance's region
+// returns : *runtime.Config validated configuration
+// returns : error potential errors
+func LoadConfigFromSSM(paramName, region string) (*runtime.Config, error) {
+	body, err := aws.GetSSMParameter(paramName, region)
+	if err != nil {
+		return nil, err
 	}
+	return ParseConfig([]byte(body), "yaml")
+}
+
+// LoadConfigFromEnv : reads a configuration from an environment variable and returns the complete, validated configuration.
+// name : string name of the environment variable holding the YAML configuration
+// returns : *runtime.Config validated configuration
+// returns : error potential errors, wrapping fs.ErrNotExist if the variable is not set
+func LoadConfigFromEnv(name string) (*runtime.Config, error) {
+	body, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("environment variable %v is not set: %w", name, fs.ErrNotExist)
+	}
+	return ParseConfig([]byte(body), "yaml")
+}
+
+// ParseConfig : parses configuration content that did not come from a local file, e.g. a remote config source.
+// data : []byte raw configuration content
+// format : string content format understood by viper, e.g. "yaml" or "json"
+// returns : *runtime.Config validated configuration
+// returns : error potential errors
+func ParseConfig(data []byte, format string) (*runtime.Config, error) {
+	v := viper.New()
+	v.SetConfigType(format)
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to read the %s configuration. error: %w", format, err)
+	}
+	var cfg runtime.Config
+	if err := unmarshalConfig(v, &cfg); err != nil {
+		return nil, err
+	}
+	if err := finaliseConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// unmarshalConfig : decodes configuration read by viper, rejecting keys the configuration doesn't have.
+// A misspelt key would otherwise be ignored, leaving its setting at zero.
+// v : *viper.Viper viper instance holding the configuration
+// cfg : *runtime.Config configuration to decode into
+// returns : error potential errors, listing any unknown keys and where they were found
+func unmarshalConfig(v *viper.Viper, cfg *runtime.Config) error {
+	err := v.UnmarshalExact(cfg)
+	var decodeErr *mapstructure.Error
+	if errors.As(err, &decodeErr) {
+		return fmt.Errorf("failed to unmarshal the configuration, check for misspelt or unsupported keys. error: %s", strings.Join(describeDecodeErrors(decodeErr.Errors), "; "))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal the configuration. error: %w", err)
+	}
+	return nil
+}
+
+// invalidKeysPattern matches mapstructure's unknown key errors, e.g. "'Volumes[0]' has invalid keys: resizethreshhold".
+var invalidKeysPattern = regexp.MustCompile(`^'([^']*)' has invalid keys: (.*)$`)
+
+// describeDecodeErrors : rewrites decode errors using config key paths, e.g. "volumes[0]: unknown keys resizethreshhold".
+// errs : []string errors reported by mapstructure
+// returns : []string the rewritten errors, sorted
+func describeDecodeErrors(errs []string) []string {
+	described := make([]string, 0, len(errs))
+	for _, e := range errs {
+		match := invalidKeysPattern.FindStringSubmatch(e)
+		if match == nil {
+			described = append(described, e)
+			continue
+		}
+
+		path := "top level"
+		if match[1] != "" {
+			segments := strings.Split(match[1], ".")
+			for i, segment := range segments {
+				segments[i] = strings.ToLower(segment[:1]) + segment[1:]
+			}
+			path = strings.Join(segments, ".")
+		}
+		described = append(described, fmt.Sprintf("%s: unknown keys %s", path, match[2]))
+	}
+	sort.Strings(described)
+	return described
+}
+
+// finaliseConfig : validates a freshly unmarshalled configuration and drops volumes missing minimum fields.
+// cfg : *runtime.Config configuration to finalise
+// returns : error potential errors
+func finaliseConfig(cfg *runtime.Config) error {
+	listed := len(cfg.Volumes)
+	if err := discoverVolumes(cfg); err != nil {
+		return err
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		return fmt.Errorf("failed to validate the application configuration. error: %w", err)
+	}
+	// Volumes discovered by tag pass the same gate as volumes added while running
+	cfg.Volumes = append(cfg.Volumes[:listed:listed], gateNewVolumes(cfg.Volumes[listed:], cfg.FailOnRegionMismatch)...)
 	validVolumes := make([]runtime.EBSVolumeConfig, 0)
 	for _, volume := range cfg.Volumes {
 		if checkMinimumFields(volume) {
 			validVolumes = append(validVolumes, volume)
 		}
 	}
+	cfg.Volumes = validVolumes
+
+	return nil
+}
+
+// discoverVolumes : adds the attached volumes matching the config's volumeTagFilters to its volume list.
+// Discovered volumes take their settings from volumeTemplate. Does nothing when no tag filters are set.
+// cfg : *runtime.Config configuration to add the volumes to
+// returns : error potential errors
+func discoverVolumes(cfg *runtime.Config) error {
+	if len(cfg.VolumeTagFilters) == 0 {
+		return nil
+	}
+
+	// Discovered volumes are dropped by checkMinimumFields unless the template says how to resize them
+	template := cfg.VolumeTemplate
+	template.AWSVolumeID = "discovered"
+	if !checkMinimumFields(template) {
+		return errors.New("volumeTagFilters requires a volumeTemplate with an increment and a threshold")
+	}
+
+	region := cfg.VolumeTemplate.AWSRegion
+	if region == "" {
+		localRegion, err := aws.GetLocalRegion()
+		if err != nil {
+			return fmt.Errorf("failed to get local region to discover volumes. error: %w", err)
+		}
+		region = localRegion
+	}
+
+	// Discovery runs before the top-level role is set for the AWS clients, so it's passed explicitly,
+	// the role discovered volumes will assume
+	roleARN := cfg.VolumeTemplate.AssumeRoleARN
+	if roleARN == "" {
+		roleARN = cfg.AssumeRoleARN
+	}
+	discovered, err := aws.GetVolumesByTags(cfg.VolumeTagFilters, region, roleARN)
+	if err != nil {
+		return err
+	}
+	cfg.Volumes = mergeDiscoveredVolumes(cfg.Volumes, discovered, cfg.VolumeTemplate)
+	return nil
+}
+
+// mergeDiscoveredVolumes : appends discovered volumes to the listed volumes, applying the template's settings.
+// Volumes already listed, by volume ID or device name, keep their listed settings.
+// listed : []runtime.EBSVolumeConfig volumes listed in the config
+// discovered : []runtime.EBSVolumeConfig volumes found by tag, with their ID, device name and region
+// template : runtime.EBSVolumeConfig settings for discovered volumes
+// returns : []runtime.EBSVolumeConfig the combined volume list
+func mergeDiscoveredVolumes(listed, discovered []runtime.EBSVolumeConfig, template runtime.EBSVolumeConfig) []runtime.EBSVolumeConfig {
+	known := make(map[string]bool, len(listed)*2)
+	for _, volume := range listed {
+		if volume.AWSVolumeID != "" {
+			known[volume.AWSVolumeID] = true
+		}
+		if volume.AWSDeviceName != "" {
+			known[volume.AWSDeviceName] = true
+		}
+	}
 
-	return validVolumes, cfg.CheckIntervalSeconds, nil
+	merged := listed
+	for _, found := range discovered {
+		if known[found.AWSVolumeID] || known[found.AWSDeviceName] {
+			continue
+		}
+		volume := template
+		volume.AWSVolumeID = found.AWSVolumeID
+		volume.AWSDeviceName = found.AWSDeviceName
+		volume.AWSRegion = found.AWSRegion
+		merged = append(merged, volume)
+	}
+	return merged
 }
 
 // checkMinimumFields : checks if a volume configuration is valid
@@ -44,8 +321,8 @@ func GetConfigFromFile(filename string) ([]runtime.EBSVolumeConfig, int, error)
 // returns : bool : validity of the volume configuration
 func checkMinimumFields(volume runtime.EBSVolumeConfig) bool {
 	if (volume.AWSVolumeID == "" && volume.AWSDeviceName == "") ||
-		(volume.IncrementSizeGB == 0 && volume.IncrementSizePercent == 0) ||
-		volume.ResizeThreshold == 0 {
+		(volume.IncrementSizeGB == 0 && volume.IncrementSizePercent == 0 && volume.GrowthStrategy != runtime.GrowthStrategyDoubleUntilCap) ||
+		(volume.ResizeThreshold == 0 && volume.UsedCeilingGB == 0 && volume.MinFreeGB == 0) {
 		return false
 	}
 	return true
@@ -56,14 +333,157 @@ func checkMinimumFields(volume runtime.EBSVolumeConfig) bool {
 // config : Config : configuration to validate
 // returns : error : potential errors
 func ValidateConfig(config *runtime.Config) error {
+	if err := validateNotificationBatch(config.NotificationBatch); err != nil {
+		return err
+	}
+	if err := validateUnmountedAction(config.UnmountedAction); err != nil {
+		return err
+	}
+	if err := logger.ValidateNotificationChannels(config.NotificationChannels, config.SlackWebhookURL); err != nil {
+		return err
+	}
+	if err := validateSNSTopicARN(config.SNSTopicARN); err != nil {
+		return fmt.Errorf("invalid snsTopicARN. error: %w", err)
+	}
+	if config.EnableSNS && config.SNSTopicARN == "" {
+		return errors.New("enableSNS requires snsTopicARN")
+	}
+	if config.LogLevel != "" {
+		if _, err := logger.ParseLevel(config.LogLevel); err != nil {
+			return fmt.Errorf("invalid logLevel. error: %w", err)
+		}
+	}
+	if err := logger.ValidateFormat(config.LogFormat); err != nil {
+		return fmt.Errorf("invalid logFormat. error: %w", err)
+	}
+	if err := logger.ValidateTarget(config.LogTarget); err != nil {
+		return fmt.Errorf("invalid logTarget. error: %w", err)
+	}
+	if config.NotificationLevel != "" {
+		if _, err := logger.ParseLevel(config.NotificationLevel); err != nil {
+			return fmt.Errorf("invalid notificationLevel. error: %w", err)
+		}
+	}
+	if err := validatePositiveInt(config.LogFileMaxSizeMB); err != nil {
+		return fmt.Errorf("invalid logFileMaxSizeMB. error: %w", err)
+	}
+	if err := validatePositiveInt(config.LogFileMaxBackups); err != nil {
+		return fmt.Errorf("invalid logFileMaxBackups. error: %w", err)
+	}
+	if err := validatePositiveInt(config.MaxUptimeHours); err != nil {
+		return fmt.Errorf("invalid maxUptimeHours. error: %w", err)
+	}
+	if err := validateMaxRetries(config.MaxRetries); err != nil {
+		return fmt.Errorf("invalid maxRetries. error: %w", err)
+	}
+	if err := validatePositiveInt(config.AlertCooldownSeconds); err != nil {
+		return fmt.Errorf("invalid alertCooldownSeconds. error: %w", err)
+	}
+	if err := validatePositiveInt(config.EventRetentionHours); err != nil {
+		return fmt.Errorf("invalid eventRetentionHours. error: %w", err)
+	}
+	if err := validateDisplayUnit(config.DisplayUnit); err != nil {
+		return fmt.Errorf("invalid displayUnit. error: %w", err)
+	}
+	if err := validateResizeCooldown(config.ResizeCooldownSeconds, config.EventRetention()); err != nil {
+		return fmt.Errorf("invalid resizeCooldownSeconds. error: %w", err)
+	}
+	if err := validatePositiveInt(config.MaxConcurrentChecks); err != nil {
+		return fmt.Errorf("invalid maxConcurrentChecks. error: %w", err)
+	}
+	if err := validateJitterPercent(config.CheckIntervalJitterPercent); err != nil {
+		return fmt.Errorf("invalid checkIntervalJitterPercent. error: %w", err)
+	}
+	if err := validatePositiveInt(config.CommandTimeoutSeconds); err != nil {
+		return fmt.Errorf("invalid commandTimeoutSeconds. error: %w", err)
+	}
+	if err := validatePositiveInt(config.ResizeCommandTimeoutSeconds); err != nil {
+		return fmt.Errorf("invalid resizeCommandTimeoutSeconds. error: %w", err)
+	}
+	if err := validateResizeCommands(config.ResizeCommands); err != nil {
+		return fmt.Errorf("invalid resizeCommands. error: %w", err)
+	}
+	if err := validateVolumePrices(config.VolumePrices); err != nil {
+		return fmt.Errorf("invalid volumePrices. error: %w", err)
+	}
+	if err := validatePositiveInt(config.RequarantineRetrySeconds); err != nil {
+		return fmt.Errorf("invalid requarantineRetrySeconds. error: %w", err)
+	}
+	if err := validatePositiveInt(config.ErrorThreshold); err != nil {
+		return fmt.Errorf("invalid errorThreshold. error: %w", err)
+	}
+	if err := validateRoleARN(config.AssumeRoleARN); err != nil {
+		return fmt.Errorf("invalid assumeRoleARN. error: %w", err)
+	}
+	if err := validateRemoteConfigURL(config.RemoteConfig.URL); err != nil {
+		return fmt.Errorf("invalid remoteConfig url. error: %w", err)
+	}
 	for i := range config.Volumes {
-		if err := validateVolume(&config.Volumes[i]); err != nil {
+		// Volumes assume the top-level role unless they set their own
+		if config.Volumes[i].AssumeRoleARN == "" {
+			config.Volumes[i].AssumeRoleARN = config.AssumeRoleARN
+		}
+		if err := validateVolume(&config.Volumes[i], config.FailOnRegionMismatch); err != nil {
 			return err
 		}
+		if err := validateVolumeRetention(config, config.Volumes[i]); err != nil {
+			return fmt.Errorf("invalid eventRetentionHours for volume %v%v. error: %w", config.Volumes[i].AWSVolumeID, config.Volumes[i].AWSDeviceName, err)
+		}
+	}
+	return nil
+}
+
+// MaxAttachedVolumes is the most EBS volumes, including the root volume, one instance can practically attach.
+// Nitro instances support up to 28 attachments.
+const MaxAttachedVolumes = 28
+
+// ValidateNewVolume : checks that a volume added while running (e.g. from a config reload), or discovered by tag,
+// can be monitored. Runs the checks initial load relies on: the volume is in the instance's region, the instance
+// is within its attachment limit, the volume is attached to this instance and in use, and its filesystem(s)
+// resolve to local mount points.
+// volume : runtime.EBSVolumeConfig : volume configuration, already validated by ValidateConfig
+// failOnRegionMismatch : bool : reject, rather than warn about, a volume outside the instance's region
+// returns : error : returns an error describing why the volume can't be monitored
+func ValidateNewVolume(volume runtime.EBSVolumeConfig, failOnRegionMismatch bool) error {
+	if err := checkVolumeRegion(volume, failOnRegionMismatch); err != nil {
+		return err
+	}
+	attached, err := aws.CountAttachedVolumes(volume.AWSRegion, volume.AssumeRoleARN)
+	if err != nil {
+		return fmt.Errorf("failed to count the volumes attached to this instance. error: %w", err)
+	}
+	if attached > MaxAttachedVolumes {
+		return fmt.Errorf("this instance has %d volumes attached, more than the %d one instance can practically attach", attached, MaxAttachedVolumes)
+	}
+	if err := aws.CheckVolumeAttached(volume); err != nil {
+		return fmt.Errorf("volume is not attached to this instance. error: %w", err)
+	}
+	if _, err := filesystem.GetVolumeMountPoints(volume); err != nil {
+		return fmt.Errorf("failed to resolve the local mount point. error: %w", err)
 	}
 	return nil
 }
 
+// gateNewVolumes : drops the volumes that fail ValidateNewVolume, logging why each was rejected.
+// volumes : []runtime.EBSVolumeConfig : volumes to check
+// failOnRegionMismatch : bool : reject, rather than warn about, volumes outside the instance's region
+// returns : []runtime.EBSVolumeConfig : the volumes that can be monitored
+func gateNewVolumes(volumes []runtime.EBSVolumeConfig, failOnRegionMismatch bool) []runtime.EBSVolumeConfig {
+	accepted := make([]runtime.EBSVolumeConfig, 0, len(volumes))
+	for _, volume := range volumes {
+		if err := ValidateNewVolume(volume, failOnRegionMismatch); err != nil {
+			l.Log(logger.LogWarning, "Rejected volume discovered by tag", map[string]interface{}{
+				"VolumeID":   volume.AWSVolumeID,
+				"DeviceName": volume.AWSDeviceName,
+				"Error":      err,
+			})
+			continue
+		}
+		accepted = append(accepted, volume)
+	}
+	return accepted
+}
+
 /*
 -------------------------
 Helper functions to validate the config
@@ -73,10 +493,16 @@ Helper functions to validate the config
 // validateAWSVolumeID : checks if a string matches AWS's volume ID format.
 // id : string volume ID to validate
 // region : string : AWS region where the volume is located
+// roleARN : string : IAM role to assume for the lookup, empty uses the default credential chain
 // returns : error potential errors
-func validateAWSVolumeID(id, region string) error {
-	valid, err := aws.ValidateVolumeID(id, region)
-	if err != nil {
+func validateAWSVolumeID(id, region, roleARN string) error {
+	valid, err := aws.ValidateVolumeID(id, region, roleARN)
+	switch {
+	case errors.Is(err, aws.ErrVolumeNotFound):
+		return fmt.Errorf("AWS volume %s does not exist in region %s. error: %w", id, region, err)
+	case errors.Is(err, aws.ErrAccessDenied):
+		return fmt.Errorf("not permitted to describe AWS volume %s, check the ec2:DescribeVolumes permission. error: %w", id, err)
+	case err != nil:
 		return fmt.Errorf("failed to validate aws volume id. error: %w", err)
 	}
 	if !valid {
@@ -88,9 +514,10 @@ func validateAWSVolumeID(id, region string) error {
 // validateAWSDeviceName : checks if a string matches AWS's device name format.
 // name : string device name to validate
 // region : string : AWS region where the device is located
+// roleARN : string : IAM role to assume for the lookup, empty uses the default credential chain
 // returns : error potential errors
-func validateAWSDeviceName(name, region string) error {
-	valid, err := aws.ValidateDeviceName(name, region)
+func validateAWSDeviceName(name, region, roleARN string) error {
+	valid, err := aws.ValidateDeviceName(name, region, roleARN)
 	if err != nil {
 		return fmt.Errorf("failed to validate aws device name. error: %w", err)
 	}
@@ -114,6 +541,50 @@ func validateAWSRegion(region string) error {
 	return nil
 }
 
+// roleARNPattern : matches an IAM role ARN, in any partition
+var roleARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/.+$`)
+
+// validateRoleARN : checks if a string is an IAM role ARN.
+// roleARN : string : role ARN to validate, empty is valid and uses the default credential chain
+// returns : error : returns an error if the ARN is not an IAM role ARN
+func validateRoleARN(roleARN string) error {
+	if roleARN != "" && !roleARNPattern.MatchString(roleARN) {
+		return fmt.Errorf("invalid IAM role ARN: %s", roleARN)
+	}
+	return nil
+}
+
+// snsTopicARNPattern : matches an SNS topic ARN, in any partition
+var snsTopicARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:sns:[a-z0-9-]+:\d{12}:[A-Za-z0-9_-]+(\.fifo)?$`)
+
+// validateSNSTopicARN : checks if a string is an SNS topic ARN.
+// arn : string : topic ARN to validate, empty is valid and disables SNS notifications
+// returns : error : returns an error if the ARN is not an SNS topic ARN
+func validateSNSTopicARN(arn string) error {
+	if arn != "" && !snsTopicARNPattern.MatchString(arn) {
+		return fmt.Errorf("invalid SNS topic ARN: %s", arn)
+	}
+	return nil
+}
+
+// validateRemoteConfigURL : checks that the remote config is fetched over https.
+// The remote config changes which volumes are resized and how, so it mustn't be open to tampering in transit.
+// rawURL : string : remoteConfig url to validate, empty is valid and disables remote config
+// returns : error : returns an error if the URL isn't an https URL
+func validateRemoteConfigURL(rawURL string) error {
+	if rawURL == "" {
+		return nil
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "https" || parsed.Host == "" {
+		return fmt.Errorf("%s is not an https URL", rawURL)
+	}
+	return nil
+}
+
 // validatePositiveInt : checks if an int is greater than or equal to 0.
 // num : int number to validate
 // returns : error potential errors
@@ -124,10 +595,317 @@ func validatePositiveInt(num int) error {
 	return nil
 }
 
+// validateMaxRetries : checks that maxRetries is a number of retries, or -1 to disable retries.
+// retries : int : retries to validate, 0 uses the default
+// returns : error : returns an error if the number is below -1
+func validateMaxRetries(retries int) error {
+	if retries < -1 {
+		return fmt.Errorf("invalid number: %d, expected -1 to disable retries or a non-negative number", retries)
+	}
+	return nil
+}
+
+// validateThresholdBasis : checks if the threshold basis is a supported value.
+// basis : string : threshold basis to validate, empty defaults to "total"
+// returns : error : returns an error if the basis is not supported
+func validateThresholdBasis(basis string) error {
+	switch basis {
+	case "", runtime.ThresholdBasisTotal, runtime.ThresholdBasisUsable:
+		return nil
+	default:
+		return fmt.Errorf("invalid threshold basis: %s, expected '%s' or '%s'", basis, runtime.ThresholdBasisTotal, runtime.ThresholdBasisUsable)
+	}
+}
+
+// validateDisplayUnit : checks if the display unit is a supported value.
+// unit : string : display unit to validate, empty defaults to "GiB"
+// returns : error : returns an error if the unit is not supported
+func validateDisplayUnit(unit string) error {
+	switch unit {
+	case "", runtime.DisplayUnitGiB, runtime.DisplayUnitGB, runtime.DisplayUnitTiB, runtime.DisplayUnitAuto:
+		return nil
+	default:
+		return fmt.Errorf("invalid display unit: %s, expected '%s', '%s', '%s' or '%s'", unit, runtime.DisplayUnitGiB, runtime.DisplayUnitGB, runtime.DisplayUnitTiB, runtime.DisplayUnitAuto)
+	}
+}
+
+// validateUnmountedAction : checks if the unmounted action is a supported value.
+// action : string : unmounted action to validate, empty defaults to "alert"
+// returns : error : returns an error if the action is not supported
+func validateUnmountedAction(action string) error {
+	switch action {
+	case "", runtime.UnmountedActionAlert, runtime.UnmountedActionError:
+		return nil
+	default:
+		return fmt.Errorf("invalid unmounted action: %s, expected '%s' or '%s'", action, runtime.UnmountedActionAlert, runtime.UnmountedActionError)
+	}
+}
+
+// validatePartitions : checks that a partition list is consistent.
+// Partition numbers must be positive and unique, filesystems must be resizable, every
+// non-swap partition needs its own mount point, and the last partition in disk order, the only one that grows
+// into a resize, must have a filesystem rather than swap.
+// partitions : []runtime.PartitionConfig : partitions to validate
+// returns : error : returns an error describing the first invalid partition
+func validatePartitions(partitions []runtime.PartitionConfig) error {
+	if len(partitions) == 0 {
+		return nil
+	}
+	numbers := make(map[int]bool, len(partitions))
+	mountPoints := make(map[string]bool, len(partitions))
+	last := partitions[0]
+	for _, partition := range partitions {
+		if partition.Partition > last.Partition {
+			last = partition
+		}
+		if partition.Partition < 1 {
+			return fmt.Errorf("invalid partition number: %d", partition.Partition)
+		}
+		if numbers[partition.Partition] {
+			return fmt.Errorf("partition %d is listed more than once", partition.Partition)
+		}
+		numbers[partition.Partition] = true
+
+		switch partition.FilesystemType {
+		case "swap":
+			continue
+		case "ext4", "xfs":
+		default:
+			return fmt.Errorf("unsupported filesystem type for partition %d: %s", partition.Partition, partition.FilesystemType)
+		}
+
+		if partition.MountPoint == "" {
+			return fmt.Errorf("partition %d is missing a mount point", partition.Partition)
+		}
+		if mountPoints[partition.MountPoint] {
+			return fmt.Errorf("mount point %s is listed for more than one partition", partition.MountPoint)
+		}
+		mountPoints[partition.MountPoint] = true
+	}
+	if len(mountPoints) == 0 {
+		return errors.New("every partition is swap, at least one must have a filesystem to monitor")
+	}
+	if last.FilesystemType == "swap" {
+		return fmt.Errorf("the last partition, %d, is swap, so no filesystem would grow when the volume is resized", last.Partition)
+	}
+	return nil
+}
+
+// checkRegionMatch : checks that a volume's region is the region of the local instance.
+// Attached EBS volumes are always in the instance's region, so a mismatch indicates a config error.
+// volumeRegion : string : region configured for the volume
+// localRegion : string : region of the local instance
+// returns : error : returns an error if the regions differ
+func checkRegionMatch(volumeRegion, localRegion string) error {
+	if volumeRegion != localRegion {
+		return fmt.Errorf("configured region %s does not match the instance region %s", volumeRegion, localRegion)
+	}
+	return nil
+}
+
+// validateGrowthWindows : checks that growth windows have valid times and a positive multiplier.
+// windows : []runtime.GrowthWindow : growth windows to validate
+// returns : error : returns an error describing the first invalid window
+func validateGrowthWindows(windows []runtime.GrowthWindow) error {
+	for _, window := range windows {
+		if _, _, err := window.Bounds(); err != nil {
+			return err
+		}
+		if window.Multiplier <= 0 {
+			return fmt.Errorf("growth window %s-%s multiplier should be greater than 0", window.WindowStart, window.WindowEnd)
+		}
+	}
+	return nil
+}
+
+// validateIncrementMode : checks that a volume sets at most one increment.
+// volume : runtime.EBSVolumeConfig : volume configuration to validate
+// returns : error : returns an error if both increments are set
+func validateIncrementMode(volume runtime.EBSVolumeConfig) error {
+	if volume.IncrementSizeGB > 0 && volume.IncrementSizePercent > 0 {
+		return errors.New("incrementSizeGB and incrementSizePercent are mutually exclusive, set only one")
+	}
+	return nil
+}
+
+// validateGrowthStrategy : checks that a volume's growth strategy is supported and has the settings it needs.
+// volume : runtime.EBSVolumeConfig : volume configuration to validate, an empty strategy is inferred from the increment
+// returns : error : returns an error if the strategy is unknown or its setting is missing
+func validateGrowthStrategy(volume runtime.EBSVolumeConfig) error {
+	switch volume.GrowthStrategy {
+	case "":
+		return nil
+	case runtime.GrowthStrategyFixed:
+		if volume.IncrementSizeGB == 0 {
+			return fmt.Errorf("growthStrategy %s requires incrementSizeGB", volume.GrowthStrategy)
+		}
+	case runtime.GrowthStrategyPercent:
+		if volume.IncrementSizePercent == 0 {
+			return fmt.Errorf("growthStrategy %s requires incrementSizePercent", volume.GrowthStrategy)
+		}
+	case runtime.GrowthStrategyDoubleUntilCap:
+		if volume.MaxSizeGB == 0 {
+			return fmt.Errorf("growthStrategy %s requires maxSizeGB", volume.GrowthStrategy)
+		}
+	default:
+		return fmt.Errorf("invalid growth strategy: %s, expected '%s', '%s' or '%s'", volume.GrowthStrategy, runtime.GrowthStrategyFixed, runtime.GrowthStrategyPercent, runtime.GrowthStrategyDoubleUntilCap)
+	}
+	return nil
+}
+
+// validateThresholdMode : checks that a volume uses exactly one threshold mode.
+// volume : runtime.EBSVolumeConfig : volume configuration to validate
+// returns : error : returns an error if more than one threshold mode is set
+func validateThresholdMode(volume runtime.EBSVolumeConfig) error {
+	if volume.ResizeThreshold > 0 && volume.UsedCeilingGB > 0 {
+		return errors.New("resizeThreshold and usedCeilingGB are mutually exclusive, set only one")
+	}
+	return nil
+}
+
+// validateLocalMountPoint : checks that a configured mount point exists and has a filesystem mounted.
+// volume : runtime.EBSVolumeConfig : volume configuration to validate
+// returns : error : returns an error if the mount point is missing, not mounted, or set on a partitioned volume
+func validateLocalMountPoint(volume runtime.EBSVolumeConfig) error {
+	if volume.LocalMountPoint == "" {
+		return nil
+	}
+	if len(volume.Partitions) > 0 {
+		return errors.New("localMountPoint can't be used with partitions, set each partition's mountPoint instead")
+	}
+	info, err := os.Stat(volume.LocalMountPoint)
+	if err != nil {
+		return fmt.Errorf("localMountPoint %s can't be read. error: %w", volume.LocalMountPoint, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("localMountPoint %s is not a directory", volume.LocalMountPoint)
+	}
+	if err := filesystem.CheckMounted(volume.LocalMountPoint); err != nil {
+		return fmt.Errorf("localMountPoint is not a mount point. error: %w", err)
+	}
+	return nil
+}
+
+// validateResizeCooldown : checks that a resize cooldown fits within the event log's history.
+// seconds : int : resize cooldown to validate, 0 disables it
+// retention : time.Duration : how long the event log keeps history
+// returns : error : returns an error if the cooldown is negative or longer than the history kept
+func validateResizeCooldown(seconds int, retention time.Duration) error {
+	if err := validatePositiveInt(seconds); err != nil {
+		return err
+	}
+	if time.Duration(seconds)*time.Second > retention {
+		return fmt.Errorf("value should be at most %d, as resizes are only remembered for %v", int(retention.Seconds()), retention)
+	}
+	return nil
+}
+
+// validateVolumeRetention : checks that the event log keeps enough history for a volume's safety settings.
+// maxResizesPerDay counts resizes over the last 24 hours, so it needs at least a day of history. Too short a
+// history for minObservationCycles is only warned about, as jitter and slow checks make its window approximate.
+// config : *runtime.Config : configuration holding the retention and default check interval
+// volume : runtime.EBSVolumeConfig : volume configuration to check
+// returns : error : returns an error if maxResizesPerDay would undercount resizes
+func validateVolumeRetention(config *runtime.Config, volume runtime.EBSVolumeConfig) error {
+	retention := config.EventRetention()
+	if volume.MaxResizesPerDay > 0 && retention < 24*time.Hour {
+		return fmt.Errorf("maxResizesPerDay needs at least 24 hours of history, but events are only kept for %v", retention)
+	}
+	if window := time.Duration(volume.MinObservationCycles) * config.CheckInterval(volume); window > retention {
+		l.Log(logger.LogWarning, "minObservationCycles spans longer than the event log keeps history, the volume may never be resized", map[string]interface{}{
+			"VolumeID":               volume.AWSVolumeID,
+			"DeviceName":             volume.AWSDeviceName,
+			"Min Observation Cycles": volume.MinObservationCycles,
+			"Observation Window":     window,
+			"Event Retention":        retention,
+		})
+	}
+	return nil
+}
+
+// validateResizeCommands : checks that each overridden resize binary is for a supported filesystem and can be run.
+// commands : map[string]string : binary for each filesystem type
+// returns : error : returns an error if a filesystem type is unsupported or its binary is missing or not executable
+func validateResizeCommands(commands map[string]string) error {
+	for fsType, binary := range commands {
+		if !filesystem.IsResizableFilesystem(fsType) {
+			return fmt.Errorf("unsupported filesystem type %q", fsType)
+		}
+		if _, err := exec.LookPath(binary); err != nil {
+			return fmt.Errorf("%s resize command %q can't be run. error: %w", fsType, binary, err)
+		}
+	}
+	return nil
+}
+
+// validateVolumePrices : checks that each volume type's price is not negative.
+// prices : map[string]float64 : price per GiB-month for each volume type
+// returns : error : returns an error if a price is negative
+func validateVolumePrices(prices map[string]float64) error {
+	for volumeType, price := range prices {
+		if price < 0 {
+			return fmt.Errorf("%s price should be greater than or equal to 0, got %v", volumeType, price)
+		}
+	}
+	return nil
+}
+
+// maxJitterPercent is the largest checkIntervalJitterPercent accepted. Nearer 100 a check could follow the last
+// almost immediately, or take nearly twice its interval, defeating the interval.
+const maxJitterPercent = 50
+
+// validateJitterPercent : checks that a jitter is a percentage of the check interval, up to maxJitterPercent.
+// percent : int : jitter percentage to validate, 0 disables it
+// returns : error : returns an error if the percentage is outside 0-maxJitterPercent
+func validateJitterPercent(percent int) error {
+	if percent < 0 || percent > maxJitterPercent {
+		return fmt.Errorf("value should be between 0 and %d, got %d", maxJitterPercent, percent)
+	}
+	return nil
+}
+
+// validateInodeResizeThreshold : checks that an inode resize threshold is a percentage.
+// threshold : int : inode resize threshold to validate, 0 disables it
+// returns : error : returns an error if the threshold is outside 0-100
+func validateInodeResizeThreshold(threshold int) error {
+	if threshold < 0 || threshold > 100 {
+		return fmt.Errorf("inodeResizeThreshold should be between 0 and 100, got %d", threshold)
+	}
+	return nil
+}
+
+// checkVolumeRegion : compares a volume's region with the local instance's, warning about a mismatch.
+// volume : runtime.EBSVolumeConfig : volume configuration with a valid region
+// failOnRegionMismatch : bool : return an error, rather than warn, when the region differs from the instance's
+// returns : error : returns an error on a mismatch, or when the instance's region is unknown, if failOnRegionMismatch is set
+func checkVolumeRegion(volume runtime.EBSVolumeConfig, failOnRegionMismatch bool) error {
+	localRegion, err := aws.GetLocalRegion()
+	if err != nil {
+		// A valid region is only compared with the instance's when the instance region is known
+		if failOnRegionMismatch {
+			return fmt.Errorf("failed to get local region to compare with %s. error: %w", volume.AWSRegion, err)
+		}
+		return nil
+	}
+	if err := checkRegionMatch(volume.AWSRegion, localRegion); err != nil {
+		if failOnRegionMismatch {
+			return fmt.Errorf("invalid region for volume %v%v. error: %w", volume.AWSVolumeID, volume.AWSDeviceName, err)
+		}
+		l.Log(logger.LogWarning, "Configured region differs from the instance region, the volume is unlikely to be found", map[string]interface{}{
+			"VolumeID":        volume.AWSVolumeID,
+			"DeviceName":      volume.AWSDeviceName,
+			"Region":          volume.AWSRegion,
+			"Instance Region": localRegion,
+		})
+	}
+	return nil
+}
+
 // validateVolume : validates the volume configuration
 // volume : runtime.EBSVolumeConfig : volume configuration to validate
+// failOnRegionMismatch : bool : return an error, rather than warn, when the region differs from the instance's
 // returns : error : potential errors
-func validateVolume(volume *runtime.EBSVolumeConfig) error {
+func validateVolume(volume *runtime.EBSVolumeConfig, failOnRegionMismatch bool) error {
 	// Try to validate the region from the config
 	err := validateAWSRegion(volume.AWSRegion)
 	if err != nil {
@@ -136,17 +914,19 @@ func validateVolume(volume *runtime.EBSVolumeConfig) error {
 		if err != nil {
 			return fmt.Errorf("failed to get local region. error: %w", err)
 		}
+	} else if err := checkVolumeRegion(*volume, failOnRegionMismatch); err != nil {
+		return err
 	}
 
 	// Use the region (either from the config or the local region) for the rest of the validations
 	// If AWSVolumeID is provided and device name is omitted, perform lookup
 	if volume.AWSVolumeID != "" {
-		if err := validateAWSVolumeID(volume.AWSVolumeID, volume.AWSRegion); err != nil {
+		if err := validateAWSVolumeID(volume.AWSVolumeID, volume.AWSRegion, volume.AssumeRoleARN); err != nil {
 			return err
 		}
 
 		if volume.AWSDeviceName == "" {
-			deviceName, err := aws.GetDeviceNameByVolumeID(volume.AWSVolumeID, volume.AWSRegion)
+			deviceName, err := aws.GetDeviceNameByVolumeID(volume.AWSVolumeID, volume.AWSRegion, volume.AssumeRoleARN)
 			if err != nil {
 				return fmt.Errorf("failed to get device name for volume ID: %v, error: %w", volume.AWSVolumeID, err)
 			}
@@ -156,11 +936,11 @@ func validateVolume(volume *runtime.EBSVolumeConfig) error {
 
 		// if AWSVolumeID is omitted but device name is provided, perform
 	} else if volume.AWSDeviceName != "" {
-		if err := validateAWSDeviceName(volume.AWSDeviceName, volume.AWSRegion); err != nil {
+		if err := validateAWSDeviceName(volume.AWSDeviceName, volume.AWSRegion, volume.AssumeRoleARN); err != nil {
 			return err
 		}
 
-		volumeID, err := aws.GetVolumeIDByDeviceName(volume.AWSDeviceName, volume.AWSRegion)
+		volumeID, err := aws.GetVolumeIDByDeviceName(volume.AWSDeviceName, volume.AWSRegion, volume.AssumeRoleARN)
 		if err != nil {
 			return fmt.Errorf("failed to get volume ID for device name: %v, error: %w", volume.AWSDeviceName, err)
 		}
@@ -177,5 +957,89 @@ func validateVolume(volume *runtime.EBSVolumeConfig) error {
 	if err := validatePositiveInt(volume.ResizeThreshold); err != nil {
 		return err
 	}
+	if err := validatePositiveInt(volume.PostAWSResizeDelaySeconds); err != nil {
+		return err
+	}
+	if err := validatePositiveInt(volume.ModificationWaitSeconds); err != nil {
+		return err
+	}
+	if err := validatePositiveInt(volume.CheckIntervalSeconds); err != nil {
+		return err
+	}
+	if err := validatePositiveInt(volume.MaxIncrementGB); err != nil {
+		return err
+	}
+	if err := validatePositiveInt(volume.AlignToGB); err != nil {
+		return err
+	}
+	if err := validatePositiveInt(volume.MaxSizeGB); err != nil {
+		return err
+	}
+	if err := validatePositiveInt(volume.UsedCeilingGB); err != nil {
+		return err
+	}
+	if err := validatePositiveInt(volume.MinFreeGB); err != nil {
+		return err
+	}
+	if err := validatePositiveInt(volume.MinObservationCycles); err != nil {
+		return err
+	}
+	if err := validatePositiveInt(volume.MaxResizesPerDay); err != nil {
+		return err
+	}
+	if err := validatePositiveInt(volume.TargetIOPS); err != nil {
+		return err
+	}
+	if err := validatePositiveInt(volume.TargetThroughput); err != nil {
+		return err
+	}
+	if err := validatePositiveInt(volume.ResizeLockTTLSeconds); err != nil {
+		return err
+	}
+	if err := validateInodeResizeThreshold(volume.InodeResizeThreshold); err != nil {
+		return err
+	}
+	if err := validateIncrementMode(*volume); err != nil {
+		return err
+	}
+	if err := validateGrowthStrategy(*volume); err != nil {
+		return err
+	}
+	if err := validateThresholdMode(*volume); err != nil {
+		return err
+	}
+	if err := validateThresholdBasis(volume.ThresholdBasis); err != nil {
+		return err
+	}
+	if err := validatePartitions(volume.Partitions); err != nil {
+		return err
+	}
+	if err := validateLocalMountPoint(*volume); err != nil {
+		return err
+	}
+	if err := validateGrowthWindows(volume.GrowthWindows); err != nil {
+		return err
+	}
+	if err := validateRoleARN(volume.AssumeRoleARN); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateNotificationBatch : checks the notification batch settings
+// batch : runtime.NotificationBatch : batch settings to validate
+// returns : error : returns an error if a setting is negative or a level is not recognised
+func validateNotificationBatch(batch runtime.NotificationBatch) error {
+	if batch.WindowSeconds < 0 {
+		return fmt.Errorf("notificationBatch windowSeconds must not be negative, got %d", batch.WindowSeconds)
+	}
+	if batch.MaxCount < 0 {
+		return fmt.Errorf("notificationBatch maxCount must not be negative, got %d", batch.MaxCount)
+	}
+	for _, name := range batch.ImmediateLevels {
+		if _, err := logger.ParseLevel(name); err != nil {
+			return fmt.Errorf("notificationBatch immediateLevels: %w", err)
+		}
+	}
 	return nil
 }
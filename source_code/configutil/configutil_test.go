@@ -1,10 +1,19 @@
 package configutil
 
 import (
+	"ebs-monitor/aws"
+	"ebs-monitor/aws/awstest"
 	"ebs-monitor/runtime"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/spf13/viper"
 )
 
@@ -104,6 +113,468 @@ func TestValidatePositiveInt(t *testing.T) {
 	}
 }
 
+// TestValidateMaxRetries : a test function for validateMaxRetries.
+func TestValidateMaxRetries(t *testing.T) {
+	tests := []struct {
+		name    string
+		retries int
+		wantErr bool
+	}{
+		{"Default", 0, false},
+		{"Retries", 5, false},
+		{"Disabled", -1, false},
+		{"Invalid", -2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateMaxRetries(tt.retries); (err != nil) != tt.wantErr {
+				t.Errorf("validateMaxRetries() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidateResizeCommands : a test function for validateResizeCommands.
+func TestValidateResizeCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands map[string]string
+		wantErr  bool
+	}{
+		{name: "No overrides", commands: nil, wantErr: false},
+		{name: "Executable on the PATH", commands: map[string]string{"ext4": "sh"}, wantErr: false},
+		{name: "Absolute path", commands: map[string]string{"xfs": "/bin/sh"}, wantErr: false},
+		{name: "Missing binary", commands: map[string]string{"ext4": "/nonexistent/resize2fs"}, wantErr: true},
+		{name: "Unsupported filesystem", commands: map[string]string{"btrfs": "/bin/sh"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateResizeCommands(tt.commands)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateResizeCommands() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidateRemoteConfigURL : a test function for validateRemoteConfigURL.
+func TestValidateRemoteConfigURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{name: "Remote config disabled", url: "", wantErr: false},
+		{name: "HTTPS", url: "https://config.example.com/ebs-monitor/config.yaml", wantErr: false},
+		{name: "Plain HTTP", url: "http://config.example.com/ebs-monitor/config.yaml", wantErr: true},
+		{name: "No host", url: "https:///config.yaml", wantErr: true},
+		{name: "Not a URL", url: "config.example.com/config.yaml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateRemoteConfigURL(tt.url); (err != nil) != tt.wantErr {
+				t.Errorf("validateRemoteConfigURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidateVolumePrices : a test function for validateVolumePrices.
+func TestValidateVolumePrices(t *testing.T) {
+	tests := []struct {
+		name    string
+		prices  map[string]float64
+		wantErr bool
+	}{
+		{name: "No overrides", prices: nil, wantErr: false},
+		{name: "Valid prices", prices: map[string]float64{"gp3": 0.0952, "sc1": 0}, wantErr: false},
+		{name: "Negative price", prices: map[string]float64{"gp3": -0.08}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateVolumePrices(tt.prices); (err != nil) != tt.wantErr {
+				t.Errorf("validateVolumePrices() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidateJitterPercent : a test function for validateJitterPercent.
+func TestValidateJitterPercent(t *testing.T) {
+	tests := []struct {
+		name    string
+		percent int
+		wantErr bool
+	}{
+		{name: "Disabled", percent: 0, wantErr: false},
+		{name: "Within range", percent: 10, wantErr: false},
+		{name: "Maximum", percent: 50, wantErr: false},
+		{name: "Negative", percent: -1, wantErr: true},
+		{name: "Above maximum", percent: 51, wantErr: true},
+		{name: "Whole interval", percent: 100, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateJitterPercent(tt.percent)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateJitterPercent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidateThresholdBasis : a test function for validateThresholdBasis.
+func TestValidateThresholdBasis(t *testing.T) {
+	tests := []struct {
+		name    string
+		basis   string
+		wantErr bool
+	}{
+		{
+			name:    "Omitted basis",
+			basis:   "",
+			wantErr: false,
+		},
+		{
+			name:    "Total basis",
+			basis:   "total",
+			wantErr: false,
+		},
+		{
+			name:    "Usable basis",
+			basis:   "usable",
+			wantErr: false,
+		},
+		{
+			name:    "Unknown basis",
+			basis:   "free",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateThresholdBasis(tt.basis)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateThresholdBasis() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+		})
+	}
+}
+
+// TestValidatePartitions : a test function for validatePartitions.
+func TestValidatePartitions(t *testing.T) {
+	tests := []struct {
+		name       string
+		partitions []runtime.PartitionConfig
+		wantErr    bool
+	}{
+		{
+			name:       "No partitions",
+			partitions: nil,
+			wantErr:    false,
+		},
+		{
+			name: "Boot, swap and data",
+			partitions: []runtime.PartitionConfig{
+				{Partition: 1, MountPoint: "/boot", FilesystemType: "ext4"},
+				{Partition: 2, FilesystemType: "swap"},
+				{Partition: 3, MountPoint: "/data", FilesystemType: "xfs"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Swap last",
+			partitions: []runtime.PartitionConfig{
+				{Partition: 3, FilesystemType: "swap"},
+				{Partition: 1, MountPoint: "/boot", FilesystemType: "ext4"},
+				{Partition: 2, MountPoint: "/data", FilesystemType: "xfs"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Only swap",
+			partitions: []runtime.PartitionConfig{
+				{Partition: 1, FilesystemType: "swap"},
+				{Partition: 2, FilesystemType: "swap"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Duplicate partition number",
+			partitions: []runtime.PartitionConfig{
+				{Partition: 1, MountPoint: "/boot", FilesystemType: "ext4"},
+				{Partition: 1, MountPoint: "/data", FilesystemType: "ext4"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Duplicate mount point",
+			partitions: []runtime.PartitionConfig{
+				{Partition: 1, MountPoint: "/data", FilesystemType: "ext4"},
+				{Partition: 2, MountPoint: "/data", FilesystemType: "ext4"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Missing mount point",
+			partitions: []runtime.PartitionConfig{
+				{Partition: 1, FilesystemType: "ext4"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Unsupported filesystem",
+			partitions: []runtime.PartitionConfig{
+				{Partition: 1, MountPoint: "/data", FilesystemType: "btrfs"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePartitions(tt.partitions)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validatePartitions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+		})
+	}
+}
+
+// TestCheckRegionMatch : a test function for checkRegionMatch.
+func TestCheckRegionMatch(t *testing.T) {
+	tests := []struct {
+		name         string
+		volumeRegion string
+		localRegion  string
+		wantErr      bool
+	}{
+		{
+			name:         "Matching region",
+			volumeRegion: "ap-southeast-2",
+			localRegion:  "ap-southeast-2",
+			wantErr:      false,
+		},
+		{
+			name:         "Valid but different region",
+			volumeRegion: "us-east-1",
+			localRegion:  "ap-southeast-2",
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRegionMatch(tt.volumeRegion, tt.localRegion)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkRegionMatch() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+		})
+	}
+}
+
+// TestValidateIncrementMode : a test function for validateIncrementMode.
+func TestValidateIncrementMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		volume  runtime.EBSVolumeConfig
+		wantErr bool
+	}{
+		{
+			name:    "Fixed increment only",
+			volume:  runtime.EBSVolumeConfig{IncrementSizeGB: 10},
+			wantErr: false,
+		},
+		{
+			name:    "Percentage increment only",
+			volume:  runtime.EBSVolumeConfig{IncrementSizePercent: 20},
+			wantErr: false,
+		},
+		{
+			name:    "Both set",
+			volume:  runtime.EBSVolumeConfig{IncrementSizeGB: 10, IncrementSizePercent: 20},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateIncrementMode(tt.volume)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateIncrementMode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+		})
+	}
+}
+
+// TestValidateLocalMountPoint tests that a configured mount point must exist, be a directory
+// and have a filesystem mounted, and can't be combined with partitions.
+func TestValidateLocalMountPoint(t *testing.T) {
+	dir := t.TempDir()
+	unmounted := filepath.Join(dir, "data")
+	if err := os.Mkdir(unmounted, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		volume  runtime.EBSVolumeConfig
+		wantErr bool
+	}{
+		{
+			name:    "Unset",
+			volume:  runtime.EBSVolumeConfig{},
+			wantErr: false,
+		},
+		{
+			name:    "Missing path",
+			volume:  runtime.EBSVolumeConfig{LocalMountPoint: filepath.Join(dir, "missing")},
+			wantErr: true,
+		},
+		{
+			name:    "Not a directory",
+			volume:  runtime.EBSVolumeConfig{LocalMountPoint: file},
+			wantErr: true,
+		},
+		{
+			name:    "Directory that is not a mount point",
+			volume:  runtime.EBSVolumeConfig{LocalMountPoint: unmounted},
+			wantErr: true,
+		},
+		{
+			name: "Combined with partitions",
+			volume: runtime.EBSVolumeConfig{
+				LocalMountPoint: unmounted,
+				Partitions:      []runtime.PartitionConfig{{Partition: 1, MountPoint: "/data", FilesystemType: "ext4"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLocalMountPoint(tt.volume)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateLocalMountPoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidateThresholdMode : a test function for validateThresholdMode.
+func TestValidateThresholdMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		volume  runtime.EBSVolumeConfig
+		wantErr bool
+	}{
+		{
+			name:    "Percentage threshold only",
+			volume:  runtime.EBSVolumeConfig{ResizeThreshold: 80},
+			wantErr: false,
+		},
+		{
+			name:    "Used ceiling only",
+			volume:  runtime.EBSVolumeConfig{UsedCeilingGB: 500},
+			wantErr: false,
+		},
+		{
+			name:    "Both set",
+			volume:  runtime.EBSVolumeConfig{ResizeThreshold: 80, UsedCeilingGB: 500},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateThresholdMode(tt.volume)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateThresholdMode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+		})
+	}
+}
+
+// TestValidateGrowthStrategy : a test function for validateGrowthStrategy.
+func TestValidateGrowthStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		volume  runtime.EBSVolumeConfig
+		wantErr bool
+	}{
+		{
+			name:    "Inferred from increment",
+			volume:  runtime.EBSVolumeConfig{IncrementSizeGB: 10},
+			wantErr: false,
+		},
+		{
+			name:    "Fixed with increment",
+			volume:  runtime.EBSVolumeConfig{GrowthStrategy: runtime.GrowthStrategyFixed, IncrementSizeGB: 10},
+			wantErr: false,
+		},
+		{
+			name:    "Fixed without increment",
+			volume:  runtime.EBSVolumeConfig{GrowthStrategy: runtime.GrowthStrategyFixed, IncrementSizePercent: 20},
+			wantErr: true,
+		},
+		{
+			name:    "Percent with increment",
+			volume:  runtime.EBSVolumeConfig{GrowthStrategy: runtime.GrowthStrategyPercent, IncrementSizePercent: 20},
+			wantErr: false,
+		},
+		{
+			name:    "Percent without increment",
+			volume:  runtime.EBSVolumeConfig{GrowthStrategy: runtime.GrowthStrategyPercent},
+			wantErr: true,
+		},
+		{
+			name:    "Double until cap with max size",
+			volume:  runtime.EBSVolumeConfig{GrowthStrategy: runtime.GrowthStrategyDoubleUntilCap, MaxSizeGB: 1000},
+			wantErr: false,
+		},
+		{
+			name:    "Double until cap without max size",
+			volume:  runtime.EBSVolumeConfig{GrowthStrategy: runtime.GrowthStrategyDoubleUntilCap},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown strategy",
+			volume:  runtime.EBSVolumeConfig{GrowthStrategy: "exponential", IncrementSizeGB: 10},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateGrowthStrategy(tt.volume); (err != nil) != tt.wantErr {
+				t.Errorf("validateGrowthStrategy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 // TestCheckMinimumFields tests the checkMinimumFields function
 func TestCheckMinimumFields(t *testing.T) {
 	tests := []struct {
@@ -123,6 +594,25 @@ func TestCheckMinimumFields(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name: "valid volume configuration with used ceiling",
+			volume: runtime.EBSVolumeConfig{
+				AWSVolumeID:     "vol-0abcd1234efgh5678",
+				IncrementSizeGB: 10,
+				UsedCeilingGB:   500,
+			},
+			expected: true,
+		},
+		{
+			name: "valid volume configuration doubling without an increment",
+			volume: runtime.EBSVolumeConfig{
+				AWSVolumeID:     "vol-0abcd1234efgh5678",
+				GrowthStrategy:  runtime.GrowthStrategyDoubleUntilCap,
+				MaxSizeGB:       1000,
+				ResizeThreshold: 80,
+			},
+			expected: true,
+		},
 		{
 			name: "invalid volume configuration",
 			volume: runtime.EBSVolumeConfig{
@@ -146,4 +636,551 @@ func TestCheckMinimumFields(t *testing.T) {
 	}
 }
 
-// TODO Add additional tests for external calling functions. Requires gomock.
+// TestValidateVolume : a test function for validateVolume, against fake AWS and instance metadata.
+func TestValidateVolume(t *testing.T) {
+	aws.SetEC2Client(&awstest.FakeEC2{
+		Volumes:   []*ec2.Volume{awstest.NewFakeVolume("vol-1", "i-1", "/dev/sdf", 100)},
+		Instances: []*ec2.Instance{awstest.NewFakeInstance("i-1", map[string]string{"/dev/sdf": "vol-1"})},
+	})
+	aws.SetInstanceMetadata(awstest.FakeMetadata{ID: "i-1", RegionID: "us-east-1"})
+	defer func() {
+		aws.SetEC2Client(nil)
+		aws.SetInstanceMetadata(nil)
+	}()
+
+	tests := []struct {
+		name       string
+		volume     runtime.EBSVolumeConfig
+		wantID     string
+		wantDevice string
+		wantRegion string
+		wantErr    error
+	}{
+		{
+			name:       "device name looked up from volume ID",
+			volume:     runtime.EBSVolumeConfig{AWSVolumeID: "vol-1", AWSRegion: "us-east-1", ResizeThreshold: 80, IncrementSizeGB: 10},
+			wantID:     "vol-1",
+			wantDevice: "/dev/sdf",
+			wantRegion: "us-east-1",
+		},
+		{
+			name:       "volume ID looked up from device name",
+			volume:     runtime.EBSVolumeConfig{AWSDeviceName: "/dev/sdf", AWSRegion: "us-east-1", ResizeThreshold: 80, IncrementSizeGB: 10},
+			wantID:     "vol-1",
+			wantDevice: "/dev/sdf",
+			wantRegion: "us-east-1",
+		},
+		{
+			name:       "missing region taken from the instance",
+			volume:     runtime.EBSVolumeConfig{AWSVolumeID: "vol-1", AWSDeviceName: "/dev/sdf", ResizeThreshold: 80, IncrementSizeGB: 10},
+			wantID:     "vol-1",
+			wantDevice: "/dev/sdf",
+			wantRegion: "us-east-1",
+		},
+		{
+			name:    "volume that doesn't exist",
+			volume:  runtime.EBSVolumeConfig{AWSVolumeID: "vol-2", AWSRegion: "us-east-1", ResizeThreshold: 80, IncrementSizeGB: 10},
+			wantErr: aws.ErrVolumeNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			volume := tt.volume
+			err := validateVolume(&volume, false)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("validateVolume() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateVolume() error = %v", err)
+			}
+			if volume.AWSVolumeID != tt.wantID || volume.AWSDeviceName != tt.wantDevice || volume.AWSRegion != tt.wantRegion {
+				t.Errorf("validateVolume() = %v %v %v, want %v %v %v", volume.AWSVolumeID, volume.AWSDeviceName, volume.AWSRegion, tt.wantID, tt.wantDevice, tt.wantRegion)
+			}
+		})
+	}
+}
+
+// TestValidateNewVolume : a test function for ValidateNewVolume.
+func TestValidateNewVolume(t *testing.T) {
+	// i-2 has more volumes attached than one instance can practically attach
+	crowded := make(map[string]string, MaxAttachedVolumes+1)
+	for i := 0; i <= MaxAttachedVolumes; i++ {
+		crowded[fmt.Sprintf("/dev/sd%d", i)] = fmt.Sprintf("vol-c%d", i)
+	}
+	crowded["/dev/sdc"] = "vol-3"
+	aws.SetEC2Client(&awstest.FakeEC2{
+		Volumes: []*ec2.Volume{
+			awstest.NewFakeVolume("vol-1", "i-1", "/dev/sdf", 100),
+			awstest.NewFakeVolume("vol-2", "i-9", "/dev/sdg", 100),
+			awstest.NewFakeVolume("vol-3", "i-2", "/dev/sdc", 100),
+		},
+		Instances: []*ec2.Instance{
+			awstest.NewFakeInstance("i-1", map[string]string{"/dev/sda1": "vol-root", "/dev/sdf": "vol-1"}),
+			awstest.NewFakeInstance("i-2", crowded),
+		},
+	})
+	defer func() {
+		aws.SetEC2Client(nil)
+		aws.SetInstanceMetadata(nil)
+	}()
+
+	// Partitions give the mount points without probing the host
+	partitions := []runtime.PartitionConfig{{Partition: 1, MountPoint: "/data", FilesystemType: "ext4"}}
+	tests := []struct {
+		name                 string
+		instanceID           string
+		volume               runtime.EBSVolumeConfig
+		failOnRegionMismatch bool
+		wantErr              bool
+	}{
+		{
+			name:       "attached volume",
+			instanceID: "i-1",
+			volume:     runtime.EBSVolumeConfig{AWSVolumeID: "vol-1", AWSRegion: "us-east-1", Partitions: partitions},
+		},
+		{
+			name:       "volume attached to another instance",
+			instanceID: "i-1",
+			volume:     runtime.EBSVolumeConfig{AWSVolumeID: "vol-2", AWSRegion: "us-east-1", Partitions: partitions},
+			wantErr:    true,
+		},
+		{
+			name:       "instance over the attachment limit",
+			instanceID: "i-2",
+			volume:     runtime.EBSVolumeConfig{AWSVolumeID: "vol-3", AWSRegion: "us-east-1", Partitions: partitions},
+			wantErr:    true,
+		},
+		{
+			name:       "region mismatch warned about",
+			instanceID: "i-1",
+			volume:     runtime.EBSVolumeConfig{AWSVolumeID: "vol-1", AWSRegion: "us-west-2", Partitions: partitions},
+		},
+		{
+			name:                 "region mismatch rejected",
+			instanceID:           "i-1",
+			volume:               runtime.EBSVolumeConfig{AWSVolumeID: "vol-1", AWSRegion: "us-west-2", Partitions: partitions},
+			failOnRegionMismatch: true,
+			wantErr:              true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aws.SetInstanceMetadata(awstest.FakeMetadata{ID: tt.instanceID, RegionID: "us-east-1"})
+			err := ValidateNewVolume(tt.volume, tt.failOnRegionMismatch)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateNewVolume() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestGateNewVolumes : a test function for gateNewVolumes.
+func TestGateNewVolumes(t *testing.T) {
+	aws.SetEC2Client(&awstest.FakeEC2{
+		Volumes: []*ec2.Volume{
+			awstest.NewFakeVolume("vol-1", "i-1", "/dev/sdf", 100),
+			awstest.NewFakeVolume("vol-2", "i-9", "/dev/sdg", 100),
+		},
+		Instances: []*ec2.Instance{awstest.NewFakeInstance("i-1", map[string]string{"/dev/sdf": "vol-1"})},
+	})
+	aws.SetInstanceMetadata(awstest.FakeMetadata{ID: "i-1", RegionID: "us-east-1"})
+	defer func() {
+		aws.SetEC2Client(nil)
+		aws.SetInstanceMetadata(nil)
+	}()
+
+	partitions := []runtime.PartitionConfig{{Partition: 1, MountPoint: "/data", FilesystemType: "ext4"}}
+	got := gateNewVolumes([]runtime.EBSVolumeConfig{
+		{AWSVolumeID: "vol-1", AWSRegion: "us-east-1", Partitions: partitions},
+		{AWSVolumeID: "vol-2", AWSRegion: "us-east-1", Partitions: partitions},
+	}, false)
+	if len(got) != 1 || got[0].AWSVolumeID != "vol-1" {
+		t.Errorf("gateNewVolumes() = %v, want only vol-1", got)
+	}
+}
+
+// TestValidateNotificationBatch : a test function for validateNotificationBatch.
+func TestValidateNotificationBatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		batch   runtime.NotificationBatch
+		wantErr bool
+	}{
+		{
+			name:    "Batching disabled",
+			batch:   runtime.NotificationBatch{},
+			wantErr: false,
+		},
+		{
+			name:    "Window with immediate levels",
+			batch:   runtime.NotificationBatch{WindowSeconds: 30, MaxCount: 10, ImmediateLevels: []string{"error", "Fatal"}},
+			wantErr: false,
+		},
+		{
+			name:    "Negative window",
+			batch:   runtime.NotificationBatch{WindowSeconds: -1},
+			wantErr: true,
+		},
+		{
+			name:    "Negative max count",
+			batch:   runtime.NotificationBatch{MaxCount: -1},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown level",
+			batch:   runtime.NotificationBatch{WindowSeconds: 30, ImmediateLevels: []string{"urgent"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateNotificationBatch(tt.batch)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateNotificationBatch() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+		})
+	}
+}
+
+// TestIsConfigNotFound : a test function for IsConfigNotFound.
+// It checks that a missing file is distinguished from a file that exists but can't be parsed.
+func TestIsConfigNotFound(t *testing.T) {
+	invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("volumes: [unterminated"), 0o600); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		want     bool
+	}{
+		{"Missing file", filepath.Join(t.TempDir(), "missing.yaml"), true},
+		{"Invalid file", invalid, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfigFromFile(tt.filename)
+			if err == nil {
+				t.Fatalf("LoadConfigFromFile() error = nil, want error")
+			}
+			if got := IsConfigNotFound(err); got != tt.want {
+				t.Errorf("IsConfigNotFound() = %v, want %v (error: %v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+// TestValidateDisplayUnit : a test function for validateDisplayUnit.
+func TestValidateDisplayUnit(t *testing.T) {
+	tests := []struct {
+		name    string
+		unit    string
+		wantErr bool
+	}{
+		{"Default", "", false},
+		{"GiB", runtime.DisplayUnitGiB, false},
+		{"GB", runtime.DisplayUnitGB, false},
+		{"TiB", runtime.DisplayUnitTiB, false},
+		{"Auto", runtime.DisplayUnitAuto, false},
+		{"Unknown", "MB", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDisplayUnit(tt.unit)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateDisplayUnit() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+		})
+	}
+}
+
+// TestValidateUnmountedAction : a test function for validateUnmountedAction.
+func TestValidateUnmountedAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		wantErr bool
+	}{
+		{"Default", "", false},
+		{"Alert", runtime.UnmountedActionAlert, false},
+		{"Error", runtime.UnmountedActionError, false},
+		{"Unknown", "ignore", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateUnmountedAction(tt.action)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateUnmountedAction() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+		})
+	}
+}
+
+// TestValidateRoleARN tests the validateRoleARN function.
+func TestValidateRoleARN(t *testing.T) {
+	tests := []struct {
+		name    string
+		roleARN string
+		wantErr bool
+	}{
+		{"Default credential chain", "", false},
+		{"Role", "arn:aws:iam::123456789012:role/ebs-monitor", false},
+		{"Role with path", "arn:aws:iam::123456789012:role/service/ebs-monitor", false},
+		{"GovCloud role", "arn:aws-us-gov:iam::123456789012:role/ebs-monitor", false},
+		{"User", "arn:aws:iam::123456789012:user/ebs-monitor", true},
+		{"Short account ID", "arn:aws:iam::1234:role/ebs-monitor", true},
+		{"Role name only", "ebs-monitor", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRoleARN(tt.roleARN)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateRoleARN() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+		})
+	}
+}
+
+// TestMergeDiscoveredVolumes tests that discovered volumes take the template's settings without overriding listed volumes.
+func TestMergeDiscoveredVolumes(t *testing.T) {
+	template := runtime.EBSVolumeConfig{IncrementSizeGB: 10, ResizeThreshold: 80}
+	listed := []runtime.EBSVolumeConfig{
+		{AWSVolumeID: "vol-listed", IncrementSizeGB: 50, ResizeThreshold: 90},
+		{AWSDeviceName: "/dev/sdh", IncrementSizePercent: 20, ResizeThreshold: 85},
+	}
+	discovered := []runtime.EBSVolumeConfig{
+		{AWSVolumeID: "vol-listed", AWSDeviceName: "/dev/sdf", AWSRegion: "ap-southeast-2"},
+		{AWSVolumeID: "vol-new", AWSDeviceName: "/dev/sdg", AWSRegion: "ap-southeast-2"},
+		{AWSVolumeID: "vol-bydevice", AWSDeviceName: "/dev/sdh", AWSRegion: "ap-southeast-2"},
+	}
+
+	got := mergeDiscoveredVolumes(listed, discovered, template)
+	want := []runtime.EBSVolumeConfig{
+		listed[0],
+		listed[1],
+		{AWSVolumeID: "vol-new", AWSDeviceName: "/dev/sdg", AWSRegion: "ap-southeast-2", IncrementSizeGB: 10, ResizeThreshold: 80},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeDiscoveredVolumes() = %+v, want %+v", got, want)
+	}
+}
+
+// TestDiscoverVolumesRequiresTemplate tests that tag discovery is rejected without resize settings for discovered volumes.
+func TestDiscoverVolumesRequiresTemplate(t *testing.T) {
+	cfg := &runtime.Config{VolumeTagFilters: map[string]string{"ebs-monitor": "enabled"}}
+	if err := discoverVolumes(cfg); err == nil {
+		t.Errorf("discoverVolumes() error = nil, want an error for a missing volumeTemplate")
+	}
+
+	if err := discoverVolumes(&runtime.Config{}); err != nil {
+		t.Errorf("discoverVolumes() error = %v without tag filters, want nil", err)
+	}
+}
+
+// TestValidateSNSTopicARN tests the validateSNSTopicARN function.
+func TestValidateSNSTopicARN(t *testing.T) {
+	tests := []struct {
+		name    string
+		arn     string
+		wantErr bool
+	}{
+		{"Disabled", "", false},
+		{"Topic", "arn:aws:sns:ap-southeast-2:123456789012:ebs-alerts", false},
+		{"FIFO topic", "arn:aws:sns:ap-southeast-2:123456789012:ebs-alerts.fifo", false},
+		{"Placeholder", "<AWS ARN>", true},
+		{"Queue", "arn:aws:sqs:ap-southeast-2:123456789012:ebs-alerts", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSNSTopicARN(tt.arn)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSNSTopicARN() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+		})
+	}
+}
+
+// TestValidateInodeResizeThreshold : a test function for validateInodeResizeThreshold.
+func TestValidateInodeResizeThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		wantErr   bool
+	}{
+		{name: "Disabled", threshold: 0, wantErr: false},
+		{name: "Percentage", threshold: 90, wantErr: false},
+		{name: "Negative", threshold: -1, wantErr: true},
+		{name: "Over 100", threshold: 101, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateInodeResizeThreshold(tt.threshold)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateInodeResizeThreshold() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidateResizeCooldown : a test function for validateResizeCooldown.
+func TestValidateResizeCooldown(t *testing.T) {
+	tests := []struct {
+		name      string
+		seconds   int
+		retention time.Duration
+		wantErr   bool
+	}{
+		{name: "Disabled", seconds: 0, wantErr: false},
+		{name: "One hour", seconds: 3600, wantErr: false},
+		{name: "Negative", seconds: -1, wantErr: true},
+		{name: "Longer than history", seconds: 2 * 86400, wantErr: true},
+		{name: "Within longer history", seconds: 2 * 86400, retention: 7 * 24 * time.Hour, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retention := tt.retention
+			if retention == 0 {
+				retention = runtime.DefaultEventRetention
+			}
+			err := validateResizeCooldown(tt.seconds, retention)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateResizeCooldown() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidateVolumeRetention : a test function for validateVolumeRetention.
+func TestValidateVolumeRetention(t *testing.T) {
+	tests := []struct {
+		name           string
+		retentionHours int
+		volume         runtime.EBSVolumeConfig
+		wantErr        bool
+	}{
+		{name: "No safety settings", retentionHours: 1, volume: runtime.EBSVolumeConfig{}, wantErr: false},
+		{name: "Daily limit with default history", volume: runtime.EBSVolumeConfig{MaxResizesPerDay: 4}, wantErr: false},
+		{name: "Daily limit with longer history", retentionHours: 168, volume: runtime.EBSVolumeConfig{MaxResizesPerDay: 4}, wantErr: false},
+		{name: "Daily limit with short history", retentionHours: 12, volume: runtime.EBSVolumeConfig{MaxResizesPerDay: 4}, wantErr: true},
+		{name: "Observation longer than history only warns", retentionHours: 1, volume: runtime.EBSVolumeConfig{MinObservationCycles: 100}, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &runtime.Config{CheckIntervalSeconds: 60, EventRetentionHours: tt.retentionHours}
+			if err := validateVolumeRetention(config, tt.volume); (err != nil) != tt.wantErr {
+				t.Errorf("validateVolumeRetention() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestParseConfigUnknownKeys tests that misspelt keys are reported with where they were found.
+func TestParseConfigUnknownKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name:    "Misspelt volume key",
+			config:  "volumes:\n  - awsDeviceName: /dev/sde\n    resizeThreshhold: 80\ncheckIntervalSeconds: 30\n",
+			wantErr: "volumes[0]: unknown keys resizethreshhold",
+		},
+		{
+			name:    "Misspelt top-level key",
+			config:  "volumes: []\ncheckIntervalSecond: 30\n",
+			wantErr: "top level: unknown keys checkintervalsecond",
+		},
+		{
+			name:    "Misspelt partition key",
+			config:  "volumes:\n  - awsDeviceName: /dev/sde\n    partitions:\n      - partition: 1\n        mountpoint: /data\n        fstype: ext4\n",
+			wantErr: "volumes[0].partitions[0]: unknown keys fstype",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tt.config), "yaml")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseConfig() error = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestParseSSMSource : a test function for parseSSMSource.
+func TestParseSSMSource(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		wantName   string
+		wantRegion string
+		wantErr    bool
+	}{
+		{name: "Path parameter", source: "ssm:///ebs-monitor/config", wantName: "/ebs-monitor/config"},
+		{name: "Plain parameter", source: "ssm://ebs-monitor-config", wantName: "ebs-monitor-config"},
+		{name: "With region", source: "ssm:///ebs-monitor/config?region=ap-southeast-2", wantName: "/ebs-monitor/config", wantRegion: "ap-southeast-2"},
+		{name: "No parameter", source: "ssm://", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, region, err := parseSSMSource(tt.source)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSSMSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if name != tt.wantName || region != tt.wantRegion {
+				t.Errorf("parseSSMSource() = %q, %q, want %q, %q", name, region, tt.wantName, tt.wantRegion)
+			}
+		})
+	}
+}
+
+// TestLoadConfigSources tests that config from an environment variable is parsed the same way as a file.
+func TestLoadConfigSources(t *testing.T) {
+	// A misspelt key fails parsing before any AWS lookups, showing where each source's content ended up
+	body := "volumes:\n  - awsDeviceName: /dev/sde\n    resizeThreshhold: 80\ncheckIntervalSeconds: 30\n"
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	t.Setenv("EBS_MONITOR_TEST_CONFIG", body)
+
+	_, fileErr := LoadConfig(path)
+	_, envErr := LoadConfig("env://EBS_MONITOR_TEST_CONFIG")
+	if fileErr == nil || envErr == nil || fileErr.Error() != envErr.Error() {
+		t.Errorf("LoadConfig() errors differ by source: file = %v, env = %v", fileErr, envErr)
+	}
+
+	_, err := LoadConfig("env://EBS_MONITOR_TEST_CONFIG_UNSET")
+	if !IsConfigNotFound(err) {
+		t.Errorf("LoadConfig() error = %v, want a not found error for an unset variable", err)
+	}
+}
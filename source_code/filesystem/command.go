@@ -0,0 +1,323 @@
+package filesystem
+
+import (
+	"context"
+	"ebs-monitor/logger"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+var l = logger.NewLogger()
+
+// Command timeouts, used when they are not configured.
+const (
+	DefaultCommandTimeout = 30 * time.Second // Commands that inspect the host, e.g. lsblk and df.
+	DefaultResizeTimeout  = 10 * time.Minute // Commands that grow a partition or filesystem, which can legitimately take a while.
+)
+
+// commandWaitDelay bounds how long a killed command's output is waited for, in case a child process holds it open.
+const commandWaitDelay = 5 * time.Second
+
+// ErrCommandTimeout is returned (wrapped) when a command is killed for running past its timeout.
+var ErrCommandTimeout = errors.New("command timed out")
+
+// CommandError is returned (wrapped) when a command that grows a partition or filesystem fails.
+// It carries what is needed to diagnose the failure, e.g. the stderr of resize2fs or xfs_growfs.
+type CommandError struct {
+	Command  string // The command line that was run.
+	ExitCode int    // The process exit code, -1 if it didn't exit normally, e.g. it was killed or didn't start.
+	Output   string // The combined standard output and standard error of the command.
+	Err      error  // The error running the command.
+}
+
+// Error describes the failure, including the exit code and output.
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%v (exit code %d), output: %s", e.Err, e.ExitCode, strings.TrimSpace(e.Output))
+}
+
+// Unwrap returns the error running the command, e.g. one wrapping ErrCommandTimeout.
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// defaultResizeBinaries are the binaries that grow each supported filesystem type, looked up on the PATH.
+var defaultResizeBinaries = map[string]string{
+	"ext4": "resize2fs",
+	"xfs":  "xfs_growfs",
+	"zfs":  "zpool",
+}
+
+var (
+	// resizeBinariesMu guards resizeBinaries, which is set once config is loaded.
+	resizeBinariesMu sync.Mutex
+	resizeBinaries   map[string]string
+
+	// timeoutMu guards commandTimeout and resizeTimeout, which are set once config is loaded.
+	timeoutMu      sync.Mutex
+	commandTimeout = DefaultCommandTimeout
+	resizeTimeout  = DefaultResizeTimeout
+)
+
+// SetCommandTimeouts : sets how long host commands may run before they are killed.
+// command : time.Duration : timeout for commands that inspect the host, 0 uses DefaultCommandTimeout
+// resize : time.Duration : timeout for commands that grow a partition or filesystem, 0 uses DefaultResizeTimeout
+func SetCommandTimeouts(command, resize time.Duration) {
+	if command <= 0 {
+		command = DefaultCommandTimeout
+	}
+	if resize <= 0 {
+		resize = DefaultResizeTimeout
+	}
+	timeoutMu.Lock()
+	defer timeoutMu.Unlock()
+	commandTimeout = command
+	resizeTimeout = resize
+}
+
+// SetResizeBinaries : overrides the binaries that grow filesystems, e.g. on images that install them outside the PATH.
+// binaries : map[string]string : binary path for each filesystem type, types not in the map use the default binary
+func SetResizeBinaries(binaries map[string]string) {
+	resizeBinariesMu.Lock()
+	defer resizeBinariesMu.Unlock()
+	resizeBinaries = binaries
+}
+
+// IsResizableFilesystem : checks if a filesystem type can be grown.
+// filesystem : string : the filesystem type, e.g. "ext4"
+// returns : bool : true if the filesystem type is supported
+func IsResizableFilesystem(filesystem string) bool {
+	_, ok := defaultResizeBinaries[filesystem]
+	return ok
+}
+
+// resizeBinary : returns the binary that grows a filesystem type, the configured override or else the default.
+// filesystem : string : the filesystem type, e.g. "ext4"
+// returns : string : the binary
+// returns : bool : false if the filesystem type is not supported
+func resizeBinary(filesystem string) (string, bool) {
+	binary, ok := defaultResizeBinaries[filesystem]
+	if !ok {
+		return "", false
+	}
+	resizeBinariesMu.Lock()
+	defer resizeBinariesMu.Unlock()
+	if override := resizeBinaries[filesystem]; override != "" {
+		binary = override
+	}
+	return binary, true
+}
+
+// CommandRunner runs the host commands this package inspects and resizes filesystems with, e.g. lsblk and resize2fs,
+// so a fake can stand in for the host in tests.
+type CommandRunner interface {
+	// Run runs a command until it exits or ctx is done, and returns its standard output.
+	// A command that exits non-zero returns a wrapped *ExitError.
+	Run(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// ExitError is returned (wrapped) by a CommandRunner when a command exits non-zero.
+type ExitError struct {
+	Code   int    // The process exit code.
+	Stderr []byte // The standard error of the command.
+	Err    error  // The error running the command.
+}
+
+// Error describes the exit.
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error running the command.
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExecRunner is the CommandRunner that runs commands on the host.
+type ExecRunner struct{}
+
+// Run runs the command, killing it once ctx is done.
+func (ExecRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = commandWaitDelay
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return output, &ExitError{Code: exitErr.ExitCode(), Stderr: exitErr.Stderr, Err: err}
+	}
+	return output, err
+}
+
+var (
+	// runnerMu guards runner, which tests replace with a fake.
+	runnerMu sync.Mutex
+	runner   CommandRunner = ExecRunner{}
+)
+
+// SetCommandRunner : replaces what runs host commands, e.g. with a fake in tests.
+// r : CommandRunner : the runner to use, nil restores ExecRunner
+func SetCommandRunner(r CommandRunner) {
+	runnerMu.Lock()
+	defer runnerMu.Unlock()
+	if r == nil {
+		r = ExecRunner{}
+	}
+	runner = r
+}
+
+// commandRunner : returns the runner set by SetCommandRunner.
+// returns : CommandRunner : the runner
+func commandRunner() CommandRunner {
+	runnerMu.Lock()
+	defer runnerMu.Unlock()
+	return runner
+}
+
+// timedCommand is a command killed once its timeout passes, run with the CommandRunner.
+// Output, CombinedOutput and Run return an error wrapping ErrCommandTimeout when it is, and log what ran at debug.
+type timedCommand struct {
+	name    string
+	args    []string
+	timeout time.Duration
+	ctx     context.Context
+}
+
+// newCommand : creates a command that inspects the host, killed after the command timeout.
+// name : string : the program to run
+// args : ...string : the program's arguments
+// returns : *timedCommand : the command
+func newCommand(name string, args ...string) *timedCommand {
+	timeoutMu.Lock()
+	timeout := commandTimeout
+	timeoutMu.Unlock()
+	return newTimedCommand(timeout, name, args...)
+}
+
+// newResizeCommand : creates a command that grows a partition or filesystem, killed after the resize timeout.
+// name : string : the program to run
+// args : ...string : the program's arguments
+// returns : *timedCommand : the command
+func newResizeCommand(name string, args ...string) *timedCommand {
+	timeoutMu.Lock()
+	timeout := resizeTimeout
+	timeoutMu.Unlock()
+	return newTimedCommand(timeout, name, args...)
+}
+
+// newTimedCommand : creates a command killed once the timeout passes.
+// timeout : time.Duration : how long the command may run
+// name : string : the program to run
+// args : ...string : the program's arguments
+// returns : *timedCommand : the command
+func newTimedCommand(timeout time.Duration, name string, args ...string) *timedCommand {
+	return &timedCommand{name: name, args: args, timeout: timeout}
+}
+
+// String returns the command line.
+func (c *timedCommand) String() string {
+	return strings.Join(append([]string{c.name}, c.args...), " ")
+}
+
+// run : runs the command with the CommandRunner, logging the command line and its output at debug.
+// returns : []byte : the standard output of the command
+// returns : []byte : the standard error of the command, if it exited non-zero
+// returns : error : the error running the command
+func (c *timedCommand) run() ([]byte, []byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	c.ctx = ctx
+
+	output, err := commandRunner().Run(ctx, c.name, c.args...)
+	var stderr []byte
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		stderr = exitErr.Stderr
+	}
+	fields := map[string]interface{}{
+		"Command": c.String(),
+		"Output":  strings.TrimSpace(string(output) + string(stderr)),
+	}
+	if err != nil {
+		fields["Error"] = err
+	}
+	l.Log(logger.LogDebug, "Ran host command.", fields)
+	return output, stderr, c.timeoutError(err)
+}
+
+// Output runs the command and returns its standard output.
+func (c *timedCommand) Output() ([]byte, error) {
+	output, _, err := c.run()
+	return output, err
+}
+
+// CombinedOutput runs the command and returns its standard output followed by its standard error.
+func (c *timedCommand) CombinedOutput() ([]byte, error) {
+	output, stderr, err := c.run()
+	return append(output, stderr...), err
+}
+
+// Run runs the command and waits for it to finish.
+func (c *timedCommand) Run() error {
+	_, _, err := c.run()
+	return err
+}
+
+// commandError : builds the CommandError of a failed command from its output and error.
+// output : []byte : the combined output of the command
+// err : error : the error the command returned
+// returns : *CommandError : the error, with the exit code taken from err
+func (c *timedCommand) commandError(output []byte, err error) *CommandError {
+	exitCode := -1
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.Code
+	}
+	return &CommandError{Command: c.String(), ExitCode: exitCode, Output: string(output), Err: err}
+}
+
+// timeoutError : replaces the error of a command killed for running past its timeout with one wrapping ErrCommandTimeout.
+// err : error : the error the command returned
+// returns : error : the error to return
+func (c *timedCommand) timeoutError(err error) error {
+	if err != nil && errors.Is(c.ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("'%v' did not finish within %v: %w", c, c.timeout, ErrCommandTimeout)
+	}
+	return err
+}
+
+// RunShellCommand : runs a configured shell command, e.g. a resize hook, killed after the resize timeout.
+// command : string : the command line, run with sh -c
+// env : []string : variables added to the command's environment, as KEY=value
+// returns : string : the combined standard output and standard error of the command
+// returns : error : a wrapped *CommandError if the command fails or exits non-zero
+func RunShellCommand(command string, env []string) (string, error) {
+	timeoutMu.Lock()
+	timeout := resizeTimeout
+	timeoutMu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Hooks are run directly rather than with the CommandRunner, as they need the added environment
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.WaitDelay = commandWaitDelay
+	cmd.Env = append(os.Environ(), env...)
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return string(output), nil
+	}
+
+	exitCode := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("'%v' did not finish within %v: %w", command, timeout, ErrCommandTimeout)
+	}
+	commandErr := &CommandError{Command: cmd.String(), ExitCode: exitCode, Output: string(output), Err: err}
+	return string(output), fmt.Errorf("failed to run '%v'. error: %w", command, commandErr)
+}
@@ -0,0 +1,198 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestTimedCommand tests that a command running past its timeout is killed with ErrCommandTimeout,
+// and that other failures are returned unchanged.
+func TestTimedCommand(t *testing.T) {
+	start := time.Now()
+	err := newTimedCommand(50*time.Millisecond, "sleep", "5").Run()
+	if !errors.Is(err, ErrCommandTimeout) {
+		t.Errorf("Run() error = %v, want %v", err, ErrCommandTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Run() took %v, want the command killed at its timeout", elapsed)
+	}
+
+	if output, err := newTimedCommand(time.Second, "echo", "ok").Output(); err != nil || string(output) != "ok\n" {
+		t.Errorf("Output() = (%q, %v), want (\"ok\\n\", nil)", output, err)
+	}
+
+	if _, err := newTimedCommand(time.Second, "false").CombinedOutput(); err == nil || errors.Is(err, ErrCommandTimeout) {
+		t.Errorf("CombinedOutput() error = %v, want a non-timeout error", err)
+	}
+}
+
+// TestSetCommandTimeouts tests that unset timeouts fall back to the defaults.
+func TestSetCommandTimeouts(t *testing.T) {
+	defer SetCommandTimeouts(0, 0)
+
+	SetCommandTimeouts(time.Second, time.Minute)
+	if commandTimeout != time.Second || resizeTimeout != time.Minute {
+		t.Errorf("timeouts = (%v, %v), want (1s, 1m0s)", commandTimeout, resizeTimeout)
+	}
+
+	SetCommandTimeouts(0, 0)
+	if commandTimeout != DefaultCommandTimeout || resizeTimeout != DefaultResizeTimeout {
+		t.Errorf("timeouts = (%v, %v), want the defaults", commandTimeout, resizeTimeout)
+	}
+}
+
+// TestCommandError tests that a failed command's error carries its command line, exit code and output.
+func TestCommandError(t *testing.T) {
+	cmd := newTimedCommand(time.Second, "sh", "-c", "echo 'resize2fs: Bad magic number' >&2; exit 3")
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatal("CombinedOutput() error = nil, want the command to fail")
+	}
+
+	var commandErr *CommandError
+	if !errors.As(fmt.Errorf("failed to resize. error: %w", cmd.commandError(output, err)), &commandErr) {
+		t.Fatalf("errors.As() = false, want a *CommandError")
+	}
+	if commandErr.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", commandErr.ExitCode)
+	}
+	if !strings.Contains(commandErr.Command, "exit 3") {
+		t.Errorf("Command = %q, want the command line", commandErr.Command)
+	}
+	if !strings.Contains(commandErr.Error(), "Bad magic number") {
+		t.Errorf("Error() = %q, want it to include the output", commandErr.Error())
+	}
+
+	cmd = newTimedCommand(50*time.Millisecond, "sleep", "5")
+	output, err = cmd.CombinedOutput()
+	if commandErr := cmd.commandError(output, err); commandErr.ExitCode != -1 || !errors.Is(commandErr, ErrCommandTimeout) {
+		t.Errorf("commandError() of a timed out command = %v, want exit code -1 wrapping %v", commandErr, ErrCommandTimeout)
+	}
+}
+
+// TestRunShellCommand tests that a shell command sees the added environment, and that a non-zero exit is a CommandError.
+func TestRunShellCommand(t *testing.T) {
+	output, err := RunShellCommand(`echo "volume $EBS_VOLUME_ID"`, []string{"EBS_VOLUME_ID=vol-1"})
+	if err != nil || strings.TrimSpace(output) != "volume vol-1" {
+		t.Errorf("RunShellCommand() = %q, %v, want \"volume vol-1\", nil", output, err)
+	}
+
+	output, err = RunShellCommand("echo quiesce failed; exit 2", nil)
+	var commandErr *CommandError
+	if !errors.As(err, &commandErr) || commandErr.ExitCode != 2 {
+		t.Fatalf("RunShellCommand() error = %v, want a *CommandError with exit code 2", err)
+	}
+	if strings.TrimSpace(output) != "quiesce failed" {
+		t.Errorf("RunShellCommand() output = %q, want \"quiesce failed\"", output)
+	}
+}
+
+// useFakeRunner : replaces the CommandRunner with a fakeRunner until the test ends
+// t : *testing.T : the test
+// results : map[string]fakeResult : the result of each command line
+// returns : *fakeRunner : the runner
+func useFakeRunner(t *testing.T, results map[string]fakeResult) *fakeRunner {
+	fake := &fakeRunner{Results: results}
+	SetCommandRunner(fake)
+	t.Cleanup(func() { SetCommandRunner(nil) })
+	return fake
+}
+
+// TestGrowPartitionRunner tests that growpart's NOCHANGE exit is not an error, and that other failures are a CommandError.
+func TestGrowPartitionRunner(t *testing.T) {
+	useFakeRunner(t, map[string]fakeResult{
+		"growpart /dev/nvme1n1 1": {Output: "NOCHANGE: partition 1 is size 41940959. it cannot be grown", ExitCode: 1},
+		"growpart /dev/nvme1n1 2": {Stderr: "failed to get start sector", ExitCode: 2},
+	})
+
+	if err := GrowPartition("/dev/nvme1n1", 1); err != nil {
+		t.Errorf("GrowPartition() error = %v, want nil for NOCHANGE", err)
+	}
+
+	err := GrowPartition("/dev/nvme1n1", 2)
+	var commandErr *CommandError
+	if !errors.As(err, &commandErr) {
+		t.Fatalf("GrowPartition() error = %v, want a *CommandError", err)
+	}
+	if commandErr.ExitCode != 2 || commandErr.Command != "growpart /dev/nvme1n1 2" || !strings.Contains(commandErr.Output, "start sector") {
+		t.Errorf("GrowPartition() error = %+v, want exit code 2 with the command line and stderr", commandErr)
+	}
+}
+
+// TestResizeFileSystemByTypeRunner tests that each filesystem type is grown with its command.
+func TestResizeFileSystemByTypeRunner(t *testing.T) {
+	fake := useFakeRunner(t, map[string]fakeResult{
+		"xfs_growfs /data":       {Output: "data blocks changed from 5242880 to 7864320"},
+		"resize2fs /dev/nvme1n1": {Output: "The filesystem on /dev/nvme1n1 is now 7864320 (4k) blocks long."},
+		"resize2fs /dev/nvme2n1": {Stderr: "resize2fs: Bad magic number in super-block", ExitCode: 1},
+	})
+
+	if err := ResizeFileSystemByType("xfs", "/data", "/dev/nvme1n1"); err != nil {
+		t.Errorf("ResizeFileSystemByType(xfs) error = %v", err)
+	}
+	if err := ResizeFileSystemByType("ext4", "/data", "/dev/nvme1n1"); err != nil {
+		t.Errorf("ResizeFileSystemByType(ext4) error = %v", err)
+	}
+	if err := ResizeFileSystemByType("ext4", "/logs", "/dev/nvme2n1"); err == nil || !strings.Contains(err.Error(), "Bad magic number") {
+		t.Errorf("ResizeFileSystemByType() error = %v, want the resize2fs failure", err)
+	}
+
+	want := []string{"xfs_growfs /data", "resize2fs /dev/nvme1n1", "resize2fs /dev/nvme2n1"}
+	if strings.Join(fake.Calls, "; ") != strings.Join(want, "; ") {
+		t.Errorf("commands run = %v, want %v", fake.Calls, want)
+	}
+}
+
+// TestGetDfDeviceNameRunner tests that df falls back to the POSIX format when --output isn't supported.
+func TestGetDfDeviceNameRunner(t *testing.T) {
+	useFakeRunner(t, map[string]fakeResult{
+		"df --output=source,target /data": {Stderr: "df: unrecognized option '--output=source,target'", ExitCode: 1},
+		"df -P /data":                     {Output: "Filesystem     1024-blocks    Used Available Capacity Mounted on\n/dev/nvme1n1      20511312 9547616  9898736      50% /data\n"},
+	})
+
+	device, err := getDfDeviceName("/data")
+	if err != nil || device != "/dev/nvme1n1" {
+		t.Errorf("getDfDeviceName() = (%q, %v), want (\"/dev/nvme1n1\", nil)", device, err)
+	}
+}
+
+// fakeResult is how a command run by a fakeRunner exits.
+type fakeResult struct {
+	Output   string // Standard output of the command.
+	Stderr   string // Standard error of the command, returned in an *ExitError when ExitCode is non-zero.
+	ExitCode int    // Exit code of the command.
+	Err      error  // Returned instead when set, e.g. a failure to start the command.
+}
+
+// fakeRunner is a CommandRunner for tests that answers each command line from its results.
+// Commands with no result fail as if the program wasn't installed.
+type fakeRunner struct {
+	mu      sync.Mutex
+	Results map[string]fakeResult // Result of each command line, e.g. "xfs_growfs /data".
+	Calls   []string              // Command lines run, in order.
+}
+
+// Run returns the result of the command line.
+func (f *fakeRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	commandLine := strings.Join(append([]string{name}, args...), " ")
+	f.Calls = append(f.Calls, commandLine)
+
+	result, ok := f.Results[commandLine]
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("exec: %q: %w", name, exec.ErrNotFound)
+	case result.Err != nil:
+		return nil, result.Err
+	case result.ExitCode != 0:
+		return []byte(result.Output), &ExitError{Code: result.ExitCode, Stderr: []byte(result.Stderr), Err: fmt.Errorf("exit status %d", result.ExitCode)}
+	}
+	return []byte(result.Output), nil
+}
@@ -1,52 +1,68 @@
 package filesystem
 
 import (
-	"bytes"
 	"ebs-monitor/runtime"
+	"errors"
 	"fmt"
-	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/shirou/gopsutil/disk"
 )
 
-// GetLocalMountPoint : Converts the AWS device name to the local device name format.
-// volumeID : string : The AWS device name.
-// Returns: string : the local device name of the volume, or an error if one occurred.
-func GetLocalMountPoint(volumeID string) (string, error) {
-	// If volumeID starts with "vol-", remove the dash ("-")
-	if strings.HasPrefix(volumeID, "vol-") {
-		volumeID = strings.Replace(volumeID, "vol-", "vol", 1)
-	}
+// lsblkColumns are the columns requested from 'lsblk -J -b' for device resolution.
+const lsblkColumns = "NAME,MOUNTPOINT,SERIAL,FSTYPE,SIZE,TYPE"
+
+// bytesPerGiB is the number of bytes in a GiB. AWS sizes EBS volumes in GiB, so local sizes are reported
+// in GiB too, keeping the two directly comparable.
+const bytesPerGiB = 1024 * 1024 * 1024
+
+// BytesToGiB : converts a size in bytes to GiB.
+// bytes : uint64 : The size in bytes.
+// returns : float64 the size in GiB
+func BytesToGiB(bytes uint64) float64 {
+	return float64(bytes) / bytesPerGiB
+}
+
+// GetLocalMountPoint : Finds the mount point of a volume from its disk's serial, or on Xen instances its device name.
+// volumeID : string : The AWS volume ID.
+// awsDeviceName : string : The AWS device name, e.g. /dev/sdf, used when no disk has the volume ID as its serial.
+// Returns: string : the mount point of the volume, or an error if one occurred.
+func GetLocalMountPoint(volumeID, awsDeviceName string) (string, error) {
+	volumeID = normaliseSerial(volumeID)
 
-	// Run the "lsblk -o NAME,MOUNTPOINT,SERIAL" command
-	cmd := exec.Command("lsblk", "-o", "NAME,MOUNTPOINT,SERIAL")
-	fmt.Println("Running command: ", cmd)
+	// Run the "lsblk -J" command for machine-readable output of the whole device tree
+	cmd := newCommand("lsblk", "-J", "-b", "-o", lsblkColumns)
 	output, err := cmd.Output()
-	fmt.Println("Output:", string(output))
-	if err != nil {
+	if errors.Is(err, ErrCommandTimeout) {
+		// A hung lsblk would hang again, so don't wait for the fallback too
 		return "", fmt.Errorf("failed to execute '%v' command on host. error: %w", cmd, err)
 	}
-
-	// Split the output into lines
-	lines := strings.Split(string(output), "\n")
-
-	// Iterate over the lines
-	for _, line := range lines {
-		// If this line contains the volume ID
-		if strings.Contains(line, volumeID) {
-			// Split the line into fields and return the second field (the local mount point)
-			fields := strings.Fields(line)
-			if len(fields) > 1 {
-				return fields[1], nil
-			} else {
-				fmt.Println("Unexpected number of fields in line:", line)
-			}
+	if err != nil {
+		// Older util-linux releases do not support JSON output, fall back to key="value" pairs
+		fmt.Println("JSON output unavailable, falling back to pairs output. error: ", err)
+		cmd = newCommand("lsblk", "-P", "-o", "NAME,MOUNTPOINT,SERIAL")
+		output, err = cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to execute '%v' command on host. error: %w", cmd, err)
 		}
+		return parseLsblkPairsMountPoint(string(output), volumeID, awsDeviceName)
 	}
 
-	// The volume ID was not found in the output
-	return "", fmt.Errorf("volume ID %s not found", volumeID)
+	return parseLsblkJSONMountPoint(output, volumeID, awsDeviceName)
+}
+
+// ResolveMountPoint : Returns the mount point of an unpartitioned volume's filesystem.
+// Uses the configured LocalMountPoint when set, otherwise looks the volume up by its serial or device name.
+// volume : EBSVolumeConfig : Configuration related to EBS volume.
+// returns : string : The mount point.
+// returns : error : Any error that occurred looking up the mount point.
+func ResolveMountPoint(volume runtime.EBSVolumeConfig) (string, error) {
+	if volume.LocalMountPoint != "" {
+		return volume.LocalMountPoint, nil
+	}
+	return GetLocalMountPoint(volume.AWSVolumeID, volume.AWSDeviceName)
 }
 
 // getLocalDeviceName : Retrieves the local NVMe device name for a given mount point.
@@ -54,27 +70,40 @@ func GetLocalMountPoint(volumeID string) (string, error) {
 // returns : string : The local NVMe device name or an empty string if not found.
 // returns : error : Any error that occurred during the operation.
 func getLocalDeviceName(mountPoint string) (string, error) {
-	cmd := exec.Command("df", mountPoint)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	err := cmd.Run()
-	if err != nil {
-		return "", fmt.Errorf("failed to execute 'df' command. error: %w", err)
+	// findmnt's JSON output is unambiguous for mount points containing whitespace
+	cmd := newCommand("findmnt", "-J", "-l", "-o", "SOURCE,TARGET", "--mountpoint", mountPoint)
+	output, err := cmd.Output()
+	if err == nil {
+		return parseFindmntJSONSource(output, filepath.Clean(mountPoint))
 	}
-
-	lines := strings.Split(out.String(), "\n")
-	if len(lines) < 2 {
-		return "", fmt.Errorf("unexpected 'df' command output")
+	if errors.Is(err, ErrCommandTimeout) {
+		return "", fmt.Errorf("failed to execute '%v' command on host. error: %w", cmd, err)
 	}
 
-	fields := strings.Fields(lines[1])
-	if len(fields) < 6 {
-		return "", fmt.Errorf("unexpected 'df' command output")
-	}
+	// findmnt is missing or too old for JSON output (util-linux before 2.27), fall back to df
+	return getDfDeviceName(mountPoint)
+}
 
-	deviceName := fields[0] // The device name should be the first field of the second line
+// getDfDeviceName : Retrieves the source device of a mount point from df.
+// mountPoint : string : The local mount point for the volume.
+// returns : string : The source device.
+// returns : error : Any error that occurred during the operation.
+func getDfDeviceName(mountPoint string) (string, error) {
+	// Request the columns explicitly so the layout does not depend on the df version's defaults
+	cmd := newCommand("df", "--output=source,target", mountPoint)
+	output, err := cmd.Output()
+	if errors.Is(err, ErrCommandTimeout) {
+		return "", fmt.Errorf("failed to execute 'df' command. error: %w", err)
+	}
+	if err != nil {
+		// df without --output support (e.g. busybox, older coreutils), fall back to the POSIX format
+		cmd = newCommand("df", "-P", mountPoint)
+		if output, err = cmd.Output(); err != nil {
+			return "", fmt.Errorf("failed to execute 'df' command. error: %w", err)
+		}
+	}
 
-	return deviceName, nil
+	return parseDfSource(string(output))
 }
 
 // getFileSystemType fetches the file system type of the given mount point.
@@ -82,69 +111,255 @@ func getLocalDeviceName(mountPoint string) (string, error) {
 // Returns : string : File system type.
 // Returns : error : Any error that occurred during operation, nil if operation was successful.
 func getFileSystemType(mountPoint string) (string, error) {
+	// Use 'lsblk' to get the filesystem type of the device mounted at the mount point
+	cmd := newCommand("lsblk", "-J", "-b", "-o", lsblkColumns)
+	output, err := cmd.Output()
+	if err == nil {
+		fsType, err := parseLsblkJSONFSType(output, mountPoint)
+		if err != nil {
+			// ZFS datasets are mounted from their pool rather than a device, so lsblk doesn't list them
+			if _, isZFS, zfsErr := ZpoolName(mountPoint); zfsErr == nil && isZFS {
+				return zfsFilesystemType, nil
+			}
+		}
+		return fsType, err
+	}
+	if errors.Is(err, ErrCommandTimeout) {
+		return "", fmt.Errorf("failed to execute '%v' command on host. error: %w", cmd, err)
+	}
+
+	// Older util-linux releases do not support JSON output, fall back to headerless output for the device
 	device, err := getLocalDeviceName(mountPoint)
 	if err != nil {
 		return "", err
 	}
-
-	// Use 'lsblk' to get the filesystem type of the device
-	cmd := exec.Command("lsblk", "-f", device, "-o", "FSTYPE")
-	output, err := cmd.CombinedOutput()
+	cmd = newCommand("lsblk", "-n", "-o", "FSTYPE", device)
+	output, err = cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to execute '%v' command on host. error: %w", cmd, err)
 	}
-
-	// Process the output to get the filesystem type
-	fsType := strings.TrimSpace(string(output))
-	lines := strings.Split(fsType, "\n")
-	if len(lines) < 2 {
-		return "", fmt.Errorf("unexpected output from '%v' command, got: %s", cmd, fsType)
-	}
-	// The filesystem type is on the second line
-	fsType = lines[1]
-
-	return fsType, nil
+	return parseLsblkPlainFSType(string(output))
 }
 
 // ResizeFileSystemByType : Resizes the file system based on its type.
+// A ZFS pool is expanded onto its grown device, the pool being the one the dataset at the mount point belongs to.
 // filesystem : string : The type of the file system.
 // mountPoint : string : The mount point whose file system needs to be resized.
-// localDeviceName : string : The local device name for the EBS volume
+// localDeviceName : string : The local device name for the EBS volume, for ZFS the pool's vdev on it
 // Returns : error : Any error that occurred during operation, nil if operation was successful.
 func ResizeFileSystemByType(filesystem, mountPoint string, localDeviceName string) error {
-	var cmd *exec.Cmd
+	target := mountPoint
+	if filesystem == zfsFilesystemType {
+		pool, isZFS, err := ZpoolName(mountPoint)
+		if err != nil {
+			return err
+		}
+		if !isZFS {
+			return fmt.Errorf("%s is not a ZFS dataset", mountPoint)
+		}
+		target = pool
+	}
+
+	args, err := resizeCommand(filesystem, target, localDeviceName)
+	if err != nil {
+		return err
+	}
+	cmd := newResizeCommand(args[0], args[1:]...)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to run '%v' filesystem resizing command on host. error: %w", cmd, cmd.commandError(output, err))
+	}
+
+	return nil
+
+}
+
+// resizeCommand : Returns the command that grows a file system of the given type, using the binary set by SetResizeBinaries if any.
+// filesystem : string : The type of the file system.
+// mountPoint : string : The mount point whose file system needs to be resized, for ZFS the pool name.
+// localDeviceName : string : The local device name for the EBS volume
+// Returns : []string : The command and its arguments.
+// Returns : error : An error if the file system type is not supported.
+func resizeCommand(filesystem, mountPoint string, localDeviceName string) ([]string, error) {
+	binary, ok := resizeBinary(filesystem)
+	if !ok {
+		return nil, fmt.Errorf("unsupported file system type: %s", filesystem)
+	}
 	switch filesystem {
-	case "ext4":
-		cmd = exec.Command("resize2fs", localDeviceName)
-		fmt.Println("Running command: ", cmd)
 	case "xfs":
-		cmd = exec.Command("xfs_growfs", mountPoint)
-		fmt.Println("Running command: ", cmd)
+		return []string{binary, mountPoint}, nil
+	case zfsFilesystemType:
+		// Expand the pool onto the grown device, relabelling a whole disk pool's partitions to fill it
+		return []string{binary, "online", "-e", mountPoint, localDeviceName}, nil
 	default:
-		return fmt.Errorf("unsupported file system type: %s", filesystem)
+		return []string{binary, localDeviceName}, nil
+	}
+}
+
+// ResizeCommands : Returns the commands ResizeFilesystem would run for a volume, without running them.
+// Used by dry runs to show what a resize would do.
+// volume : EBSVolumeConfig : Configuration related to EBS volume.
+// Returns : []string : Each command, formatted for display.
+// Returns : error : Any error that occurred while looking up the volume's devices.
+func ResizeCommands(volume runtime.EBSVolumeConfig) ([]string, error) {
+	commands := make([]string, 0)
+
+	if len(volume.Partitions) > 0 {
+		disk, err := getLocalDisk(volume.AWSVolumeID, volume.AWSDeviceName)
+		if err != nil {
+			return nil, err
+		}
+		return partitionResizeCommands(disk.Name, volume.Partitions)
+	}
+
+	localMountPoint, err := ResolveMountPoint(volume)
+	if err != nil {
+		return nil, err
+	}
+	lv, pv, isLVM, err := getLogicalVolume(localMountPoint)
+	if err != nil {
+		return nil, err
+	}
+	if isLVM {
+		for _, args := range lvmResizeCommands(lv, pv) {
+			commands = append(commands, strings.Join(args, " "))
+		}
+		return commands, nil
+	}
+	pool, vdev, isZFS, err := getZpool(volume.AWSVolumeID, volume.AWSDeviceName, localMountPoint)
+	if err != nil {
+		return nil, err
+	}
+	if isZFS {
+		args, err := resizeCommand(zfsFilesystemType, pool, vdev)
+		if err != nil {
+			return nil, err
+		}
+		return append(commands, strings.Join(args, " ")), nil
+	}
+	deviceName, err := getLocalDeviceName(localMountPoint)
+	if err != nil {
+		return nil, err
 	}
+	filesystem, err := getFileSystemType(localMountPoint)
+	if err != nil {
+		return nil, err
+	}
+	if disk, partition, ok := splitPartitionDevice(deviceName); ok {
+		commands = append(commands, strings.Join(growPartitionCommand(disk, partition), " "))
+	}
+	args, err := resizeCommand(filesystem, localMountPoint, deviceName)
+	if err != nil {
+		return nil, err
+	}
+	return append(commands, strings.Join(args, " ")), nil
+}
 
+// getLogicalVolume : Checks whether the filesystem at a mount point is on an LVM logical volume, via 'lsblk -o TYPE'.
+// mountPoint : string : The mount point to check.
+// Returns : string : The logical volume's device name, e.g. vg0-data.
+// Returns : string : The physical volume's device name, e.g. nvme1n1 or nvme1n1p1.
+// Returns : bool : False if the mount point is not on an LVM logical volume.
+// Returns : error : Any error that occurred running lsblk.
+func getLogicalVolume(mountPoint string) (string, string, bool, error) {
+	cmd := newCommand("lsblk", "-J", "-b", "-o", lsblkColumns)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", "", false, fmt.Errorf("failed to execute '%v' command on host. error: %w", cmd, err)
+	}
+	return parseLsblkJSONLVM(output, mountPoint)
+}
+
+// lvmResizeCommands : Returns the commands that grow an LVM logical volume and its filesystem into an enlarged physical volume.
+// The physical volume's partition, if it is on one, is grown first.
+// lv : string : The logical volume's device name.
+// pv : string : The physical volume's device name.
+// Returns : [][]string : Each command and its arguments, in order.
+func lvmResizeCommands(lv, pv string) [][]string {
+	commands := make([][]string, 0, 3)
+	if disk, partition, ok := splitPartitionDevice("/dev/" + pv); ok {
+		commands = append(commands, growPartitionCommand(disk, partition))
+	}
+	return append(commands,
+		[]string{"pvresize", "/dev/" + pv},
+		[]string{"lvextend", "-r", "-l", "+100%FREE", "/dev/mapper/" + lv},
+	)
+}
+
+// resizeLVM : Grows the LVM logical volume mounted at a mount point, and its filesystem, to fill its enlarged physical volume.
+// Runs pvresize on the physical volume, then 'lvextend -r' which also resizes the filesystem.
+// mountPoint : string : The mount point of the logical volume.
+// Returns : error : Any error that occurred during resizing, nil if resizing was successful.
+func resizeLVM(mountPoint string) error {
+	lv, pv, isLVM, err := getLogicalVolume(mountPoint)
+	if err != nil {
+		return err
+	}
+	if !isLVM {
+		return fmt.Errorf("%s is not on an LVM logical volume", mountPoint)
+	}
+
+	// Grow the partition first when the physical volume is on one
+	if err := growPartition("/dev/" + pv); err != nil {
+		return err
+	}
+
+	cmd := newResizeCommand("pvresize", "/dev/"+pv)
 	output, err := cmd.CombinedOutput()
-	fmt.Println("Output: ", string(output))
 	if err != nil {
-		return fmt.Errorf("failed to run '%v' filesystem resizing command on host. error: %w", cmd, err)
+		return fmt.Errorf("failed to run '%v' physical volume resizing command on host. error: %w", cmd, cmd.commandError(output, err))
 	}
 
-	return nil
+	cmd = newResizeCommand("lvextend", "-r", "-l", "+100%FREE", "/dev/mapper/"+lv)
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		// lvextend fails when there are no free extents to add, i.e. the logical volume already fills the physical volume
+		if strings.Contains(string(output), "matches existing size") {
+			return nil
+		}
+		return fmt.Errorf("failed to run '%v' logical volume resizing command on host. error: %w", cmd, cmd.commandError(output, err))
+	}
 
+	return nil
 }
 
 // ResizeFilesystem : Resizes the filesystem of a given volume to maximum available space.
 // volume : EBSVolumeConfig : Configuration related to EBS volume.
 // Returns : error Any error that occurred during resizing, or nil if resizing was successful.
 func ResizeFilesystem(volume runtime.EBSVolumeConfig) error {
-	// Get local mount point based on AWS device name
-	localMountPoint, err := GetLocalMountPoint(volume.AWSVolumeID)
+	// Partitioned volumes grow each listed partition and its filesystem instead
+	if len(volume.Partitions) > 0 {
+		return ResizePartitions(volume)
+	}
+
+	// Get local mount point, configured or looked up by the volume's serial
+	localMountPoint, err := ResolveMountPoint(volume)
 	fmt.Println("localMountPoint: ", localMountPoint)
 	if err != nil {
 		return err
 	}
 
+	// LVM logical volumes grow through their physical volume rather than the filesystem's device
+	lv, pv, isLVM, err := getLogicalVolume(localMountPoint)
+	if err != nil {
+		return err
+	}
+	if isLVM {
+		fmt.Printf("%s is on LVM logical volume %s (physical volume %s)\n", localMountPoint, lv, pv)
+		return resizeLVM(localMountPoint)
+	}
+
+	// ZFS datasets grow with their pool, which is expanded onto the volume's vdev rather than a mounted device
+	pool, vdev, isZFS, err := getZpool(volume.AWSVolumeID, volume.AWSDeviceName, localMountPoint)
+	if err != nil {
+		return err
+	}
+	if isZFS {
+		fmt.Printf("%s is on ZFS pool %s (device %s)\n", localMountPoint, pool, vdev)
+		return ResizeFileSystemByType(zfsFilesystemType, localMountPoint, vdev)
+	}
+
 	deviceName, err := getLocalDeviceName(localMountPoint)
 	fmt.Println("deviceName: ", deviceName)
 	if err != nil {
@@ -158,6 +373,11 @@ func ResizeFilesystem(volume runtime.EBSVolumeConfig) error {
 		return err
 	}
 
+	// Grow the partition first when the filesystem is on one, as the filesystem can't grow past it
+	if err := growPartition(deviceName); err != nil {
+		return err
+	}
+
 	// Resize the filesystem based on its type
 	fmt.Println("Attempting to resize the filesystem now!")
 	err = ResizeFileSystemByType(filesystem, localMountPoint, deviceName)
@@ -168,31 +388,120 @@ func ResizeFilesystem(volume runtime.EBSVolumeConfig) error {
 	return nil
 }
 
-// GetLocalDiskSizeGB : retrieves the LocalDiskSizeGB.
-// returns : float64 LocalDiskSizeGB
+// GetLocalDiskSizeGiB : retrieves the LocalDiskSizeGiB.
+// returns : float64 LocalDiskSizeGiB
 // returns : error potential errors
-func GetLocalDiskSizeGB(localMountPoint string) (float64, error) {
+func GetLocalDiskSizeGiB(localMountPoint string) (float64, error) {
 	usageStat, err := disk.Usage(localMountPoint)
 	if err != nil {
 		return -1, fmt.Errorf("failed to get disk usage for '%v'. error: %w", localMountPoint, err)
 	}
 
-	// Convert disk usage values to GB
-	LocalDiskSizeGB := float64(usageStat.Total) / (1024 * 1024 * 1024)
-	return LocalDiskSizeGB, nil
+	return BytesToGiB(usageStat.Total), nil
 }
 
-// GetUsedSpaceGB : retrieves the UsedSpaceGB.
-// returns : float64 UsedSpaceGB
+// GetUsedSpaceGiB : retrieves the UsedSpaceGiB.
+// returns : float64 UsedSpaceGiB
 // returns : error potential errors
-func GetUsedSpaceGB(localMountPoint string) (float64, error) {
+func GetUsedSpaceGiB(localMountPoint string) (float64, error) {
 	usageStat, err := disk.Usage(localMountPoint)
 	if err != nil {
 		fmt.Printf("Error: %v", err)
 		return -1, fmt.Errorf("failed to get disk utilization for '%v' from host. error: %w", localMountPoint, err)
 	}
 
-	// Convert disk usage values to GB
-	UsedSpaceGB := float64(usageStat.Used) / (1024 * 1024 * 1024)
-	return UsedSpaceGB, nil
+	return BytesToGiB(usageStat.Used), nil
+}
+
+// GetInodeUsagePercent : retrieves the percentage of the filesystem's inodes in use.
+// Filesystems that allocate inodes dynamically (e.g. btrfs) report no inode total and return 0.
+// localMountPoint : string : The mount point of the filesystem.
+// returns : float64 percentage of inodes used
+// returns : error potential errors
+func GetInodeUsagePercent(localMountPoint string) (float64, error) {
+	usageStat, err := disk.Usage(localMountPoint)
+	if err != nil {
+		return -1, fmt.Errorf("failed to get inode usage for '%v' from host. error: %w", localMountPoint, err)
+	}
+	return inodeUsagePercent(usageStat.InodesUsed, usageStat.InodesTotal), nil
+}
+
+// inodeUsagePercent : returns the percentage of inodes used.
+// used : uint64 : Inodes in use.
+// total : uint64 : Total inodes, 0 when the filesystem has no fixed inode count.
+// returns : float64 percentage of inodes used, 0 when total is 0
+func inodeUsagePercent(used, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(used) / float64(total) * 100
+}
+
+// GetReservedSpaceGiB : retrieves the space the filesystem reserves for root on the given mount point.
+// Only ext2/3/4 filesystems are inspected (via 'tune2fs -l'), other filesystems report no reserve.
+// localMountPoint : string : The mount point of the filesystem.
+// returns : float64 ReservedSpaceGiB
+// returns : error potential errors
+func GetReservedSpaceGiB(localMountPoint string) (float64, error) {
+	fsType, err := getFileSystemType(localMountPoint)
+	if err != nil {
+		return -1, err
+	}
+
+	if !strings.HasPrefix(fsType, "ext") {
+		return 0, nil
+	}
+
+	device, err := getLocalDeviceName(localMountPoint)
+	if err != nil {
+		return -1, err
+	}
+
+	cmd := newCommand("tune2fs", "-l", device)
+	output, err := cmd.Output()
+	if err != nil {
+		return -1, fmt.Errorf("failed to execute '%v' command on host. error: %w", cmd, err)
+	}
+
+	reservedBytes, err := parseReservedBytes(string(output))
+	if err != nil {
+		return -1, fmt.Errorf("failed to parse '%v' output. error: %w", cmd, err)
+	}
+
+	return BytesToGiB(reservedBytes), nil
+}
+
+// parseReservedBytes : extracts the reserved space in bytes from 'tune2fs -l' output.
+// output : string : The output of 'tune2fs -l'.
+// returns : uint64 : Reserved block count multiplied by the block size.
+// returns : error : An error if either value is missing or malformed.
+func parseReservedBytes(output string) (uint64, error) {
+	var reservedBlocks, blockSize uint64
+	var foundReserved, foundBlockSize bool
+
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch strings.TrimSpace(key) {
+		case "Reserved block count":
+			reservedBlocks, err = strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+			foundReserved = true
+		case "Block size":
+			blockSize, err = strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+			foundBlockSize = true
+		}
+		if err != nil {
+			return 0, fmt.Errorf("invalid value on line %q. error: %w", line, err)
+		}
+	}
+
+	if !foundReserved || !foundBlockSize {
+		return 0, fmt.Errorf("reserved block count or block size not found")
+	}
+
+	return reservedBlocks * blockSize, nil
 }
@@ -1,6 +1,7 @@
 package filesystem
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -24,7 +25,7 @@ func TestGetLocalMountPoint(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		result, err := GetLocalMountPoint(tc.deviceName)
+		result, err := GetLocalMountPoint(tc.deviceName, "")
 		if result != tc.expected || err != nil {
 			t.Errorf("Device name %s: Expected %s, got %s", tc.deviceName, tc.expected, result)
 		}
@@ -32,3 +33,148 @@ func TestGetLocalMountPoint(t *testing.T) {
 }
 
 // TODO: add additional tests - requires mocking external calls
+
+// TestParseReservedBytes tests the parseReservedBytes function.
+func TestParseReservedBytes(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   string
+		expected uint64
+		wantErr  bool
+	}{
+		{
+			name: "ext4 with default reserve",
+			output: `tune2fs 1.46.5 (30-Dec-2021)
+Filesystem volume name:   <none>
+Block count:              2621440
+Reserved block count:     131072
+Free blocks:              2500000
+Block size:               4096
+`,
+			expected: 131072 * 4096,
+			wantErr:  false,
+		},
+		{
+			name:     "missing block size",
+			output:   "Reserved block count:     131072\n",
+			expected: 0,
+			wantErr:  true,
+		},
+		{
+			name:     "malformed reserved block count",
+			output:   "Reserved block count:     lots\nBlock size:               4096\n",
+			expected: 0,
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseReservedBytes(tc.output)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("parseReservedBytes() error = %v, wantErr %v", err, tc.wantErr)
+				return
+			}
+			if got != tc.expected {
+				t.Errorf("parseReservedBytes() = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+// TestResizeCommand tests the command chosen to grow each file system type.
+func TestResizeCommand(t *testing.T) {
+	testCases := []struct {
+		filesystem string
+		expected   []string
+		wantErr    bool
+	}{
+		{"ext4", []string{"resize2fs", "/dev/nvme1n1"}, false},
+		{"xfs", []string{"xfs_growfs", "/mnt/data"}, false},
+		{"btrfs", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.filesystem, func(t *testing.T) {
+			got, err := resizeCommand(tc.filesystem, "/mnt/data", "/dev/nvme1n1")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("resizeCommand() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("resizeCommand() = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+// TestResizeCommandOverride tests that a configured binary replaces the default only for its filesystem type.
+func TestResizeCommandOverride(t *testing.T) {
+	defer SetResizeBinaries(nil)
+	SetResizeBinaries(map[string]string{"ext4": "/opt/e2fsprogs/sbin/resize2fs"})
+
+	if got, _ := resizeCommand("ext4", "/mnt/data", "/dev/nvme1n1"); !reflect.DeepEqual(got, []string{"/opt/e2fsprogs/sbin/resize2fs", "/dev/nvme1n1"}) {
+		t.Errorf("resizeCommand(ext4) = %v, want the configured binary", got)
+	}
+	if got, _ := resizeCommand("xfs", "/mnt/data", "/dev/nvme1n1"); !reflect.DeepEqual(got, []string{"xfs_growfs", "/mnt/data"}) {
+		t.Errorf("resizeCommand(xfs) = %v, want the default binary", got)
+	}
+}
+
+// TestLVMResizeCommands tests that the partition is grown first only when the physical volume is a partition.
+func TestLVMResizeCommands(t *testing.T) {
+	testCases := []struct {
+		name     string
+		lv       string
+		pv       string
+		expected [][]string
+	}{
+		{
+			name: "physical volume on whole disk",
+			lv:   "data-logs",
+			pv:   "nvme3n1",
+			expected: [][]string{
+				{"pvresize", "/dev/nvme3n1"},
+				{"lvextend", "-r", "-l", "+100%FREE", "/dev/mapper/data-logs"},
+			},
+		},
+		{
+			name: "physical volume on partition",
+			lv:   "archive-lv0",
+			pv:   "nvme4n1p1",
+			expected: [][]string{
+				{"growpart", "/dev/nvme4n1", "1"},
+				{"pvresize", "/dev/nvme4n1p1"},
+				{"lvextend", "-r", "-l", "+100%FREE", "/dev/mapper/archive-lv0"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := lvmResizeCommands(tc.lv, tc.pv); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("lvmResizeCommands() = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+// TestInodeUsagePercent tests the inode usage percentage calculation.
+func TestInodeUsagePercent(t *testing.T) {
+	tests := []struct {
+		name     string
+		used     uint64
+		total    uint64
+		expected float64
+	}{
+		{name: "quarter used", used: 250, total: 1000, expected: 25},
+		{name: "no fixed inode count", used: 0, total: 0, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inodeUsagePercent(tt.used, tt.total); got != tt.expected {
+				t.Errorf("inodeUsagePercent() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+// Package fstest provides an in-memory fake of the instance's filesystems, for tests.
+package fstest
+
+import (
+	"ebs-monitor/filesystem"
+	"ebs-monitor/runtime"
+	"fmt"
+	"sync"
+)
+
+// FakeFilesystem is a filesystem mounted on a FakeHost.
+type FakeFilesystem struct {
+	SizeGiB           float64 // Size of the filesystem.
+	UsedGiB           float64 // Space used on the filesystem.
+	InodesUsedPercent float64 // Percentage of the filesystem's inodes in use.
+	ReservedGiB       float64 // Space reserved for root.
+	ReservedErr       error   // Returned by GetReservedSpaceGiB when set, as when tune2fs fails.
+	Unmounted         bool    // Whether the filesystem has been unmounted, failing CheckMounted.
+}
+
+// FakeHost is an in-memory filesystem.Host for tests.
+type FakeHost struct {
+	mu          sync.Mutex
+	MountPoints map[string][]string                                 // Mount points of each volume, by volume ID.
+	Filesystems map[string]*FakeFilesystem                          // Filesystems, by mount point.
+	Commands    []string                                            // Commands ResizeCommands returns.
+	DiskSizeGiB func(volume runtime.EBSVolumeConfig) (int64, error) // Size of a volume's disk, e.g. aws.GetAWSDeviceSizeGiB, ResizeFilesystem grows the volume's filesystem into it. Nil leaves them as they are.
+	Err         error                                               // Returned by every call when set.
+	ResizeErr   error                                               // Returned by ResizeFilesystem when set.
+	Resized     []string                                            // Volume IDs passed to ResizeFilesystem, in order.
+}
+
+// filesystem : returns the fake filesystem mounted at a mount point
+// mountPoint : string : the mount point
+// returns : *FakeFilesystem : the filesystem
+// returns : error : the configured Err, or an error if nothing is mounted there
+func (h *FakeHost) filesystem(mountPoint string) (*FakeFilesystem, error) {
+	if h.Err != nil {
+		return nil, h.Err
+	}
+	fs, ok := h.Filesystems[mountPoint]
+	if !ok {
+		return nil, fmt.Errorf("no filesystem at '%v'", mountPoint)
+	}
+	return fs, nil
+}
+
+// GetVolumeMountPoints returns the volume's mount points.
+func (h *FakeHost) GetVolumeMountPoints(volume runtime.EBSVolumeConfig) ([]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.Err != nil {
+		return nil, h.Err
+	}
+	mountPoints, ok := h.MountPoints[volume.AWSVolumeID]
+	if !ok || len(mountPoints) == 0 {
+		return nil, fmt.Errorf("volume %v: %w", volume.AWSVolumeID, filesystem.ErrVolumeNotMounted)
+	}
+	return mountPoints, nil
+}
+
+// GrownMountPoint returns the last partition's mount point for partitioned volumes, otherwise the volume's mount point.
+func (h *FakeHost) GrownMountPoint(volume runtime.EBSVolumeConfig) (string, error) {
+	if len(volume.Partitions) == 0 {
+		mountPoints, err := h.GetVolumeMountPoints(volume)
+		if err != nil {
+			return "", err
+		}
+		return mountPoints[0], nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.Err != nil {
+		return "", h.Err
+	}
+	return filesystem.GrownMountPoint(volume)
+}
+
+// CheckMounted returns ErrNotMounted (wrapped) for a missing or unmounted filesystem.
+func (h *FakeHost) CheckMounted(mountPoint string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.Err != nil {
+		return h.Err
+	}
+	if fs, ok := h.Filesystems[mountPoint]; !ok || fs.Unmounted {
+		return fmt.Errorf("%s is %w", mountPoint, filesystem.ErrNotMounted)
+	}
+	return nil
+}
+
+// GetLocalDiskSizeGiB returns the filesystem's size.
+func (h *FakeHost) GetLocalDiskSizeGiB(mountPoint string) (float64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fs, err := h.filesystem(mountPoint)
+	if err != nil {
+		return -1, err
+	}
+	return fs.SizeGiB, nil
+}
+
+// GetUsedSpaceGiB returns the space used on the filesystem.
+func (h *FakeHost) GetUsedSpaceGiB(mountPoint string) (float64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fs, err := h.filesystem(mountPoint)
+	if err != nil {
+		return -1, err
+	}
+	return fs.UsedGiB, nil
+}
+
+// GetInodeUsagePercent returns the filesystem's inode usage.
+func (h *FakeHost) GetInodeUsagePercent(mountPoint string) (float64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fs, err := h.filesystem(mountPoint)
+	if err != nil {
+		return -1, err
+	}
+	return fs.InodesUsedPercent, nil
+}
+
+// GetReservedSpaceGiB returns the filesystem's reserved space.
+func (h *FakeHost) GetReservedSpaceGiB(mountPoint string) (float64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fs, err := h.filesystem(mountPoint)
+	if err != nil {
+		return -1, err
+	}
+	if fs.ReservedErr != nil {
+		return -1, fs.ReservedErr
+	}
+	return fs.ReservedGiB, nil
+}
+
+// ResizeFilesystem records the resize, and grows the volume's filesystem to fill its disk unless ResizeErr is set.
+// Only the last partition of a partitioned volume grows, into the disk space its filesystems don't already
+// take, and nothing grows when that partition is swap.
+func (h *FakeHost) ResizeFilesystem(volume runtime.EBSVolumeConfig) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Resized = append(h.Resized, volume.AWSVolumeID)
+	if h.Err != nil {
+		return h.Err
+	}
+	if h.ResizeErr != nil {
+		return h.ResizeErr
+	}
+	if h.DiskSizeGiB == nil {
+		return nil
+	}
+	diskGiB, err := h.DiskSizeGiB(volume)
+	if err != nil {
+		return err
+	}
+	if len(volume.Partitions) == 0 {
+		for _, mountPoint := range h.MountPoints[volume.AWSVolumeID] {
+			if fs, ok := h.Filesystems[mountPoint]; ok && fs.SizeGiB < float64(diskGiB) {
+				fs.SizeGiB = float64(diskGiB)
+			}
+		}
+		return nil
+	}
+	grownMountPoint, err := filesystem.GrownMountPoint(volume)
+	if err != nil {
+		return err
+	}
+	grown, ok := h.Filesystems[grownMountPoint]
+	if !ok {
+		return nil
+	}
+	usedGiB := 0.0
+	for _, mountPoint := range h.MountPoints[volume.AWSVolumeID] {
+		if fs, ok := h.Filesystems[mountPoint]; ok {
+			usedGiB += fs.SizeGiB
+		}
+	}
+	if free := float64(diskGiB) - usedGiB; free > 0 {
+		grown.SizeGiB += free
+	}
+	return nil
+}
+
+// ResizeCommands returns Commands.
+func (h *FakeHost) ResizeCommands(runtime.EBSVolumeConfig) ([]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.Err != nil {
+		return nil, h.Err
+	}
+	return h.Commands, nil
+}
+
+var _ filesystem.Host = (*FakeHost)(nil)
@@ -0,0 +1,67 @@
+package filesystem
+
+import "ebs-monitor/runtime"
+
+// Host is what monitoring and resizing need from the filesystems of the instance,
+// so a fake can stand in for the host in tests.
+type Host interface {
+	GetVolumeMountPoints(volume runtime.EBSVolumeConfig) ([]string, error)
+	GrownMountPoint(volume runtime.EBSVolumeConfig) (string, error)
+	CheckMounted(mountPoint string) error
+	GetLocalDiskSizeGiB(mountPoint string) (float64, error)
+	GetUsedSpaceGiB(mountPoint string) (float64, error)
+	GetInodeUsagePercent(mountPoint string) (float64, error)
+	GetReservedSpaceGiB(mountPoint string) (float64, error)
+	ResizeFilesystem(volume runtime.EBSVolumeConfig) error
+	ResizeCommands(volume runtime.EBSVolumeConfig) ([]string, error)
+}
+
+// LocalHost is the Host ebs-monitor runs on, implemented by this package's functions.
+type LocalHost struct{}
+
+// GetVolumeMountPoints calls GetVolumeMountPoints.
+func (LocalHost) GetVolumeMountPoints(volume runtime.EBSVolumeConfig) ([]string, error) {
+	return GetVolumeMountPoints(volume)
+}
+
+// GrownMountPoint calls GrownMountPoint.
+func (LocalHost) GrownMountPoint(volume runtime.EBSVolumeConfig) (string, error) {
+	return GrownMountPoint(volume)
+}
+
+// CheckMounted calls CheckMounted.
+func (LocalHost) CheckMounted(mountPoint string) error {
+	return CheckMounted(mountPoint)
+}
+
+// GetLocalDiskSizeGiB calls GetLocalDiskSizeGiB.
+func (LocalHost) GetLocalDiskSizeGiB(mountPoint string) (float64, error) {
+	return GetLocalDiskSizeGiB(mountPoint)
+}
+
+// GetUsedSpaceGiB calls GetUsedSpaceGiB.
+func (LocalHost) GetUsedSpaceGiB(mountPoint string) (float64, error) {
+	return GetUsedSpaceGiB(mountPoint)
+}
+
+// GetInodeUsagePercent calls GetInodeUsagePercent.
+func (LocalHost) GetInodeUsagePercent(mountPoint string) (float64, error) {
+	return GetInodeUsagePercent(mountPoint)
+}
+
+// GetReservedSpaceGiB calls GetReservedSpaceGiB.
+func (LocalHost) GetReservedSpaceGiB(mountPoint string) (float64, error) {
+	return GetReservedSpaceGiB(mountPoint)
+}
+
+// ResizeFilesystem calls ResizeFilesystem.
+func (LocalHost) ResizeFilesystem(volume runtime.EBSVolumeConfig) error {
+	return ResizeFilesystem(volume)
+}
+
+// ResizeCommands calls ResizeCommands.
+func (LocalHost) ResizeCommands(volume runtime.EBSVolumeConfig) ([]string, error) {
+	return ResizeCommands(volume)
+}
+
+var _ Host = LocalHost{}
@@ -0,0 +1,55 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotMounted is returned when a monitored volume or partition has no filesystem mounted.
+// Measuring usage at an unmounted path would report the parent filesystem instead.
+var ErrNotMounted = errors.New("not mounted")
+
+// ErrVolumeNotMounted is returned when no block device on the host has a volume's serial.
+// It is usually transient, as a volume AWS reports attached can take a while to appear, e.g. during boot.
+var ErrVolumeNotMounted = errors.New("not found")
+
+// procMounts lists the mounts of the host.
+const procMounts = "/proc/mounts"
+
+// mountEscapes decodes the octal escapes /proc/mounts uses for whitespace and backslashes in paths.
+var mountEscapes = strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`)
+
+// CheckMounted : confirms a filesystem is mounted at the mount point, according to /proc/mounts.
+// mountPoint : string : The mount point to check.
+// returns : error : ErrNotMounted (wrapped) if nothing is mounted there, or an error reading /proc/mounts.
+func CheckMounted(mountPoint string) error {
+	mounts, err := os.ReadFile(procMounts)
+	if err != nil {
+		return fmt.Errorf("failed to read %s. error: %w", procMounts, err)
+	}
+	if !isMountedIn(string(mounts), mountPoint) {
+		return fmt.Errorf("%s is %w", mountPoint, ErrNotMounted)
+	}
+	return nil
+}
+
+// isMountedIn : reports whether the mount point appears in /proc/mounts content.
+// mounts : string : The content of /proc/mounts.
+// mountPoint : string : The mount point to look for.
+// returns : bool : True if a filesystem is mounted at the mount point.
+func isMountedIn(mounts, mountPoint string) bool {
+	mountPoint = filepath.Clean(mountPoint)
+	for _, line := range strings.Split(mounts, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if filepath.Clean(mountEscapes.Replace(fields[1])) == mountPoint {
+			return true
+		}
+	}
+	return false
+}
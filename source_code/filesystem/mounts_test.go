@@ -0,0 +1,32 @@
+package filesystem
+
+import "testing"
+
+// TestIsMountedIn tests that mount points are found in /proc/mounts content, including escaped paths,
+// and that an unmounted directory is not mistaken for a mount.
+func TestIsMountedIn(t *testing.T) {
+	mounts := `/dev/nvme0n1p1 / ext4 rw,relatime 0 0
+/dev/nvme1n1 /mnt/data xfs rw,relatime 0 0
+/dev/nvme2n1 /mnt/with\040space ext4 rw,relatime 0 0
+`
+
+	tests := []struct {
+		name       string
+		mountPoint string
+		want       bool
+	}{
+		{"mounted", "/mnt/data", true},
+		{"trailing slash", "/mnt/data/", true},
+		{"escaped space", "/mnt/with space", true},
+		{"unmounted directory on root filesystem", "/mnt/logs", false},
+		{"subdirectory of a mount", "/mnt/data/sub", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isMountedIn(mounts, tt.mountPoint); got != tt.want {
+				t.Errorf("isMountedIn(%q) = %v, want %v", tt.mountPoint, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package filesystem
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lsblkOutput mirrors the JSON document produced by 'lsblk -J'.
+type lsblkOutput struct {
+	BlockDevices []lsblkDevice `json:"blockdevices"`
+}
+
+// lsblkDevice is a single block device entry in 'lsblk -J' output.
+// Columns that were not requested, or have no value, decode as empty strings.
+// Partitions, LVM logical volumes and other holders are nested under Children.
+type lsblkDevice struct {
+	Name       string        `json:"name"`
+	MountPoint string        `json:"mountpoint"`
+	Serial     string        `json:"serial"`
+	FSType     string        `json:"fstype"`
+	Type       string        `json:"type"`
+	Size       lsblkSize     `json:"size"`
+	Children   []lsblkDevice `json:"children"`
+}
+
+// lsblkSize is a device size in bytes from 'lsblk -J -b'.
+// Newer util-linux releases emit sizes as JSON numbers, older releases as strings.
+type lsblkSize uint64
+
+// UnmarshalJSON decodes a size given as a JSON number, a numeric string or null.
+func (size *lsblkSize) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "null" || value == "" {
+		*size = 0
+		return nil
+	}
+	parsed, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid lsblk size %s. error: %w", data, err)
+	}
+	*size = lsblkSize(parsed)
+	return nil
+}
+
+// findMounted : searches a device and its descendants, depth first, for the first mounted device.
+// device : lsblkDevice : The root of the device tree to search.
+// returns : *lsblkDevice : The first mounted device, or nil if none is mounted.
+func (device *lsblkDevice) findMounted() *lsblkDevice {
+	if device.MountPoint != "" {
+		return device
+	}
+	for i := range device.Children {
+		if mounted := device.Children[i].findMounted(); mounted != nil {
+			return mounted
+		}
+	}
+	return nil
+}
+
+// normaliseSerial : converts an AWS volume ID or a disk serial to the NVMe serial format, e.g. vol0abcd1234efgh5678.
+// Nitro instances report the volume ID without its dash as the serial, padded with spaces by some lsblk versions.
+// serial : string : The volume ID, with or without the dash, or a serial reported by lsblk.
+// returns : string : The serial in a form that can be compared exactly.
+func normaliseSerial(serial string) string {
+	serial = strings.ToLower(strings.TrimSpace(serial))
+	if strings.HasPrefix(serial, "vol-") {
+		serial = "vol" + strings.TrimPrefix(serial, "vol-")
+	}
+	return serial
+}
+
+// findBySerial : searches the top level devices for the disk with the given serial.
+// Serials are compared exactly once normalised, so a volume ID never matches another that it is a prefix of.
+// devices : []lsblkDevice : The devices to search.
+// serial : string : The volume ID, with or without the dash.
+// returns : *lsblkDevice : The matching disk, or nil if none matches.
+func findBySerial(devices []lsblkDevice, serial string) *lsblkDevice {
+	serial = normaliseSerial(serial)
+	for i := range devices {
+		if normaliseSerial(devices[i].Serial) == serial {
+			return &devices[i]
+		}
+	}
+	return nil
+}
+
+// localDeviceNames : returns the names a volume attached as an AWS device name may have on a Xen instance.
+// Xen instances attach /dev/sdf as /dev/xvdf, though some older kernels keep the sd name.
+// awsDeviceName : string : The AWS device name, e.g. /dev/sdf.
+// returns : []string : The candidate device names, e.g. xvdf and sdf, or nil if no device name is set.
+func localDeviceNames(awsDeviceName string) []string {
+	name := strings.TrimPrefix(awsDeviceName, "/dev/")
+	switch {
+	case name == "":
+		return nil
+	case strings.HasPrefix(name, "sd"):
+		return []string{"xvd" + strings.TrimPrefix(name, "sd"), name}
+	case strings.HasPrefix(name, "xvd"):
+		return []string{name, "sd" + strings.TrimPrefix(name, "xvd")}
+	}
+	return []string{name}
+}
+
+// findVolumeDisk : searches the top level devices for a volume's disk, by serial and then by device name.
+// NVMe (Nitro) disks report the volume ID as their serial. Xen disks don't, so they are matched by the name
+// the AWS device name maps to instead.
+// devices : []lsblkDevice : The devices to search.
+// serial : string : The volume ID, with or without the dash.
+// awsDeviceName : string : The AWS device name, e.g. /dev/sdf, empty to match by serial only.
+// returns : *lsblkDevice : The matching disk, or nil if none matches.
+func findVolumeDisk(devices []lsblkDevice, serial, awsDeviceName string) *lsblkDevice {
+	if disk := findBySerial(devices, serial); disk != nil {
+		return disk
+	}
+	for _, name := range localDeviceNames(awsDeviceName) {
+		for i := range devices {
+			if devices[i].Name == name {
+				return &devices[i]
+			}
+		}
+	}
+	return nil
+}
+
+// findByMountPoint : searches a device tree, depth first, for the device mounted at the given mount point.
+// devices : []lsblkDevice : The device trees to search.
+// mountPoint : string : The mount point to find.
+// returns : *lsblkDevice : The device mounted at the mount point, or nil if none is.
+func findByMountPoint(devices []lsblkDevice, mountPoint string) *lsblkDevice {
+	for i := range devices {
+		if devices[i].MountPoint == mountPoint {
+			return &devices[i]
+		}
+		if found := findByMountPoint(devices[i].Children, mountPoint); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// lvmDeviceType is the lsblk TYPE of an LVM logical volume.
+const lvmDeviceType = "lvm"
+
+// findLogicalVolume : searches a device tree, depth first, for an LVM logical volume mounted at the given mount point.
+// devices : []lsblkDevice : The device trees to search.
+// parent : *lsblkDevice : The device the trees are nested under, nil at the top level.
+// mountPoint : string : The mount point to find.
+// returns : *lsblkDevice : The logical volume, or nil if the mount point is not on one.
+// returns : *lsblkDevice : The physical volume the logical volume is nested under.
+func findLogicalVolume(devices []lsblkDevice, parent *lsblkDevice, mountPoint string) (*lsblkDevice, *lsblkDevice) {
+	for i := range devices {
+		if devices[i].MountPoint == mountPoint && devices[i].Type == lvmDeviceType && parent != nil {
+			return &devices[i], parent
+		}
+		if lv, pv := findLogicalVolume(devices[i].Children, &devices[i], mountPoint); lv != nil {
+			return lv, pv
+		}
+	}
+	return nil, nil
+}
+
+// parseLsblkJSONLVM : finds the LVM logical volume mounted at a mount point and its physical volume.
+// output : []byte : The output of 'lsblk -J' including the TYPE column.
+// mountPoint : string : The mount point to check.
+// returns : string : The logical volume's device name, e.g. data-logs.
+// returns : string : The physical volume's device name, e.g. nvme3n1.
+// returns : bool : False if the mount point is not on an LVM logical volume.
+// returns : error : An error if the output is invalid.
+func parseLsblkJSONLVM(output []byte, mountPoint string) (string, string, bool, error) {
+	parsed, err := parseLsblkJSON(output)
+	if err != nil {
+		return "", "", false, err
+	}
+	lv, pv := findLogicalVolume(parsed.BlockDevices, nil, mountPoint)
+	if lv == nil {
+		return "", "", false, nil
+	}
+	return lv.Name, pv.Name, true, nil
+}
+
+// parseLsblkJSON : decodes 'lsblk -J' output.
+// output : []byte : The JSON output of lsblk.
+// returns : lsblkOutput : The decoded device list.
+// returns : error : An error if the output is not valid lsblk JSON.
+func parseLsblkJSON(output []byte) (lsblkOutput, error) {
+	var parsed lsblkOutput
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return parsed, fmt.Errorf("failed to decode lsblk JSON output. error: %w", err)
+	}
+	return parsed, nil
+}
+
+// parseLsblkJSONMountPoint : finds the mount point of the device whose serial matches the volume ID.
+// The serial is only reported on the disk itself, so its partitions and holders are searched when the disk is not mounted directly.
+// Disks without the volume ID as their serial are matched by their AWS device name, see findVolumeDisk.
+// output : []byte : The output of 'lsblk -J -o NAME,MOUNTPOINT,SERIAL,FSTYPE'.
+// serial : string : The volume ID, with or without the dash.
+// awsDeviceName : string : The AWS device name, e.g. /dev/sdf, empty to match by serial only.
+// returns : string : The mount point of the matching device.
+// returns : error : An error if the output is invalid or no mounted device matches.
+func parseLsblkJSONMountPoint(output []byte, serial, awsDeviceName string) (string, error) {
+	parsed, err := parseLsblkJSON(output)
+	if err != nil {
+		return "", err
+	}
+
+	device := findVolumeDisk(parsed.BlockDevices, serial, awsDeviceName)
+	if device == nil {
+		// The volume ID was not found in the output
+		return "", fmt.Errorf("volume ID %s %w", serial, ErrVolumeNotMounted)
+	}
+	mounted := device.findMounted()
+	if mounted == nil {
+		return "", fmt.Errorf("volume ID %s is attached as %s but %w", serial, device.Name, ErrNotMounted)
+	}
+	return mounted.MountPoint, nil
+}
+
+// parseLsblkPairs : decodes 'lsblk -P' output into one map of column name to value per device.
+// output : string : The key="value" pairs output of lsblk.
+// returns : []map[string]string : The columns of each device, in output order.
+func parseLsblkPairs(output string) []map[string]string {
+	devices := make([]map[string]string, 0)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		columns := make(map[string]string)
+		for line != "" {
+			key, rest, ok := strings.Cut(line, "=")
+			if !ok {
+				break
+			}
+			value, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				break
+			}
+			columns[strings.TrimSpace(key)], _ = strconv.Unquote(value)
+			line = strings.TrimSpace(rest[len(value):])
+		}
+		devices = append(devices, columns)
+	}
+	return devices
+}
+
+// parseLsblkPairsMountPoint : finds the mount point of the device whose serial matches the volume ID.
+// Disks without the volume ID as their serial are matched by their AWS device name, see findVolumeDisk.
+// output : string : The output of 'lsblk -P -o NAME,MOUNTPOINT,SERIAL'.
+// serial : string : The volume ID, with or without the dash.
+// awsDeviceName : string : The AWS device name, e.g. /dev/sdf, empty to match by serial only.
+// returns : string : The mount point of the matching device.
+// returns : error : An error if no mounted device matches.
+func parseLsblkPairsMountPoint(output, serial, awsDeviceName string) (string, error) {
+	serial = normaliseSerial(serial)
+	devices := parseLsblkPairs(output)
+	matches := []func(map[string]string) bool{
+		func(device map[string]string) bool { return normaliseSerial(device["SERIAL"]) == serial },
+	}
+	for _, name := range localDeviceNames(awsDeviceName) {
+		name := name
+		matches = append(matches, func(device map[string]string) bool { return device["NAME"] == name })
+	}
+
+	for _, match := range matches {
+		for _, device := range devices {
+			if !match(device) {
+				continue
+			}
+			if device["MOUNTPOINT"] == "" {
+				return "", fmt.Errorf("volume ID %s is attached as %s but %w", serial, device["NAME"], ErrNotMounted)
+			}
+			return device["MOUNTPOINT"], nil
+		}
+	}
+
+	// The volume ID was not found in the output
+	return "", fmt.Errorf("volume ID %s %w", serial, ErrVolumeNotMounted)
+}
+
+// parseLsblkJSONFSType : extracts the filesystem type of the device mounted at the given mount point.
+// output : []byte : The output of 'lsblk -J -o NAME,MOUNTPOINT,SERIAL,FSTYPE'.
+// mountPoint : string : The mount point whose filesystem type is required.
+// returns : string : The filesystem type of the device.
+// returns : error : An error if the output is invalid, nothing is mounted there, or no filesystem is reported.
+func parseLsblkJSONFSType(output []byte, mountPoint string) (string, error) {
+	parsed, err := parseLsblkJSON(output)
+	if err != nil {
+		return "", err
+	}
+	device := findByMountPoint(parsed.BlockDevices, mountPoint)
+	if device == nil {
+		return "", fmt.Errorf("no device mounted at %s", mountPoint)
+	}
+	if device.FSType == "" {
+		return "", fmt.Errorf("no filesystem type reported by lsblk for %s", device.Name)
+	}
+	return device.FSType, nil
+}
+
+// parseLsblkPlainFSType : extracts the filesystem type from 'lsblk -n -o FSTYPE <device>' output.
+// output : string : The headerless output of lsblk.
+// returns : string : The filesystem type of the device.
+// returns : error : An error if the output reports no filesystem.
+func parseLsblkPlainFSType(output string) (string, error) {
+	for _, line := range strings.Split(output, "\n") {
+		if fsType := strings.TrimSpace(line); fsType != "" {
+			return fsType, nil
+		}
+	}
+	return "", fmt.Errorf("no filesystem type reported by lsblk")
+}
+
+// findmntOutput mirrors the JSON document produced by 'findmnt -J -l'.
+type findmntOutput struct {
+	Filesystems []findmntFilesystem `json:"filesystems"`
+}
+
+// findmntFilesystem is a single mount in 'findmnt -J -l' output.
+type findmntFilesystem struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	FSType string `json:"fstype"` // Only present when the FSTYPE column is requested.
+}
+
+// parseFindmntJSONSource : extracts the source device of a mount point from findmnt output.
+// When several filesystems are mounted on the same target, the last one mounted is the one visible there.
+// output : []byte : The output of 'findmnt -J -l -o SOURCE,TARGET --mountpoint <mountPoint>'.
+// mountPoint : string : The mount point to find.
+// returns : string : The source device of the mount point, without any bind mount or subvolume suffix.
+// returns : error : An error if the output is invalid or has no mount at the mount point.
+func parseFindmntJSONSource(output []byte, mountPoint string) (string, error) {
+	var parsed findmntOutput
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return "", fmt.Errorf("failed to decode findmnt JSON output. error: %w", err)
+	}
+
+	source := ""
+	for _, fs := range parsed.Filesystems {
+		if fs.Target == mountPoint {
+			source = fs.Source
+		}
+	}
+	if source == "" {
+		return "", fmt.Errorf("%s is %w", mountPoint, ErrNotMounted)
+	}
+
+	// Bind mounts and btrfs subvolumes are reported as device[/path]
+	if i := strings.Index(source, "["); i > 0 {
+		source = source[:i]
+	}
+	return source, nil
+}
+
+// parseDfSource : extracts the source device from df output.
+// Accepts both 'df --output=source,target' and 'df -P' output, where the source is the first column.
+// output : string : The output of df for a single mount point.
+// returns : string : The source device of the mount point.
+// returns : error : An error if the output has no data line.
+func parseDfSource(output string) (string, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return "", fmt.Errorf("unexpected 'df' command output")
+	}
+
+	// The header is the first line; the device name is the first field of the second line
+	fields := strings.Fields(lines[1])
+	if len(fields) < 2 {
+		return "", fmt.Errorf("unexpected 'df' command output")
+	}
+
+	return fields[0], nil
+}
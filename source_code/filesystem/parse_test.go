@@ -0,0 +1,402 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// TestParseLsblkJSONMountPoint tests the parseLsblkJSONMountPoint function against a captured fixture.
+func TestParseLsblkJSONMountPoint(t *testing.T) {
+	output, err := os.ReadFile("lsblk_test.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		serial   string
+		expected string
+		wantErr  bool
+		errIs    error
+	}{
+		{
+			name:     "mounted volume",
+			serial:   "vol0abcd1234efgh5678",
+			expected: "/data",
+			wantErr:  false,
+		},
+		{
+			name:     "mounted partition",
+			serial:   "vol0123456789abcdef0",
+			expected: "/",
+			wantErr:  false,
+		},
+		{
+			name:     "mounted LVM logical volume",
+			serial:   "vol0aaaabbbbccccdddd",
+			expected: "/var/log/app",
+			wantErr:  false,
+		},
+		{
+			name:     "attached but not mounted",
+			serial:   "vol0efgh5678abcd1234",
+			expected: "",
+			wantErr:  true,
+			errIs:    ErrNotMounted,
+		},
+		{
+			name:     "unknown volume",
+			serial:   "vol0000000000000000",
+			expected: "",
+			wantErr:  true,
+			errIs:    ErrVolumeNotMounted,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseLsblkJSONMountPoint(output, tc.serial, "")
+			if (err != nil) != tc.wantErr {
+				t.Errorf("parseLsblkJSONMountPoint() error = %v, wantErr %v", err, tc.wantErr)
+				return
+			}
+			if tc.errIs != nil && !errors.Is(err, tc.errIs) {
+				t.Errorf("parseLsblkJSONMountPoint() error = %v, want %v", err, tc.errIs)
+			}
+			if got != tc.expected {
+				t.Errorf("parseLsblkJSONMountPoint() = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+// TestParseLsblkPairsMountPoint tests the parseLsblkPairsMountPoint function.
+func TestParseLsblkPairsMountPoint(t *testing.T) {
+	output := `NAME="nvme0n1" MOUNTPOINT="" SERIAL="vol0123456789abcdef0"
+NAME="nvme1n1" MOUNTPOINT="/mnt/my data" SERIAL="vol0abcd1234efgh5678"
+`
+
+	got, err := parseLsblkPairsMountPoint(output, "vol0abcd1234efgh5678", "")
+	if err != nil || got != "/mnt/my data" {
+		t.Errorf("parseLsblkPairsMountPoint() = (%v, %v), want (/mnt/my data, nil)", got, err)
+	}
+
+	if _, err := parseLsblkPairsMountPoint(output, "vol0123456789abcdef0", ""); err == nil {
+		t.Errorf("parseLsblkPairsMountPoint() error = nil for unmounted volume, want error")
+	}
+
+	if _, err := parseLsblkPairsMountPoint(output, "vol0000000000000000", ""); !errors.Is(err, ErrVolumeNotMounted) {
+		t.Errorf("parseLsblkPairsMountPoint() error = %v for unknown volume, want %v", err, ErrVolumeNotMounted)
+	}
+}
+
+// TestNormaliseSerial tests that volume IDs and lsblk serials normalise to the same form.
+func TestNormaliseSerial(t *testing.T) {
+	testCases := []struct {
+		serial   string
+		expected string
+	}{
+		{"vol-0abcd1234efgh5678", "vol0abcd1234efgh5678"},
+		{"vol0abcd1234efgh5678", "vol0abcd1234efgh5678"},
+		{"vol0abcd1234efgh5678   ", "vol0abcd1234efgh5678"},
+		{"VOL-0ABCD1234EFGH5678", "vol0abcd1234efgh5678"},
+		{"", ""},
+	}
+
+	for _, tc := range testCases {
+		if got := normaliseSerial(tc.serial); got != tc.expected {
+			t.Errorf("normaliseSerial(%q) = %q, want %q", tc.serial, got, tc.expected)
+		}
+	}
+}
+
+// TestSerialPrefixCollision tests that a volume ID is matched exactly, never to a volume whose ID it is a prefix of,
+// and that it matches with or without the dash.
+func TestSerialPrefixCollision(t *testing.T) {
+	jsonOutput := []byte(`{"blockdevices": [
+		{"name": "nvme1n1", "mountpoint": "/longer", "serial": "vol0abcd"},
+		{"name": "nvme2n1", "mountpoint": "/shorter", "serial": "vol0abc"}
+	]}`)
+	pairsOutput := `NAME="nvme1n1" MOUNTPOINT="/longer" SERIAL="vol0abcd"
+NAME="nvme2n1" MOUNTPOINT="/shorter" SERIAL="vol0abc"
+`
+
+	testCases := []struct {
+		volumeID string
+		expected string
+	}{
+		{"vol0abc", "/shorter"},
+		{"vol-0abc", "/shorter"},
+		{"vol0abcd", "/longer"},
+		{"vol-0abcd", "/longer"},
+	}
+
+	for _, tc := range testCases {
+		if got, err := parseLsblkJSONMountPoint(jsonOutput, tc.volumeID, ""); err != nil || got != tc.expected {
+			t.Errorf("parseLsblkJSONMountPoint(%q) = (%v, %v), want %v", tc.volumeID, got, err, tc.expected)
+		}
+		if got, err := parseLsblkPairsMountPoint(pairsOutput, tc.volumeID, ""); err != nil || got != tc.expected {
+			t.Errorf("parseLsblkPairsMountPoint(%q) = (%v, %v), want %v", tc.volumeID, got, err, tc.expected)
+		}
+	}
+
+	if _, err := parseLsblkJSONMountPoint(jsonOutput, "vol0ab", ""); !errors.Is(err, ErrVolumeNotMounted) {
+		t.Errorf("parseLsblkJSONMountPoint(vol0ab) error = %v, want %v", err, ErrVolumeNotMounted)
+	}
+}
+
+// TestParseLsblkMountPointXen tests that NVMe disks are matched by serial, and Xen disks, which have no volume ID
+// serial, by the local name of their AWS device name.
+func TestParseLsblkMountPointXen(t *testing.T) {
+	jsonOutput := []byte(`{"blockdevices": [
+		{"name": "nvme1n1", "mountpoint": "/nvme", "serial": "vol0abcd1234efgh5678"},
+		{"name": "xvdf", "mountpoint": null, "serial": null,
+			"children": [{"name": "xvdf1", "mountpoint": "/xen", "serial": null}]
+		},
+		{"name": "sdg", "mountpoint": "/legacy", "serial": null}
+	]}`)
+	pairsOutput := `NAME="nvme1n1" MOUNTPOINT="/nvme" SERIAL="vol0abcd1234efgh5678"
+NAME="xvdf" MOUNTPOINT="/xen" SERIAL=""
+NAME="sdg" MOUNTPOINT="/legacy" SERIAL=""
+`
+
+	testCases := []struct {
+		name          string
+		volumeID      string
+		awsDeviceName string
+		expected      string
+		errIs         error
+	}{
+		{"nvme serial", "vol-0abcd1234efgh5678", "/dev/sdf", "/nvme", nil},
+		{"xvd name from sd device name", "vol-0123456789abcdef0", "/dev/sdf", "/xen", nil},
+		{"xvd name from xvd device name", "vol-0123456789abcdef0", "/dev/xvdf", "/xen", nil},
+		{"sd name kept by older kernels", "vol-0123456789abcdef0", "/dev/sdg", "/legacy", nil},
+		{"no matching name", "vol-0123456789abcdef0", "/dev/sdh", "", ErrVolumeNotMounted},
+		{"no device name", "vol-0123456789abcdef0", "", "", ErrVolumeNotMounted},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseLsblkJSONMountPoint(jsonOutput, tc.volumeID, tc.awsDeviceName)
+			if got != tc.expected || !errors.Is(err, tc.errIs) {
+				t.Errorf("parseLsblkJSONMountPoint() = (%v, %v), want (%v, %v)", got, err, tc.expected, tc.errIs)
+			}
+			got, err = parseLsblkPairsMountPoint(pairsOutput, tc.volumeID, tc.awsDeviceName)
+			if got != tc.expected || !errors.Is(err, tc.errIs) {
+				t.Errorf("parseLsblkPairsMountPoint() = (%v, %v), want (%v, %v)", got, err, tc.expected, tc.errIs)
+			}
+		})
+	}
+}
+
+// TestParseLsblkFSType tests the parseLsblkJSONFSType and parseLsblkPlainFSType functions.
+func TestParseLsblkFSType(t *testing.T) {
+	output, err := os.ReadFile("lsblk_test.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		mountPoint string
+		expected   string
+		wantErr    bool
+	}{
+		{
+			name:       "whole disk",
+			mountPoint: "/data",
+			expected:   "xfs",
+			wantErr:    false,
+		},
+		{
+			name:       "nested partition",
+			mountPoint: "/boot/efi",
+			expected:   "vfat",
+			wantErr:    false,
+		},
+		{
+			name:       "nested LVM logical volume",
+			mountPoint: "/var/log/app",
+			expected:   "ext4",
+			wantErr:    false,
+		},
+		{
+			name:       "nothing mounted",
+			mountPoint: "/missing",
+			expected:   "",
+			wantErr:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseLsblkJSONFSType(output, tc.mountPoint)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("parseLsblkJSONFSType() error = %v, wantErr %v", err, tc.wantErr)
+				return
+			}
+			if got != tc.expected {
+				t.Errorf("parseLsblkJSONFSType() = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+
+	got, err := parseLsblkPlainFSType("\n  ext4\n")
+	if err != nil || got != "ext4" {
+		t.Errorf("parseLsblkPlainFSType() = (%v, %v), want (ext4, nil)", got, err)
+	}
+}
+
+// TestParseDfSource tests the parseDfSource function with both supported df layouts.
+func TestParseDfSource(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "df --output=source,target",
+			output:   "Filesystem     Mounted on\n/dev/nvme1n1   /data\n",
+			expected: "/dev/nvme1n1",
+			wantErr:  false,
+		},
+		{
+			name:     "df -P",
+			output:   "Filesystem     1024-blocks    Used Available Capacity Mounted on\n/dev/nvme1n1      10255636 1234567   8480000      13% /data\n",
+			expected: "/dev/nvme1n1",
+			wantErr:  false,
+		},
+		{
+			name:     "header only",
+			output:   "Filesystem     Mounted on\n",
+			expected: "",
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseDfSource(tc.output)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("parseDfSource() error = %v, wantErr %v", err, tc.wantErr)
+				return
+			}
+			if got != tc.expected {
+				t.Errorf("parseDfSource() = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+// TestParseFindmntJSONSource tests the parseFindmntJSONSource function, including mount points with spaces,
+// bind mount suffixes and filesystems mounted over one another.
+func TestParseFindmntJSONSource(t *testing.T) {
+	testCases := []struct {
+		name       string
+		output     string
+		mountPoint string
+		expected   string
+		wantErr    bool
+	}{
+		{
+			name:       "single mount",
+			output:     `{"filesystems": [{"source": "/dev/nvme1n1", "target": "/data"}]}`,
+			mountPoint: "/data",
+			expected:   "/dev/nvme1n1",
+		},
+		{
+			name:       "mount point with a space",
+			output:     `{"filesystems": [{"source": "/dev/nvme1n1p2", "target": "/mnt/my data"}]}`,
+			mountPoint: "/mnt/my data",
+			expected:   "/dev/nvme1n1p2",
+		},
+		{
+			name:       "subvolume suffix",
+			output:     `{"filesystems": [{"source": "/dev/nvme2n1[/@data]", "target": "/data"}]}`,
+			mountPoint: "/data",
+			expected:   "/dev/nvme2n1",
+		},
+		{
+			name:       "mounted over",
+			output:     `{"filesystems": [{"source": "/dev/nvme1n1", "target": "/data"}, {"source": "/dev/nvme3n1", "target": "/data"}]}`,
+			mountPoint: "/data",
+			expected:   "/dev/nvme3n1",
+		},
+		{
+			name:       "not mounted",
+			output:     `{"filesystems": []}`,
+			mountPoint: "/data",
+			wantErr:    true,
+		},
+		{
+			name:       "invalid JSON",
+			output:     `/dev/nvme1n1 /data`,
+			mountPoint: "/data",
+			wantErr:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseFindmntJSONSource([]byte(tc.output), tc.mountPoint)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("parseFindmntJSONSource() error = %v, wantErr %v", err, tc.wantErr)
+				return
+			}
+			if got != tc.expected {
+				t.Errorf("parseFindmntJSONSource() = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+// TestLsblkSizeUnmarshal tests that lsblk sizes decode from both numbers and strings.
+func TestLsblkSizeUnmarshal(t *testing.T) {
+	parsed, err := parseLsblkJSON([]byte(`{"blockdevices": [{"size": 10737418240}, {"size": "1073741824"}, {"size": null}]}`))
+	if err != nil {
+		t.Fatalf("parseLsblkJSON() error = %v", err)
+	}
+
+	want := []lsblkSize{10737418240, 1073741824, 0}
+	for i, device := range parsed.BlockDevices {
+		if device.Size != want[i] {
+			t.Errorf("device %d size = %d, want %d", i, device.Size, want[i])
+		}
+	}
+}
+
+// TestParseLsblkJSONLVM tests that LVM logical volumes and their physical volumes are found by mount point.
+func TestParseLsblkJSONLVM(t *testing.T) {
+	output, err := os.ReadFile("lsblk_test.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		mountPoint string
+		wantLV     string
+		wantPV     string
+		wantLVM    bool
+	}{
+		{name: "LVM on whole disk", mountPoint: "/var/log/app", wantLV: "data-logs", wantPV: "nvme3n1", wantLVM: true},
+		{name: "LVM on partition", mountPoint: "/srv/archive", wantLV: "archive-lv0", wantPV: "nvme4n1p1", wantLVM: true},
+		{name: "plain disk", mountPoint: "/data", wantLVM: false},
+		{name: "plain partition", mountPoint: "/", wantLVM: false},
+		{name: "not mounted", mountPoint: "/missing", wantLVM: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lv, pv, isLVM, err := parseLsblkJSONLVM(output, tc.mountPoint)
+			if err != nil {
+				t.Fatalf("parseLsblkJSONLVM() error = %v", err)
+			}
+			if lv != tc.wantLV || pv != tc.wantPV || isLVM != tc.wantLVM {
+				t.Errorf("parseLsblkJSONLVM() = (%s, %s, %v), want (%s, %s, %v)", lv, pv, isLVM, tc.wantLV, tc.wantPV, tc.wantLVM)
+			}
+		})
+	}
+}
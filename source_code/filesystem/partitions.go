@@ -0,0 +1,267 @@
+package filesystem
+
+import (
+	"ebs-monitor/runtime"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// swapFilesystemType is the filesystem type of swap partitions, which are grown but never resized online.
+const swapFilesystemType = "swap"
+
+// getLocalDisk : resolves the local disk of an EBS volume from the lsblk device tree.
+// volumeID : string : The AWS volume ID.
+// awsDeviceName : string : The AWS device name, used on Xen instances where the serial isn't the volume ID.
+// returns : lsblkDevice : The disk, including its partitions as children.
+// returns : error : Any error that occurred during the operation.
+func getLocalDisk(volumeID, awsDeviceName string) (lsblkDevice, error) {
+	serial := normaliseSerial(volumeID)
+
+	cmd := newCommand("lsblk", "-J", "-b", "-o", lsblkColumns)
+	output, err := cmd.Output()
+	if err != nil {
+		return lsblkDevice{}, fmt.Errorf("failed to execute '%v' command on host. error: %w", cmd, err)
+	}
+
+	parsed, err := parseLsblkJSON(output)
+	if err != nil {
+		return lsblkDevice{}, err
+	}
+
+	disk := findVolumeDisk(parsed.BlockDevices, serial, awsDeviceName)
+	if disk == nil {
+		return lsblkDevice{}, fmt.Errorf("volume ID %s %w", serial, ErrVolumeNotMounted)
+	}
+	return *disk, nil
+}
+
+// partitionDeviceName : builds the device name of a partition on a disk.
+// Disks whose name ends in a digit (e.g. nvme1n1) separate the partition number with a "p".
+// disk : string : The disk device name, e.g. nvme1n1 or xvdf.
+// partition : int : The partition number.
+// returns : string : The partition device name, e.g. nvme1n1p2 or xvdf2.
+func partitionDeviceName(disk string, partition int) string {
+	if disk != "" && unicode.IsDigit(rune(disk[len(disk)-1])) {
+		return fmt.Sprintf("%sp%d", disk, partition)
+	}
+	return fmt.Sprintf("%s%d", disk, partition)
+}
+
+// orderPartitions : returns the partitions in the order they are safe to grow.
+// growpart can only extend a partition into free space directly after it, so partitions are processed in disk order.
+// partitions : []runtime.PartitionConfig : The configured partitions.
+// returns : []runtime.PartitionConfig : A sorted copy of the partitions.
+func orderPartitions(partitions []runtime.PartitionConfig) []runtime.PartitionConfig {
+	ordered := append([]runtime.PartitionConfig(nil), partitions...)
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].Partition < ordered[j].Partition
+	})
+	return ordered
+}
+
+// validatePartitionLayout : checks that each configured partition exists on the disk and that the
+// partitions on the disk do not add up to more than the disk itself.
+// disk : lsblkDevice : The disk, including its partitions as children.
+// partitions : []runtime.PartitionConfig : The configured partitions.
+// returns : error : An error describing the first inconsistency found.
+func validatePartitionLayout(disk lsblkDevice, partitions []runtime.PartitionConfig) error {
+	present := make(map[string]bool, len(disk.Children))
+	var total lsblkSize
+	for _, child := range disk.Children {
+		present[child.Name] = true
+		total += child.Size
+	}
+
+	for _, partition := range partitions {
+		name := partitionDeviceName(disk.Name, partition.Partition)
+		if !present[name] {
+			return fmt.Errorf("partition %d (%s) not found on %s", partition.Partition, name, disk.Name)
+		}
+	}
+
+	if disk.Size > 0 && total > disk.Size {
+		return fmt.Errorf("partitions on %s total %d bytes, more than the disk size of %d bytes", disk.Name, total, disk.Size)
+	}
+
+	return nil
+}
+
+var (
+	// suffixedPartitionPattern matches partitions of disks whose names end in a digit, e.g. /dev/nvme1n1p1.
+	suffixedPartitionPattern = regexp.MustCompile(`^(/dev/.*\d)p(\d+)$`)
+	// plainPartitionPattern matches partitions of sd, xvd, vd and hd disks, e.g. /dev/xvdf1.
+	plainPartitionPattern = regexp.MustCompile(`^(/dev/(?:sd|xvd|vd|hd)[a-z]+)(\d+)$`)
+)
+
+// splitPartitionDevice : splits a partition device into its disk and partition number, the inverse of partitionDeviceName.
+// device : string : The device, e.g. /dev/nvme1n1p1.
+// returns : string : The disk device, e.g. /dev/nvme1n1.
+// returns : int : The partition number.
+// returns : bool : False if the device is a whole disk (or not a recognised partition).
+func splitPartitionDevice(device string) (string, int, bool) {
+	for _, pattern := range []*regexp.Regexp{suffixedPartitionPattern, plainPartitionPattern} {
+		if match := pattern.FindStringSubmatch(device); match != nil {
+			partition, err := strconv.Atoi(match[2])
+			if err != nil {
+				return "", 0, false
+			}
+			return match[1], partition, true
+		}
+	}
+	return "", 0, false
+}
+
+// growPartition : grows the partition a filesystem is on to fill its enlarged disk.
+// Whole-disk devices have nothing to grow and are skipped.
+// device : string : The device the filesystem is on, e.g. /dev/nvme1n1p1.
+// returns : error : Any error that occurred running growpart.
+func growPartition(device string) error {
+	disk, partition, ok := splitPartitionDevice(device)
+	if !ok {
+		fmt.Printf("%s is not a partition, skipping growpart\n", device)
+		return nil
+	}
+	return GrowPartition(disk, partition)
+}
+
+// growPartitionCommand : Returns the command that grows a partition to fill its disk.
+// disk : string : The disk device, e.g. /dev/nvme1n1.
+// partition : int : The partition number.
+// returns : []string : The command and its arguments.
+func growPartitionCommand(disk string, partition int) []string {
+	return []string{"growpart", disk, strconv.Itoa(partition)}
+}
+
+// GrowPartition : Grows a partition into the free space following it on the disk.
+// A partition that cannot be grown any further is not treated as an error.
+// disk : string : The disk device, e.g. /dev/nvme1n1.
+// partition : int : The partition number to grow.
+// Returns : error : Any error that occurred during operation, nil if operation was successful.
+func GrowPartition(disk string, partition int) error {
+	args := growPartitionCommand(disk, partition)
+	cmd := newResizeCommand(args[0], args[1:]...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		// growpart exits non-zero with NOCHANGE when the partition already fills the available space
+		if strings.Contains(string(output), "NOCHANGE") {
+			return nil
+		}
+		return fmt.Errorf("failed to run '%v' partition growing command on host. error: %w", cmd, cmd.commandError(output, err))
+	}
+
+	return nil
+}
+
+// lastPartition : returns the partition that grows into the space added to its disk.
+// Only the last partition in disk order has free space after it once the volume is resized.
+// partitions : []runtime.PartitionConfig : The configured partitions, at least one.
+// returns : runtime.PartitionConfig : The last partition.
+func lastPartition(partitions []runtime.PartitionConfig) runtime.PartitionConfig {
+	ordered := orderPartitions(partitions)
+	return ordered[len(ordered)-1]
+}
+
+// ResizePartitions : Grows the last partition of a volume, then resizes the filesystem on each configured partition in disk order.
+// Filesystems on partitions that didn't grow are already full size, so resizing them does nothing.
+// Swap partitions are grown but their swap space is left as is.
+// volume : EBSVolumeConfig : Configuration related to EBS volume.
+// Returns : error Any error that occurred during resizing, or nil if resizing was successful.
+func ResizePartitions(volume runtime.EBSVolumeConfig) error {
+	disk, err := getLocalDisk(volume.AWSVolumeID, volume.AWSDeviceName)
+	if err != nil {
+		return err
+	}
+
+	if err := validatePartitionLayout(disk, volume.Partitions); err != nil {
+		return err
+	}
+
+	if err := GrowPartition("/dev/"+disk.Name, lastPartition(volume.Partitions).Partition); err != nil {
+		return err
+	}
+
+	for _, partition := range orderPartitions(volume.Partitions) {
+		if partition.FilesystemType == swapFilesystemType {
+			fmt.Printf("Partition %d is swap, skipping filesystem resize\n", partition.Partition)
+			continue
+		}
+
+		device := "/dev/" + partitionDeviceName(disk.Name, partition.Partition)
+		if err := ResizeFileSystemByType(partition.FilesystemType, partition.MountPoint, device); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// partitionResizeCommands : Returns the commands ResizePartitions runs for a partitioned disk, in order.
+// diskName : string : The disk device name, e.g. nvme1n1.
+// partitions : []runtime.PartitionConfig : The configured partitions, at least one.
+// Returns : []string : Each command, formatted for display.
+// Returns : error : An error if a partition's filesystem type is not supported.
+func partitionResizeCommands(diskName string, partitions []runtime.PartitionConfig) ([]string, error) {
+	commands := []string{strings.Join(growPartitionCommand("/dev/"+diskName, lastPartition(partitions).Partition), " ")}
+	for _, partition := range orderPartitions(partitions) {
+		if partition.FilesystemType == swapFilesystemType {
+			continue
+		}
+		device := "/dev/" + partitionDeviceName(diskName, partition.Partition)
+		args, err := resizeCommand(partition.FilesystemType, partition.MountPoint, device)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, strings.Join(args, " "))
+	}
+	return commands, nil
+}
+
+// GrownMountPoint : Returns the mount point of the filesystem that grows into a volume's new space when it is resized.
+// For partitioned volumes this is the last partition in disk order, the only one growpart grows.
+// volume : EBSVolumeConfig : Configuration related to EBS volume.
+// returns : string : The mount point, empty when the grown partition is swap so no filesystem grows.
+// returns : error : Any error that occurred looking up the mount point.
+func GrownMountPoint(volume runtime.EBSVolumeConfig) (string, error) {
+	if len(volume.Partitions) == 0 {
+		return ResolveMountPoint(volume)
+	}
+	return grownPartitionMountPoint(volume.Partitions), nil
+}
+
+// grownPartitionMountPoint : Returns the mount point of the last partition in disk order.
+// partitions : []runtime.PartitionConfig : The configured partitions, at least one.
+// returns : string : The mount point, empty when the last partition is swap.
+func grownPartitionMountPoint(partitions []runtime.PartitionConfig) string {
+	last := lastPartition(partitions)
+	if last.FilesystemType == swapFilesystemType {
+		return ""
+	}
+	return last.MountPoint
+}
+
+// GetVolumeMountPoints : Returns the mount points of the filesystems on a volume.
+// For partitioned volumes these are the configured non-swap mount points, otherwise the volume's single mount point.
+// volume : EBSVolumeConfig : Configuration related to EBS volume.
+// returns : []string : The mount points.
+// returns : error : Any error that occurred during the operation.
+func GetVolumeMountPoints(volume runtime.EBSVolumeConfig) ([]string, error) {
+	if len(volume.Partitions) == 0 {
+		mountPoint, err := ResolveMountPoint(volume)
+		if err != nil {
+			return nil, err
+		}
+		return []string{mountPoint}, nil
+	}
+
+	mountPoints := make([]string, 0, len(volume.Partitions))
+	for _, partition := range orderPartitions(volume.Partitions) {
+		if partition.FilesystemType != swapFilesystemType {
+			mountPoints = append(mountPoints, partition.MountPoint)
+		}
+	}
+	return mountPoints, nil
+}
@@ -0,0 +1,146 @@
+package filesystem
+
+import (
+	"ebs-monitor/runtime"
+	"reflect"
+	"testing"
+)
+
+// TestPartitionDeviceName tests the partitionDeviceName function.
+func TestPartitionDeviceName(t *testing.T) {
+	testCases := []struct {
+		disk      string
+		partition int
+		expected  string
+	}{
+		{disk: "nvme1n1", partition: 2, expected: "nvme1n1p2"},
+		{disk: "xvdf", partition: 1, expected: "xvdf1"},
+	}
+
+	for _, tc := range testCases {
+		if got := partitionDeviceName(tc.disk, tc.partition); got != tc.expected {
+			t.Errorf("partitionDeviceName(%s, %d) = %s, want %s", tc.disk, tc.partition, got, tc.expected)
+		}
+	}
+}
+
+// TestSplitPartitionDevice tests that partition devices are split into disk and number, and whole disks are not.
+func TestSplitPartitionDevice(t *testing.T) {
+	testCases := []struct {
+		device        string
+		wantDisk      string
+		wantPartition int
+		wantOK        bool
+	}{
+		{device: "/dev/nvme1n1p1", wantDisk: "/dev/nvme1n1", wantPartition: 1, wantOK: true},
+		{device: "/dev/nvme0n1p12", wantDisk: "/dev/nvme0n1", wantPartition: 12, wantOK: true},
+		{device: "/dev/xvdf2", wantDisk: "/dev/xvdf", wantPartition: 2, wantOK: true},
+		{device: "/dev/sda1", wantDisk: "/dev/sda", wantPartition: 1, wantOK: true},
+		{device: "/dev/nvme1n1", wantOK: false},
+		{device: "/dev/xvdf", wantOK: false},
+		{device: "/dev/mapper/data-lv1", wantOK: false},
+		{device: "/dev/md0", wantOK: false},
+	}
+
+	for _, tc := range testCases {
+		disk, partition, ok := splitPartitionDevice(tc.device)
+		if ok != tc.wantOK || disk != tc.wantDisk || partition != tc.wantPartition {
+			t.Errorf("splitPartitionDevice(%s) = (%s, %d, %v), want (%s, %d, %v)", tc.device, disk, partition, ok, tc.wantDisk, tc.wantPartition, tc.wantOK)
+		}
+	}
+}
+
+// TestOrderPartitions tests that orderPartitions sorts by partition number without modifying its input.
+func TestOrderPartitions(t *testing.T) {
+	partitions := []runtime.PartitionConfig{
+		{Partition: 3, FilesystemType: "swap"},
+		{Partition: 1, MountPoint: "/boot", FilesystemType: "ext4"},
+		{Partition: 2, MountPoint: "/data", FilesystemType: "xfs"},
+	}
+
+	got := orderPartitions(partitions)
+	want := []runtime.PartitionConfig{partitions[1], partitions[2], partitions[0]}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orderPartitions() = %v, want %v", got, want)
+	}
+	if partitions[0].Partition != 3 {
+		t.Errorf("orderPartitions() modified its input")
+	}
+}
+
+// TestPartitionResizeCommands tests that a two-partition volume grows only its last partition,
+// then resizes each filesystem in disk order.
+func TestPartitionResizeCommands(t *testing.T) {
+	partitions := []runtime.PartitionConfig{
+		{Partition: 2, MountPoint: "/data", FilesystemType: "xfs"},
+		{Partition: 1, MountPoint: "/logs", FilesystemType: "ext4"},
+	}
+
+	got, err := partitionResizeCommands("nvme1n1", partitions)
+	if err != nil {
+		t.Fatalf("partitionResizeCommands() error = %v", err)
+	}
+	want := []string{
+		"growpart /dev/nvme1n1 2",
+		"resize2fs /dev/nvme1n1p1",
+		"xfs_growfs /data",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("partitionResizeCommands() = %v, want %v", got, want)
+	}
+
+	if _, err := partitionResizeCommands("nvme1n1", []runtime.PartitionConfig{{Partition: 1, FilesystemType: "vfat"}}); err == nil {
+		t.Errorf("partitionResizeCommands() expected an error for an unsupported filesystem")
+	}
+}
+
+// TestValidatePartitionLayout tests the validatePartitionLayout function.
+func TestValidatePartitionLayout(t *testing.T) {
+	disk := lsblkDevice{
+		Name: "nvme1n1",
+		Size: 100,
+		Children: []lsblkDevice{
+			{Name: "nvme1n1p1", Size: 10},
+			{Name: "nvme1n1p2", Size: 80},
+			{Name: "nvme1n1p3", Size: 10},
+		},
+	}
+	oversized := disk
+	oversized.Size = 50
+
+	testCases := []struct {
+		name       string
+		disk       lsblkDevice
+		partitions []runtime.PartitionConfig
+		wantErr    bool
+	}{
+		{
+			name:       "all partitions present",
+			disk:       disk,
+			partitions: []runtime.PartitionConfig{{Partition: 1}, {Partition: 2}, {Partition: 3}},
+			wantErr:    false,
+		},
+		{
+			name:       "missing partition",
+			disk:       disk,
+			partitions: []runtime.PartitionConfig{{Partition: 4}},
+			wantErr:    true,
+		},
+		{
+			name:       "partitions larger than disk",
+			disk:       oversized,
+			partitions: []runtime.PartitionConfig{{Partition: 2}},
+			wantErr:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validatePartitionLayout(tc.disk, tc.partitions)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("validatePartitionLayout() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package filesystem
+
+import (
+	"ebs-monitor/runtime"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// lookPath finds a tool on the PATH, replaced in tests.
+var lookPath = exec.LookPath
+
+// RequiredResizeTools : Returns the tools ResizeFilesystem needs to grow a volume, found by probing its filesystems,
+// e.g. growpart and resize2fs for an ext4 filesystem on a partition.
+// volume : EBSVolumeConfig : Configuration related to EBS volume.
+// Returns : []string : Each tool once, the configured binary for filesystem types set with SetResizeBinaries.
+// Returns : error : Any error that occurred probing the volume's filesystems.
+func RequiredResizeTools(volume runtime.EBSVolumeConfig) ([]string, error) {
+	tools := make([]string, 0, 4)
+	add := func(tool string) {
+		for _, existing := range tools {
+			if existing == tool {
+				return
+			}
+		}
+		tools = append(tools, tool)
+	}
+
+	if len(volume.Partitions) > 0 {
+		add("growpart")
+		for _, partition := range orderPartitions(volume.Partitions) {
+			if partition.FilesystemType == swapFilesystemType {
+				continue
+			}
+			binary, err := filesystemResizeTool(partition.MountPoint)
+			if err != nil {
+				return nil, err
+			}
+			add(binary)
+		}
+		return tools, nil
+	}
+
+	localMountPoint, err := ResolveMountPoint(volume)
+	if err != nil {
+		return nil, err
+	}
+
+	// LVM logical volumes grow through their physical volume, then 'lvextend -r' grows the filesystem
+	_, pv, isLVM, err := getLogicalVolume(localMountPoint)
+	if err != nil {
+		return nil, err
+	}
+	if isLVM {
+		if _, _, ok := splitPartitionDevice("/dev/" + pv); ok {
+			add("growpart")
+		}
+		add("pvresize")
+		add("lvextend")
+		binary, err := filesystemResizeTool(localMountPoint)
+		if err != nil {
+			return nil, err
+		}
+		add(binary)
+		return tools, nil
+	}
+
+	// ZFS datasets grow with their pool
+	_, isZFS, err := ZpoolName(localMountPoint)
+	if errors.Is(err, ErrCommandTimeout) {
+		return nil, err
+	}
+	if err == nil && isZFS {
+		binary, _ := resizeBinary(zfsFilesystemType)
+		add(binary)
+		return tools, nil
+	}
+
+	deviceName, err := getLocalDeviceName(localMountPoint)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, ok := splitPartitionDevice(deviceName); ok {
+		add("growpart")
+	}
+	binary, err := filesystemResizeTool(localMountPoint)
+	if err != nil {
+		return nil, err
+	}
+	add(binary)
+	return tools, nil
+}
+
+// filesystemResizeTool : Returns the binary that grows the filesystem mounted at a mount point, probing its type.
+// mountPoint : string : The mount point of the filesystem.
+// Returns : string : The binary.
+// Returns : error : An error if the type can't be probed or is not supported.
+func filesystemResizeTool(mountPoint string) (string, error) {
+	filesystem, err := getFileSystemType(mountPoint)
+	if err != nil {
+		return "", err
+	}
+	binary, ok := resizeBinary(filesystem)
+	if !ok {
+		return "", fmt.Errorf("unsupported file system type at %s: %s", mountPoint, filesystem)
+	}
+	return binary, nil
+}
+
+// MissingResizeTools : Returns the tools ResizeFilesystem needs to grow a volume that aren't installed.
+// volume : EBSVolumeConfig : Configuration related to EBS volume.
+// Returns : []string : The missing tools, empty if they are all installed.
+// Returns : error : Any error that occurred probing the volume's filesystems.
+func MissingResizeTools(volume runtime.EBSVolumeConfig) ([]string, error) {
+	tools, err := RequiredResizeTools(volume)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the resize tools of volume %s. error: %w", volume.AWSVolumeID, err)
+	}
+	missing := make([]string, 0)
+	for _, tool := range tools {
+		if _, err := lookPath(tool); err != nil {
+			missing = append(missing, tool)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,117 @@
+package filesystem
+
+import (
+	"ebs-monitor/runtime"
+	"os"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+// TestRequiredResizeTools tests that the tools needed are derived from the filesystem probed at each volume's mount point.
+func TestRequiredResizeTools(t *testing.T) {
+	lsblk, err := os.ReadFile("lsblk_test.json")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	useFakeRunner(t, map[string]fakeResult{
+		"lsblk -J -b -o " + lsblkColumns:                               {Output: string(lsblk)},
+		"findmnt -J -l -o SOURCE,TARGET --mountpoint /data":            {Output: `{"filesystems": [{"source": "/dev/nvme1n1", "target": "/data"}]}`},
+		"findmnt -J -l -o SOURCE,TARGET,FSTYPE --mountpoint /data":     {Output: `{"filesystems": [{"source": "/dev/nvme1n1", "target": "/data", "fstype": "xfs"}]}`},
+		"findmnt -J -l -o SOURCE,TARGET --mountpoint /":                {Output: `{"filesystems": [{"source": "/dev/nvme0n1p1", "target": "/"}]}`},
+		"findmnt -J -l -o SOURCE,TARGET,FSTYPE --mountpoint /":         {Output: `{"filesystems": [{"source": "/dev/nvme0n1p1", "target": "/", "fstype": "ext4"}]}`},
+		"findmnt -J -l -o SOURCE,TARGET,FSTYPE --mountpoint /boot/efi": {Output: `{"filesystems": [{"source": "/dev/nvme0n1p15", "target": "/boot/efi", "fstype": "vfat"}]}`},
+	})
+
+	tests := []struct {
+		name    string
+		volume  runtime.EBSVolumeConfig
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "xfs on a whole disk",
+			volume: runtime.EBSVolumeConfig{AWSVolumeID: "vol-0abcd1234efgh5678", LocalMountPoint: "/data"},
+			want:   []string{"xfs_growfs"},
+		},
+		{
+			name:   "ext4 on a partition",
+			volume: runtime.EBSVolumeConfig{AWSVolumeID: "vol-0123456789abcdef0", LocalMountPoint: "/"},
+			want:   []string{"growpart", "resize2fs"},
+		},
+		{
+			name:   "ext4 on LVM",
+			volume: runtime.EBSVolumeConfig{AWSVolumeID: "vol-0aaaabbbbccccdddd", LocalMountPoint: "/var/log/app"},
+			want:   []string{"pvresize", "lvextend", "resize2fs"},
+		},
+		{
+			name:   "xfs on LVM on a partition",
+			volume: runtime.EBSVolumeConfig{AWSVolumeID: "vol-0bbbbccccddddeeee", LocalMountPoint: "/srv/archive"},
+			want:   []string{"growpart", "pvresize", "lvextend", "xfs_growfs"},
+		},
+		{
+			name: "partitions with swap",
+			volume: runtime.EBSVolumeConfig{AWSVolumeID: "vol-0123456789abcdef0", Partitions: []runtime.PartitionConfig{
+				{Partition: 2, FilesystemType: "swap"},
+				{Partition: 1, MountPoint: "/", FilesystemType: "ext4"},
+			}},
+			want: []string{"growpart", "resize2fs"},
+		},
+		{
+			name:    "unsupported filesystem",
+			volume:  runtime.EBSVolumeConfig{AWSVolumeID: "vol-0123456789abcdef0", LocalMountPoint: "/boot/efi"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RequiredResizeTools(tt.volume)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RequiredResizeTools() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RequiredResizeTools() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMissingResizeTools tests that only the tools that can't be found are reported, using the configured binary.
+func TestMissingResizeTools(t *testing.T) {
+	useFakeRunner(t, map[string]fakeResult{
+		"findmnt -J -l -o SOURCE,TARGET --mountpoint /data": {Output: `{"filesystems": [{"source": "/dev/nvme1n1p1", "target": "/data"}]}`},
+		"lsblk -J -b -o " + lsblkColumns: {Output: `{"blockdevices": [{"name": "nvme1n1", "type": "disk", "children": [
+			{"name": "nvme1n1p1", "mountpoint": "/data", "fstype": "ext4", "type": "part"}]}]}`},
+	})
+	SetResizeBinaries(map[string]string{"ext4": "/opt/e2fsprogs/resize2fs"})
+	installed := map[string]bool{"growpart": true}
+	lookPath = func(file string) (string, error) {
+		if installed[file] {
+			return "/usr/bin/" + file, nil
+		}
+		return "", exec.ErrNotFound
+	}
+	t.Cleanup(func() {
+		SetResizeBinaries(nil)
+		lookPath = exec.LookPath
+	})
+
+	volume := runtime.EBSVolumeConfig{AWSVolumeID: "vol-1", LocalMountPoint: "/data"}
+	missing, err := MissingResizeTools(volume)
+	if err != nil {
+		t.Fatalf("MissingResizeTools() error = %v", err)
+	}
+	if want := []string{"/opt/e2fsprogs/resize2fs"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("MissingResizeTools() = %v, want %v", missing, want)
+	}
+
+	installed["/opt/e2fsprogs/resize2fs"] = true
+	if missing, err := MissingResizeTools(volume); err != nil || len(missing) != 0 {
+		t.Errorf("MissingResizeTools() = (%v, %v), want none missing", missing, err)
+	}
+
+	if _, err := MissingResizeTools(runtime.EBSVolumeConfig{AWSVolumeID: "vol-2", LocalMountPoint: "/missing"}); err == nil {
+		t.Error("MissingResizeTools() of an unmounted volume error = nil, want the probe error")
+	}
+}
@@ -0,0 +1,147 @@
+package filesystem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// zfsFilesystemType is the filesystem type of a mounted ZFS dataset.
+const zfsFilesystemType = "zfs"
+
+// zfsMemberType is the lsblk FSTYPE of a device that belongs to a ZFS pool.
+const zfsMemberType = "zfs_member"
+
+// ZpoolName : Returns the ZFS pool of the dataset mounted at a mount point, via 'findmnt -o FSTYPE'.
+// mountPoint : string : The mount point to check.
+// Returns : string : The pool name, e.g. tank.
+// Returns : bool : False if the mount point is not a ZFS dataset.
+// Returns : error : Any error that occurred running findmnt.
+func ZpoolName(mountPoint string) (string, bool, error) {
+	cmd := newCommand("findmnt", "-J", "-l", "-o", "SOURCE,TARGET,FSTYPE", "--mountpoint", mountPoint)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to execute '%v' command on host. error: %w", cmd, err)
+	}
+	return parseFindmntJSONZpool(output, filepath.Clean(mountPoint))
+}
+
+// parseFindmntJSONZpool : extracts the pool of the ZFS dataset mounted at a mount point from findmnt output.
+// ZFS datasets are mounted with the dataset name, e.g. tank/data, as their source.
+// output : []byte : The output of 'findmnt -J -l -o SOURCE,TARGET,FSTYPE --mountpoint <mountPoint>'.
+// mountPoint : string : The mount point to look for.
+// returns : string : The pool name.
+// returns : bool : False if the mount point is not a ZFS dataset.
+// returns : error : An error if the output is invalid or nothing is mounted at the mount point.
+func parseFindmntJSONZpool(output []byte, mountPoint string) (string, bool, error) {
+	var parsed findmntOutput
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return "", false, fmt.Errorf("failed to decode findmnt JSON output. error: %w", err)
+	}
+
+	var mounted *findmntFilesystem
+	for i, fs := range parsed.Filesystems {
+		if fs.Target == mountPoint {
+			mounted = &parsed.Filesystems[i]
+		}
+	}
+	if mounted == nil {
+		return "", false, fmt.Errorf("%s is %w", mountPoint, ErrNotMounted)
+	}
+	if mounted.FSType != zfsFilesystemType {
+		return "", false, nil
+	}
+	pool, _, _ := strings.Cut(mounted.Source, "/")
+	return pool, true, nil
+}
+
+// findZFSMember : searches a device and its descendants, depth first, for a ZFS pool member.
+// Pools created on a whole disk put their data on the disk's first partition.
+// device : lsblkDevice : The root of the device tree to search.
+// returns : *lsblkDevice : The first pool member, or nil if the device is not in a pool.
+func (device *lsblkDevice) findZFSMember() *lsblkDevice {
+	if device.FSType == zfsMemberType {
+		return device
+	}
+	for i := range device.Children {
+		if member := device.Children[i].findZFSMember(); member != nil {
+			return member
+		}
+	}
+	return nil
+}
+
+// parseZpoolStatusVdevs : extracts the device paths of a pool's vdevs from 'zpool status -P' output.
+// output : string : The output of 'zpool status -P <pool>'.
+// returns : []string : The device paths, as the pool refers to them.
+func parseZpoolStatusVdevs(output string) []string {
+	vdevs := make([]string, 0)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && strings.HasPrefix(fields[0], "/dev/") {
+			vdevs = append(vdevs, fields[0])
+		}
+	}
+	return vdevs
+}
+
+// getZpool : Checks whether the filesystem at a mount point is a ZFS dataset, and finds its pool's vdev on the volume.
+// volumeID : string : The AWS Volume ID.
+// awsDeviceName : string : The AWS device name, used on Xen instances where the serial isn't the volume ID.
+// mountPoint : string : The mount point to check.
+// Returns : string : The pool name.
+// Returns : string : The vdev on the volume, as the pool refers to it.
+// Returns : bool : False if the mount point is not a ZFS dataset.
+// Returns : error : Any error that occurred, or if the volume is not in the dataset's pool.
+func getZpool(volumeID, awsDeviceName, mountPoint string) (string, string, bool, error) {
+	pool, isZFS, err := ZpoolName(mountPoint)
+	if errors.Is(err, ErrCommandTimeout) {
+		return "", "", false, err
+	}
+	if err != nil || !isZFS {
+		// Without findmnt JSON output (util-linux before 2.27) ZFS datasets can't be identified, so the
+		// mount point is resized as a regular filesystem as before
+		return "", "", false, nil
+	}
+	vdev, err := getZpoolDevice(volumeID, awsDeviceName, pool)
+	if err != nil {
+		return "", "", false, err
+	}
+	return pool, vdev, true, nil
+}
+
+// getZpoolDevice : Returns the vdev of a pool that is on the volume with the given ID.
+// The volume's pool member is found with lsblk, then matched against the pool's vdevs, which may be
+// symlinks such as /dev/disk/by-id paths.
+// volumeID : string : The AWS Volume ID.
+// awsDeviceName : string : The AWS device name, used on Xen instances where the serial isn't the volume ID.
+// pool : string : The pool the volume should belong to.
+// Returns : string : The vdev path as the pool refers to it, for use with 'zpool online'.
+// Returns : error : Any error that occurred, or if the volume is not in the pool.
+func getZpoolDevice(volumeID, awsDeviceName, pool string) (string, error) {
+	disk, err := getLocalDisk(volumeID, awsDeviceName)
+	if err != nil {
+		return "", err
+	}
+	member := disk.findZFSMember()
+	if member == nil {
+		return "", fmt.Errorf("volume ID %s is attached as %s but is not a ZFS pool member", volumeID, disk.Name)
+	}
+
+	binary, _ := resizeBinary(zfsFilesystemType)
+	cmd := newCommand(binary, "status", "-P", pool)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to execute '%v' command on host. error: %w", cmd, err)
+	}
+
+	device := "/dev/" + member.Name
+	for _, vdev := range parseZpoolStatusVdevs(string(output)) {
+		if resolved, err := filepath.EvalSymlinks(vdev); vdev == device || (err == nil && resolved == device) {
+			return vdev, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not a device of ZFS pool %s", device, pool)
+}
@@ -0,0 +1,104 @@
+package filesystem
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestParseFindmntJSONZpool tests that the pool is taken from a ZFS dataset's source and other filesystems are not ZFS.
+func TestParseFindmntJSONZpool(t *testing.T) {
+	testCases := []struct {
+		name      string
+		output    string
+		wantPool  string
+		wantZFS   bool
+		wantErrIs error
+	}{
+		{
+			name:     "dataset",
+			output:   `{"filesystems": [{"source": "tank/data", "target": "/data", "fstype": "zfs"}]}`,
+			wantPool: "tank",
+			wantZFS:  true,
+		},
+		{
+			name:     "pool root dataset",
+			output:   `{"filesystems": [{"source": "tank", "target": "/data", "fstype": "zfs"}]}`,
+			wantPool: "tank",
+			wantZFS:  true,
+		},
+		{
+			name:    "not zfs",
+			output:  `{"filesystems": [{"source": "/dev/nvme1n1", "target": "/data", "fstype": "ext4"}]}`,
+			wantZFS: false,
+		},
+		{
+			name:      "not mounted",
+			output:    `{"filesystems": []}`,
+			wantErrIs: ErrNotMounted,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pool, isZFS, err := parseFindmntJSONZpool([]byte(tc.output), "/data")
+			if tc.wantErrIs != nil {
+				if !errors.Is(err, tc.wantErrIs) {
+					t.Fatalf("parseFindmntJSONZpool() error = %v, want %v", err, tc.wantErrIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFindmntJSONZpool() error = %v", err)
+			}
+			if pool != tc.wantPool || isZFS != tc.wantZFS {
+				t.Errorf("parseFindmntJSONZpool() = (%q, %v), want (%q, %v)", pool, isZFS, tc.wantPool, tc.wantZFS)
+			}
+		})
+	}
+}
+
+// TestParseZpoolStatusVdevs tests that only device paths are taken from the config section of 'zpool status -P'.
+func TestParseZpoolStatusVdevs(t *testing.T) {
+	output := `  pool: tank
+ state: ONLINE
+config:
+
+	NAME                                                     STATE     READ WRITE CKSUM
+	tank                                                     ONLINE       0     0     0
+	  /dev/disk/by-id/nvme-Amazon_Elastic_Block_Store_vol0abcd-part1  ONLINE       0     0     0
+	  /dev/nvme2n1p1                                         ONLINE       0     0     0
+
+errors: No known data errors
+`
+	want := []string{"/dev/disk/by-id/nvme-Amazon_Elastic_Block_Store_vol0abcd-part1", "/dev/nvme2n1p1"}
+	if got := parseZpoolStatusVdevs(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseZpoolStatusVdevs() = %v, want %v", got, want)
+	}
+}
+
+// TestFindZFSMember tests that a whole disk pool's member partition is found under the disk.
+func TestFindZFSMember(t *testing.T) {
+	disk := lsblkDevice{Name: "nvme1n1", Children: []lsblkDevice{
+		{Name: "nvme1n1p1", FSType: zfsMemberType},
+		{Name: "nvme1n1p9"},
+	}}
+	if member := disk.findZFSMember(); member == nil || member.Name != "nvme1n1p1" {
+		t.Errorf("findZFSMember() = %v, want nvme1n1p1", member)
+	}
+
+	if member := (&lsblkDevice{Name: "nvme2n1", FSType: "ext4"}).findZFSMember(); member != nil {
+		t.Errorf("findZFSMember() = %v, want nil for a disk not in a pool", member)
+	}
+}
+
+// TestZFSResizeCommand tests that a pool is expanded onto its vdev.
+func TestZFSResizeCommand(t *testing.T) {
+	got, err := resizeCommand(zfsFilesystemType, "tank", "/dev/nvme1n1p1")
+	if err != nil {
+		t.Fatalf("resizeCommand() error = %v", err)
+	}
+	if want := []string{"zpool", "online", "-e", "tank", "/dev/nvme1n1p1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("resizeCommand() = %v, want %v", got, want)
+	}
+}
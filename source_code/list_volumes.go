@@ -0,0 +1,142 @@
+package main
+
+import (
+	"ebs-monitor/aws"
+	"ebs-monitor/filesystem"
+	"ebs-monitor/runtime"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// Output formats of the list-volumes subcommand
+const (
+	listOutputTable = "table"
+	listOutputYAML  = "yaml"
+)
+
+// Starting thresholds written into the config skeleton printed by list-volumes, matching the sample config
+const (
+	skeletonIncrementSizeGB = 10
+	skeletonResizeThreshold = 80
+)
+
+var (
+	// listRegion : string The region of the local instance, looked up from instance metadata when empty
+	listRegion string
+	// listOutput : string The output format of the list-volumes subcommand, "table" or "yaml"
+	listOutput string
+)
+
+// listVolumesCmd : Prints the EBS volumes attached to the local instance and exits
+var listVolumesCmd = &cobra.Command{
+	Use:   "list-volumes",
+	Short: "Print the EBS volumes attached to this instance and exit",
+	Long:  `Lists the EBS volumes attached to this instance with their device name, size, type and local mount point. Use --output yaml to print a volumes section to paste into a config file.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		listVolumes(cmd, args)
+	},
+}
+
+// init : Registers the list-volumes subcommand
+func init() {
+	listVolumesCmd.Flags().StringVar(&listRegion, "region", "", "AWS region of this instance, defaults to the region from instance metadata")
+	listVolumesCmd.Flags().StringVar(&listOutput, "output", listOutputTable, "Output format, \"table\" or \"yaml\"")
+	rootCmd.AddCommand(listVolumesCmd)
+}
+
+// ListedVolume : An attached volume and where it is mounted, as printed by the list-volumes subcommand
+type ListedVolume struct {
+	aws.AttachedVolume
+	LocalMountPoint string // Mount point of the volume's filesystem, empty if none was found.
+}
+
+// listVolumes : The function that runs the list-volumes subcommand
+// cmd : *cobra.Command The list-volumes command
+// args : []string The arguments passed to the list-volumes command
+func listVolumes(cmd *cobra.Command, args []string) {
+	if listOutput != listOutputTable && listOutput != listOutputYAML {
+		fmt.Fprintf(os.Stderr, "Invalid output format %q, must be %q or %q\n", listOutput, listOutputTable, listOutputYAML)
+		os.Exit(1)
+	}
+
+	region := listRegion
+	if region == "" {
+		localRegion, err := aws.GetLocalRegion()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to get the region from instance metadata, set --region: %v\n", err)
+			os.Exit(1)
+		}
+		region = localRegion
+	}
+
+	attached, err := aws.GetAttachedVolumes(region)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list attached volumes: %v\n", err)
+		os.Exit(1)
+	}
+
+	volumes := make([]ListedVolume, 0, len(attached))
+	for _, volume := range attached {
+		// Unmounted volumes are still listed, without a mount point
+		mountPoint, _ := filesystem.ResolveMountPoint(runtime.EBSVolumeConfig{
+			AWSVolumeID:   volume.AWSVolumeID,
+			AWSDeviceName: volume.AWSDeviceName,
+		})
+		volumes = append(volumes, ListedVolume{AttachedVolume: volume, LocalMountPoint: mountPoint})
+	}
+
+	if listOutput == listOutputYAML {
+		err = PrintVolumesYAML(os.Stdout, volumes)
+	} else {
+		err = PrintVolumesTable(os.Stdout, volumes)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to print volumes: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// PrintVolumesTable : Prints attached volumes as an aligned table.
+// w : io.Writer Where to print the table.
+// volumes : []ListedVolume The volumes to print.
+// Returns: error Any error writing the table.
+func PrintVolumesTable(w io.Writer, volumes []ListedVolume) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VOLUME ID\tDEVICE\tSIZE GiB\tTYPE\tMOUNT POINT")
+	for _, v := range volumes {
+		mountPoint := v.LocalMountPoint
+		if mountPoint == "" {
+			mountPoint = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", v.AWSVolumeID, v.AWSDeviceName, v.SizeGiB, v.VolumeType, mountPoint)
+	}
+	return tw.Flush()
+}
+
+// PrintVolumesYAML : Prints attached volumes as the volumes section of a config file.
+// Each volume gets the sample config's increment and threshold as a starting point, and a comment with its size,
+// type and mount point.
+// w : io.Writer Where to print the YAML.
+// volumes : []ListedVolume The volumes to print.
+// Returns: error Any error writing the YAML.
+func PrintVolumesYAML(w io.Writer, volumes []ListedVolume) error {
+	if _, err := fmt.Fprintln(w, "volumes:"); err != nil {
+		return err
+	}
+	for _, v := range volumes {
+		mounted := "not mounted"
+		if v.LocalMountPoint != "" {
+			mounted = "mounted at " + v.LocalMountPoint
+		}
+		_, err := fmt.Fprintf(w, "  # %d GiB %s, %s\n  - awsVolumeID: %q\n    awsDeviceName: %q\n    awsRegion: %q\n    incrementSizeGB: %d\n    resizeThreshold: %d\n",
+			v.SizeGiB, v.VolumeType, mounted, v.AWSVolumeID, v.AWSDeviceName, v.AWSRegion, skeletonIncrementSizeGB, skeletonResizeThreshold)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"io"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Log file rotation defaults, used when the size or backup count is not configured.
+const (
+	DefaultLogFileMaxSizeMB  = 100 // Rotate the log file once it reaches this many megabytes.
+	DefaultLogFileMaxBackups = 5   // Keep this many rotated log files.
+)
+
+// logFile is the rotating log file every logger also writes to, nil when file logging is off. Guarded by registryMu.
+var logFile *lumberjack.Logger
+
+// fileHook is a logrus hook writing entries to a log file, in addition to the logger's target.
+type fileHook struct {
+	writer    io.Writer
+	formatter logrus.Formatter
+}
+
+// newFileHook creates a fileHook.
+// writer : io.Writer The file to write to.
+// formatter : logrus.Formatter The formatter entries are written with.
+// returns : *fileHook The hook.
+func newFileHook(writer io.Writer, formatter logrus.Formatter) *fileHook {
+	return &fileHook{writer: writer, formatter: formatter}
+}
+
+// Levels returns the logrus levels the hook fires for.
+func (hook *fileHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire writes a log entry to the file.
+// entry : *logrus.Entry The entry to write.
+// returns : error Any error formatting or writing the entry.
+func (hook *fileHook) Fire(entry *logrus.Entry) error {
+	line, err := hook.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = hook.writer.Write(line)
+	return err
+}
+
+// SetLogFile makes all loggers also write to a log file, rotated by size. Any previous log file is closed.
+// path: string Path of the log file. Empty turns file logging off.
+// maxSizeMB: int Size in megabytes at which the file is rotated, 0 uses DefaultLogFileMaxSizeMB.
+// maxBackups: int Number of rotated files to keep, 0 uses DefaultLogFileMaxBackups.
+// returns: error An error if the previous log file can't be closed.
+func SetLogFile(path string, maxSizeMB, maxBackups int) error {
+	if maxSizeMB <= 0 {
+		maxSizeMB = DefaultLogFileMaxSizeMB
+	}
+	if maxBackups <= 0 {
+		maxBackups = DefaultLogFileMaxBackups
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	err := closeLogFileLocked()
+	if path != "" {
+		logFile = &lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    maxSizeMB,
+			MaxBackups: maxBackups,
+		}
+	}
+	for _, l := range loggers {
+		l.applyTarget(target)
+	}
+	return err
+}
+
+// CloseLogFile stops writing to the log file and closes it. Call it before exiting.
+// returns: error An error if the file can't be closed.
+func CloseLogFile() error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	err := closeLogFileLocked()
+	for _, l := range loggers {
+		l.applyTarget(target)
+	}
+	return err
+}
+
+// closeLogFileLocked closes the log file, if open. The caller must hold registryMu.
+// returns: error An error if the file can't be closed.
+func closeLogFileLocked() error {
+	if logFile == nil {
+		return nil
+	}
+	err := logFile.Close()
+	logFile = nil
+	return err
+}
@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Log formats selectable with SetFormat.
+const (
+	FormatText = "text" // Human-readable key=value lines (default).
+	FormatJSON = "json" // One JSON object per line, with the level, message and each field as top-level keys.
+)
+
+// format is the log format applied to new and existing loggers. Guarded by registryMu.
+var format = FormatText
+
+// SetFormat sets the format all loggers, and the log file, write entries in.
+// newFormat: string One of "text" or "json". Empty selects text.
+// returns: error An error if the format is not recognised.
+func SetFormat(newFormat string) error {
+	if err := ValidateFormat(newFormat); err != nil {
+		return err
+	}
+	if newFormat == "" {
+		newFormat = FormatText
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	format = newFormat
+	for _, l := range loggers {
+		l.applyTarget(target)
+	}
+	return nil
+}
+
+// ValidateFormat checks a log format without applying it.
+// name: string One of "text" or "json". Empty selects text.
+// returns: error An error if the format is not recognised.
+func ValidateFormat(name string) error {
+	switch name {
+	case "", FormatText, FormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid log format: %s, expected '%s' or '%s'", name, FormatText, FormatJSON)
+	}
+}
+
+// newFormatter creates the formatter for a log format.
+// format: string The log format.
+// toFile: bool Whether entries are written to a file, where text entries get no colours and a full timestamp.
+// returns: logrus.Formatter The formatter.
+func newFormatter(format string, toFile bool) logrus.Formatter {
+	if format == FormatJSON {
+		return &jsonFormatter{}
+	}
+	return &logrus.TextFormatter{DisableColors: toFile, FullTimestamp: toFile}
+}
+
+// logrusLevels maps each Level to its logrus level.
+var logrusLevels = map[Level]logrus.Level{
+	LogDebug:   logrus.DebugLevel,
+	LogInfo:    logrus.InfoLevel,
+	LogWarning: logrus.WarnLevel,
+	LogError:   logrus.ErrorLevel,
+	LogFatal:   logrus.FatalLevel,
+}
+
+// jsonFormatter formats entries as JSON, reporting the Level an entry was logged at as its "level".
+// Log records its Level in a "level" field, which would otherwise clash with the logrus level.
+type jsonFormatter struct {
+	logrus.JSONFormatter
+}
+
+// Format renders an entry as a line of JSON.
+// entry: *logrus.Entry The entry to format.
+// returns: []byte The JSON line.
+// returns: error Any error marshalling the entry.
+func (f *jsonFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	formatted := *entry
+	formatted.Level = logrusLevels[entryLevel(entry)]
+	formatted.Data = make(logrus.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		if key != "level" {
+			formatted.Data[key] = value
+		}
+	}
+	return f.JSONFormatter.Format(&formatted)
+}
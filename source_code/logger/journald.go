@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/coreos/go-systemd/v22/journal"
+	"github.com/sirupsen/logrus"
+)
+
+// levelPrefixes maps the "level" field written by Log back to the Level it was logged at.
+var levelPrefixes = map[string]Level{
+	"[DEBUG]": LogDebug,
+	"[INFO]":  LogInfo,
+	"[WARN]":  LogWarning,
+	"[ERROR]": LogError,
+	"[FATAL]": LogFatal,
+}
+
+// journaldHook is a logrus hook sending entries to journald using the native sd-journal protocol,
+// with each log field passed as a journal field.
+type journaldHook struct{}
+
+// Levels returns the logrus levels the hook fires for.
+func (hook *journaldHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire sends a log entry to journald.
+// entry : *logrus.Entry The entry to send.
+// returns : error Any error from the journal socket.
+func (hook *journaldHook) Fire(entry *logrus.Entry) error {
+	vars := make(map[string]string, len(entry.Data)+1)
+	for key, value := range entry.Data {
+		vars[journalFieldName(key)] = fmt.Sprint(value)
+	}
+	vars["SYSLOG_IDENTIFIER"] = "ebs-monitor"
+
+	return journal.Send(entry.Message, journalPriority(entryLevel(entry)), vars)
+}
+
+// entryLevel : determines the Level a logrus entry was logged at.
+// Log records its Level in the "level" field, entries without it fall back to the logrus level.
+// entry : *logrus.Entry The entry.
+// returns : Level The Level of the entry.
+func entryLevel(entry *logrus.Entry) Level {
+	if prefix, ok := entry.Data["level"].(string); ok {
+		if level, known := levelPrefixes[prefix]; known {
+			return level
+		}
+	}
+
+	switch entry.Level {
+	case logrus.DebugLevel, logrus.TraceLevel:
+		return LogDebug
+	case logrus.WarnLevel:
+		return LogWarning
+	case logrus.ErrorLevel:
+		return LogError
+	case logrus.FatalLevel, logrus.PanicLevel:
+		return LogFatal
+	default:
+		return LogInfo
+	}
+}
+
+// journalPriority : maps a Level to its journald priority.
+// level : Level The level to map.
+// returns : journal.Priority The matching journald priority.
+func journalPriority(level Level) journal.Priority {
+	switch level {
+	case LogDebug:
+		return journal.PriDebug
+	case LogWarning:
+		return journal.PriWarning
+	case LogError:
+		return journal.PriErr
+	case LogFatal:
+		return journal.PriCrit
+	default:
+		return journal.PriInfo
+	}
+}
+
+// journalFieldName : converts a log field name to a valid journal field name.
+// Journal field names may only contain upper case letters, digits and underscores, and may not start with an underscore or digit.
+// key : string The log field name, e.g. "Error Count".
+// returns : string The journal field name, e.g. "ERROR_COUNT".
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, key)
+
+	name = strings.TrimLeft(name, "_")
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "FIELD_" + name
+	}
+	return name
+}
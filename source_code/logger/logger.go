@@ -2,23 +2,32 @@ package logger
 
 import (
 	"ebs-monitor/aws"
+	"ebs-monitor/notify"
+	"ebs-monitor/runtime"
 	"fmt"
+	"io"
 	"log/syslog"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/journal"
 
 	"github.com/sirupsen/logrus"
 	logrus_syslog "github.com/sirupsen/logrus/hooks/syslog"
 )
 
-type Level int
+// Level is the severity of a log message, shared with the notification channels.
+type Level = notify.Level
 
 const (
-	LogDebug Level = iota
-	LogInfo
-	LogWarning
-	LogError
-	LogFatal
+	LogDebug   = notify.LevelDebug
+	LogInfo    = notify.LevelInfo
+	LogWarning = notify.LevelWarning
+	LogError   = notify.LevelError
+	LogFatal   = notify.LevelFatal
 )
 
 // Logger is a struct representing a custom logger.
@@ -27,30 +36,457 @@ type Logger struct {
 	debugMode bool
 }
 
-// SNS topic ARN
-var snsARN = "<AWS ARN>"
-var snsRegion = "ap-southeast-2"
+// Log targets selectable with SetTarget.
+const (
+	TargetSyslog   = "syslog"   // Send logs to the local syslog daemon (default).
+	TargetJournald = "journald" // Send logs to journald with structured fields.
+	TargetStdout   = "stdout"   // Write logs to stdout only.
+)
+
+var (
+	// registryMu guards loggers, target, format, logFile, notifications, immediateLevels, notifyLevel and logLevel.
+	registryMu sync.Mutex
+	// loggers holds every Logger created, so the log target can be changed once config is loaded.
+	loggers []*Logger
+	// target is the log target applied to new and existing loggers.
+	target = TargetSyslog
+)
+
+var (
+	// snsMu guards snsEnabled, snsARN and snsRegion.
+	snsMu sync.Mutex
+	// snsEnabled turns SNS publishing on. It is off until enabled in config.
+	snsEnabled bool
+	// snsARN is the SNS topic notifications are published to. Publishing is skipped when empty.
+	snsARN string
+	// snsRegion is the region of the SNS topic.
+	snsRegion string
+)
+
+// SetSNSEnabled turns publishing to the SNS topic on or off.
+// enabled: bool Publish notifications to SNS.
+func SetSNSEnabled(enabled bool) {
+	snsMu.Lock()
+	defer snsMu.Unlock()
+	snsEnabled = enabled
+}
+
+// ConfigureSNS sets the SNS topic used by the sns notification channel.
+// arn: string ARN of the SNS topic. SNS notifications are skipped when empty.
+// region: string Region of the SNS topic. Empty uses the region in the topic ARN.
+func ConfigureSNS(arn, region string) {
+	if region == "" {
+		region = regionFromARN(arn)
+	}
+
+	snsMu.Lock()
+	defer snsMu.Unlock()
+	snsARN = arn
+	snsRegion = region
+}
+
+// snsTopic returns the SNS topic set by ConfigureSNS.
+// returns: string ARN of the SNS topic, empty when not configured or SNS is disabled.
+// returns: string Region of the SNS topic.
+func snsTopic() (string, string) {
+	snsMu.Lock()
+	defer snsMu.Unlock()
+	if !snsEnabled {
+		return "", snsRegion
+	}
+	return snsARN, snsRegion
+}
+
+// regionFromARN returns the region field of an ARN, e.g. ap-southeast-2 in arn:aws:sns:ap-southeast-2:123456789012:alerts.
+// arn: string The ARN.
+// returns: string The region, empty if the ARN has no region field.
+func regionFromARN(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return ""
+	}
+	return parts[3]
+}
+
+// notifyQueueSize is how many notifications each channel holds before new ones are dropped.
+const notifyQueueSize = 100
+
+// notifyFlushTimeout is how long a fatal log waits for queued notifications to be delivered.
+const notifyFlushTimeout = 10 * time.Second
+
+// droppedNotifications counts notifications dropped because a channel's queue was full, across every dispatcher.
+var droppedNotifications atomic.Uint64
+
+// notifyLogger reports notification failures. It writes directly to logrus so failures aren't re-notified.
+var notifyLogger = NewLogger()
+
+// Notification channels selectable with SetNotificationChannels.
+const (
+	ChannelSNS   = "sns"   // Publish to the SNS topic, e.g. for AWS Chatbot (default).
+	ChannelSlack = "slack" // Post to a Slack incoming webhook.
+)
+
+// notifications sends log notifications asynchronously, so a slow channel doesn't block the monitoring loop.
+// It is replaced by SetNotificationChannels and guarded by registryMu.
+var notifications = notify.NewDispatcher(notifyQueueSize, reportNotifyError, snsNotifier{})
+
+// alerts suppresses repeats of the same alert within the alert cooldown.
+var alerts = notify.NewSuppressor(notify.DefaultCooldown)
+
+// SetAlertCooldown sets how long a repeated alert is suppressed after it is sent.
+// cooldown: time.Duration The cooldown. Zero uses notify.DefaultCooldown.
+func SetAlertCooldown(cooldown time.Duration) {
+	alerts.SetCooldown(cooldown)
+}
+
+// alertSubject returns the volume an alert is about, from its log fields.
+// fields: map[string]interface{} The log fields.
+// returns: string The volume ID, empty if the alert isn't about a volume.
+func alertSubject(fields map[string]interface{}) string {
+	for _, key := range []string{"VolumeID", "AWSVolumeID"} {
+		if volumeID, ok := fields[key]; ok {
+			return fmt.Sprint(volumeID)
+		}
+	}
+	return ""
+}
+
+// batcher coalesces notifications raised close together into a digest. Batching is off until configured.
+var batcher = notify.NewBatcher(dispatch, 0, 0)
+
+// dispatch queues a notification on the current notification channels.
+// n: notify.Notification The notification to send.
+func dispatch(n notify.Notification) {
+	currentNotifications().Dispatch(n)
+}
+
+// currentNotifications returns the dispatcher for the configured notification channels.
+// returns: *notify.Dispatcher The current dispatcher.
+func currentNotifications() *notify.Dispatcher {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return notifications
+}
+
+// SetNotificationChannels chooses where notifications are sent. Notifications already queued on the
+// previous channels are still delivered.
+// channels: []string Any of "sns" and "slack". Empty selects sns.
+// slackWebhookURL: string The Slack incoming webhook URL, required for the slack channel.
+// returns: error An error if a channel is not recognised or is missing its settings.
+func SetNotificationChannels(channels []string, slackWebhookURL string) error {
+	notifiers, err := buildNotifiers(channels, slackWebhookURL)
+	if err != nil {
+		return err
+	}
+
+	registryMu.Lock()
+	previous := notifications
+	notifications = notify.NewDispatcher(notifyQueueSize, reportNotifyError, notifiers...)
+	registryMu.Unlock()
+
+	// Drain the previous channels in the background, so a slow channel doesn't delay startup
+	go previous.Close(notifyFlushTimeout)
+	return nil
+}
+
+// ValidateNotificationChannels checks notification channel settings without applying them.
+// channels: []string Any of "sns" and "slack". Empty selects sns.
+// slackWebhookURL: string The Slack incoming webhook URL, required for the slack channel.
+// returns: error An error if a channel is not recognised or is missing its settings.
+func ValidateNotificationChannels(channels []string, slackWebhookURL string) error {
+	_, err := buildNotifiers(channels, slackWebhookURL)
+	return err
+}
+
+// buildNotifiers creates a notifier for each notification channel.
+// channels: []string Any of "sns" and "slack". Empty selects sns.
+// slackWebhookURL: string The Slack incoming webhook URL, required for the slack channel.
+// returns: []notify.Notifier The notifiers, in the order given.
+// returns: error An error if a channel is not recognised or is missing its settings.
+func buildNotifiers(channels []string, slackWebhookURL string) ([]notify.Notifier, error) {
+	if len(channels) == 0 {
+		channels = []string{ChannelSNS}
+	}
+
+	notifiers := make([]notify.Notifier, 0, len(channels))
+	seen := make(map[string]bool, len(channels))
+	for _, channel := range channels {
+		channel = strings.ToLower(channel)
+		if seen[channel] {
+			continue
+		}
+		seen[channel] = true
+
+		switch channel {
+		case ChannelSNS:
+			notifiers = append(notifiers, snsNotifier{})
+		case ChannelSlack:
+			if slackWebhookURL == "" {
+				return nil, fmt.Errorf("the %s notification channel requires slackWebhookURL", ChannelSlack)
+			}
+			notifiers = append(notifiers, notify.NewSlackNotifier(slackWebhookURL))
+		default:
+			return nil, fmt.Errorf("invalid notification channel: %s, expected '%s' or '%s'", channel, ChannelSNS, ChannelSlack)
+		}
+	}
+	return notifiers, nil
+}
+
+// immediateLevels holds the levels that bypass the batch window, guarded by registryMu.
+var immediateLevels = map[Level]bool{LogError: true, LogFatal: true}
+
+// notifyLevel is the lowest level sent as a notification, guarded by registryMu.
+var notifyLevel = LogError
+
+// logLevel is the lowest level written by loggers, guarded by registryMu.
+var logLevel = LogInfo
+
+// SetNotificationLevel sets the lowest level sent to the notification channels.
+// Lower levels are still logged.
+// level: Level The lowest level to notify, LogDebug is never notified.
+func SetNotificationLevel(level Level) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	notifyLevel = level
+}
+
+// shouldNotify reports whether messages at the level are sent to the notification channels.
+// level: Level The log level.
+// returns: bool True if the level is notified.
+func shouldNotify(level Level) bool {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return level != LogDebug && level >= notifyLevel
+}
+
+// levelNames maps level names used in config to levels.
+var levelNames = map[string]Level{
+	"debug":   LogDebug,
+	"info":    LogInfo,
+	"warn":    LogWarning,
+	"warning": LogWarning,
+	"error":   LogError,
+	"fatal":   LogFatal,
+}
+
+// ParseLevel converts a level name from config to a Level.
+// name: string One of "debug", "info", "warning" (or "warn"), "error" or "fatal", case-insensitive.
+// returns: Level The level.
+// returns: error An error if the name is not recognised.
+func ParseLevel(name string) (Level, error) {
+	level, ok := levelNames[strings.ToLower(name)]
+	if !ok {
+		return LogInfo, fmt.Errorf("invalid log level: %s, expected 'debug', 'info', 'warning', 'error' or 'fatal'", name)
+	}
+	return level, nil
+}
+
+// SetNotificationBatch configures coalescing of notifications into digests.
+// window: time.Duration How long to collect notifications before sending a digest. Zero disables batching.
+// maxCount: int Send the digest early once it holds this many notifications. Zero means no limit.
+// immediate: []Level Levels sent straight away rather than batched.
+func SetNotificationBatch(window time.Duration, maxCount int, immediate []Level) {
+	registryMu.Lock()
+	immediateLevels = make(map[Level]bool, len(immediate))
+	for _, level := range immediate {
+		immediateLevels[level] = true
+	}
+	registryMu.Unlock()
+
+	batcher.Configure(window, maxCount)
+}
+
+// isImmediate reports whether notifications at the level bypass the batch window.
+// level: Level The log level.
+// returns: bool True if the level is sent immediately.
+func isImmediate(level Level) bool {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return immediateLevels[level] || level == LogFatal
+}
+
+// snsNotifier publishes notifications to the SNS topic set by ConfigureSNS.
+type snsNotifier struct{}
+
+// Name returns the channel name used when reporting errors.
+func (n snsNotifier) Name() string {
+	return ChannelSNS
+}
+
+// Notify publishes the message and its fields to the SNS topic as text. Nothing is published when SNS is
+// disabled or no topic is configured.
+// level: Level The level of the notification, not shown as SNS alerts are all titled the same.
+// message: string The message to publish.
+// fields: map[string]interface{} The fields, listed under the message.
+// returns: error An error if the publish fails.
+func (n snsNotifier) Notify(level Level, message string, fields map[string]interface{}) error {
+	arn, region := snsTopic()
+	if arn == "" {
+		return nil
+	}
+	return aws.PublishToSNS(arn, region, notify.Format(message, fields))
+}
+
+// reportNotifyError logs a failed or dropped notification.
+// channel: string Name of the notification channel.
+// err: error The send error, or notify.ErrQueueFull when the notification was dropped.
+func reportNotifyError(channel string, err error) {
+	entry := notifyLogger.logger.WithFields(logrus.Fields{"channel": channel, "NotifyError": err})
+	if err == notify.ErrQueueFull {
+		dropped := droppedNotifications.Add(1)
+		entry.WithFields(logrus.Fields{"level": "[WARN]", "Dropped Total": dropped}).Warn("Notification queue full, dropping notification")
+		return
+	}
+	entry.WithField("level", "[ERROR]").Error("Failed to publish notification")
+}
+
+// FlushNotifications stops accepting notifications and waits for queued ones to be delivered.
+// Call it before exiting so final alerts are not lost.
+// timeout: time.Duration Maximum time to wait.
+func FlushNotifications(timeout time.Duration) {
+	batcher.Flush()
+	delivered := currentNotifications().Close(timeout)
+	if dropped := DroppedNotifications(); !delivered || dropped > 0 {
+		notifyLogger.logger.WithFields(logrus.Fields{
+			"level":         "[WARN]",
+			"All Delivered": delivered,
+			"Dropped Total": dropped,
+		}).Warn("Not every notification was delivered before exiting")
+	}
+}
+
+// DroppedNotifications returns how many notifications were dropped because a channel's queue was full.
+// returns: uint64 The number of dropped notifications since the process started.
+func DroppedNotifications() uint64 {
+	return droppedNotifications.Load()
+}
 
 // NewLogger creates a new Logger object with logrus as the underlying logger.
 // Returns a new Logger object.
 func NewLogger() *Logger {
 	logger := logrus.New()
 
-	// Set up syslog hook
-	hook, err := logrus_syslog.NewSyslogHook("", "", syslog.LOG_INFO, "")
+	l := &Logger{
+		logger:    logger,
+		debugMode: false,
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	logger.SetLevel(logrusLevels[logLevel])
+	l.applyTarget(target)
+	loggers = append(loggers, l)
+
+	return l
+}
+
+// SetTarget sets where all loggers send their output.
+// journald falls back to syslog, and syslog to stdout, when the target isn't available on the host.
+// newTarget: string One of "syslog", "journald" or "stdout". Empty selects syslog.
+// returns: error An error if the target is not recognised.
+func SetTarget(newTarget string) error {
+	if err := ValidateTarget(newTarget); err != nil {
+		return err
+	}
+	if newTarget == "" {
+		newTarget = TargetSyslog
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	target = newTarget
+	for _, l := range loggers {
+		l.applyTarget(target)
+	}
+	return nil
+}
+
+// ValidateTarget checks a log target without applying it.
+// name: string One of "syslog", "journald" or "stdout". Empty selects syslog.
+// returns: error An error if the target is not recognised.
+func ValidateTarget(name string) error {
+	switch name {
+	case "", TargetSyslog, TargetJournald, TargetStdout:
+		return nil
+	default:
+		return fmt.Errorf("invalid log target: %s, expected '%s', '%s' or '%s'", name, TargetSyslog, TargetJournald, TargetStdout)
+	}
+}
+
+// applyTarget applies the log format and replaces the logger's hooks with the hook for the given target,
+// plus the log file hook if set. The replaced hooks' connections, e.g. to syslog, are closed.
+// target: string The log target to apply.
+func (l *Logger) applyTarget(target string) {
+	l.logger.SetFormatter(newFormatter(format, false))
+	closeHooks(l.logger.ReplaceHooks(make(logrus.LevelHooks)))
+	if logFile != nil {
+		l.logger.AddHook(newFileHook(logFile, newFormatter(format, true)))
+	}
+	// Entries also go to stderr, as from logrus.New, unless stdout is the target or the last fallback,
+	// so switching away from stdout restores it
+	output := os.Stderr
+	if l.debugMode {
+		output = os.Stdout
+	}
+	defer func() { l.logger.SetOutput(output) }()
+
+	if target == TargetJournald {
+		if journal.Enabled() {
+			l.logger.AddHook(&journaldHook{})
+			return
+		}
+		l.logger.WithFields(logrus.Fields{"prefix": "[WARN]"}).Warn("journald is not available, falling back to syslog")
+		target = TargetSyslog
+	}
 
+	if target == TargetSyslog {
+		// Set up syslog hook
+		hook, err := newSyslogHook()
+
+		if err != nil {
+			l.logger.WithFields(logrus.Fields{"prefix": "[ERROR]"}).Error("Unable to connect to local syslog daemon")
+		} else {
+			l.logger.AddHook(hook)
+			return
+		}
+	}
+
+	// stdout only, either requested or as the last fallback
+	output = os.Stdout
+}
+
+// newSyslogHook connects a hook to the local syslog daemon, replaced in tests.
+var newSyslogHook = func() (logrus.Hook, error) {
+	hook, err := logrus_syslog.NewSyslogHook("", "", syslog.LOG_INFO, "")
 	if err != nil {
-		logger.WithFields(logrus.Fields{"prefix": "[ERROR]"}).Error("Unable to connect to local syslog daemon")
-	} else {
-		logger.AddHook(hook)
+		return nil, err
 	}
+	return syslogHook{hook}, nil
+}
 
-	// Set default log level to Warning
-	logger.SetLevel(logrus.InfoLevel)
+// syslogHook is a logrus syslog hook that can be closed when the logger's hooks are replaced.
+type syslogHook struct {
+	*logrus_syslog.SyslogHook
+}
 
-	return &Logger{
-		logger:    logger,
-		debugMode: false,
+// Close closes the connection to the syslog daemon.
+// returns: error Any error closing the connection.
+func (hook syslogHook) Close() error {
+	return hook.Writer.Close()
+}
+
+// closeHooks closes the hooks holding a connection, e.g. to syslog, once they have been replaced.
+// A hook is listed under each level it fires for, so it's only closed once.
+// hooks: logrus.LevelHooks The replaced hooks.
+func closeHooks(hooks logrus.LevelHooks) {
+	closed := make(map[io.Closer]bool)
+	for _, levelHooks := range hooks {
+		for _, hook := range levelHooks {
+			if closer, ok := hook.(io.Closer); ok && !closed[closer] {
+				closed[closer] = true
+				closer.Close()
+			}
+		}
 	}
 }
 
@@ -59,36 +495,61 @@ func NewLogger() *Logger {
 // message: string The log message.
 // fields: map[string]interface{} The fields to be added to the log.
 func (l *Logger) Log(level Level, message string, fields map[string]interface{}) {
-	entry := l.logger.WithFields(fields)
+	// Repeats of the same alert for the same volume are suppressed until the cooldown passes, fatal alerts are always sent
+	notifyAlert, suppressed := false, 0
+	if shouldNotify(level) {
+		notifyAlert, suppressed = alerts.Allow(alertSubject(fields), message, runtime.Now())
+		notifyAlert = notifyAlert || level == LogFatal
+	}
 
-	if level != LogDebug {
-		// Convert the fields to a string, formatted for readability
-		fieldStrs := make([]string, 0, len(fields))
-		for key, value := range fields {
-			fieldStrs = append(fieldStrs, fmt.Sprintf("%s: %v", key, value))
+	if notifyAlert {
+		notifyMessage := message
+		if suppressed > 0 {
+			notifyMessage += fmt.Sprintf("\n(Repeated %d more times since last notified)", suppressed)
 		}
-		fieldsStr := strings.Join(fieldStrs, ",\n\t")
 
-		// Combine the message and fields into a single string with a formatted context section
-		combinedMessage := fmt.Sprintf("%s\nAdditional Information:\n    %s", message, fieldsStr)
-
-		// Sending the combined log message to the SNS queue
-		err := aws.PublishToSNS(snsARN, snsRegion, combinedMessage)
-		if err != nil {
-			entry.WithField("SNSPublishError", err).Error("Failed to publish error message to SNS")
+		// Queue the message and a copy of its fields, which the caller may reuse, for delivery to the notification channels
+		notifyFields := make(map[string]interface{}, len(fields))
+		for key, value := range fields {
+			notifyFields[key] = value
 		}
+		batcher.Add(notify.Notification{Level: level, Message: notifyMessage, Fields: notifyFields}, isImmediate(level))
 	}
 
+	l.write(level, message, fields)
+}
+
+// Alert logs a message like Log, but notifies with a dedicated alert instead of the message and its fields.
+// The alert is always sent straight away, bypassing the notification level, the alert cooldown and batching,
+// so the alerts on-call most needs stand out from routine messages.
+// level: Level The log level of the message.
+// message: string The log message.
+// fields: map[string]interface{} The fields to be added to the log.
+// alert: string The notification to send, e.g. from notify.VolumeDropped.
+func (l *Logger) Alert(level Level, message string, fields map[string]interface{}, alert string) {
+	batcher.Add(notify.Notification{Level: level, Message: alert}, true)
+	l.write(level, message, fields)
+}
+
+// write writes a message and its fields to the log at the given level, without notifying.
+// level: Level The log level of the message.
+// message: string The log message.
+// fields: map[string]interface{} The fields to be added to the log.
+func (l *Logger) write(level Level, message string, fields map[string]interface{}) {
+	entry := l.logger.WithFields(fields)
+
 	switch level {
 	case LogDebug:
-		fmt.Printf("DEBUG: %s\n", message)
+		entry.WithField("level", "[DEBUG]").Debug(message)
 	case LogInfo:
-		entry.WithField("level", "[INFO]").Warn(message)
+		entry.WithField("level", "[INFO]").Info(message)
 	case LogWarning:
 		entry.WithField("level", "[WARN]").Warn(message)
 	case LogError:
 		entry.WithField("level", "[ERROR]").Error(message)
 	case LogFatal:
+		// Fatal exits the process, so deliver queued notifications first
+		FlushNotifications(notifyFlushTimeout)
 		entry.WithField("level", "[FATAL]").Fatal(message)
 	default:
 		entry.Info(message)
@@ -99,15 +560,21 @@ func (l *Logger) Log(level Level, message string, fields map[string]interface{})
 	}
 }
 
-// SetDebugMode sets the debug mode of the logger.
+// SetDebugMode sets the debug mode of the logger, which echoes every message to stdout.
+// The log level is set separately with SetLevel.
 // debugMode: bool The debug mode to set.
 func (l *Logger) SetDebugMode(debugMode bool) {
 	l.debugMode = debugMode
-	if debugMode {
-		l.logger.SetLevel(logrus.DebugLevel)
-		l.logger.SetOutput(os.Stdout)
-	} else {
-		l.logger.SetLevel(logrus.InfoLevel)
-		l.logger.SetOutput(os.Stdout)
+	l.logger.SetOutput(os.Stdout)
+}
+
+// SetLevel sets the lowest level all loggers write. Lower levels are dropped, but may still be echoed in debug mode.
+// level: Level The lowest level to log.
+func SetLevel(level Level) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	logLevel = level
+	for _, l := range loggers {
+		l.logger.SetLevel(logrusLevels[logLevel])
 	}
 }
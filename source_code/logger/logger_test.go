@@ -1 +1,422 @@
 package logger
+
+import (
+	"ebs-monitor/notify"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coreos/go-systemd/v22/journal"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// TestJournalFieldName tests the journalFieldName function.
+func TestJournalFieldName(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "VolumeID", want: "VOLUMEID"},
+		{key: "Error Count", want: "ERROR_COUNT"},
+		{key: "config file path", want: "CONFIG_FILE_PATH"},
+		{key: "_private", want: "PRIVATE"},
+		{key: "1st", want: "FIELD_1ST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := journalFieldName(tt.key); got != tt.want {
+				t.Errorf("journalFieldName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEntryLevelPriority tests that entries map to the journald priority of the Level they were logged at.
+func TestEntryLevelPriority(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry *logrus.Entry
+		want  journal.Priority
+	}{
+		{
+			name:  "info logged at logrus warn level",
+			entry: &logrus.Entry{Level: logrus.WarnLevel, Data: logrus.Fields{"level": "[INFO]"}},
+			want:  journal.PriInfo,
+		},
+		{
+			name:  "error",
+			entry: &logrus.Entry{Level: logrus.ErrorLevel, Data: logrus.Fields{"level": "[ERROR]"}},
+			want:  journal.PriErr,
+		},
+		{
+			name:  "no level field",
+			entry: &logrus.Entry{Level: logrus.WarnLevel, Data: logrus.Fields{}},
+			want:  journal.PriWarning,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := journalPriority(entryLevel(tt.entry)); got != tt.want {
+				t.Errorf("journalPriority(entryLevel()) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSetTarget tests that SetTarget rejects unknown targets.
+func TestSetTarget(t *testing.T) {
+	if err := SetTarget("carrier-pigeon"); err == nil {
+		t.Errorf("SetTarget() error = nil for unknown target, want error")
+	}
+	if err := SetTarget(TargetStdout); err != nil {
+		t.Errorf("SetTarget() error = %v, want nil", err)
+	}
+}
+
+// closingHook is a fake syslog hook counting how often it's closed.
+type closingHook struct {
+	closes int
+}
+
+// Levels returns the logrus levels the hook fires for.
+func (hook *closingHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire discards the entry.
+func (hook *closingHook) Fire(*logrus.Entry) error {
+	return nil
+}
+
+// Close counts the close.
+func (hook *closingHook) Close() error {
+	hook.closes++
+	return nil
+}
+
+// TestSetTargetReplacesHooks tests that changing the target closes the replaced syslog hook, rather than
+// adding another, and that output to stdout is undone when switching away from it.
+func TestSetTargetReplacesHooks(t *testing.T) {
+	var hooks []*closingHook
+	connectSyslog := newSyslogHook
+	newSyslogHook = func() (logrus.Hook, error) {
+		hook := &closingHook{}
+		hooks = append(hooks, hook)
+		return hook, nil
+	}
+	t.Cleanup(func() {
+		newSyslogHook = connectSyslog
+		SetTarget(TargetStdout)
+	})
+	l := NewLogger()
+
+	if err := SetTarget(TargetSyslog); err != nil {
+		t.Fatalf("SetTarget() error = %v", err)
+	}
+	if err := SetTarget(TargetSyslog); err != nil {
+		t.Fatalf("SetTarget() error = %v", err)
+	}
+	if got := len(l.logger.Hooks[logrus.InfoLevel]); got != 1 {
+		t.Errorf("logger has %d info hooks after setting syslog twice, want 1", got)
+	}
+	if l.logger.Out != os.Stderr {
+		t.Errorf("logger output = %v for syslog, want stderr", l.logger.Out)
+	}
+
+	if err := SetTarget(TargetStdout); err != nil {
+		t.Fatalf("SetTarget() error = %v", err)
+	}
+	if l.logger.Out != os.Stdout {
+		t.Errorf("logger output = %v for stdout, want stdout", l.logger.Out)
+	}
+	for i, hook := range hooks {
+		if hook.closes != 1 {
+			t.Errorf("syslog hook %d closed %d times, want 1", i, hook.closes)
+		}
+	}
+
+	if err := SetTarget(TargetSyslog); err != nil {
+		t.Fatalf("SetTarget() error = %v", err)
+	}
+	if l.logger.Out != os.Stderr {
+		t.Errorf("logger output = %v after switching from stdout to syslog, want stderr", l.logger.Out)
+	}
+}
+
+// TestParseLevel tests that level names are parsed case-insensitively and unknown names rejected.
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Level
+		wantErr bool
+	}{
+		{"debug", LogDebug, false},
+		{"Warning", LogWarning, false},
+		{"warn", LogWarning, false},
+		{"ERROR", LogError, false},
+		{"urgent", LogInfo, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildNotifiers tests that notification channels are selected by name.
+func TestBuildNotifiers(t *testing.T) {
+	tests := []struct {
+		name       string
+		channels   []string
+		webhookURL string
+		want       []string
+		wantErr    bool
+	}{
+		{"Default", nil, "", []string{ChannelSNS}, false},
+		{"SNS", []string{"sns"}, "", []string{ChannelSNS}, false},
+		{"Both", []string{"slack", "SNS"}, "https://hooks.slack.com/services/T/B/X", []string{ChannelSlack, ChannelSNS}, false},
+		{"Duplicate", []string{"sns", "sns"}, "", []string{ChannelSNS}, false},
+		{"Slack without webhook", []string{"slack"}, "", nil, true},
+		{"Unknown", []string{"email"}, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifiers, err := buildNotifiers(tt.channels, tt.webhookURL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildNotifiers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var got []string
+			for _, n := range notifiers {
+				got = append(got, n.Name())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildNotifiers() channels = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestConfigureSNS tests that the SNS region defaults to the topic ARN's region.
+func TestConfigureSNS(t *testing.T) {
+	defer ConfigureSNS("", "")
+	defer SetSNSEnabled(false)
+	SetSNSEnabled(true)
+
+	tests := []struct {
+		name       string
+		arn        string
+		region     string
+		wantRegion string
+	}{
+		{"Region from ARN", "arn:aws:sns:us-west-2:123456789012:ebs-alerts", "", "us-west-2"},
+		{"Explicit region", "arn:aws:sns:us-west-2:123456789012:ebs-alerts", "eu-west-1", "eu-west-1"},
+		{"Disabled", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ConfigureSNS(tt.arn, tt.region)
+			arn, region := snsTopic()
+			if arn != tt.arn || region != tt.wantRegion {
+				t.Errorf("snsTopic() = %q, %q, want %q, %q", arn, region, tt.arn, tt.wantRegion)
+			}
+		})
+	}
+}
+
+// TestSNSNotifierSkipsWithoutTopic tests that nothing is published when no SNS topic is configured.
+func TestSNSNotifierSkipsWithoutTopic(t *testing.T) {
+	defer SetSNSEnabled(false)
+	SetSNSEnabled(true)
+	ConfigureSNS("", "")
+	if err := (snsNotifier{}).Notify(LogInfo, "Volume resized", nil); err != nil {
+		t.Errorf("Notify() error = %v, want nil without a topic", err)
+	}
+}
+
+// TestSNSDisabled tests that a configured topic is not published to until SNS is enabled.
+func TestSNSDisabled(t *testing.T) {
+	defer ConfigureSNS("", "")
+	ConfigureSNS("arn:aws:sns:us-west-2:123456789012:ebs-alerts", "")
+
+	SetSNSEnabled(false)
+	if arn, _ := snsTopic(); arn != "" {
+		t.Errorf("snsTopic() ARN = %q while disabled, want empty", arn)
+	}
+	if err := (snsNotifier{}).Notify(LogInfo, "Volume resized", nil); err != nil {
+		t.Errorf("Notify() error = %v while disabled, want nil", err)
+	}
+}
+
+// TestDroppedNotificationsCounted tests that notifications dropped from a full queue are counted, and send failures aren't.
+func TestDroppedNotificationsCounted(t *testing.T) {
+	before := DroppedNotifications()
+	reportNotifyError(ChannelSNS, notify.ErrQueueFull)
+	reportNotifyError(ChannelSlack, notify.ErrQueueFull)
+	reportNotifyError(ChannelSNS, errors.New("endpoint unavailable"))
+	if got := DroppedNotifications() - before; got != 2 {
+		t.Errorf("DroppedNotifications() increased by %d, want 2", got)
+	}
+}
+
+// TestShouldNotify tests that only levels at or above the notification level are notified.
+func TestShouldNotify(t *testing.T) {
+	defer SetNotificationLevel(LogError)
+
+	tests := []struct {
+		name        string
+		notifyLevel Level
+		level       Level
+		want        bool
+	}{
+		{"Default skips info", LogError, LogInfo, false},
+		{"Default skips warning", LogError, LogWarning, false},
+		{"Default sends error", LogError, LogError, true},
+		{"Default sends fatal", LogError, LogFatal, true},
+		{"Info sends info", LogInfo, LogInfo, true},
+		{"Debug is never sent", LogDebug, LogDebug, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetNotificationLevel(tt.notifyLevel)
+			if got := shouldNotify(tt.level); got != tt.want {
+				t.Errorf("shouldNotify(%v) = %v, want %v", tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSetLogFile tests that loggers also write to the log file until it is closed.
+func TestSetLogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ebs-monitor.log")
+	l := NewLogger()
+
+	if err := SetLogFile(path, 0, 0); err != nil {
+		t.Fatalf("SetLogFile() error = %v", err)
+	}
+	l.Log(LogWarning, "written to file", map[string]interface{}{"VolumeID": "vol-1"})
+	if err := CloseLogFile(); err != nil {
+		t.Fatalf("CloseLogFile() error = %v", err)
+	}
+	l.Log(LogWarning, "not written to file", nil)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if !strings.Contains(string(data), "written to file") || !strings.Contains(string(data), "VolumeID=vol-1") {
+		t.Errorf("log file = %q, want the logged message and fields", data)
+	}
+	if strings.Contains(string(data), "not written to file") {
+		t.Errorf("log file = %q, want no messages after CloseLogFile()", data)
+	}
+}
+
+// TestJSONFormatter tests that JSON entries carry the Level they were logged at and fields as top-level keys.
+func TestJSONFormatter(t *testing.T) {
+	tests := []struct {
+		name      string
+		entry     *logrus.Entry
+		wantLevel string
+	}{
+		{
+			name:      "info logged by Log",
+			entry:     &logrus.Entry{Level: logrus.WarnLevel, Message: "Volume resized", Data: logrus.Fields{"level": "[INFO]", "VolumeID": "vol-1"}},
+			wantLevel: "info",
+		},
+		{
+			name:      "entry without a level field",
+			entry:     &logrus.Entry{Level: logrus.ErrorLevel, Message: "Volume resized", Data: logrus.Fields{"VolumeID": "vol-1"}},
+			wantLevel: "error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := newFormatter(FormatJSON, false).Format(tt.entry)
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(line, &got); err != nil {
+				t.Fatalf("Format() wrote invalid JSON %q: %v", line, err)
+			}
+			if got["level"] != tt.wantLevel || got["msg"] != "Volume resized" || got["VolumeID"] != "vol-1" {
+				t.Errorf("Format() = %s, want level %q, the message and VolumeID", line, tt.wantLevel)
+			}
+			if _, clashed := got["fields.level"]; clashed {
+				t.Errorf("Format() = %s, want no fields.level key", line)
+			}
+		})
+	}
+}
+
+// TestSetFormat tests that only known log formats are accepted.
+func TestSetFormat(t *testing.T) {
+	defer SetFormat(FormatText)
+
+	for _, format := range []string{"", FormatText, FormatJSON} {
+		if err := SetFormat(format); err != nil {
+			t.Errorf("SetFormat(%q) error = %v", format, err)
+		}
+	}
+	if err := SetFormat("xml"); err == nil {
+		t.Errorf("SetFormat(%q) error = nil, want an error", "xml")
+	}
+}
+
+// TestSetLevel tests that messages are written at their own logrus level and dropped below the log level.
+func TestSetLevel(t *testing.T) {
+	l := NewLogger()
+	hook := test.NewLocal(l.logger)
+	defer SetLevel(LogInfo)
+
+	tests := []struct {
+		name      string
+		logLevel  Level
+		level     Level
+		wantLevel logrus.Level
+		written   bool
+	}{
+		{name: "info at info", logLevel: LogInfo, level: LogInfo, wantLevel: logrus.InfoLevel, written: true},
+		{name: "warning at info", logLevel: LogInfo, level: LogWarning, wantLevel: logrus.WarnLevel, written: true},
+		{name: "debug at info", logLevel: LogInfo, level: LogDebug, written: false},
+		{name: "debug at debug", logLevel: LogDebug, level: LogDebug, wantLevel: logrus.DebugLevel, written: true},
+		{name: "info at warning", logLevel: LogWarning, level: LogInfo, written: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetLevel(tt.logLevel)
+			hook.Reset()
+
+			l.Log(tt.level, "message", nil)
+
+			entry := hook.LastEntry()
+			if (entry != nil) != tt.written {
+				t.Fatalf("Log() wrote an entry = %v, want %v", entry != nil, tt.written)
+			}
+			if entry != nil && entry.Level != tt.wantLevel {
+				t.Errorf("Log() level = %v, want %v", entry.Level, tt.wantLevel)
+			}
+		})
+	}
+}
@@ -3,15 +3,24 @@ package main
 import (
 	"ebs-monitor/aws"
 	"ebs-monitor/configutil"
+	"ebs-monitor/filesystem"
 	"ebs-monitor/logger"
+	"ebs-monitor/metrics"
 	"ebs-monitor/monitor"
+	"ebs-monitor/notify"
 	"ebs-monitor/resize"
 	"ebs-monitor/runtime"
+	"errors"
 	"fmt"
+	"math/rand"
 	"os"
+	"os/signal"
 	"reflect"
 	rt "runtime"
+	"runtime/debug"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -20,8 +29,41 @@ import (
 // Initialise logger
 var l = logger.NewLogger()
 
-// How many consecutive errors before a volume is removed from monitoring
-const errorThreshold = 5
+// getVolumeState gathers a volume's state for CheckVolume, replaced in tests.
+var getVolumeState = monitor.GetVolumeState
+
+// checkVolumeAttached confirms a volume is attached in AWS for IsVolumeNotYetVisible, replaced in tests.
+var checkVolumeAttached = aws.CheckVolumeAttached
+
+// How long IsVolumeNotYetVisible reuses a volume's attachment check, so a device slow to appear isn't described every cycle
+const attachmentCheckInterval = 5 * time.Minute
+
+// attachmentCheck is the result of a volume's last attachment check.
+type attachmentCheck struct {
+	checkedAt time.Time
+	err       error
+}
+
+// attachmentChecks holds each volume's last attachment check, as volumes are checked concurrently.
+var attachmentChecks = struct {
+	sync.Mutex
+	byVolume map[string]attachmentCheck
+}{byVolume: map[string]attachmentCheck{}}
+
+// How many consecutive errors before a volume is removed from monitoring when errorThreshold is not configured
+const defaultErrorThreshold = 5
+
+// How many volumes are checked at once when maxConcurrentChecks is not configured
+const defaultMaxConcurrentChecks = 4
+
+// How often dropped volumes are retried when requarantineRetrySeconds is not configured
+const defaultRequarantineRetrySeconds = 300
+
+// How many EC2 calls in a row can fail before /healthz reports the service unhealthy
+const healthAWSFailureLimit = 5
+
+// How many of a dropped volume's most recent errors are listed in the volume dropped alert
+const droppedAlertErrors = 5
 
 // Version of the application
 var version string
@@ -40,17 +82,78 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// notifyFlushTimeout : How long to wait for queued notifications to be delivered before exiting
+const notifyFlushTimeout = 10 * time.Second
+
+// Backoff between attempts to load a config file that doesn't exist yet
+const (
+	initialConfigRetryDelay = time.Second
+	maxConfigRetryDelay     = 30 * time.Second
+)
+
 var (
-	// configFile : string The path to the configuration file
+	// configFile : string The path to the configuration file, or an ssm:// or env:// config source
 	configFile string
+	// configDir : string A directory of *.yaml config fragments, merged into one config, used instead of configFile
+	configDir string
+	// configWait : time.Duration How long to keep retrying at startup while the config file (or parameter or variable) doesn't exist
+	configWait time.Duration
 	// debugMode : bool A flag indicating whether the application should run in debug mode and extra output sent to stdout.
 	debugMode bool
+	// dryRun : bool A flag indicating resizes should be simulated without calling AWS or resizing filesystems.
+	dryRun bool
+	// metricsAddr : string The address to serve Prometheus metrics on, metrics are disabled when empty
+	metricsAddr string
+	// eventLogFile : string The file the event log is saved to and restored from, history is not kept when empty
+	eventLogFile string
+	// logLevel : string The lowest level written to the logs, overriding logLevel in the config when set
+	logLevel string
+	// logFormat : string The format log entries are written in, overriding logFormat in the config when set
+	logFormat string
+	// logFilePath : string The file logs are also written to, overriding logFilePath in the config when set
+	logFilePath string
+	// dumpJSON : bool A flag indicating the runtime state is dumped to stdout as JSON on SIGUSR1, and in debug mode each cycle
+	dumpJSON bool
+	// healthAddr : string The address to serve /healthz on, when it isn't served with the metrics
+	healthAddr string
+	// stateSocketPath : string The Unix socket the last-known volume states are served on, disabled when empty
+	stateSocketPath string
+	// offlineRegionValidation : bool A flag indicating regions are validated without calling DescribeRegions
+	offlineRegionValidation bool
+	// runOnce : bool A flag indicating a single pass over the volumes is made before exiting, instead of monitoring continuously
+	runOnce bool
+	// skipPreflight : bool A flag indicating the AWS credentials and permissions aren't checked at startup
+	skipPreflight bool
+	// metricsRegistry : *metrics.Registry The exported metrics, nil when metrics are disabled
+	metricsRegistry *metrics.Registry
+	// healthServer : *metrics.HealthServer The standalone health check server, nil unless healthAddr is set
+	healthServer *metrics.HealthServer
+	// stateSocket : *metrics.StateSocket The socket serving the last-known volume states, nil unless stateSocketPath is set
+	stateSocket *metrics.StateSocket
+	// localConfig : *runtime.Config The config last loaded from the config file, which the remote config is merged over
+	localConfig *runtime.Config
+	// remoteSource : *configutil.RemoteSource The remote config source, nil unless remoteConfig is set
+	remoteSource *configutil.RemoteSource
 )
 
 // init : Initializes the root command
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "Config file path")
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "Config file path, ssm://<parameter name>[?region=<region>] to read it from SSM Parameter Store, or env://<variable> to read it from an environment variable")
+	rootCmd.PersistentFlags().StringVar(&configDir, "config-dir", "", "Directory of *.yaml config files to merge into one config, instead of --config")
 	rootCmd.PersistentFlags().BoolVarP(&debugMode, "debug", "d", false, "Run in debug mode")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Simulate resizes without calling AWS or resizing filesystems")
+	rootCmd.PersistentFlags().StringVar(&eventLogFile, "event-log-file", "", "Save the event log to this file and restore it on restart")
+	rootCmd.PersistentFlags().StringVar(&logFilePath, "log-file", "", "Also write logs to this file, rotated by size (overrides logFilePath in the config)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "Lowest level written to the logs: debug, info, warn or error (overrides logLevel in the config)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "", "Log entry format, \"text\" or \"json\" (overrides logFormat in the config)")
+	rootCmd.PersistentFlags().StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics at /metrics and a health check at /healthz on this address, e.g. :9100")
+	rootCmd.PersistentFlags().StringVar(&healthAddr, "health-addr", "", "Serve a health check at /healthz on this address, e.g. :9101, when it isn't the metrics address")
+	rootCmd.PersistentFlags().StringVar(&stateSocketPath, "state-socket", "", "Serve each volume's last-known state and the event log as JSON to clients connecting to this Unix socket, e.g. /run/ebs-monitor/state.sock")
+	rootCmd.PersistentFlags().BoolVar(&dumpJSON, "dump-json", false, "Print the runtime config, event log and error log to stdout as JSON on SIGUSR1, and each cycle in debug mode")
+	rootCmd.PersistentFlags().BoolVar(&offlineRegionValidation, "offline-region-validation", false, "Validate regions against a built-in list and their format only, without calling DescribeRegions")
+	rootCmd.PersistentFlags().BoolVar(&runOnce, "run-once", false, "Check and resize every volume once, then exit non-zero if any check failed, e.g. when run from cron")
+	rootCmd.PersistentFlags().BoolVar(&skipPreflight, "skip-preflight", false, "Start without checking the AWS credentials and IAM permissions against a configured volume")
+	rootCmd.PersistentFlags().DurationVar(&configWait, "config-wait", 0, "How long to keep retrying at startup while the config file doesn't exist, e.g. 2m")
 	rootCmd.Flags().BoolP("version", "v", false, "Show version")
 }
 
@@ -58,43 +161,128 @@ func init() {
 // cmd : *cobra.Command The root command
 // args : []string The arguments passed to the root command
 func run(cmd *cobra.Command, args []string) {
+	// Record the start time, used to enforce maxUptimeHours
+	startTime := runtime.Now()
+
 	// Check if the filepath argument is provided
 	DebugPrint(debugMode, "Running command...")
+	if err := ResolveConfigSource(); err != nil {
+		l.Log(logger.LogError, "Invalid config source", map[string]interface{}{
+			"error": err,
+		})
+		Exit(1)
+	}
 	if configFile == "" {
 		l.Log(logger.LogError, "Config file path is missing", nil)
-		os.Exit(1)
+		Exit(1)
 	}
 
 	// Initialise core structs
 	appRuntime, appConfig := InitialiseApp()
 
 	// Load config from file
-	volumes, checkIntervalSeconds, err := LoadConfig(configFile)
+	fileConfig, err := LoadConfig(configFile)
 	if err != nil {
 		l.Log(logger.LogFatal, "Failed to load config", map[string]interface{}{
 			"config file path": configFile,
 			"error":            err,
 			"configFile":       configFile,
 		})
-		os.Exit(1)
+		Exit(1)
+	}
+	// Take every setting from the file, then the ones overridden on the command line
+	localConfig = fileConfig
+	*appConfig = *fileConfig
+	ApplyCommandLineOverrides(appConfig)
+	volumes, checkIntervalSeconds := appConfig.Volumes, appConfig.CheckIntervalSeconds
+
+	// Apply the logging, notification, AWS and host command settings
+	if err := ApplySettings(*appConfig); err != nil {
+		l.Log(logger.LogFatal, "Invalid configuration", map[string]interface{}{
+			"configFile": configFile,
+			"error":      err,
+		})
+		Exit(1)
 	}
 
 	// Check if volumes and other configurations are correctly loaded
-	if len(volumes) == 0 || checkIntervalSeconds == 0 {
+	// Volumes may be omitted from the file when they are supplied by a remote config source
+	if (len(volumes) == 0 && appConfig.RemoteConfig.URL == "") || checkIntervalSeconds == 0 {
 		l.Log(logger.LogFatal, "Invalid configuration", map[string]interface{}{
 			"volumes":              volumes,
 			"checkIntervalSeconds": checkIntervalSeconds,
 		})
-		os.Exit(1)
+		Exit(1)
+	}
+
+	// Check the credentials and IAM permissions against the first volume, so misconfigured IAM fails here
+	// rather than deep inside the first check
+	if !skipPreflight && len(volumes) > 0 {
+		DebugPrint(debugMode, "Running AWS preflight checks...")
+		callerARN, err := aws.Preflight(volumes[0])
+		if err != nil {
+			fields := map[string]interface{}{
+				"AWS Volume ID": volumes[0].AWSVolumeID,
+				"AWS Region":    volumes[0].AWSRegion,
+				"error":         err,
+			}
+			var missing *aws.MissingPermissionError
+			if errors.As(err, &missing) {
+				fields["Missing Permission"] = missing.Action
+			}
+			l.Log(logger.LogFatal, "AWS preflight check failed, fix the credentials or IAM policy, or start with --skip-preflight", fields)
+			Exit(1)
+		}
+		DebugPrint(debugMode, fmt.Sprintf("AWS preflight checks passed as %s", callerARN))
+	}
+
+	// Check the tools that grow each volume's filesystems are installed, so a missing one is found now
+	// rather than when a volume fills up
+	if !CheckResizeTools(volumes) && appConfig.FailOnMissingResizeTools {
+		l.Log(logger.LogFatal, "Resize tools are missing, install them or unset failOnMissingResizeTools to start anyway", nil)
+		Exit(1)
 	}
 
 	// Initialise Runtime with config and debug mode set to true
 	DebugPrint(debugMode, "Initializing core structs...")
 	DebugPrint(debugMode, "Loading config from file...")
-	appConfig.AddEBSVolumeConfigs(volumes...)
-	appConfig.SetCheckInterval(checkIntervalSeconds)
 	appRuntime.Configuration = *appConfig
 	appRuntime.DebugMode = debugMode
+	appRuntime.DryRun = dryRun
+
+	// Startup counts as a completed cycle, so a first cycle that hangs is reported by the health check
+	appRuntime.LastCycle.Complete(runtime.Now(), time.Duration(appRuntime.Configuration.CheckIntervalSeconds)*time.Second)
+	healthCheck := func() (bool, string) { return HealthStatus(appRuntime) }
+
+	// Start the metrics server, if enabled
+	if metricsAddr != "" {
+		metricsRegistry = metrics.NewRegistry()
+		if err := metricsRegistry.Serve(metricsAddr, healthCheck); err != nil {
+			l.Log(logger.LogFatal, "Failed to start metrics server", map[string]interface{}{
+				"metricsAddr": metricsAddr,
+				"error":       err,
+			})
+			Exit(1)
+		}
+	}
+	// Start the standalone health server, if enabled
+	if healthAddr != "" && healthAddr != metricsAddr {
+		server, err := metrics.ServeHealth(healthAddr, healthCheck)
+		if err != nil {
+			l.Log(logger.LogFatal, "Failed to start health server", map[string]interface{}{
+				"healthAddr": healthAddr,
+				"error":      err,
+			})
+			Exit(1)
+		}
+		healthServer = server
+	}
+
+	// Shut down cleanly when stopped
+	HandleShutdownSignals()
+	if dryRun {
+		l.Log(logger.LogInfo, "Running in dry-run mode, resizes will be simulated", nil)
+	}
 	// Set logger debug mode
 	if debugMode {
 		l.SetDebugMode(debugMode)
@@ -104,13 +292,65 @@ func run(cmd *cobra.Command, args []string) {
 	eventLog := runtime.InitialiseEventLog(*appConfig)
 	errorLog := make(map[string]int)
 
+	// Restore recent history saved before the last restart
+	if eventLogFile != "" {
+		RestoreEventLog(eventLog, eventLogFile, appConfig.EventRetention())
+	}
+
+	// Serve the last-known volume states to local tooling, if enabled
+	if stateSocketPath != "" {
+		socket, err := metrics.ServeState(stateSocketPath)
+		if err != nil {
+			l.Log(logger.LogFatal, "Failed to start state socket", map[string]interface{}{
+				"stateSocket": stateSocketPath,
+				"error":       err,
+			})
+			Exit(1)
+		}
+		stateSocket = socket
+		PublishState(eventLog)
+	}
+
+	// Set up the remote config source, if configured, and apply its config before the first check
+	var lastRemotePoll time.Time
+	if appRuntime.Configuration.RemoteConfig.URL != "" {
+		DebugPrint(debugMode, "Polling remote config source...")
+		remoteSource = configutil.NewRemoteSource(appRuntime.Configuration.RemoteConfig.URL)
+		FetchInitialRemoteConfig(remoteSource, appRuntime, eventLog, errorLog)
+		lastRemotePoll = runtime.Now()
+	}
+
+	// Reload the config file on SIGHUP
+	HandleReloadSignals()
+	// Dump the runtime state on SIGUSR1
+	if dumpJSON {
+		HandleDumpSignals()
+	}
+	reloadRequested := false
+
 	// Infinite loop until no volumes left to monitor
 	for {
 		DebugPrint(debugMode, "Running main monitoring loop...")
-		// Check if there are volumes left to monitor
-		if len(appRuntime.Configuration.Volumes) == 0 {
+		// Apply config file changes if a reload was requested, between cycles so the volume list is stable while checking
+		if reloadRequested || ReloadRequested() {
+			ReloadConfig(appRuntime, eventLog, errorLog)
+			reloadRequested = false
+		}
+
+		// Poll the remote config source once its poll interval has elapsed
+		if remoteSource != nil && runtime.Now().Sub(lastRemotePoll) >= remotePollInterval(appRuntime.Configuration) {
+			DebugPrint(debugMode, "Polling remote config source...")
+			PollRemoteConfig(remoteSource, appRuntime, eventLog, errorLog)
+			lastRemotePoll = runtime.Now()
+		}
+
+		// Return dropped volumes that have recovered to monitoring
+		RetryQuarantined(appRuntime, errorLog, runtime.Now())
+
+		// Check if there are volumes left to monitor, or waiting to recover
+		if len(appRuntime.Configuration.Volumes) == 0 && len(appRuntime.Quarantined) == 0 {
 			l.Log(logger.LogError, "No more volumes to monitor", nil)
-			os.Exit(1)
+			Exit(1)
 		}
 
 		// If debug mode is enabled, print runtime state
@@ -118,135 +358,378 @@ func run(cmd *cobra.Command, args []string) {
 			DebugPrint(debugMode, strings.Repeat("-", 20))
 			DebugPrint(debugMode, "     RUN TIME OUTPUT     ")
 			DebugPrint(debugMode, strings.Repeat("-", 20))
-			DumpRuntime(appConfig, eventLog, errorLog)
+			if dumpJSON {
+				DumpRuntimeJSON(appRuntime, eventLog, errorLog)
+			} else {
+				DumpRuntime(&appRuntime.Configuration, eventLog, errorLog, appRuntime.LastChecked)
+			}
 			DebugPrint(debugMode, strings.Repeat("-", 20))
 		}
 
-		// Iterate through all volumes in runtime config
-		for index := 0; index < len(appRuntime.Configuration.Volumes); {
-			DebugPrint(debugMode, fmt.Sprintf("Checking volume at index %d", index))
+		// Check the volumes due a check, several at a time, and schedule each one's next check on its own interval
+		due := appRuntime.DueVolumes(runtime.Now())
+		removed := CheckVolumes(appRuntime, due, eventLog, errorLog)
+		for _, volume := range due {
+			appRuntime.ScheduleCheck(volume.AWSVolumeID, runtime.Now().Add(NextCheckDelay(appRuntime.Configuration, volume, rand.Float64())))
+		}
+		// Quarantine the volumes that keep failing
+		for volumeID, reason := range removed {
+			QuarantineVolume(appRuntime, eventLog, volumeID, reason, errorLog[volumeID])
+		}
 
-			// Get volumeID of current one to check
-			volume := appRuntime.Configuration.Volumes[index]
+		// In run-once mode, save the history for the next run and exit after the single pass
+		if runOnce {
+			eventLog.PruneStaleEvents(appRuntime.Configuration.EventRetention())
+			SaveEventLog(eventLog)
+			code := RunOnceExitCode(errorLog, appRuntime.Transient)
+			l.Log(logger.LogInfo, "Completed a single pass over the volumes, exiting", map[string]interface{}{
+				"Volumes Checked": len(due),
+				"Exit Code":       code,
+			})
+			Exit(code)
+		}
 
-			// Get current volume state & handle any errors in this process
-			volumeState, err := monitor.GetVolumeState(volume, &eventLog)
-			if err != nil {
-				errorLog[volume.AWSVolumeID]++
-				l.Log(logger.LogError, "Encountered error when getting volume state", map[string]interface{}{
-					"VolumeID":    volume.AWSVolumeID,
-					"Error":       err,
-					"Error Count": errorLog[volume.AWSVolumeID],
-				})
-				DebugPrint(debugMode, "Encountered error when getting volume state, increasing error log count...")
-				DebugPrint(debugMode, fmt.Sprintf("error: %v", err))
-			} else {
-				DebugPrint(debugMode, "Volume state retrieved successfully.")
+		// Check if there are volumes left to monitor after the for loop
+		if len(appRuntime.Configuration.Volumes) == 0 && len(appRuntime.Quarantined) == 0 {
+			l.Log(logger.LogError, "No more volumes to monitor", nil)
+			SaveEventLog(eventLog)
+			Exit(1)
+		}
 
-			}
+		// Exit cleanly between cycles once the maximum uptime is reached, for systemd to restart the service
+		if MaxUptimeReached(startTime, runtime.Now(), appRuntime.Configuration.MaxUptimeHours) {
+			l.Log(logger.LogInfo, "Maximum uptime reached, exiting for a scheduled restart", map[string]interface{}{
+				"maxUptimeHours": appRuntime.Configuration.MaxUptimeHours,
+				"uptime":         runtime.Now().Sub(startTime).Round(time.Second),
+			})
+			SaveEventLog(eventLog)
+			Exit(0)
+		}
 
-			// Prints runtime state if debugmode is true
-			if debugMode {
-				PrintStructFields(volumeState, "")
-			}
+		// Record the completed cycle for the health check, expecting the next one after this cycle's sleep,
+		// or after the check interval when the sleep is shorter, so a short sleep doesn't make the next cycle look overdue
+		sleep := CheckSleep(appRuntime, runtime.Now())
+		appRuntime.LastCycle.Complete(runtime.Now(), CycleInterval(appRuntime, sleep))
+		PublishState(eventLog)
 
-			if err != nil {
-				// Create an event based on the volume state
-				event := runtime.CreateVolumeStateEvent(volumeState, false)
+		// Prunes any events from the eventLog that are >24 hours old.
+		reloadRequested = PruneAndSleep(appRuntime, &eventLog, errorLog, sleep)
+	}
+}
 
-				// Add the event to the log
-				fields, err := eventLog.AddEvent(volume.AWSVolumeID, event)
-				if err != nil {
-					l.Log(logger.LogError, fmt.Sprint(err), fields)
-				}
+// CheckVolumes : Checks the given volumes, running up to maxConcurrentChecks checks at a time.
+// If a shutdown is requested no new checks are started, and the service shuts down once the running checks finish.
+// appRuntime : *runtime.Runtime The runtime holding the monitored volumes.
+// volumes : []runtime.EBSVolumeConfig The volumes to check, from the runtime's volume list.
+// eventLog : runtime.EventLog The log of events.
+// errorLog : map[string]int The count of errors.
+// Returns: map[string]error The volumes that reached the error threshold and should be removed, keyed by ID, with the error that caused it.
+func CheckVolumes(appRuntime *runtime.Runtime, volumes []runtime.EBSVolumeConfig, eventLog runtime.EventLog, errorLog map[string]int) map[string]error {
+	workers := appRuntime.Configuration.MaxConcurrentChecks
+	if workers <= 0 {
+		workers = defaultMaxConcurrentChecks
+	}
 
-				// If error threshold has exceeded errorThreshold, drop the volume and log fatal error.
-				if errorLog[volume.AWSVolumeID] >= errorThreshold {
-					// Remove volume from the list
-					appRuntime.Configuration.Volumes = append(appRuntime.Configuration.Volumes[:index], appRuntime.Configuration.Volumes[index+1:]...)
-					l.Log(logger.LogError, "A disk has been removed due to recurrent errors", map[string]interface{}{
-						"VolumeID":    volume.AWSVolumeID,
-						"Error Count": errorLog[volume.AWSVolumeID],
-					})
-					continue
+	var (
+		mu             sync.Mutex
+		wg             sync.WaitGroup
+		removed        = make(map[string]error)
+		shutdownSignal os.Signal
+	)
+	queue := make(chan runtime.EBSVolumeConfig)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for volume := range queue {
+				if reason := CheckVolume(appRuntime, volume, eventLog, errorLog, &mu); reason != nil {
+					mu.Lock()
+					removed[volume.AWSVolumeID] = reason
+					mu.Unlock()
 				}
+			}
+		}()
+	}
 
-			} else {
-				// Create an event based on the volume state
-				event := runtime.CreateVolumeStateEvent(volumeState, true)
+	// Hand out volumes as workers become free, stopping early on shutdown
+dispatch:
+	for _, volume := range volumes {
+		select {
+		case queue <- volume:
+		case shutdownSignal = <-shutdownSignals:
+			break dispatch
+		}
+	}
+	close(queue)
+	wg.Wait()
 
-				// Add the event to the log
-				fields, err := eventLog.AddEvent(volume.AWSVolumeID, event)
-				if err != nil {
-					l.Log(logger.LogError, fmt.Sprint(err), fields)
-				}
+	if shutdownSignal != nil {
+		Shutdown(eventLog, shutdownSignal)
+	}
+	return removed
+}
 
-				// Determine if resize is needed
-				if IsThresholdExceeded(&volumeState, float64(volume.ResizeThreshold)) {
-					DebugPrint(debugMode, "Threshold exceeded for volume, starting resizing process...")
-
-					// Calculate the new size
-					currentSize, err := aws.GetAWSDeviceSizeGB(volume)
-					if err != nil {
-						DebugPrint(debugMode, fmt.Sprintf("Failed to get current size for volume %s: %v\n", volume.AWSVolumeID, err))
-						DebugPrint(debugMode, fmt.Sprintf("error: %v", err))
-						errorLog[volume.AWSVolumeID]++ // increase error count
-						l.Log(logger.LogError, fmt.Sprintf("Failed to get current size for volume."), map[string]interface{}{
-							"VolumeID":    volume.AWSVolumeID,
-							"Error":       err,
-							"Error Count": errorLog[volume.AWSVolumeID],
-						})
-					} else {
-						var newSize int64
-						// Check if IncreaseSizeGB is declared in config.yaml
-						// will be < 0 if not declaed in config.yaml
-						if volume.IncrementSizeGB > 0 {
-							newSize = currentSize + int64(volume.IncrementSizeGB)
-							DebugPrint(debugMode, fmt.Sprintf("Manually calculated new size for volume %s is %d\n", volume.AWSVolumeID, newSize))
-						} else {
-							// calculate new size based on percentage as increaseByGB was not specified
-							newSize = resize.CalculateNewSize(volume, currentSize)
-							DebugPrint(debugMode, fmt.Sprintf("Calculated new size for volume %s is %d\n", volume.AWSVolumeID, newSize))
-						}
-
-						DebugPrint(debugMode, "Performing resize...")
-
-						// Perform the resize
-						// NOTE: event log logging for resize actions is handled by resize.PerformResize function
-						awsResized, fsResized, err := resize.PerformResize(volume, newSize, &eventLog)
-						if err != nil {
-							DebugPrint(debugMode, fmt.Sprintf(" %s: %v\n", volume.AWSVolumeID, err))
-							DebugPrint(debugMode, fmt.Sprintf("error: %v", err))
-							errorLog[volume.AWSVolumeID]++ // increase error count
-							l.Log(logger.LogError, fmt.Sprintf("Failed to resize volume."), map[string]interface{}{
-								"VolumeID":                        volume.AWSVolumeID,
-								"Error":                           err,
-								"Successfully Resized AWS Volume": awsResized,
-								"Successfully Resized Filesystem": fsResized,
-								"Error Count":                     errorLog[volume.AWSVolumeID],
-							})
-						} else {
-							l.Log(logger.LogInfo, fmt.Sprintf(":white_check_mark: Successfully resized device: %s from %vGB to %vGB.", volume.AWSDeviceName, currentSize, newSize), nil)
-							// Reset the error counter after a successful operation
-							errorLog[volume.AWSVolumeID] = 0
-						}
-					}
+// CheckVolume : Checks a volume's utilisation and resizes it when its threshold is exceeded.
+// Safe to run concurrently for different volumes: shared state is only touched while holding mu, and the
+// volume's events and error count are recorded privately and merged back when the check finishes.
+// A panic is recovered and counted as an error, so one volume can't take down the process. Transient errors,
+// e.g. AWS throttling, are retried next cycle without counting towards the volume's removal.
+// appRuntime : *runtime.Runtime The runtime, its volume list must not change while checks run.
+// volume : runtime.EBSVolumeConfig The volume to check.
+// eventLog : runtime.EventLog The log of events.
+// errorLog : map[string]int The count of errors.
+// mu : *sync.Mutex Guards eventLog, errorLog and the runtime's per-volume state.
+// Returns: error Why the volume should be removed, once it reaches the error threshold, or nil to keep monitoring it.
+func CheckVolume(appRuntime *runtime.Runtime, volume runtime.EBSVolumeConfig, eventLog runtime.EventLog, errorLog map[string]int, mu *sync.Mutex) (removeReason error) {
+	withLock := func(fn func()) {
+		mu.Lock()
+		defer mu.Unlock()
+		fn()
+	}
 
-				}
+	volumeID := volume.AWSVolumeID
+	var volumeLog runtime.EventLog
+	var errorCount int
+	var transient bool
+	withLock(func() {
+		volumeLog = runtime.EventLog{volumeID: append([]runtime.Event(nil), eventLog[volumeID]...)}
+		errorCount = errorLog[volumeID]
 
+		// Alert if the volume went unchecked for longer than its interval allows
+		CheckForLateCheck(appRuntime, volume, runtime.Now())
+	})
+
+	defer func() {
+		if r := recover(); r != nil {
+			errorCount++
+			l.Log(logger.LogError, "Recovered from a panic while checking volume", map[string]interface{}{
+				"VolumeID":    volumeID,
+				"Panic":       r,
+				"Stack":       string(debug.Stack()),
+				"Error Count": errorCount,
+			})
+			if errorCount >= errorThreshold(appRuntime.Configuration) {
+				removeReason = fmt.Errorf("panic while checking volume: %v", r)
 			}
-			index++
 		}
+		withLock(func() {
+			eventLog[volumeID] = volumeLog[volumeID]
+			if errorCount > 0 || errorLog[volumeID] > 0 {
+				errorLog[volumeID] = errorCount
+			}
+			appRuntime.SetTransient(volumeID, transient)
+		})
+	}()
 
-		// Check if there are volumes left to monitor after the for loop
-		if len(appRuntime.Configuration.Volumes) == 0 {
-			l.Log(logger.LogError, "No more volumes to monitor", nil)
-			os.Exit(1)
+	DebugPrint(debugMode, fmt.Sprintf("Checking volume %s", volumeID))
+
+	// Get current volume state & handle any errors in this process
+	volumeState, err := getVolumeState(volume, &volumeLog)
+	var skipUnmounted bool
+	withLock(func() {
+		skipUnmounted = HandleUnmounted(appRuntime, volumeLog, volume, volumeState, err)
+	})
+	if skipUnmounted || IsVolumeNotYetVisible(volume, err) {
+		return nil
+	}
+	if monitor.IsTransient(err) {
+		transient = true
+		l.Log(logger.LogWarning, "Transient error when getting volume state, retrying next cycle", map[string]interface{}{
+			"VolumeID":    volumeID,
+			"Error":       err,
+			"Error Count": errorCount,
+		})
+		return nil
+	}
+	if err != nil {
+		errorCount++
+		l.Log(logger.LogError, "Encountered error when getting volume state", map[string]interface{}{
+			"VolumeID":    volumeID,
+			"Error":       err,
+			"Error Count": errorCount,
+		})
+		DebugPrint(debugMode, "Encountered error when getting volume state, increasing error log count...")
+		DebugPrint(debugMode, fmt.Sprintf("error: %v", err))
+	} else {
+		DebugPrint(debugMode, "Volume state retrieved successfully.")
+		metricsRegistry.SetVolumeState(volumeState)
+	}
+
+	// Prints runtime state if debugmode is true
+	if debugMode {
+		PrintStructFields(volumeState, "")
+	}
+
+	if err != nil {
+		// Create an event based on the volume state
+		event := runtime.CreateVolumeStateEvent(volumeState, false).WithError(err)
+
+		// Add the event to the log
+		fields, err := volumeLog.AddEvent(volumeID, event)
+		if err != nil {
+			l.Log(logger.LogError, fmt.Sprint(err), fields)
 		}
 
-		// Prunes any events from the eventLog that are >24 hours old.
-		PruneAndSleep(&eventLog, appRuntime.Configuration.CheckIntervalSeconds)
+		// If the error count has reached the error threshold, or the volume was deleted, the volume is dropped
+		if errorCount >= errorThreshold(appRuntime.Configuration) || monitor.IsVolumeGone(err) {
+			return err
+		}
+		return nil
 	}
+
+	// Create an event based on the volume state
+	event := runtime.CreateVolumeStateEvent(volumeState, true)
+
+	// Add the event to the log
+	fields, err := volumeLog.AddEvent(volumeID, event)
+	if err != nil {
+		l.Log(logger.LogError, fmt.Sprint(err), fields)
+	}
+
+	// Determine if resize is needed
+	if !IsThresholdExceeded(&volumeState, volume) {
+		RecordSkip(appRuntime, volumeLog, volumeState, runtime.SkipReasonBelowThreshold)
+		return nil
+	}
+	// The rule that fired is recorded with the resize, so each growth can be traced back to its cause
+	triggerReason := monitor.ExceededRule(volumeState, volume)
+	// Monitor only volumes alert on the exceeded threshold, but are never resized
+	if volume.MonitorOnly {
+		RecordSkip(appRuntime, volumeLog, volumeState, runtime.SkipReasonMonitorOnly)
+		message := "Volume exceeded its resize threshold, not resizing as it is monitor only."
+		fields := map[string]interface{}{
+			"VolumeID":          volumeID,
+			"Local Mount Point": volumeState.LocalMountPoint,
+			"Rule":              triggerReason,
+			"Used Space":        runtime.FormatSize(volumeState.UsedSpaceGiB),
+			"Free Space":        runtime.FormatSize(volumeState.LocalDiskSizeGiB - volumeState.UsedSpaceGiB),
+		}
+		// Alert when usage first exceeds the threshold, as nothing will free the space, rather than on every check
+		if volumeLog.ConsecutiveStates(volumeID, func(state runtime.EBSVolumeState) bool {
+			return monitor.IsResizeNeeded(state, volume)
+		}) == 1 {
+			l.Alert(logger.LogWarning, message, fields, notify.Format(message, fields))
+		} else {
+			l.Log(logger.LogWarning, message, fields)
+		}
+		return nil
+	}
+	// Hold off while a recent resize may still be settling, as AWS rejects modifications made too close together
+	if remaining := ResizeCooldownRemaining(appRuntime, volumeLog, volumeID, runtime.Now()); remaining > 0 {
+		RecordSkip(appRuntime, volumeLog, volumeState, runtime.SkipReasonCooldown)
+		l.Log(logger.LogInfo, "Resize skipped, volume was resized recently.", map[string]interface{}{
+			"VolumeID":           volumeID,
+			"Cooldown Remaining": remaining.Round(time.Second),
+		})
+		return nil
+	}
+	// Stop resizing a volume that keeps needing it, e.g. a filesystem that never actually grows, once it hits maxResizesPerDay
+	if volume.MaxResizesPerDay > 0 {
+		if resizes := volumeLog.ResizesSince(volumeID, runtime.Now().Add(-24*time.Hour)); resizes >= volume.MaxResizesPerDay {
+			// Logged as an error so it notifies, as the volume keeps filling up with no more resizes until tomorrow
+			RecordSkip(appRuntime, volumeLog, volumeState, runtime.SkipReasonDailyLimit)
+			l.Log(logger.LogError, "Resize skipped, volume reached its daily resize limit.", map[string]interface{}{
+				"VolumeID":            volumeID,
+				"Resizes In 24 Hours": resizes,
+				"Max Resizes Per Day": volume.MaxResizesPerDay,
+			})
+			return nil
+		}
+	}
+	// Wait until usage has stayed above the threshold for minObservationCycles checks, e.g. on a volume restored near full
+	if observed := volumeLog.ConsecutiveStates(volumeID, func(state runtime.EBSVolumeState) bool {
+		return monitor.IsResizeNeeded(state, volume)
+	}); observed < volume.MinObservationCycles {
+		RecordSkip(appRuntime, volumeLog, volumeState, runtime.SkipReasonObserving)
+		l.Log(logger.LogInfo, "Resize deferred, volume has not been above its threshold for enough checks.", map[string]interface{}{
+			"VolumeID":               volumeID,
+			"Observed Cycles":        observed,
+			"Min Observation Cycles": volume.MinObservationCycles,
+		})
+		return nil
+	}
+	DebugPrint(debugMode, "Threshold exceeded for volume, starting resizing process...")
+
+	// Calculate the new size
+	currentSize, err := aws.GetAWSDeviceSizeGiB(volume)
+	if err != nil {
+		DebugPrint(debugMode, fmt.Sprintf("Failed to get current size for volume %s: %v\n", volumeID, err))
+		DebugPrint(debugMode, fmt.Sprintf("error: %v", err))
+		if aws.IsTransient(err) {
+			transient = true
+		} else {
+			errorCount++ // increase error count
+		}
+		l.Log(logger.LogError, fmt.Sprintf("Failed to get current size for volume."), map[string]interface{}{
+			"VolumeID":    volumeID,
+			"Error":       err,
+			"Error Count": errorCount,
+		})
+		return nil
+	}
+
+	// Calculate new size from the volume's increment and alignment settings
+	newSize := resize.CalculateNewSize(volume, currentSize)
+	DebugPrint(debugMode, fmt.Sprintf("Calculated new size for volume %s is %d\n", volumeID, newSize))
+	// A volume already at its maxSizeGB cap can't grow, so there is nothing to resize
+	if volume.MaxSizeGB > 0 && currentSize >= int64(volume.MaxSizeGB) {
+		RecordSkip(appRuntime, volumeLog, volumeState, runtime.SkipReasonMaxSize)
+		l.Log(logger.LogWarning, "Resize skipped, volume is at its maximum size.", map[string]interface{}{
+			"VolumeID":         volumeID,
+			"Current Size GiB": currentSize,
+			"Max Size GiB":     volume.MaxSizeGB,
+		})
+		return nil
+	}
+	// Nor can a volume whose increment works out to nothing, e.g. a percentage that rounds down on a small volume
+	if newSize <= currentSize {
+		RecordSkip(appRuntime, volumeLog, volumeState, runtime.SkipReasonNoIncrement)
+		l.Log(logger.LogWarning, "Resize skipped, the volume's increment doesn't grow it.", map[string]interface{}{
+			"VolumeID":         volumeID,
+			"Current Size GiB": currentSize,
+			"Growth Strategy":  resize.GrowthStrategy(volume),
+		})
+		return nil
+	}
+
+	DebugPrint(debugMode, "Performing resize...")
+
+	// Perform the resize
+	// NOTE: event log logging for resize actions, and the resize summary notification, are handled by resize.PerformResize function
+	awsResized, fsResized, err := resize.PerformResize(volume, newSize, triggerReason, &volumeLog, appRuntime.DryRun)
+	var skipped *resize.SkippedError
+	if errors.As(err, &skipped) {
+		// A skipped resize is not a failure, so the error count is left untouched
+		RecordSkip(appRuntime, volumeLog, volumeState, skipped.Reason)
+		l.Log(logger.LogWarning, "Resize skipped.", map[string]interface{}{
+			"VolumeID":   volumeID,
+			"SkipReason": skipped.Reason,
+		})
+	} else if err != nil {
+		DebugPrint(debugMode, fmt.Sprintf(" %s: %v\n", volumeID, err))
+		DebugPrint(debugMode, fmt.Sprintf("error: %v", err))
+		if aws.IsTransient(err) {
+			transient = true
+		} else {
+			errorCount++ // increase error count
+		}
+		metricsRegistry.IncResizeError(volumeID)
+		l.Log(logger.LogError, fmt.Sprintf("Failed to resize volume."), map[string]interface{}{
+			"VolumeID":                        volumeID,
+			"Error":                           err,
+			"Trigger Reason":                  triggerReason,
+			"Successfully Resized AWS Volume": awsResized,
+			"Successfully Resized Filesystem": fsResized,
+			"Error Count":                     errorCount,
+		})
+	} else if appRuntime.DryRun {
+		DebugPrint(debugMode, fmt.Sprintf("Dry run: simulated resize of volume %s to %dGiB", volumeID, newSize))
+	} else {
+		// Reset the error counter after a successful operation
+		errorCount = 0
+		metricsRegistry.IncResize(volumeID)
+	}
+	return nil
 }
 
 // main : The entry point of the application
@@ -255,7 +738,7 @@ func main() {
 		l.Log(logger.LogError, "Failed to execute root command", map[string]interface{}{
 			"error": err,
 		})
-		os.Exit(1)
+		Exit(1)
 	}
 }
 
@@ -291,7 +774,8 @@ func PrintStructFields(data interface{}, indent string) {
 // config : *runtime.Config The config to print
 // eventLog : runtime.EventLog The event log to print
 // errorLog : make(map[string]int) The error log for each volume
-func DumpRuntime(config *runtime.Config, eventLog runtime.EventLog, errorLog map[string]int) {
+// lastChecked : map[string]time.Time The time each volume was last checked
+func DumpRuntime(config *runtime.Config, eventLog runtime.EventLog, errorLog map[string]int, lastChecked map[string]time.Time) {
 	DebugPrint(debugMode, "=== CONFIG.YAML ===")
 	DebugPrint(debugMode, fmt.Sprintf("Config: %v\n", config))
 
@@ -299,41 +783,485 @@ func DumpRuntime(config *runtime.Config, eventLog runtime.EventLog, errorLog map
 	for volumeID, events := range eventLog {
 		DebugPrint(debugMode, fmt.Sprintf("VolumeID: %s", volumeID))
 		DebugPrint(debugMode, fmt.Sprintf("Error Count: %d", errorLog[volumeID]))
+		DebugPrint(debugMode, fmt.Sprintf("Last Checked: %v", lastChecked[volumeID]))
 		for _, event := range events {
-			DebugPrint(debugMode, "Event Details:")
+			if event.Simulated {
+				DebugPrint(debugMode, "Event Details (simulated):")
+			} else {
+				DebugPrint(debugMode, "Event Details:")
+			}
 			DebugPrint(debugMode, fmt.Sprintf("%v", event))
 		}
 	}
 }
 
+// DumpRuntimeJSON : Prints the runtime config, event log, error log and per-volume state to stdout as JSON
+// appRuntime : *runtime.Runtime The runtime to print
+// eventLog : runtime.EventLog The event log to print
+// errorLog : map[string]int The error log for each volume
+func DumpRuntimeJSON(appRuntime *runtime.Runtime, eventLog runtime.EventLog, errorLog map[string]int) {
+	if err := appRuntime.Dump(eventLog, errorLog, runtime.Now()).WriteJSON(os.Stdout); err != nil {
+		l.Log(logger.LogError, "Failed to dump the runtime state", map[string]interface{}{
+			"error": err,
+		})
+	}
+}
+
+// PublishState : Updates the volume states served on the state socket, if it is enabled
+// eventLog : runtime.EventLog The event log the states are taken from
+func PublishState(eventLog runtime.EventLog) {
+	if err := stateSocket.Publish(eventLog.Snapshot(runtime.Now())); err != nil {
+		l.Log(logger.LogError, "Failed to publish the volume states", map[string]interface{}{
+			"error": err,
+		})
+	}
+}
+
+// CheckResizeTools : Checks the tools each volume needs to be resized are installed, probing the volumes' filesystems
+// for the tools they need, and logs an error for each volume missing one.
+// volumes : []runtime.EBSVolumeConfig The volumes to check, monitor only volumes are skipped as they are never resized
+// Returns: bool False if any volume is missing a tool, volumes whose filesystems can't be probed are only warned about
+func CheckResizeTools(volumes []runtime.EBSVolumeConfig) bool {
+	ok := true
+	for _, volume := range volumes {
+		if volume.MonitorOnly {
+			continue
+		}
+		missing, err := filesystem.MissingResizeTools(volume)
+		if err != nil {
+			l.Log(logger.LogWarning, "Could not check the resize tools of volume, its filesystems could not be probed", map[string]interface{}{
+				"VolumeID": volume.AWSVolumeID,
+				"error":    err,
+			})
+			continue
+		}
+		if len(missing) > 0 {
+			ok = false
+			l.Log(logger.LogError, "Resize tools are missing, the volume can't be resized until they are installed", map[string]interface{}{
+				"VolumeID":      volume.AWSVolumeID,
+				"Missing Tools": strings.Join(missing, ", "),
+			})
+		}
+	}
+	return ok
+}
+
 // InitialiseApp : Initializes the application by creating runtime and configuration.
 // Returns: (*runtime.Runtime, *runtime.Config)
 func InitialiseApp() (*runtime.Runtime, *runtime.Config) {
 	return runtime.InitialiseRuntime(), runtime.InitialiseConfig()
 }
 
+// ResolveConfigSource : Points configFile at the --config-dir directory, when one is given, so it is loaded
+// and reloaded like any other config source.
+// Returns: error An error if both --config and --config-dir are given.
+func ResolveConfigSource() error {
+	if configDir == "" {
+		return nil
+	}
+	if configFile != "" {
+		return fmt.Errorf("--config and --config-dir can't be used together")
+	}
+	configFile = configutil.DirSource(configDir)
+	return nil
+}
+
 // LoadConfig : Function to load configuration values from a file.
 // configFile : string The path to the configuration file.
-// Returns a slices, an int, and an error. The first slice contains the volumes, the second contains the check intervals in seconds.
-func LoadConfig(configFile string) (volumes []runtime.EBSVolumeConfig, checkIntervalSeconds int, err error) {
-	volumes, checkIntervalSeconds, err = configutil.GetConfigFromFile(configFile)
+// Returns the loaded configuration and an error.
+func LoadConfig(configFile string) (*runtime.Config, error) {
+	aws.SetOfflineRegionValidation(offlineRegionValidation)
+	cfg, err := configutil.LoadConfig(configFile)
+	// Wait for a config file that hasn't appeared yet, e.g. on a slow network mount during boot.
+	// An invalid config fails straight away.
+	deadline := runtime.Now().Add(configWait)
+	for attempt := 0; err != nil && configutil.IsConfigNotFound(err) && runtime.Now().Before(deadline); attempt++ {
+		delay := configRetryDelay(attempt)
+		l.Log(logger.LogWarning, "Config file not found, retrying", map[string]interface{}{
+			"configFile": configFile,
+			"retryIn":    delay,
+		})
+		time.Sleep(delay)
+		cfg, err = configutil.LoadConfig(configFile)
+	}
 	if err != nil {
 		l.Log(logger.LogError, "Failed to get config from file", map[string]interface{}{
 			"config file location": configFile,
 			"error":                err,
 			"configFile":           configFile,
 		})
-		os.Exit(1)
+		Exit(1)
+	}
+	return cfg, err
+}
+
+// CycleInterval : Returns the expected time until the next cycle completes, the longer of the sleep and the check interval.
+// appRuntime : *runtime.Runtime The runtime holding the check interval.
+// sleep : time.Duration The sleep before the next cycle.
+// Returns: time.Duration
+func CycleInterval(appRuntime *runtime.Runtime, sleep time.Duration) time.Duration {
+	interval := time.Duration(appRuntime.Configuration.CheckIntervalSeconds) * time.Second
+	if sleep > interval {
+		return sleep
+	}
+	return interval
+}
+
+// configRetryDelay : Returns how long to wait before the next attempt to load a missing config file.
+// The delay doubles with each attempt, up to maxConfigRetryDelay.
+// attempt : int Number of retries already made.
+// Returns: time.Duration
+func configRetryDelay(attempt int) time.Duration {
+	delay := initialConfigRetryDelay
+	for i := 0; i < attempt && delay < maxConfigRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxConfigRetryDelay {
+		delay = maxConfigRetryDelay
+	}
+	return delay
+}
+
+// HealthStatus : Reports whether monitoring is healthy, for the /healthz endpoint.
+// Unhealthy when the last monitoring cycle completed more than twice the check interval ago, or EC2 calls keep failing.
+// appRuntime : *runtime.Runtime The runtime tracking the last cycle.
+// Returns: bool True if healthy.
+// Returns: string Why the service is unhealthy, empty when healthy.
+func HealthStatus(appRuntime *runtime.Runtime) (bool, string) {
+	if overdue, since := appRuntime.LastCycle.Overdue(runtime.Now()); overdue {
+		return false, fmt.Sprintf("last monitoring cycle completed %v ago", since.Round(time.Second))
+	}
+	if failures := aws.ConsecutiveFailures(); failures >= healthAWSFailureLimit {
+		return false, fmt.Sprintf("last %d AWS calls failed", failures)
+	}
+	return true, ""
+}
+
+// errorThreshold : Returns how many consecutive errors drop a volume from monitoring.
+// Only permanent errors count towards it, transient ones such as AWS throttling are retried without counting.
+// config : runtime.Config The current configuration.
+// Returns: int
+func errorThreshold(config runtime.Config) int {
+	if config.ErrorThreshold > 0 {
+		return config.ErrorThreshold
+	}
+	return defaultErrorThreshold
+}
+
+// requarantineRetryInterval : Returns how often dropped volumes are retried.
+// config : runtime.Config The current configuration.
+// Returns: time.Duration
+func requarantineRetryInterval(config runtime.Config) time.Duration {
+	if config.RequarantineRetrySeconds > 0 {
+		return time.Duration(config.RequarantineRetrySeconds) * time.Second
+	}
+	return defaultRequarantineRetrySeconds * time.Second
+}
+
+// QuarantineVolume : Drops a volume that reached the error threshold from monitoring, keeping it to retry until it recovers.
+// Logged as an error with a dedicated volume dropped alert, sent immediately, listing the volume's recent errors and remediation steps.
+// appRuntime : *runtime.Runtime The runtime the volume is dropped from.
+// eventLog : runtime.EventLog The log of events, holding the volume's recent errors.
+// volumeID : string AWS Volume ID of the volume.
+// reason : error The error that caused the drop.
+// errorCount : int The volume's error count.
+func QuarantineVolume(appRuntime *runtime.Runtime, eventLog runtime.EventLog, volumeID string, reason error, errorCount int) {
+	var deviceName string
+	for _, volume := range appRuntime.Configuration.Volumes {
+		if volume.AWSVolumeID == volumeID {
+			deviceName = volume.AWSDeviceName
+			appRuntime.Quarantine(volume, fmt.Sprint(reason), runtime.Now())
+			break
+		}
+	}
+	appRuntime.Configuration.RemoveEBSVolumeConfig(volumeID)
+	delete(appRuntime.LastChecked, volumeID)
+	delete(appRuntime.NextCheck, volumeID)
+	delete(appRuntime.Unmounted, volumeID)
+	metricsRegistry.RemoveVolume(volumeID)
+
+	alert := notify.VolumeDropped{
+		VolumeID:      volumeID,
+		DeviceName:    deviceName,
+		ErrorCount:    errorCount,
+		Reason:        fmt.Sprint(reason),
+		RecentErrors:  eventLog.RecentErrors(volumeID, droppedAlertErrors),
+		RetryInterval: requarantineRetryInterval(appRuntime.Configuration),
+	}
+	l.Alert(logger.LogError, "A disk has been removed from monitoring due to recurrent errors", map[string]interface{}{
+		"VolumeID":       volumeID,
+		"DeviceName":     deviceName,
+		"Error Count":    errorCount,
+		"Reason":         reason,
+		"Retry Interval": alert.RetryInterval,
+	}, alert.Message())
+}
+
+// RetryQuarantined : Retries the dropped volumes due a retry, monitoring them again with a reset error count
+// if they pass the checks new volumes are gated on and their state can be read.
+// appRuntime : *runtime.Runtime The runtime holding the quarantined volumes.
+// errorLog : map[string]int The count of errors.
+// now : time.Time The current time.
+func RetryQuarantined(appRuntime *runtime.Runtime, errorLog map[string]int, now time.Time) {
+	for _, volume := range appRuntime.DueForRetry(now, requarantineRetryInterval(appRuntime.Configuration)) {
+		err := configutil.ValidateNewVolume(volume, appRuntime.Configuration.FailOnRegionMismatch)
+		if err == nil {
+			_, err = monitor.GetVolumeState(volume, &runtime.EventLog{})
+		}
+		if err != nil {
+			l.Log(logger.LogDebug, "Dropped volume has not recovered", map[string]interface{}{
+				"VolumeID": volume.AWSVolumeID,
+				"Error":    err,
+			})
+			continue
+		}
+
+		delete(appRuntime.Quarantined, volume.AWSVolumeID)
+		delete(errorLog, volume.AWSVolumeID)
+		appRuntime.Configuration.AddEBSVolumeConfigs(volume)
+		l.Log(logger.LogInfo, "Dropped volume has recovered, monitoring resumed", map[string]interface{}{
+			"VolumeID":   volume.AWSVolumeID,
+			"DeviceName": volume.AWSDeviceName,
+		})
 	}
-	return volumes, checkIntervalSeconds, err
 }
 
-// IsThresholdExceeded : Checks if the disk utilisation of volume state is above the resizeThreshold and prints a message.
+// remotePollInterval : Returns how often the remote config source is polled.
+// Falls back to the check interval when no poll interval is configured.
+// config : runtime.Config The current configuration.
+// Returns: time.Duration
+func remotePollInterval(config runtime.Config) time.Duration {
+	if config.RemoteConfig.PollIntervalSeconds > 0 {
+		return time.Duration(config.RemoteConfig.PollIntervalSeconds) * time.Second
+	}
+	return time.Duration(config.CheckIntervalSeconds) * time.Second
+}
+
+// PollRemoteConfig : Fetches the desired config from the remote source and applies it if it changed.
+// A failed fetch leaves the last-known-good config in place.
+// source : *configutil.RemoteSource The remote config source.
+// appRuntime : *runtime.Runtime The runtime whose configuration is updated.
+// eventLog : runtime.EventLog The log of events.
+// errorLog : map[string]int The count of errors.
+func PollRemoteConfig(source *configutil.RemoteSource, appRuntime *runtime.Runtime, eventLog runtime.EventLog, errorLog map[string]int) {
+	desired, changed, err := source.Fetch()
+	if err != nil {
+		l.Log(logger.LogWarning, "Failed to fetch remote config, continuing with last-known-good config", map[string]interface{}{
+			"URL":   source.URL,
+			"Error": err,
+		})
+	}
+	if !changed || desired == nil {
+		DebugPrint(debugMode, "Remote config unchanged.")
+		return
+	}
+	ApplyConfig(appRuntime, mergedConfig(desired), eventLog, errorLog)
+}
+
+// FetchInitialRemoteConfig : Polls the remote config source at startup. While it can't be fetched and the config
+// file has no volumes there is nothing to monitor, so it is retried rather than exiting. The delay doubles with
+// each attempt, up to maxConfigRetryDelay, and a shutdown signal stops the retries.
+// source : *configutil.RemoteSource The remote config source.
+// appRuntime : *runtime.Runtime The runtime whose configuration is updated.
+// eventLog : runtime.EventLog The log of events.
+// errorLog : map[string]int The count of errors.
+func FetchInitialRemoteConfig(source *configutil.RemoteSource, appRuntime *runtime.Runtime, eventLog runtime.EventLog, errorLog map[string]int) {
+	for attempt := 0; ; attempt++ {
+		PollRemoteConfig(source, appRuntime, eventLog, errorLog)
+		if source.LastKnownGood() != nil || len(appRuntime.Configuration.Volumes) > 0 {
+			return
+		}
+		delay := configRetryDelay(attempt)
+		l.Log(logger.LogWarning, "No volumes to monitor until the remote config is fetched, retrying", map[string]interface{}{
+			"URL":     source.URL,
+			"retryIn": delay,
+		})
+		select {
+		case <-time.After(delay):
+		case sig := <-shutdownSignals:
+			Shutdown(eventLog, sig)
+			return
+		}
+	}
+}
+
+// mergedConfig : Returns the config to apply, the config file's with the remote config merged over it.
+// Neither source replaces the other, so a reload keeps the remote volumes and settings, and a poll keeps the file's.
+// remote : *runtime.Config The last-known-good remote config, nil when there is none.
+// Returns: *runtime.Config
+func mergedConfig(remote *runtime.Config) *runtime.Config {
+	if remote == nil {
+		return localConfig
+	}
+	return configutil.MergeRemoteConfig(localConfig, remote)
+}
+
+// ApplyConfig : Applies volume additions, removals and setting changes from a new config without restarting.
+// appRuntime : *runtime.Runtime The runtime whose configuration is updated.
+// desired : *runtime.Config The config to apply.
+// eventLog : runtime.EventLog The log of events.
+// errorLog : map[string]int The count of errors.
+func ApplyConfig(appRuntime *runtime.Runtime, desired *runtime.Config, eventLog runtime.EventLog, errorLog map[string]int) {
+	settingsChanged := applyGlobalSettings(appRuntime, desired)
+	added, removed, changed := appRuntime.Configuration.DiffVolumes(desired.Volumes)
+	added = applyQuarantinedConfig(appRuntime, desired.Volumes, added)
+
+	for _, volume := range removed {
+		appRuntime.Configuration.RemoveEBSVolumeConfig(volume.AWSVolumeID)
+		delete(eventLog, volume.AWSVolumeID)
+		delete(errorLog, volume.AWSVolumeID)
+		delete(appRuntime.LastChecked, volume.AWSVolumeID)
+		delete(appRuntime.NextCheck, volume.AWSVolumeID)
+		delete(appRuntime.Unmounted, volume.AWSVolumeID)
+		metricsRegistry.RemoveVolume(volume.AWSVolumeID)
+	}
+	for _, volume := range changed {
+		appRuntime.Configuration.ReplaceEBSVolumeConfig(volume)
+		// Check changed volumes straight away, so a shorter check interval applies without waiting out the old one
+		delete(appRuntime.NextCheck, volume.AWSVolumeID)
+	}
+	rejected := 0
+	for _, volume := range added {
+		// Gate new volumes on the same checks as initial load, rather than letting them error in the loop
+		if err := configutil.ValidateNewVolume(volume, desired.FailOnRegionMismatch); err != nil {
+			rejected++
+			l.Log(logger.LogWarning, "Rejected new volume from configuration", map[string]interface{}{
+				"VolumeID":   volume.AWSVolumeID,
+				"DeviceName": volume.AWSDeviceName,
+				"Error":      err,
+			})
+			continue
+		}
+		appRuntime.Configuration.AddEBSVolumeConfigs(volume)
+		eventLog[volume.AWSVolumeID] = make([]runtime.Event, 0)
+	}
+
+	intervalChanged := desired.CheckIntervalSeconds > 0 && desired.CheckIntervalSeconds != appRuntime.Configuration.CheckIntervalSeconds
+	if intervalChanged {
+		appRuntime.Configuration.SetCheckInterval(desired.CheckIntervalSeconds)
+	}
+
+	if len(added) > 0 || len(removed) > 0 || len(changed) > 0 || intervalChanged || settingsChanged {
+		l.Log(logger.LogInfo, "Applied configuration changes", map[string]interface{}{
+			"Added Volumes":          len(added) - rejected,
+			"Rejected Volumes":       rejected,
+			"Removed Volumes":        len(removed),
+			"Changed Volumes":        len(changed),
+			"Check Interval Seconds": appRuntime.Configuration.CheckIntervalSeconds,
+			"Settings Changed":       settingsChanged,
+		})
+	}
+}
+
+// applyGlobalSettings : Replaces the runtime's global settings with a new config's, keeping its volumes and
+// check interval for ApplyConfig to update. The remote config source is only set up at startup, so a change
+// to it is kept back with a warning.
+// appRuntime : *runtime.Runtime The runtime whose configuration is updated.
+// desired : *runtime.Config The config to apply.
+// Returns: bool True if any setting changed.
+func applyGlobalSettings(appRuntime *runtime.Runtime, desired *runtime.Config) bool {
+	current := appRuntime.Configuration
+	updated := *desired
+	ApplyCommandLineOverrides(&updated)
+	updated.Volumes = current.Volumes
+	updated.CheckIntervalSeconds = current.CheckIntervalSeconds
+
+	// A remote config usually doesn't name itself, so only a source that is set and differs is reported
+	if updated.RemoteConfig != current.RemoteConfig && updated.RemoteConfig.URL != "" {
+		l.Log(logger.LogWarning, "Changes to remoteConfig take effect after a restart", map[string]interface{}{
+			"Current URL": current.RemoteConfig.URL,
+			"New URL":     updated.RemoteConfig.URL,
+		})
+	}
+	updated.RemoteConfig = current.RemoteConfig
+
+	if reflect.DeepEqual(updated, current) {
+		return false
+	}
+	if err := ApplySettings(updated); err != nil {
+		l.Log(logger.LogError, "Invalid settings in the new config, continuing with the current settings", map[string]interface{}{
+			"error": err,
+		})
+		return false
+	}
+	appRuntime.Configuration = updated
+	return true
+}
+
+// applyQuarantinedConfig : Updates quarantined volumes from a new config, releasing those no longer configured.
+// Quarantined volumes are not in the monitored list, so they show up as added; they stay quarantined until a retry finds them healthy.
+// appRuntime : *runtime.Runtime The runtime holding the quarantined volumes.
+// desired : []runtime.EBSVolumeConfig The volumes in the new config.
+// added : []runtime.EBSVolumeConfig The volumes the new config adds.
+// Returns: []runtime.EBSVolumeConfig The added volumes that aren't quarantined.
+func applyQuarantinedConfig(appRuntime *runtime.Runtime, desired, added []runtime.EBSVolumeConfig) []runtime.EBSVolumeConfig {
+	configured := make(map[string]bool, len(desired))
+	for _, volume := range desired {
+		configured[volume.AWSVolumeID] = true
+	}
+	for volumeID := range appRuntime.Quarantined {
+		if !configured[volumeID] {
+			delete(appRuntime.Quarantined, volumeID)
+		}
+	}
+
+	notQuarantined := make([]runtime.EBSVolumeConfig, 0, len(added))
+	for _, volume := range added {
+		quarantined, ok := appRuntime.Quarantined[volume.AWSVolumeID]
+		if !ok {
+			notQuarantined = append(notQuarantined, volume)
+			continue
+		}
+		quarantined.Volume = volume
+		appRuntime.Quarantined[volume.AWSVolumeID] = quarantined
+	}
+	return notQuarantined
+}
+
+// ReloadConfig : Re-reads the config file and applies its volume changes without restarting.
+// The last-known-good remote config, if any, is merged over the file's, so its volumes and settings are kept.
+// History and error counts are kept for volumes that are still configured. An invalid config is logged and ignored.
+// appRuntime : *runtime.Runtime The runtime whose configuration is updated.
+// eventLog : runtime.EventLog The log of events.
+// errorLog : map[string]int The count of errors.
+func ReloadConfig(appRuntime *runtime.Runtime, eventLog runtime.EventLog, errorLog map[string]int) {
+	l.Log(logger.LogInfo, "Reloading configuration", map[string]interface{}{
+		"configFile": configFile,
+	})
+
+	desired, err := configutil.LoadConfig(configFile)
+	if err != nil {
+		l.Log(logger.LogError, "Failed to reload config, continuing with the current config", map[string]interface{}{
+			"configFile": configFile,
+			"error":      err,
+		})
+		return
+	}
+	localConfig = desired
+	var remote *runtime.Config
+	if remoteSource != nil {
+		remote = remoteSource.LastKnownGood()
+	}
+	ApplyConfig(appRuntime, mergedConfig(remote), eventLog, errorLog)
+}
+
+// IsThresholdExceeded : Checks if the disk utilisation of volume state is above the volume's resize threshold and prints a message.
 // volumeState : *runtime.EBSVolumeState The state of the volume.
-// resizeThreshold : float64 The threshold to resize.
+// volume : runtime.EBSVolumeConfig The configuration of the volume, including its threshold settings.
 // Returns a boolean value indicating if the threshold has been exceeded.
-func IsThresholdExceeded(volumeState *runtime.EBSVolumeState, resizeThreshold float64) bool {
-	resizeThresholdGB := volumeState.LocalDiskSizeGB * (resizeThreshold / 100.0)
+func IsThresholdExceeded(volumeState *runtime.EBSVolumeState, volume runtime.EBSVolumeConfig) bool {
+	// A zero size means the filesystem could not be measured, so there is no percentage to compare against
+	if volumeState.LocalDiskSizeGiB <= 0 {
+		l.Log(logger.LogWarning, "Local disk size is zero, skipping threshold check", map[string]interface{}{
+			"VolumeID":          volumeState.AWSVolumeID,
+			"Local Mount Point": volumeState.LocalMountPoint,
+		})
+		return false
+	}
+
+	capacityGiB := monitor.CapacityGiB(*volumeState, volume.ThresholdBasis)
+	resizeThresholdGiB := monitor.ResizeThresholdGiB(*volumeState, volume)
+	resizeThreshold := resizeThresholdGiB / capacityGiB * 100
 
 	var (
 		plusSeparator = strings.Repeat("+", 25)
@@ -349,35 +1277,65 @@ func IsThresholdExceeded(volumeState *runtime.EBSVolumeState, resizeThreshold fl
 		AWS Device Name: %s
 		Local Mount Point: %s
 		%s
-		AWS Device Size (GB): %v
-		Local Disk Size (GB): %0.2f
+		AWS Device Size: %s
+		Local Disk Size: %s
+		Reserved Space: %s
+		Threshold Basis: %s
 		%s
-		Current Used Space (GB): %0.2f
-		Resize Threshold (GB): %0.2f
+		Current Used Space: %s
+		Resize Threshold: %s
+		Free Space: %s
+		Min Free Space: %s
 		%s
 		Current Used Space(%%): %0.2f
 		Resize Threshold(%%): %0.2f
+		%s
+		Inodes Used(%%): %0.2f
+		Inode Resize Threshold(%%): %d
 	`
 
 	formattedVolumeInfo := fmt.Sprintf(volumeInfo,
 		plusSeparator, volumeState.AWSDeviceName, plusSeparator,
 		volumeState.AWSVolumeID, volumeState.AWSDeviceName, volumeState.LocalMountPoint, dashSeparator,
-		volumeState.AWSDeviceSizeGB, volumeState.LocalDiskSizeGB, dashSeparator,
-		volumeState.UsedSpaceGB, resizeThresholdGB, dashSeparator,
-		(volumeState.UsedSpaceGB/volumeState.LocalDiskSizeGB)*100, resizeThreshold,
+		runtime.FormatSize(float64(volumeState.AWSDeviceSizeGiB)), runtime.FormatSize(volumeState.LocalDiskSizeGiB), runtime.FormatSize(volumeState.ReservedSpaceGiB), volume.ThresholdBasis, dashSeparator,
+		runtime.FormatSize(volumeState.UsedSpaceGiB), runtime.FormatSize(resizeThresholdGiB), runtime.FormatSize(volumeState.LocalDiskSizeGiB-volumeState.UsedSpaceGiB), runtime.FormatSize(float64(volume.MinFreeGB)), dashSeparator,
+		(volumeState.UsedSpaceGiB/capacityGiB)*100, resizeThreshold,
+		dashSeparator, volumeState.InodesUsedPercent, volume.InodeResizeThreshold,
 	)
 
 	DebugPrint(debugMode, formattedVolumeInfo)
 
-	if volumeState.UsedSpaceGB > resizeThresholdGB {
-		// Calculate exceeded value
-		exceededBy := volumeState.UsedSpaceGB - resizeThresholdGB
-		DebugPrint(debugMode, fmt.Sprintf("\n%s\nExceeded threshold by %.2f GB", dashSeparator, exceededBy))
-		return true
-	} else {
+	rule := monitor.ExceededRule(*volumeState, volume)
+	switch rule {
+	case "":
 		DebugPrint(debugMode, fmt.Sprintf("\n%s\nBelow threshold", dashSeparator))
 		return false
+	case monitor.RuleInodeResizeThreshold:
+		// Growing the filesystem adds inodes only in proportion to the space added, so a resize may
+		// not relieve inode exhaustion
+		l.Log(logger.LogWarning, "Resizing volume because of inode exhaustion, which a resize may not relieve", map[string]interface{}{
+			"VolumeID":               volumeState.AWSVolumeID,
+			"Local Mount Point":      volumeState.LocalMountPoint,
+			"Rule":                   rule,
+			"Inodes Used (%)":        volumeState.InodesUsedPercent,
+			"Inode Resize Threshold": volume.InodeResizeThreshold,
+		})
+		DebugPrint(debugMode, fmt.Sprintf("\n%s\nExceeded inode threshold by %.2f%%", dashSeparator, volumeState.InodesUsedPercent-float64(volume.InodeResizeThreshold)))
+		return true
 	}
+
+	l.Log(logger.LogInfo, "Resize threshold exceeded", map[string]interface{}{
+		"VolumeID":          volumeState.AWSVolumeID,
+		"Local Mount Point": volumeState.LocalMountPoint,
+		"Rule":              rule,
+		"Used Space":        runtime.FormatSize(volumeState.UsedSpaceGiB),
+		"Free Space":        runtime.FormatSize(volumeState.LocalDiskSizeGiB - volumeState.UsedSpaceGiB),
+		"Resize Threshold":  runtime.FormatSize(resizeThresholdGiB),
+	})
+	// Calculate exceeded value
+	exceededBy := volumeState.UsedSpaceGiB - resizeThresholdGiB
+	DebugPrint(debugMode, fmt.Sprintf("\n%s\nExceeded %s threshold by %s", dashSeparator, rule, runtime.FormatSize(exceededBy)))
+	return true
 }
 
 // MonitorVolume : Monitors the volume and checks the state of it.
@@ -394,12 +1352,382 @@ func MonitorVolume(monitoredVolume runtime.EBSVolumeConfig, eventLog *runtime.Ev
 	return volumeState, err
 }
 
-// PruneAndSleep : Prunes stale events from the log and sleeps for check interval.
+// CheckForLateCheck : Records a volume check and alerts if the gap since its previous check
+// exceeded the check interval by more than the configured margin, indicating the monitor is overloaded or stuck.
+// appRuntime : *runtime.Runtime The runtime tracking last check times.
+// volume : runtime.EBSVolumeConfig The volume being checked.
+// checkTime : time.Time The time of this check.
+func CheckForLateCheck(appRuntime *runtime.Runtime, volume runtime.EBSVolumeConfig, checkTime time.Time) {
+	gap := appRuntime.MarkChecked(volume.AWSVolumeID, checkTime)
+
+	marginSeconds := appRuntime.Configuration.LateCheckMarginSeconds
+	if marginSeconds <= 0 || gap == 0 {
+		return
+	}
+
+	interval := appRuntime.Configuration.CheckInterval(volume)
+	if gap > interval+time.Duration(marginSeconds)*time.Second {
+		l.Log(logger.LogWarning, "Volume was not checked within its check interval", map[string]interface{}{
+			"VolumeID":       volume.AWSVolumeID,
+			"Check Interval": interval,
+			"Actual Gap":     gap.Round(time.Second),
+			"Margin":         time.Duration(marginSeconds) * time.Second,
+		})
+	}
+}
+
+// ApplyCommandLineOverrides : Replaces the settings of a loaded config that were also given on the command line
+// config : *runtime.Config : The loaded config.
+func ApplyCommandLineOverrides(config *runtime.Config) {
+	if logLevel != "" {
+		config.LogLevel = logLevel
+	}
+	if logFormat != "" {
+		config.LogFormat = logFormat
+	}
+	if logFilePath != "" {
+		config.LogFilePath = logFilePath
+	}
+}
+
+// ApplySettings : Applies a config's logging, notification, AWS and host command settings to the packages
+// that read them, at startup and when the config is reloaded. Settings read from the runtime's configuration,
+// e.g. errorThreshold, take effect once it is replaced.
+// config : runtime.Config : The config, with the command-line overrides applied.
+// Returns: error : An error if a log or notification setting is invalid, in which case nothing is applied.
+func ApplySettings(config runtime.Config) error {
+	// Check the settings that can be rejected first, so an invalid config changes nothing
+	if config.LogLevel != "" {
+		if _, err := logger.ParseLevel(config.LogLevel); err != nil {
+			return fmt.Errorf("invalid logLevel. error: %w", err)
+		}
+	}
+	if err := logger.ValidateFormat(config.LogFormat); err != nil {
+		return fmt.Errorf("invalid logFormat. error: %w", err)
+	}
+	if err := logger.ValidateTarget(config.LogTarget); err != nil {
+		return fmt.Errorf("invalid logTarget. error: %w", err)
+	}
+	if err := logger.ValidateNotificationChannels(config.NotificationChannels, config.SlackWebhookURL); err != nil {
+		return fmt.Errorf("invalid notificationChannels. error: %w", err)
+	}
+
+	// Write log entries at or above the configured level, in the configured format, to the configured target
+	ApplyLogLevel(config.LogLevel)
+	logger.SetFormat(config.LogFormat)
+	logger.SetTarget(config.LogTarget)
+
+	// Also write logs to a rotating file, if configured
+	if err := logger.SetLogFile(config.LogFilePath, config.LogFileMaxSizeMB, config.LogFileMaxBackups); err != nil {
+		l.Log(logger.LogError, "Failed to close the previous log file", map[string]interface{}{
+			"error": err,
+		})
+	}
+
+	// Publish SNS alerts to the configured topic, if enabled
+	logger.ConfigureSNS(config.SNSTopicARN, config.SNSRegion)
+	logger.SetSNSEnabled(config.EnableSNS)
+	aws.LoadEBSVersions(version, config.CheckAptVersions)
+	ApplyNotificationLevel(config.NotificationLevel)
+	logger.SetAlertCooldown(time.Duration(config.AlertCooldownSeconds) * time.Second)
+
+	// Send alerts to the configured notification channels, coalescing alerts raised close together if configured
+	logger.SetNotificationChannels(config.NotificationChannels, config.SlackWebhookURL)
+	ApplyNotificationBatch(config.NotificationBatch)
+
+	// Retry throttled or failed EC2 calls
+	aws.SetMaxRetries(config.MaxRetries)
+	filesystem.SetCommandTimeouts(time.Duration(config.CommandTimeoutSeconds)*time.Second, time.Duration(config.ResizeCommandTimeoutSeconds)*time.Second)
+	filesystem.SetResizeBinaries(config.ResizeCommands)
+	resize.SetVolumePrices(config.VolumePrices)
+	runtime.SetDisplayUnit(config.DisplayUnit)
+
+	// Assume the configured role for AWS calls not tied to a volume
+	aws.SetAssumeRoleARN(config.AssumeRoleARN)
+	return nil
+}
+
+// defaultImmediateLevels : Levels sent without waiting for the batch window when none are configured
+var defaultImmediateLevels = []logger.Level{logger.LogError, logger.LogFatal}
+
+// ApplyNotificationLevel : Configures the lowest log level sent as a notification
+// name : string : Level name, already validated. Empty selects the default of error.
+func ApplyNotificationLevel(name string) {
+	if name == "" {
+		logger.SetNotificationLevel(logger.LogError)
+		return
+	}
+	if level, err := logger.ParseLevel(name); err == nil {
+		logger.SetNotificationLevel(level)
+	}
+}
+
+// ApplyLogLevel : Configures the lowest level written to the logs, exiting if the level is not recognised
+// name : string : Level name from --log-level or the config. Empty selects the default of info.
+func ApplyLogLevel(name string) {
+	if name == "" {
+		logger.SetLevel(logger.LogInfo)
+		return
+	}
+	level, err := logger.ParseLevel(name)
+	if err != nil {
+		l.Log(logger.LogFatal, "Invalid log level", map[string]interface{}{
+			"logLevel": name,
+			"error":    err,
+		})
+		Exit(1)
+	}
+	logger.SetLevel(level)
+}
+
+// ApplyNotificationBatch : Configures the logger to coalesce alerts into digests
+// batch : runtime.NotificationBatch : Batch settings, already validated
+func ApplyNotificationBatch(batch runtime.NotificationBatch) {
+	immediate := defaultImmediateLevels
+	if len(batch.ImmediateLevels) > 0 {
+		immediate = nil
+		for _, name := range batch.ImmediateLevels {
+			level, err := logger.ParseLevel(name)
+			if err != nil {
+				continue
+			}
+			immediate = append(immediate, level)
+		}
+	}
+	logger.SetNotificationBatch(time.Duration(batch.WindowSeconds)*time.Second, batch.MaxCount, immediate)
+}
+
+// HandleUnmounted : Alerts when a volume becomes unmounted or is mounted again, and decides whether to skip it.
+// Unless unmountedAction is "error", an unmounted volume is skipped rather than counted as a failed check.
+// appRuntime : *runtime.Runtime : Runtime tracking which volumes are unmounted
+// eventLog : runtime.EventLog : Event log to record the skip in
+// volume : runtime.EBSVolumeConfig : The volume being checked
+// volumeState : runtime.EBSVolumeState : State returned by monitor.GetVolumeState
+// err : error : Error returned by monitor.GetVolumeState
+// returns : bool : True if the volume should be skipped this cycle
+func HandleUnmounted(appRuntime *runtime.Runtime, eventLog runtime.EventLog, volume runtime.EBSVolumeConfig, volumeState runtime.EBSVolumeState, err error) bool {
+	unmounted := errors.Is(err, filesystem.ErrNotMounted)
+	if appRuntime.SetMounted(volume.AWSVolumeID, !unmounted) {
+		if unmounted {
+			l.Log(logger.LogWarning, "Monitored volume is not mounted", map[string]interface{}{
+				"VolumeID": volume.AWSVolumeID,
+				"Error":    err,
+			})
+		} else {
+			l.Log(logger.LogInfo, "Monitored volume is mounted again", map[string]interface{}{
+				"VolumeID": volume.AWSVolumeID,
+			})
+		}
+	}
+
+	if !unmounted || appRuntime.Configuration.UnmountedAction == runtime.UnmountedActionError {
+		return false
+	}
+	RecordSkip(appRuntime, eventLog, volumeState, runtime.SkipReasonNotMounted)
+	return true
+}
+
+// IsVolumeNotYetVisible : Reports whether a volume is attached in AWS but its device hasn't appeared on the host yet.
+// This is transient, e.g. during boot, so the check is retried next cycle rather than counted towards the volume's removal.
+// A volume that isn't attached in AWS either still counts, so a detached volume is eventually dropped.
+// The attachment check is reused for attachmentCheckInterval, rather than calling DescribeVolumes every cycle.
+// volume : runtime.EBSVolumeConfig : The volume being checked
+// err : error : Error returned by monitor.GetVolumeState
+// returns : bool : True if the volume should be skipped this cycle
+func IsVolumeNotYetVisible(volume runtime.EBSVolumeConfig, err error) bool {
+	if !errors.Is(err, filesystem.ErrVolumeNotMounted) {
+		return false
+	}
+	if attachErr := cachedVolumeAttached(volume, runtime.Now()); attachErr != nil {
+		l.Log(logger.LogDebug, "Volume not found on the host or attached in AWS", map[string]interface{}{
+			"VolumeID": volume.AWSVolumeID,
+			"Error":    attachErr,
+		})
+		return false
+	}
+	l.Log(logger.LogDebug, "Volume is attached but not visible on the host yet, retrying next cycle", map[string]interface{}{
+		"VolumeID": volume.AWSVolumeID,
+		"Error":    err,
+	})
+	return true
+}
+
+// cachedVolumeAttached : Confirms a volume is attached in AWS, reusing the last result for attachmentCheckInterval.
+// volume : runtime.EBSVolumeConfig : The volume being checked
+// now : time.Time : The current time
+// returns : error : The error from the attachment check, nil if the volume is attached
+func cachedVolumeAttached(volume runtime.EBSVolumeConfig, now time.Time) error {
+	attachmentChecks.Lock()
+	last, ok := attachmentChecks.byVolume[volume.AWSVolumeID]
+	attachmentChecks.Unlock()
+	if ok && now.Sub(last.checkedAt) < attachmentCheckInterval {
+		return last.err
+	}
+
+	// Don't hold the lock during the EC2 call, so other volumes' checks aren't held up
+	err := checkVolumeAttached(volume)
+	attachmentChecks.Lock()
+	attachmentChecks.byVolume[volume.AWSVolumeID] = attachmentCheck{checkedAt: now, err: err}
+	attachmentChecks.Unlock()
+	return err
+}
+
+// RunOnceExitCode : Returns the exit status of a --run-once pass, failing if any volume's check failed.
+// Transient errors, e.g. AWS throttling, fail the pass too, as the volume wasn't checked, even though they
+// don't count towards its removal.
+// errorLog : map[string]int The count of errors from the pass.
+// transient : map[string]bool The volumes whose check hit a transient error.
+// Returns: int 0 if every check succeeded, otherwise 1.
+func RunOnceExitCode(errorLog map[string]int, transient map[string]bool) int {
+	if len(transient) > 0 {
+		return 1
+	}
+	for _, count := range errorLog {
+		if count > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// MaxUptimeReached : Reports whether the process has run for its configured maximum uptime
+// start : time.Time : When the process started
+// now : time.Time : The current time
+// maxUptimeHours : int : Maximum uptime in hours, 0 is unlimited
+// returns : bool : True if the process should exit for a restart
+func MaxUptimeReached(start, now time.Time, maxUptimeHours int) bool {
+	if maxUptimeHours <= 0 {
+		return false
+	}
+	return now.Sub(start) >= time.Duration(maxUptimeHours)*time.Hour
+}
+
+// RecordSkip : Records a skipped resize in the event log when recordSkippedResizes is enabled
+// appRuntime : *runtime.Runtime : Runtime holding the configuration
+// eventLog : runtime.EventLog : Event log to record the skip in
+// volumeState : runtime.EBSVolumeState : State of the volume when the resize was skipped
+// reason : string : Why the resize was skipped
+func RecordSkip(appRuntime *runtime.Runtime, eventLog runtime.EventLog, volumeState runtime.EBSVolumeState, reason string) {
+	DebugPrint(appRuntime.DebugMode, fmt.Sprintf("Resize skipped for volume %s: %s", volumeState.AWSVolumeID, reason))
+	if !appRuntime.Configuration.RecordSkippedResizes {
+		return
+	}
+	event := runtime.CreateResizeSkippedEvent(volumeState, reason)
+	if fields, err := eventLog.AddEvent(volumeState.AWSVolumeID, event); err != nil {
+		l.Log(logger.LogError, fmt.Sprint(err), fields)
+	}
+}
+
+// ResizeCooldownRemaining : Returns how long until a volume may be resized again under resizeCooldownSeconds.
+// appRuntime : *runtime.Runtime The runtime holding the configured cooldown.
+// eventLog : runtime.EventLog The log of events, holding the volume's previous resizes.
+// volumeID : string The volume to check.
+// now : time.Time The current time.
+// Returns: time.Duration The time left in the cooldown, 0 if the volume may be resized.
+func ResizeCooldownRemaining(appRuntime *runtime.Runtime, eventLog runtime.EventLog, volumeID string, now time.Time) time.Duration {
+	cooldown := time.Duration(appRuntime.Configuration.ResizeCooldownSeconds) * time.Second
+	if cooldown <= 0 {
+		return 0
+	}
+	lastResize, resized := eventLog.LastResizeTime(volumeID)
+	if !resized {
+		return 0
+	}
+	if remaining := lastResize.Add(cooldown).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// NextCheckDelay : Returns how long until a volume's next check, its check interval varied by up to checkIntervalJitterPercent
+// either way, so hosts sharing a config don't call AWS in lockstep.
+// config : runtime.Config The current configuration.
+// volume : runtime.EBSVolumeConfig The volume just checked.
+// random : float64 Random value in [0, 1), 0.5 gives the interval unchanged.
+// Returns: time.Duration
+func NextCheckDelay(config runtime.Config, volume runtime.EBSVolumeConfig, random float64) time.Duration {
+	interval := config.CheckInterval(volume)
+	jitter := float64(config.CheckIntervalJitterPercent) / 100 * (2*random - 1)
+	return time.Duration(float64(interval) * (1 + jitter))
+}
+
+// CheckSleep : Returns how long to sleep until the next volume is due a check. The sleep is at most the top-level
+// check interval, so remote config polls and quarantine retries still run when every volume has a longer interval.
+// appRuntime : *runtime.Runtime The runtime holding the volumes' schedules.
+// now : time.Time The current time.
+// Returns: time.Duration
+func CheckSleep(appRuntime *runtime.Runtime, now time.Time) time.Duration {
+	sleep := time.Duration(appRuntime.Configuration.CheckIntervalSeconds) * time.Second
+	if next, ok := appRuntime.NextCheckDue(); ok && next.Sub(now) < sleep {
+		sleep = next.Sub(now)
+	}
+	if sleep < 0 {
+		return 0
+	}
+	return sleep
+}
+
+// PruneAndSleep : Prunes stale events from the log, saves it, and sleeps until the next check.
+// Shuts down instead if a shutdown is requested while sleeping, and wakes early if a reload is requested.
+// A dump requested while sleeping is printed without cutting the sleep short.
+// appRuntime : *runtime.Runtime The runtime to dump.
 // eventLog : *runtime.EventLog The log of events.
-// checkIntervalSeconds : int The check interval in seconds.
-func PruneAndSleep(eventLog *runtime.EventLog, checkIntervalSeconds int) {
-	eventLog.PruneStaleEvents()
-	time.Sleep(time.Duration(checkIntervalSeconds) * time.Second)
+// errorLog : map[string]int The count of errors.
+// sleep : time.Duration How long to sleep, from CheckSleep.
+// Returns: bool True if the sleep was cut short by a reload request.
+func PruneAndSleep(appRuntime *runtime.Runtime, eventLog *runtime.EventLog, errorLog map[string]int, sleep time.Duration) bool {
+	eventLog.PruneStaleEvents(appRuntime.Configuration.EventRetention())
+	SaveEventLog(*eventLog)
+
+	wake := time.After(sleep)
+	for {
+		select {
+		case <-wake:
+			return false
+		case <-reloadSignals:
+			return true
+		case <-dumpSignals:
+			DumpRuntimeJSON(appRuntime, *eventLog, errorLog)
+		case sig := <-shutdownSignals:
+			Shutdown(*eventLog, sig)
+			return false
+		}
+	}
+}
+
+// RestoreEventLog : Loads the saved event log into eventLog, for volumes that are still configured
+// A log that can't be read is logged and ignored, starting with empty history.
+// eventLog : runtime.EventLog The initialised event log to restore into.
+// path : string The saved event log file.
+// retention : time.Duration How long events are kept, older saved events are dropped.
+func RestoreEventLog(eventLog runtime.EventLog, path string, retention time.Duration) {
+	saved, err := runtime.LoadEventLog(path, retention)
+	if err != nil {
+		l.Log(logger.LogWarning, "Failed to restore the saved event log, starting with empty history", map[string]interface{}{
+			"eventLogFile": path,
+			"error":        err,
+		})
+		return
+	}
+	for volumeID, events := range saved {
+		if _, monitored := eventLog[volumeID]; monitored {
+			eventLog[volumeID] = events
+		}
+	}
+}
+
+// SaveEventLog : Saves the event log to the --event-log-file, if set
+// eventLog : runtime.EventLog The log of events.
+func SaveEventLog(eventLog runtime.EventLog) {
+	if eventLogFile == "" {
+		return
+	}
+	if err := eventLog.SaveToFile(eventLogFile); err != nil {
+		l.Log(logger.LogError, "Failed to save the event log", map[string]interface{}{
+			"eventLogFile": eventLogFile,
+			"error":        err,
+		})
+	}
 }
 
 // DebugPrint : used to provide conditional printing of debug messages
@@ -416,3 +1744,58 @@ func DebugPrint(debugMode bool, message string) {
 		fmt.Printf("[DEBUG] %s %s:%d - %s\n", fn, functionName, line, message)
 	}
 }
+
+// shutdownSignals : Receives SIGINT and SIGTERM. Checked before starting each volume's check and while sleeping, so a resize is never interrupted.
+var shutdownSignals = make(chan os.Signal, 1)
+
+// reloadSignals : Receives SIGHUP, requesting the config file be reloaded.
+var reloadSignals = make(chan os.Signal, 1)
+
+// dumpSignals : Receives SIGUSR1, requesting the runtime state be dumped as JSON. Handled while sleeping between checks.
+var dumpSignals = make(chan os.Signal, 1)
+
+// HandleDumpSignals : Requests a JSON dump of the runtime state on SIGUSR1
+func HandleDumpSignals() {
+	signal.Notify(dumpSignals, syscall.SIGUSR1)
+}
+
+// HandleReloadSignals : Requests a config reload on SIGHUP
+func HandleReloadSignals() {
+	signal.Notify(reloadSignals, syscall.SIGHUP)
+}
+
+// ReloadRequested : Reports whether a reload signal has been received, without blocking
+// Returns: bool True if a reload was requested.
+func ReloadRequested() bool {
+	select {
+	case <-reloadSignals:
+		return true
+	default:
+		return false
+	}
+}
+
+// HandleShutdownSignals : Requests a clean shutdown on SIGINT or SIGTERM
+func HandleShutdownSignals() {
+	signal.Notify(shutdownSignals, syscall.SIGINT, syscall.SIGTERM)
+}
+
+// Shutdown : Saves the event log and exits cleanly after a shutdown signal
+// eventLog : runtime.EventLog The log of events.
+// sig : os.Signal The signal received.
+func Shutdown(eventLog runtime.EventLog, sig os.Signal) {
+	DebugPrint(debugMode, fmt.Sprintf("Received %v, shutting down...", sig))
+	SaveEventLog(eventLog)
+	Exit(0)
+}
+
+// Exit : Stops the metrics and health servers and the state socket and delivers any queued notifications, closes the log file, then exits with the given status code
+// code : int - the process exit status
+func Exit(code int) {
+	metricsRegistry.Shutdown()
+	healthServer.Shutdown()
+	stateSocket.Shutdown()
+	logger.FlushNotifications(notifyFlushTimeout)
+	logger.CloseLogFile()
+	os.Exit(code)
+}
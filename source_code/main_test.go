@@ -0,0 +1,719 @@
+package main
+
+import (
+	"bytes"
+	"ebs-monitor/aws"
+	"ebs-monitor/aws/awstest"
+	"ebs-monitor/configutil"
+	"ebs-monitor/filesystem"
+	"ebs-monitor/monitor"
+	"ebs-monitor/runtime"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// useFakeAWS serves EC2 calls from a fake with vol-1 and vol-2 attached to the local instance i-1.
+func useFakeAWS(t *testing.T) {
+	t.Helper()
+	aws.SetEC2Client(&awstest.FakeEC2{
+		Volumes: []*ec2.Volume{
+			awstest.NewFakeVolume("vol-1", "i-1", "/dev/sdf", 100),
+			awstest.NewFakeVolume("vol-2", "i-1", "/dev/sdg", 100),
+		},
+		Instances: []*ec2.Instance{awstest.NewFakeInstance("i-1", map[string]string{"/dev/sdf": "vol-1", "/dev/sdg": "vol-2"})},
+	})
+	aws.SetInstanceMetadata(awstest.FakeMetadata{ID: "i-1", RegionID: "us-east-1"})
+	t.Cleanup(func() {
+		aws.SetEC2Client(nil)
+		aws.SetInstanceMetadata(nil)
+		ApplySettings(runtime.Config{LogTarget: "stdout"})
+	})
+}
+
+// testVolume returns a volume attached to the fake instance, whose partition gives its mount point without probing the host.
+func testVolume(volumeID, deviceName string) runtime.EBSVolumeConfig {
+	return runtime.EBSVolumeConfig{
+		AWSVolumeID:     volumeID,
+		AWSDeviceName:   deviceName,
+		AWSRegion:       "us-east-1",
+		ResizeThreshold: 80,
+		IncrementSizeGB: 10,
+		Partitions:      []runtime.PartitionConfig{{Partition: 1, MountPoint: "/data-" + volumeID, FilesystemType: "ext4"}},
+	}
+}
+
+// TestReloadConfig tests that a reload applies the volumes and global settings of a valid config file,
+// keeping the history of volumes that remain, and leaves everything as it was for an invalid one.
+func TestReloadConfig(t *testing.T) {
+	useFakeAWS(t)
+	volumeYAML := `volumes:
+  - awsVolumeID: vol-1
+    awsDeviceName: /dev/sdf
+    awsRegion: us-east-1
+    resizeThreshold: 80
+    incrementSizeGB: 10
+    partitions: [{partition: 1, mountPoint: /data-vol-1, filesystemType: ext4}]
+  - awsVolumeID: vol-2
+    awsDeviceName: /dev/sdg
+    awsRegion: us-east-1
+    resizeThreshold: 80
+    incrementSizeGB: 10
+    partitions: [{partition: 1, mountPoint: /data-vol-2, filesystemType: ext4}]
+`
+	tests := []struct {
+		name             string
+		settings         string
+		wantVolumes      int
+		wantInterval     int
+		wantThreshold    int
+		wantDisplayedGiB string
+	}{
+		{
+			name:             "valid config",
+			settings:         "checkIntervalSeconds: 30\nerrorThreshold: 3\ndisplayUnit: GB\nlogTarget: stdout\nremoteConfig: {url: https://config.example.com/config.yaml}\n",
+			wantVolumes:      2,
+			wantInterval:     30,
+			wantThreshold:    3,
+			wantDisplayedGiB: "GB",
+		},
+		{
+			name:             "invalid config",
+			settings:         "checkIntervalSeconds: 30\nerrorThreshold: 3\nlogFormat: xml\n",
+			wantVolumes:      1,
+			wantInterval:     60,
+			wantThreshold:    5,
+			wantDisplayedGiB: "GiB",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runtime.SetDisplayUnit("")
+			defer runtime.SetDisplayUnit("")
+			configFile = filepath.Join(t.TempDir(), "config.yaml")
+			defer func() { configFile = "" }()
+			if err := os.WriteFile(configFile, []byte(volumeYAML+tt.settings), 0o600); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			appRuntime := runtime.InitialiseRuntime()
+			appRuntime.Configuration = runtime.Config{Volumes: []runtime.EBSVolumeConfig{testVolume("vol-1", "/dev/sdf")}, CheckIntervalSeconds: 60, ErrorThreshold: 5}
+			eventLog := runtime.EventLog{"vol-1": {{ExecutionSuccess: true}}}
+			errorLog := map[string]int{"vol-1": 2}
+			ReloadConfig(appRuntime, eventLog, errorLog)
+
+			config := appRuntime.Configuration
+			if len(config.Volumes) != tt.wantVolumes || config.CheckIntervalSeconds != tt.wantInterval || config.ErrorThreshold != tt.wantThreshold {
+				t.Errorf("ReloadConfig() left %d volumes, interval %d and errorThreshold %d, want %d, %d and %d",
+					len(config.Volumes), config.CheckIntervalSeconds, config.ErrorThreshold, tt.wantVolumes, tt.wantInterval, tt.wantThreshold)
+			}
+			if config.RemoteConfig.URL != "" {
+				t.Errorf("ReloadConfig() set remoteConfig to %q, want it kept until a restart", config.RemoteConfig.URL)
+			}
+			if got := runtime.FormatSize(1); !strings.HasSuffix(got, " "+tt.wantDisplayedGiB) {
+				t.Errorf("FormatSize(1) = %q after ReloadConfig(), want it shown in %s", got, tt.wantDisplayedGiB)
+			}
+			if len(eventLog["vol-1"]) != 1 || errorLog["vol-1"] != 2 {
+				t.Errorf("ReloadConfig() history of vol-1 = %v and %d errors, want it kept", eventLog["vol-1"], errorLog["vol-1"])
+			}
+		})
+	}
+}
+
+// TestReloadConfigKeepsRemote tests that reloading the config file keeps the remote config's volumes and settings,
+// and that polling the remote config keeps the file's, rather than each replacing the other.
+func TestReloadConfigKeepsRemote(t *testing.T) {
+	useFakeAWS(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`errorThreshold: 3
+volumes:
+  - awsVolumeID: vol-2
+    awsDeviceName: /dev/sdg
+    awsRegion: us-east-1
+    resizeThreshold: 80
+    incrementSizeGB: 10
+    partitions: [{partition: 1, mountPoint: /data-vol-2, filesystemType: ext4}]
+`))
+	}))
+	defer server.Close()
+
+	configFile = filepath.Join(t.TempDir(), "config.yaml")
+	fileYAML := `checkIntervalSeconds: 60
+logTarget: stdout
+volumes:
+  - awsVolumeID: vol-1
+    awsDeviceName: /dev/sdf
+    awsRegion: us-east-1
+    resizeThreshold: 80
+    incrementSizeGB: 10
+    partitions: [{partition: 1, mountPoint: /data-vol-1, filesystemType: ext4}]
+`
+	if err := os.WriteFile(configFile, []byte(fileYAML), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	var err error
+	if localConfig, err = configutil.LoadConfig(configFile); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	remoteSource = configutil.NewRemoteSource(server.URL)
+	defer func() { configFile, localConfig, remoteSource = "", nil, nil }()
+
+	appRuntime := runtime.InitialiseRuntime()
+	appRuntime.Configuration = *localConfig
+	eventLog := runtime.EventLog{"vol-1": {}}
+	errorLog := map[string]int{}
+	check := func(step string) {
+		t.Helper()
+		config := appRuntime.Configuration
+		if len(config.Volumes) != 2 || config.CheckIntervalSeconds != 60 || config.ErrorThreshold != 3 {
+			t.Errorf("%s left %d volumes, interval %d and errorThreshold %d, want 2, 60 and 3",
+				step, len(config.Volumes), config.CheckIntervalSeconds, config.ErrorThreshold)
+		}
+	}
+
+	PollRemoteConfig(remoteSource, appRuntime, eventLog, errorLog)
+	check("PollRemoteConfig()")
+	ReloadConfig(appRuntime, eventLog, errorLog)
+	check("ReloadConfig()")
+	// The remote config is unchanged, so the next poll changes nothing either
+	PollRemoteConfig(remoteSource, appRuntime, eventLog, errorLog)
+	check("PollRemoteConfig() after a reload")
+}
+
+// TestFetchInitialRemoteConfig tests that the first remote config fetch is retried while there is nothing to monitor,
+// rather than starting with no volumes.
+func TestFetchInitialRemoteConfig(t *testing.T) {
+	useFakeAWS(t)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Write([]byte("volumes: []\n"))
+			return
+		}
+		w.Write([]byte(`volumes:
+  - awsVolumeID: vol-1
+    awsDeviceName: /dev/sdf
+    awsRegion: us-east-1
+    resizeThreshold: 80
+    incrementSizeGB: 10
+    partitions: [{partition: 1, mountPoint: /data-vol-1, filesystemType: ext4}]
+`))
+	}))
+	defer server.Close()
+
+	localConfig = &runtime.Config{CheckIntervalSeconds: 60}
+	defer func() { localConfig = nil }()
+	source := configutil.NewRemoteSource(server.URL)
+	appRuntime := runtime.InitialiseRuntime()
+	appRuntime.Configuration = *localConfig
+
+	FetchInitialRemoteConfig(source, appRuntime, runtime.EventLog{}, map[string]int{})
+
+	if requests != 2 {
+		t.Errorf("FetchInitialRemoteConfig() made %d requests, want 2", requests)
+	}
+	if len(appRuntime.Configuration.Volumes) != 1 || appRuntime.Configuration.Volumes[0].AWSVolumeID != "vol-1" {
+		t.Errorf("FetchInitialRemoteConfig() left volumes %v, want vol-1", appRuntime.Configuration.Volumes)
+	}
+}
+
+// TestApplyConfigSettings tests that invalid global settings are rejected without holding back volume changes,
+// and that settings given on the command line are kept.
+func TestApplyConfigSettings(t *testing.T) {
+	useFakeAWS(t)
+	logLevel = "debug"
+	defer func() { logLevel = "" }()
+
+	tests := []struct {
+		name          string
+		desired       runtime.Config
+		wantFormat    string
+		wantThreshold int
+	}{
+		{
+			name:          "valid settings",
+			desired:       runtime.Config{LogFormat: "json", LogTarget: "stdout", ErrorThreshold: 3},
+			wantFormat:    "json",
+			wantThreshold: 3,
+		},
+		{
+			name:          "invalid log format",
+			desired:       runtime.Config{LogFormat: "xml", LogTarget: "stdout", ErrorThreshold: 3},
+			wantFormat:    "",
+			wantThreshold: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appRuntime := runtime.InitialiseRuntime()
+			appRuntime.Configuration = runtime.Config{Volumes: []runtime.EBSVolumeConfig{testVolume("vol-1", "/dev/sdf")}, CheckIntervalSeconds: 60, ErrorThreshold: 5}
+			desired := tt.desired
+			desired.Volumes = []runtime.EBSVolumeConfig{testVolume("vol-1", "/dev/sdf"), testVolume("vol-2", "/dev/sdg")}
+			ApplyConfig(appRuntime, &desired, runtime.EventLog{}, map[string]int{})
+
+			config := appRuntime.Configuration
+			if len(config.Volumes) != 2 {
+				t.Errorf("ApplyConfig() left %d volumes, want 2", len(config.Volumes))
+			}
+			if config.LogFormat != tt.wantFormat || config.ErrorThreshold != tt.wantThreshold {
+				t.Errorf("ApplyConfig() logFormat = %q and errorThreshold = %d, want %q and %d", config.LogFormat, config.ErrorThreshold, tt.wantFormat, tt.wantThreshold)
+			}
+			if tt.wantThreshold == 3 && config.LogLevel != "debug" {
+				t.Errorf("ApplyConfig() logLevel = %q, want the command-line level kept", config.LogLevel)
+			}
+		})
+	}
+}
+
+// useFakeVolumeState replaces how CheckVolume gathers volume states for the duration of a test.
+func useFakeVolumeState(t *testing.T, fn func(volume runtime.EBSVolumeConfig, eventLog *runtime.EventLog) (runtime.EBSVolumeState, error)) {
+	t.Helper()
+	getVolumeState = fn
+	t.Cleanup(func() { getVolumeState = monitor.GetVolumeState })
+}
+
+// testVolumes returns n volumes named vol-0, vol-1 and so on.
+func testVolumes(n int) []runtime.EBSVolumeConfig {
+	volumes := make([]runtime.EBSVolumeConfig, 0, n)
+	for i := 0; i < n; i++ {
+		volumes = append(volumes, runtime.EBSVolumeConfig{AWSVolumeID: fmt.Sprintf("vol-%d", i), ResizeThreshold: 80, IncrementSizeGB: 10})
+	}
+	return volumes
+}
+
+// TestCheckVolumesWorkers tests that no more than maxConcurrentChecks volumes are checked at once, 4 by default.
+func TestCheckVolumesWorkers(t *testing.T) {
+	tests := []struct {
+		name                string
+		maxConcurrentChecks int
+		wantWorkers         int
+	}{
+		{name: "default", maxConcurrentChecks: 0, wantWorkers: defaultMaxConcurrentChecks},
+		{name: "configured", maxConcurrentChecks: 2, wantWorkers: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var active, peak int32
+			full := make(chan struct{})
+			release := make(chan struct{})
+			var fullOnce sync.Once
+			useFakeVolumeState(t, func(volume runtime.EBSVolumeConfig, eventLog *runtime.EventLog) (runtime.EBSVolumeState, error) {
+				running := atomic.AddInt32(&active, 1)
+				defer atomic.AddInt32(&active, -1)
+				for {
+					seen := atomic.LoadInt32(&peak)
+					if running <= seen || atomic.CompareAndSwapInt32(&peak, seen, running) {
+						break
+					}
+				}
+				if int(running) == tt.wantWorkers {
+					fullOnce.Do(func() { close(full) })
+				}
+				<-release
+				return runtime.EBSVolumeState{AWSVolumeID: volume.AWSVolumeID}, errors.New("check failed")
+			})
+
+			appRuntime := runtime.InitialiseRuntime()
+			appRuntime.Configuration.MaxConcurrentChecks = tt.maxConcurrentChecks
+			done := make(chan struct{})
+			go func() {
+				CheckVolumes(appRuntime, testVolumes(3*tt.wantWorkers), runtime.EventLog{}, map[string]int{})
+				close(done)
+			}()
+
+			select {
+			case <-full:
+			case <-time.After(5 * time.Second):
+				close(release)
+				t.Fatalf("CheckVolumes() ran %d checks at once, want %d", atomic.LoadInt32(&active), tt.wantWorkers)
+			}
+			// Give a worker beyond the limit time to start, if there was one
+			time.Sleep(10 * time.Millisecond)
+			close(release)
+			<-done
+			if got := int(atomic.LoadInt32(&peak)); got != tt.wantWorkers {
+				t.Errorf("CheckVolumes() ran up to %d checks at once, want %d", got, tt.wantWorkers)
+			}
+		})
+	}
+}
+
+// TestCheckVolumesMerge tests that each volume's events and error count are merged back into the shared logs,
+// and that volumes reaching the error threshold are returned for removal.
+func TestCheckVolumesMerge(t *testing.T) {
+	useFakeVolumeState(t, func(volume runtime.EBSVolumeConfig, eventLog *runtime.EventLog) (runtime.EBSVolumeState, error) {
+		return runtime.EBSVolumeState{AWSVolumeID: volume.AWSVolumeID}, errors.New("check failed")
+	})
+
+	appRuntime := runtime.InitialiseRuntime()
+	appRuntime.Configuration.ErrorThreshold = 3
+	volumes := testVolumes(8)
+	eventLog := runtime.EventLog{"vol-0": {{ExecutionSuccess: true}}}
+	errorLog := map[string]int{"vol-0": 2}
+	removed := CheckVolumes(appRuntime, volumes, eventLog, errorLog)
+
+	for _, volume := range volumes {
+		wantEvents, wantErrors := 1, 1
+		if volume.AWSVolumeID == "vol-0" {
+			wantEvents, wantErrors = 2, 3
+		}
+		if got := len(eventLog[volume.AWSVolumeID]); got != wantEvents {
+			t.Errorf("CheckVolumes() left %d events for %s, want %d", got, volume.AWSVolumeID, wantEvents)
+		}
+		if got := errorLog[volume.AWSVolumeID]; got != wantErrors {
+			t.Errorf("CheckVolumes() left %d errors for %s, want %d", got, volume.AWSVolumeID, wantErrors)
+		}
+	}
+	if _, ok := removed["vol-0"]; len(removed) != 1 || !ok {
+		t.Errorf("CheckVolumes() removed %v, want only vol-0", removed)
+	}
+}
+
+// TestCheckVolumePanic tests that a panic while checking a volume is recovered and counted as an error.
+func TestCheckVolumePanic(t *testing.T) {
+	useFakeVolumeState(t, func(volume runtime.EBSVolumeConfig, eventLog *runtime.EventLog) (runtime.EBSVolumeState, error) {
+		panic("unexpected state")
+	})
+
+	tests := []struct {
+		name        string
+		errorCount  int
+		wantRemoved bool
+	}{
+		{name: "below the error threshold", errorCount: 0, wantRemoved: false},
+		{name: "reaching the error threshold", errorCount: defaultErrorThreshold - 1, wantRemoved: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appRuntime := runtime.InitialiseRuntime()
+			errorLog := map[string]int{"vol-0": tt.errorCount}
+			removed := CheckVolumes(appRuntime, testVolumes(1), runtime.EventLog{}, errorLog)
+			if errorLog["vol-0"] != tt.errorCount+1 {
+				t.Errorf("CheckVolumes() error count = %d, want %d", errorLog["vol-0"], tt.errorCount+1)
+			}
+			if _, got := removed["vol-0"]; got != tt.wantRemoved {
+				t.Errorf("CheckVolumes() removed vol-0 = %v, want %v", got, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+// TestCheckVolumeCannotGrow tests that a volume whose new size isn't larger is skipped as at its maximum size
+// only when it has reached maxSizeGB, and otherwise as having no increment.
+func TestCheckVolumeCannotGrow(t *testing.T) {
+	useFakeVolumeState(t, func(volume runtime.EBSVolumeConfig, eventLog *runtime.EventLog) (runtime.EBSVolumeState, error) {
+		return runtime.EBSVolumeState{AWSVolumeID: volume.AWSVolumeID, LocalDiskSizeGiB: 10, UsedSpaceGiB: 9}, nil
+	})
+	aws.SetEC2Client(&awstest.FakeEC2{
+		Volumes:   []*ec2.Volume{awstest.NewFakeVolume("vol-1", "i-1", "/dev/sdf", 10)},
+		Instances: []*ec2.Instance{awstest.NewFakeInstance("i-1", map[string]string{"/dev/sdf": "vol-1"})},
+	})
+	aws.SetInstanceMetadata(awstest.FakeMetadata{ID: "i-1", RegionID: "us-east-1"})
+	t.Cleanup(func() {
+		aws.SetEC2Client(nil)
+		aws.SetInstanceMetadata(nil)
+	})
+
+	tests := []struct {
+		name       string
+		maxSizeGB  int
+		wantReason string
+	}{
+		{name: "at max size", maxSizeGB: 10, wantReason: runtime.SkipReasonMaxSize},
+		{name: "increment rounds to nothing", maxSizeGB: 0, wantReason: runtime.SkipReasonNoIncrement},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appRuntime := runtime.InitialiseRuntime()
+			appRuntime.Configuration.RecordSkippedResizes = true
+			volume := testVolume("vol-1", "/dev/sdf")
+			volume.IncrementSizeGB, volume.IncrementSizePercent, volume.MaxSizeGB = 0, 5, tt.maxSizeGB
+			eventLog := runtime.EventLog{}
+
+			CheckVolume(appRuntime, volume, eventLog, map[string]int{}, &sync.Mutex{})
+
+			events := eventLog["vol-1"]
+			if len(events) == 0 || events[len(events)-1].SkipReason != tt.wantReason {
+				t.Errorf("CheckVolume() recorded %+v, want a skip for %q", events, tt.wantReason)
+			}
+		})
+	}
+}
+
+// TestCheckVolumeTransient tests that a transient error is recorded without counting towards the volume's removal,
+// and cleared by the next check that doesn't hit one.
+func TestCheckVolumeTransient(t *testing.T) {
+	var stateErr error
+	useFakeVolumeState(t, func(volume runtime.EBSVolumeConfig, eventLog *runtime.EventLog) (runtime.EBSVolumeState, error) {
+		return runtime.EBSVolumeState{AWSVolumeID: volume.AWSVolumeID}, stateErr
+	})
+	appRuntime := runtime.InitialiseRuntime()
+	errorLog := map[string]int{}
+	volume := testVolumes(1)[0]
+
+	stateErr = &monitor.TransientError{Err: errors.New("throttled")}
+	CheckVolume(appRuntime, volume, runtime.EventLog{}, errorLog, &sync.Mutex{})
+	if !appRuntime.Transient["vol-0"] || errorLog["vol-0"] != 0 {
+		t.Errorf("CheckVolume() with a transient error left transient %v and %d errors, want true and 0", appRuntime.Transient["vol-0"], errorLog["vol-0"])
+	}
+
+	stateErr = nil
+	CheckVolume(appRuntime, volume, runtime.EventLog{}, errorLog, &sync.Mutex{})
+	if appRuntime.Transient["vol-0"] {
+		t.Errorf("CheckVolume() without an error left the volume marked transient")
+	}
+}
+
+// TestResizeCooldownRemaining tests the time left before a volume may be resized again.
+func TestResizeCooldownRemaining(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	resizedAt := func(start time.Time, success bool) runtime.EventLog {
+		return runtime.EventLog{"vol-1": {{ExecutionSuccess: success, VolumeAction: runtime.EBSVolumeResize{StartTime: start}}}}
+	}
+
+	tests := []struct {
+		name     string
+		cooldown int
+		eventLog runtime.EventLog
+		want     time.Duration
+	}{
+		{name: "cooldown disabled", cooldown: 0, eventLog: resizedAt(now.Add(-time.Minute), true), want: 0},
+		{name: "never resized", cooldown: 3600, eventLog: runtime.EventLog{}, want: 0},
+		{name: "within the cooldown", cooldown: 3600, eventLog: resizedAt(now.Add(-20*time.Minute), true), want: 40 * time.Minute},
+		{name: "cooldown passed", cooldown: 3600, eventLog: resizedAt(now.Add(-2*time.Hour), true), want: 0},
+		{name: "failed resize", cooldown: 3600, eventLog: resizedAt(now.Add(-20*time.Minute), false), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appRuntime := runtime.InitialiseRuntime()
+			appRuntime.Configuration.ResizeCooldownSeconds = tt.cooldown
+			if got := ResizeCooldownRemaining(appRuntime, tt.eventLog, "vol-1", now); got != tt.want {
+				t.Errorf("ResizeCooldownRemaining() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNextCheckDelay tests that the check interval is varied by up to the jitter percentage either way.
+func TestNextCheckDelay(t *testing.T) {
+	tests := []struct {
+		name          string
+		jitterPercent int
+		volume        runtime.EBSVolumeConfig
+		random        float64
+		want          time.Duration
+	}{
+		{name: "no jitter", jitterPercent: 0, random: 0, want: 100 * time.Second},
+		{name: "midpoint unchanged", jitterPercent: 10, random: 0.5, want: 100 * time.Second},
+		{name: "shortest", jitterPercent: 10, random: 0, want: 90 * time.Second},
+		{name: "longest", jitterPercent: 10, random: 0.75, want: 105 * time.Second},
+		{name: "volume interval", jitterPercent: 10, volume: runtime.EBSVolumeConfig{CheckIntervalSeconds: 20}, random: 0, want: 18 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := runtime.Config{CheckIntervalSeconds: 100, CheckIntervalJitterPercent: tt.jitterPercent}
+			if got := NextCheckDelay(config, tt.volume, tt.random); got != tt.want {
+				t.Errorf("NextCheckDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHandleUnmounted tests which unmounted volumes are skipped, and that the skip is recorded.
+func TestHandleUnmounted(t *testing.T) {
+	notMounted := fmt.Errorf("failed to confirm '/data' is mounted. error: %w", filesystem.ErrNotMounted)
+	tests := []struct {
+		name            string
+		unmountedAction string
+		err             error
+		wantSkip        bool
+	}{
+		{name: "mounted", err: nil, wantSkip: false},
+		{name: "other error", err: errors.New("df failed"), wantSkip: false},
+		{name: "unmounted alerts by default", err: notMounted, wantSkip: true},
+		{name: "unmounted counted as an error", unmountedAction: runtime.UnmountedActionError, err: notMounted, wantSkip: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appRuntime := runtime.InitialiseRuntime()
+			appRuntime.Configuration.UnmountedAction = tt.unmountedAction
+			appRuntime.Configuration.RecordSkippedResizes = true
+			eventLog := runtime.EventLog{}
+			volume := runtime.EBSVolumeConfig{AWSVolumeID: "vol-1"}
+			state := runtime.EBSVolumeState{AWSVolumeID: "vol-1"}
+
+			if got := HandleUnmounted(appRuntime, eventLog, volume, state, tt.err); got != tt.wantSkip {
+				t.Errorf("HandleUnmounted() = %v, want %v", got, tt.wantSkip)
+			}
+			if recorded := len(eventLog["vol-1"]) == 1; recorded != tt.wantSkip {
+				t.Errorf("HandleUnmounted() recorded a skip = %v, want %v", recorded, tt.wantSkip)
+			}
+			if unmounted := appRuntime.Unmounted["vol-1"]; unmounted != errors.Is(tt.err, filesystem.ErrNotMounted) {
+				t.Errorf("HandleUnmounted() marked unmounted = %v", unmounted)
+			}
+		})
+	}
+}
+
+// TestMaxUptimeReached tests when the process exits for a scheduled restart.
+func TestMaxUptimeReached(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		uptime         time.Duration
+		maxUptimeHours int
+		want           bool
+	}{
+		{name: "unlimited", uptime: 1000 * time.Hour, maxUptimeHours: 0, want: false},
+		{name: "before the limit", uptime: 23 * time.Hour, maxUptimeHours: 24, want: false},
+		{name: "at the limit", uptime: 24 * time.Hour, maxUptimeHours: 24, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaxUptimeReached(start, start.Add(tt.uptime), tt.maxUptimeHours); got != tt.want {
+				t.Errorf("MaxUptimeReached() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCycleInterval tests that the health check expects the next cycle no sooner than the check interval.
+func TestCycleInterval(t *testing.T) {
+	appRuntime := runtime.InitialiseRuntime()
+	appRuntime.Configuration.CheckIntervalSeconds = 60
+	tests := []struct {
+		name  string
+		sleep time.Duration
+		want  time.Duration
+	}{
+		{name: "sleep shorter than the interval", sleep: 5 * time.Second, want: time.Minute},
+		{name: "sleep longer than the interval", sleep: 5 * time.Minute, want: 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CycleInterval(appRuntime, tt.sleep); got != tt.want {
+				t.Errorf("CycleInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPrintVolumesYAMLParses tests that the volumes section printed by list-volumes is a config that loads,
+// giving back the listed volumes.
+func TestPrintVolumesYAMLParses(t *testing.T) {
+	useFakeAWS(t)
+	volumes := []ListedVolume{
+		{AttachedVolume: aws.AttachedVolume{AWSVolumeID: "vol-1", AWSDeviceName: "/dev/sdf", AWSRegion: "us-east-1", SizeGiB: 100, VolumeType: "gp3"}, LocalMountPoint: "/data"},
+		{AttachedVolume: aws.AttachedVolume{AWSVolumeID: "vol-2", AWSDeviceName: "/dev/sdg", AWSRegion: "us-east-1", SizeGiB: 100, VolumeType: "gp3"}},
+	}
+	var out bytes.Buffer
+	if err := PrintVolumesYAML(&out, volumes); err != nil {
+		t.Fatalf("PrintVolumesYAML() error = %v", err)
+	}
+
+	cfg, err := configutil.ParseConfig(out.Bytes(), "yaml")
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v, output:\n%s", err, out.String())
+	}
+	if len(cfg.Volumes) != len(volumes) {
+		t.Fatalf("ParseConfig() gave %d volumes, want %d", len(cfg.Volumes), len(volumes))
+	}
+	for i, volume := range cfg.Volumes {
+		if volume.AWSVolumeID != volumes[i].AWSVolumeID || volume.AWSDeviceName != volumes[i].AWSDeviceName ||
+			volume.IncrementSizeGB != skeletonIncrementSizeGB || volume.ResizeThreshold != skeletonResizeThreshold {
+			t.Errorf("ParseConfig() volume %d = %+v, want %s on %s with the skeleton thresholds", i, volume, volumes[i].AWSVolumeID, volumes[i].AWSDeviceName)
+		}
+	}
+}
+
+// TestRunOnceExitCode tests that a run-once pass fails only when a volume's check failed, transiently or not.
+func TestRunOnceExitCode(t *testing.T) {
+	tests := []struct {
+		name      string
+		errorLog  map[string]int
+		transient map[string]bool
+		want      int
+	}{
+		{name: "no checks", errorLog: map[string]int{}, want: 0},
+		{name: "all succeeded", errorLog: map[string]int{"vol-1": 0, "vol-2": 0}, want: 0},
+		{name: "one failed", errorLog: map[string]int{"vol-1": 0, "vol-2": 1}, want: 1},
+		{name: "one failed transiently", errorLog: map[string]int{"vol-1": 0, "vol-2": 0}, transient: map[string]bool{"vol-2": true}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RunOnceExitCode(tt.errorLog, tt.transient); got != tt.want {
+				t.Errorf("RunOnceExitCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestIsVolumeNotYetVisible tests that a volume missing from the host is retried only while attached in AWS,
+// and that the attachment check is reused rather than repeated every cycle.
+func TestIsVolumeNotYetVisible(t *testing.T) {
+	calls := 0
+	attachErr := error(nil)
+	checkVolumeAttached = func(volume runtime.EBSVolumeConfig) error {
+		calls++
+		return attachErr
+	}
+	t.Cleanup(func() {
+		checkVolumeAttached = aws.CheckVolumeAttached
+		attachmentChecks.byVolume = map[string]attachmentCheck{}
+	})
+
+	volume := runtime.EBSVolumeConfig{AWSVolumeID: "vol-1"}
+	notMounted := fmt.Errorf("volume ID vol1 %w", filesystem.ErrVolumeNotMounted)
+	if IsVolumeNotYetVisible(volume, errors.New("df failed")) {
+		t.Errorf("IsVolumeNotYetVisible() = true for an unrelated error")
+	}
+	if calls != 0 {
+		t.Errorf("IsVolumeNotYetVisible() checked the attachment %d times for an unrelated error, want 0", calls)
+	}
+
+	for i := 0; i < 3; i++ {
+		if !IsVolumeNotYetVisible(volume, notMounted) {
+			t.Errorf("IsVolumeNotYetVisible() = false for an attached volume")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("IsVolumeNotYetVisible() checked the attachment %d times, want 1", calls)
+	}
+
+	// Once the cached check expires the volume is checked again
+	attachErr = errors.New("volume is not attached")
+	attachmentChecks.byVolume["vol-1"] = attachmentCheck{checkedAt: time.Now().Add(-attachmentCheckInterval)}
+	if IsVolumeNotYetVisible(volume, notMounted) {
+		t.Errorf("IsVolumeNotYetVisible() = true for a detached volume")
+	}
+	if calls != 2 {
+		t.Errorf("IsVolumeNotYetVisible() checked the attachment %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HealthCheck reports whether the service is healthy, with a short reason when it isn't.
+type HealthCheck func() (healthy bool, reason string)
+
+// HealthHandler serves a health check: 200 "ok" when healthy, otherwise 503 with the reason.
+// check: HealthCheck The check run on each request.
+// returns: http.Handler The handler.
+func HealthHandler(check HealthCheck) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		healthy, reason := check()
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, reason)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
+
+// HealthServer serves /healthz on its own, for when metrics are disabled.
+type HealthServer struct {
+	server *http.Server
+}
+
+// ServeHealth starts serving /healthz on the address in the background.
+// addr: string Listen address, e.g. ":9101".
+// check: HealthCheck The check run on each request.
+// returns: *HealthServer The server.
+// returns: error An error if the address can't be listened on.
+func ServeHealth(addr string, check HealthCheck) (*HealthServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s for health checks. error: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", HealthHandler(check))
+	h := &HealthServer{server: &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}}
+	go h.server.Serve(listener)
+	return h, nil
+}
+
+// Shutdown stops the health server, waiting briefly for in-flight requests.
+func (h *HealthServer) Shutdown() {
+	if h == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	h.server.Shutdown(ctx)
+}
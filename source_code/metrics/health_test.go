@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestHealthHandler tests that the health handler returns 200 when healthy and 503 with the reason otherwise.
+func TestHealthHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		healthy    bool
+		reason     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"healthy", true, "", http.StatusOK, "ok"},
+		{"unhealthy", false, "last monitoring cycle completed 10m0s ago", http.StatusServiceUnavailable, "last monitoring cycle completed 10m0s ago"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := HealthHandler(func() (bool, string) { return tt.healthy, tt.reason })
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package metrics
+
+import (
+	"context"
+	"ebs-monitor/runtime"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// contentType is the Prometheus text exposition format content type.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// shutdownTimeout bounds how long Shutdown waits for in-flight scrapes.
+const shutdownTimeout = 5 * time.Second
+
+// volumeMetrics holds the latest gauge values and the counters for one volume.
+type volumeMetrics struct {
+	usedGiB      float64
+	sizeGiB      float64
+	usedPercent  float64
+	resizes      uint64
+	resizeErrors uint64
+}
+
+// Registry holds the metrics exported for each monitored volume.
+// A nil *Registry is valid and ignores all updates, so callers don't need to check whether metrics are enabled.
+type Registry struct {
+	mu      sync.Mutex
+	volumes map[string]*volumeMetrics
+	server  *http.Server
+}
+
+// NewRegistry creates an empty Registry.
+// returns: *Registry The registry.
+func NewRegistry() *Registry {
+	return &Registry{volumes: make(map[string]*volumeMetrics)}
+}
+
+// volume returns the metrics for a volume, creating them on first use. The caller must hold r.mu.
+// volumeID: string AWS Volume ID.
+// returns: *volumeMetrics The volume's metrics.
+func (r *Registry) volume(volumeID string) *volumeMetrics {
+	v, ok := r.volumes[volumeID]
+	if !ok {
+		v = &volumeMetrics{}
+		r.volumes[volumeID] = v
+	}
+	return v
+}
+
+// SetVolumeState updates a volume's gauges from its latest state.
+// state: runtime.EBSVolumeState The state gathered by monitor.GetVolumeState.
+func (r *Registry) SetVolumeState(state runtime.EBSVolumeState) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v := r.volume(state.AWSVolumeID)
+	v.usedGiB = state.UsedSpaceGiB
+	v.sizeGiB = state.LocalDiskSizeGiB
+	v.usedPercent = 0
+	if state.LocalDiskSizeGiB > 0 {
+		v.usedPercent = state.UsedSpaceGiB / state.LocalDiskSizeGiB * 100
+	}
+}
+
+// IncResize counts a successful resize of a volume.
+// volumeID: string AWS Volume ID.
+func (r *Registry) IncResize(volumeID string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.volume(volumeID).resizes++
+}
+
+// IncResizeError counts a failed resize of a volume.
+// volumeID: string AWS Volume ID.
+func (r *Registry) IncResizeError(volumeID string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.volume(volumeID).resizeErrors++
+}
+
+// RemoveVolume stops exporting metrics for a volume that is no longer monitored.
+// volumeID: string AWS Volume ID.
+func (r *Registry) RemoveVolume(volumeID string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.volumes, volumeID)
+}
+
+// metric describes one exported metric and how to read its value.
+type metric struct {
+	name  string
+	kind  string
+	help  string
+	value func(v *volumeMetrics) string
+}
+
+// exported lists the metrics in the order they are written.
+var exported = []metric{
+	{"ebs_volume_used_gb", "gauge", "Used space on the volume's filesystem in GiB (2^30 bytes).", func(v *volumeMetrics) string { return formatFloat(v.usedGiB) }},
+	{"ebs_volume_size_gb", "gauge", "Size of the volume's filesystem in GiB (2^30 bytes).", func(v *volumeMetrics) string { return formatFloat(v.sizeGiB) }},
+	{"ebs_volume_used_percent", "gauge", "Used space as a percentage of the volume's filesystem size.", func(v *volumeMetrics) string { return formatFloat(v.usedPercent) }},
+	{"ebs_resize_total", "counter", "Number of successful resizes.", func(v *volumeMetrics) string { return fmt.Sprint(v.resizes) }},
+	{"ebs_resize_errors_total", "counter", "Number of failed resizes.", func(v *volumeMetrics) string { return fmt.Sprint(v.resizeErrors) }},
+}
+
+// formatFloat formats a gauge value for the exposition format.
+// value: float64 The value.
+// returns: string The formatted value.
+func formatFloat(value float64) string {
+	return fmt.Sprintf("%g", value)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format, volumes sorted by ID.
+// w: io.Writer Destination.
+// returns: int64 Bytes written.
+// returns: error Any write error.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, 0, len(r.volumes))
+	for id := range r.volumes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var written int64
+	for _, m := range exported {
+		n, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+		for _, id := range ids {
+			n, err := fmt.Fprintf(w, "%s{volume_id=%q} %s\n", m.name, id, m.value(r.volumes[id]))
+			written += int64(n)
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// ServeHTTP serves the metrics for scraping.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	r.WriteTo(w)
+}
+
+// Serve starts serving /metrics on the address in the background, and /healthz when a health check is given.
+// addr: string Listen address, e.g. ":9100".
+// check: HealthCheck The health check, nil to not serve /healthz.
+// returns: error An error if the address can't be listened on.
+func (r *Registry) Serve(addr string, check HealthCheck) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s for metrics. error: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r)
+	if check != nil {
+		mux.Handle("/healthz", HealthHandler(check))
+	}
+	r.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go r.server.Serve(listener)
+	return nil
+}
+
+// Shutdown stops the metrics server, waiting briefly for in-flight scrapes.
+func (r *Registry) Shutdown() {
+	if r == nil || r.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	r.server.Shutdown(ctx)
+}
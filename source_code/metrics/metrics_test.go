@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"ebs-monitor/runtime"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestWriteTo tests the exposition format written for each volume.
+func TestWriteTo(t *testing.T) {
+	r := NewRegistry()
+	r.SetVolumeState(runtime.EBSVolumeState{AWSVolumeID: "vol-b", UsedSpaceGiB: 45, LocalDiskSizeGiB: 50})
+	r.SetVolumeState(runtime.EBSVolumeState{AWSVolumeID: "vol-a", UsedSpaceGiB: 10, LocalDiskSizeGiB: 40})
+	r.IncResize("vol-b")
+	r.IncResizeError("vol-b")
+	r.IncResizeError("vol-b")
+
+	var out strings.Builder
+	if _, err := r.WriteTo(&out); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"# TYPE ebs_volume_used_gb gauge\n",
+		`ebs_volume_used_gb{volume_id="vol-a"} 10` + "\n",
+		`ebs_volume_size_gb{volume_id="vol-b"} 50` + "\n",
+		`ebs_volume_used_percent{volume_id="vol-a"} 25` + "\n",
+		`ebs_volume_used_percent{volume_id="vol-b"} 90` + "\n",
+		"# TYPE ebs_resize_total counter\n",
+		`ebs_resize_total{volume_id="vol-a"} 0` + "\n",
+		`ebs_resize_total{volume_id="vol-b"} 1` + "\n",
+		`ebs_resize_errors_total{volume_id="vol-b"} 2` + "\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("WriteTo() output missing %q\n%s", want, out.String())
+		}
+	}
+	if strings.Index(out.String(), `{volume_id="vol-a"}`) > strings.Index(out.String(), `{volume_id="vol-b"}`) {
+		t.Errorf("WriteTo() volumes not sorted by ID\n%s", out.String())
+	}
+
+	r.RemoveVolume("vol-a")
+	out.Reset()
+	r.WriteTo(&out)
+	if strings.Contains(out.String(), "vol-a") {
+		t.Errorf("WriteTo() still exports removed volume\n%s", out.String())
+	}
+}
+
+// TestServeHTTP tests that metrics are served with the exposition content type.
+func TestServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.SetVolumeState(runtime.EBSVolumeState{AWSVolumeID: "vol-a", UsedSpaceGiB: 10, LocalDiskSizeGiB: 40})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if got := rec.Header().Get("Content-Type"); got != contentType {
+		t.Errorf("Content-Type = %q, want %q", got, contentType)
+	}
+	if !strings.Contains(rec.Body.String(), `ebs_volume_size_gb{volume_id="vol-a"} 40`) {
+		t.Errorf("body missing volume gauge\n%s", rec.Body.String())
+	}
+}
+
+// TestNilRegistry tests that a nil registry, used when metrics are disabled, ignores updates.
+func TestNilRegistry(t *testing.T) {
+	var r *Registry
+	r.SetVolumeState(runtime.EBSVolumeState{AWSVolumeID: "vol-a"})
+	r.IncResize("vol-a")
+	r.IncResizeError("vol-a")
+	r.RemoveVolume("vol-a")
+	r.Shutdown()
+}
@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// stateSocketMode restricts the state socket to the user ebs-monitor runs as.
+const stateSocketMode = 0600
+
+// stateWriteTimeout bounds how long a client connected to the state socket has to read the state.
+const stateWriteTimeout = 10 * time.Second
+
+// StateSocket serves the last published state as JSON to each client that connects to a Unix socket,
+// so local tooling can query it without the metrics server.
+type StateSocket struct {
+	listener net.Listener
+	path     string
+	mu       sync.Mutex
+	state    []byte
+}
+
+// ServeState starts serving the state on a Unix socket in the background. The socket can only be
+// connected to by the user ebs-monitor runs as, and a socket left behind by a previous run is replaced.
+// path: string Path of the socket, e.g. /run/ebs-monitor/state.sock.
+// returns: *StateSocket The socket, serving "null" until a state is published.
+// returns: error An error if the socket can't be created.
+func ServeState(path string) (*StateSocket, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	listener, err := listenPrivate(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &StateSocket{listener: listener, path: path, state: []byte("null\n")}
+	go s.serve()
+	return s, nil
+}
+
+// listenPrivate creates the socket in a directory only the current user can open, restricts the socket, and
+// only then moves it to path. Creating it at path directly would leave it open to any local user until
+// it's restricted, as a socket is created with the permissions the umask allows.
+// path: string Path of the socket.
+// returns: net.Listener The listener for the socket at path.
+// returns: error An error if the socket can't be created, restricted or moved.
+func listenPrivate(path string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".state-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a private directory for %s. error: %w", path, err)
+	}
+	defer os.RemoveAll(dir)
+
+	private := filepath.Join(dir, filepath.Base(path))
+	listener, err := net.Listen("unix", private)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s for state queries. error: %w", path, err)
+	}
+	// The socket is removed by Shutdown, as the listener would only remove it from the private directory
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(private, stateSocketMode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict access to %s. error: %w", path, err)
+	}
+	if err := os.Rename(private, path); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to move the socket to %s. error: %w", path, err)
+	}
+	return listener, nil
+}
+
+// removeStaleSocket removes a socket nothing is listening on, left behind when ebs-monitor didn't shut down cleanly.
+// path: string Path of the socket.
+// returns: error An error if the path is in use, isn't a socket, or can't be removed.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check %s. error: %w", path, err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and isn't a socket", path)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another process", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale socket %s. error: %w", path, err)
+	}
+	return nil
+}
+
+// serve writes the state to each client that connects, until the socket is shut down.
+func (s *StateSocket) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		go s.write(conn)
+	}
+}
+
+// write writes the state to a client and closes the connection.
+// conn: net.Conn The client's connection.
+func (s *StateSocket) write(conn net.Conn) {
+	defer conn.Close()
+	s.mu.Lock()
+	state := s.state
+	s.mu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(stateWriteTimeout))
+	conn.Write(state)
+}
+
+// Publish replaces the state served to clients. The state is encoded straight away, so it may change
+// once Publish returns.
+// state: interface{} The state, encoded as JSON.
+// returns: error An error if the state can't be encoded, in which case the previous state is still served.
+func (s *StateSocket) Publish(state interface{}) error {
+	if s == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode the state. error: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = append(encoded, '\n')
+	return nil
+}
+
+// Shutdown stops serving the state and removes the socket.
+func (s *StateSocket) Shutdown() {
+	if s == nil {
+		return
+	}
+	s.listener.Close()
+	os.Remove(s.path)
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// queryState connects to a state socket and returns what it serves.
+func queryState(t *testing.T, path string) string {
+	t.Helper()
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	body, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// TestStateSocket tests that the socket serves the published state, is restricted to its owner, and is removed on shutdown.
+func TestStateSocket(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.sock")
+	s, err := ServeState(path)
+	if err != nil {
+		t.Fatalf("ServeState() error = %v", err)
+	}
+	// The private directory the socket was created in is removed once it's moved into place
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 1 {
+		t.Errorf("ReadDir() = %v, %v, want only the socket", entries, err)
+	}
+
+	if got := queryState(t, path); got != "null" {
+		t.Errorf("state before publishing = %q, want null", got)
+	}
+	if err := s.Publish(map[string]int{"vol-1": 20}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if got, want := queryState(t, path), `{"vol-1":20}`; got != want {
+		t.Errorf("state = %q, want %q", got, want)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != stateSocketMode {
+		t.Errorf("socket mode = %o, want %o", mode, stateSocketMode)
+	}
+
+	s.Shutdown()
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat() after Shutdown error = %v, want the socket removed", err)
+	}
+}
+
+// TestServeStateExistingPath tests that a stale socket is replaced, while a socket in use or another file is kept.
+func TestServeStateExistingPath(t *testing.T) {
+	dir := t.TempDir()
+
+	// A listener whose file isn't removed on close leaves a stale socket behind, as after a crash
+	stale := filepath.Join(dir, "stale.sock")
+	listener, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	s, err := ServeState(stale)
+	if err != nil {
+		t.Fatalf("ServeState() over a stale socket error = %v", err)
+	}
+	defer s.Shutdown()
+
+	if _, err := ServeState(stale); err == nil {
+		t.Error("ServeState() over a socket in use error = nil, want an error")
+	}
+
+	file := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(file, []byte("{}"), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := ServeState(file); err == nil {
+		t.Error("ServeState() over a regular file error = nil, want an error")
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("Stat() error = %v, want the file kept", err)
+	}
+}
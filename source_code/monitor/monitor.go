@@ -4,13 +4,50 @@ import (
 	"ebs-monitor/aws"
 	"ebs-monitor/filesystem"
 	"ebs-monitor/runtime"
+	"errors"
 	"fmt"
 )
 
+// TransientError wraps an error expected to clear by itself, e.g. AWS throttling, so the check can be
+// retried without counting towards the volume's removal.
+type TransientError struct {
+	Err error
+}
+
+// Error returns the wrapped error's message.
+func (e *TransientError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+// IsTransient : reports whether an error returned by GetVolumeState is transient.
+// err : error the error to check
+// returns : bool true if the error is a TransientError
+func IsTransient(err error) bool {
+	var transient *TransientError
+	return errors.As(err, &transient)
+}
+
+// IsVolumeGone : reports whether an error returned by GetVolumeState means the volume no longer exists in AWS,
+// e.g. it was deleted, so checking it again can't succeed.
+// err : error the error to check
+// returns : bool true if AWS reported the volume as not found
+func IsVolumeGone(err error) bool {
+	return errors.Is(err, aws.ErrVolumeNotFound)
+}
+
+// host is the instance's filesystems, replaced in tests.
+var host filesystem.Host = filesystem.LocalHost{}
+
 // GetVolumeState : gathers information on a specific volume and performs error handling.
+// For partitioned volumes the state reflects the filesystem that decides the resize, see selectFilesystemState.
 // volumeConfig : runtime.EBSVolumeConfig configuration of the volume to gather state from
 // returns : runtime.EBSVolumeState gathered volume state
-// returns : error potential errors
+// returns : error potential errors, a *TransientError if the failure is expected to clear by itself
 func GetVolumeState(volumeConfig runtime.EBSVolumeConfig, eventLog *runtime.EventLog) (runtime.EBSVolumeState, error) {
 	state := runtime.InitialiseEBSVolumeState()
 
@@ -18,33 +55,120 @@ func GetVolumeState(volumeConfig runtime.EBSVolumeConfig, eventLog *runtime.Even
 	state.AWSVolumeID = volumeConfig.AWSVolumeID
 	state.AWSDeviceName = volumeConfig.AWSDeviceName
 
-	// Get LocalMountPoint
-	mnt, err := filesystem.GetLocalMountPoint(state.AWSVolumeID)
+	// Get LocalMountPoint(s)
+	mountPoints, err := host.GetVolumeMountPoints(volumeConfig)
 	if err != nil {
 		return state, fmt.Errorf("failed to get local mount point information for '%v'. error: %w", state.AWSDeviceName, err)
 	}
-	state.LocalMountPoint = mnt
+	// A volume whose partitions are all swap has no filesystem to measure
+	if len(mountPoints) == 0 {
+		return state, fmt.Errorf("no filesystem to monitor on '%v', every configured partition is swap", state.AWSDeviceName)
+	}
+	state.LocalMountPoint = mountPoints[0]
 
-	// Get AWS Device Size in GB
-	devGB, err := aws.GetAWSDeviceSizeGB(volumeConfig)
+	// Get AWS Device Size in GiB
+	devGiB, err := aws.GetAWSDeviceSizeGiB(volumeConfig)
 	if err != nil {
-		return state, fmt.Errorf("failed to get device size for '%v'. error: %w", state.AWSDeviceName, err)
+		err = fmt.Errorf("failed to get device size for '%v'. error: %w", state.AWSDeviceName, err)
+		if aws.IsTransient(err) {
+			return state, &TransientError{Err: err}
+		}
+		return state, err
 	}
-	state.AWSDeviceSizeGB = float64(devGB)
+	state.AWSDeviceSizeGiB = float64(devGiB)
+
+	// Gather the state of each filesystem, keeping the one that decides the resize and the highest inode usage
+	fsStates := make([]runtime.EBSVolumeState, 0, len(mountPoints))
+	maxInodesUsed := 0.0
+	for _, mnt := range mountPoints {
+		fsState := state
+		fsState.LocalMountPoint = mnt
+		if err := getFilesystemState(volumeConfig, &fsState); err != nil {
+			return state, err
+		}
 
-	// Get Local Device Size in GB
-	mntGB, err := filesystem.GetLocalDiskSizeGB(mnt)
+		if fsState.InodesUsedPercent > maxInodesUsed {
+			maxInodesUsed = fsState.InodesUsedPercent
+		}
+		fsStates = append(fsStates, fsState)
+	}
+	state = selectFilesystemState(volumeConfig, fsStates)
+	state.InodesUsedPercent = maxInodesUsed
+
+	return state, nil
+}
+
+// selectFilesystemState : picks the filesystem whose state stands for a volume with several filesystems.
+// A filesystem over its threshold is preferred, so a resize triggers if any of them needs one,
+// as absolute rules such as minFreeGB can be exceeded by a larger filesystem that isn't the most utilised.
+// volumeConfig : runtime.EBSVolumeConfig configuration of the volume
+// fsStates : []runtime.EBSVolumeState state of each filesystem, at least one
+// returns : runtime.EBSVolumeState the most utilised filesystem over its threshold, or the most utilised if none are
+func selectFilesystemState(volumeConfig runtime.EBSVolumeConfig, fsStates []runtime.EBSVolumeState) runtime.EBSVolumeState {
+	selected := fsStates[0]
+	selectedExceeded := IsResizeNeeded(selected, volumeConfig)
+	for _, fsState := range fsStates[1:] {
+		exceeded := IsResizeNeeded(fsState, volumeConfig)
+		if exceeded && !selectedExceeded || exceeded == selectedExceeded && utilisation(fsState) > utilisation(selected) {
+			selected, selectedExceeded = fsState, exceeded
+		}
+	}
+	return selected
+}
+
+// getFilesystemState : gathers the size and usage of the filesystem mounted at state.LocalMountPoint.
+// volumeConfig : runtime.EBSVolumeConfig configuration of the volume the filesystem is on
+// state : *runtime.EBSVolumeState state to populate
+// returns : error potential errors
+func getFilesystemState(volumeConfig runtime.EBSVolumeConfig, state *runtime.EBSVolumeState) error {
+	mnt := state.LocalMountPoint
+
+	// Confirm the filesystem is mounted, as an unmounted path would report its parent filesystem's usage
+	if err := host.CheckMounted(mnt); err != nil {
+		return fmt.Errorf("failed to confirm '%v' is mounted. error: %w", mnt, err)
+	}
+
+	// Get Local Device Size in GiB
+	mntGiB, err := host.GetLocalDiskSizeGiB(mnt)
 	if err != nil {
-		return state, fmt.Errorf("failed to get local disk size for '%v'. error: %w", mnt, err)
+		return fmt.Errorf("failed to get local disk size for '%v'. error: %w", mnt, err)
 	}
-	state.LocalDiskSizeGB = mntGB
+	state.LocalDiskSizeGiB = mntGiB
 
 	// Get used space
-	used, err := filesystem.GetUsedSpaceGB(mnt)
+	used, err := host.GetUsedSpaceGiB(mnt)
 	if err != nil {
-		return state, fmt.Errorf("failed to get disk utilization for '%v'. error: %w", mnt, err)
+		return fmt.Errorf("failed to get disk utilization for '%v'. error: %w", mnt, err)
 	}
-	state.UsedSpaceGB = used
+	state.UsedSpaceGiB = used
 
-	return state, nil
+	// Get inode usage
+	inodes, err := host.GetInodeUsagePercent(mnt)
+	if err != nil {
+		return fmt.Errorf("failed to get inode usage for '%v'. error: %w", mnt, err)
+	}
+	state.InodesUsedPercent = inodes
+
+	// Get root-reserved space, only read when the threshold is measured against usable space,
+	// so tune2fs isn't run on every check otherwise
+	state.ReservedSpaceGiB = 0
+	if volumeConfig.ThresholdBasis == runtime.ThresholdBasisUsable {
+		reserved, err := host.GetReservedSpaceGiB(mnt)
+		if err != nil {
+			return fmt.Errorf("failed to get reserved space for '%v'. error: %w", mnt, err)
+		}
+		state.ReservedSpaceGiB = reserved
+	}
+
+	return nil
+}
+
+// utilisation : returns the fraction of a filesystem's size that is used.
+// state : runtime.EBSVolumeState state of the filesystem
+// returns : float64 used space divided by size, 0 for an empty size
+func utilisation(state runtime.EBSVolumeState) float64 {
+	if state.LocalDiskSizeGiB <= 0 {
+		return 0
+	}
+	return state.UsedSpaceGiB / state.LocalDiskSizeGiB
 }
@@ -1,3 +1,452 @@
 package monitor
 
-// TODO: add tests - requires mocking external calls
+import (
+	"ebs-monitor/aws"
+	"ebs-monitor/aws/awstest"
+	"ebs-monitor/filesystem"
+	"ebs-monitor/filesystem/fstest"
+	"ebs-monitor/runtime"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// TestUtilisation tests the utilisation function.
+func TestUtilisation(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    runtime.EBSVolumeState
+		expected float64
+	}{
+		{
+			name:     "half used",
+			state:    runtime.EBSVolumeState{LocalDiskSizeGiB: 10, UsedSpaceGiB: 5},
+			expected: 0.5,
+		},
+		{
+			name:     "empty size",
+			state:    runtime.EBSVolumeState{},
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := utilisation(tt.state); got != tt.expected {
+				t.Errorf("utilisation() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestIsResizeNeeded tests the IsResizeNeeded function across threshold modes.
+func TestIsResizeNeeded(t *testing.T) {
+	state := runtime.EBSVolumeState{LocalDiskSizeGiB: 100, UsedSpaceGiB: 82, ReservedSpaceGiB: 5}
+
+	tests := []struct {
+		name     string
+		volume   runtime.EBSVolumeConfig
+		expected bool
+	}{
+		{
+			name:     "percentage of total exceeded",
+			volume:   runtime.EBSVolumeConfig{ResizeThreshold: 80},
+			expected: true,
+		},
+		{
+			name:     "percentage of total not exceeded",
+			volume:   runtime.EBSVolumeConfig{ResizeThreshold: 85},
+			expected: false,
+		},
+		{
+			name:     "percentage of usable exceeded",
+			volume:   runtime.EBSVolumeConfig{ResizeThreshold: 85, ThresholdBasis: runtime.ThresholdBasisUsable},
+			expected: true,
+		},
+		{
+			name:     "used ceiling exceeded",
+			volume:   runtime.EBSVolumeConfig{UsedCeilingGB: 80},
+			expected: true,
+		},
+		{
+			name:     "used ceiling not exceeded regardless of size",
+			volume:   runtime.EBSVolumeConfig{UsedCeilingGB: 90},
+			expected: false,
+		},
+		{
+			name:     "used ceiling exactly reached",
+			volume:   runtime.EBSVolumeConfig{UsedCeilingGB: 82},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsResizeNeeded(state, tt.volume); got != tt.expected {
+				t.Errorf("IsResizeNeeded() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestThresholdGiBConsistency tests that a 100 GiB AWS volume and its filesystem report the same size,
+// so the threshold percentage is measured against the volume's real capacity.
+func TestThresholdGiBConsistency(t *testing.T) {
+	const gib = 1024 * 1024 * 1024
+	tests := []struct {
+		name      string
+		usedBytes uint64
+		expected  bool
+	}{
+		{
+			name:      "at threshold",
+			usedBytes: 80 * gib,
+			expected:  false,
+		},
+		{
+			name:      "above threshold",
+			usedBytes: 81 * gib,
+			expected:  true,
+		},
+	}
+
+	volume := runtime.EBSVolumeConfig{ResizeThreshold: 80}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := runtime.EBSVolumeState{
+				AWSDeviceSizeGiB: 100,
+				LocalDiskSizeGiB: filesystem.BytesToGiB(100 * gib),
+				UsedSpaceGiB:     filesystem.BytesToGiB(tt.usedBytes),
+			}
+			if state.LocalDiskSizeGiB != state.AWSDeviceSizeGiB {
+				t.Fatalf("LocalDiskSizeGiB = %v, want %v", state.LocalDiskSizeGiB, state.AWSDeviceSizeGiB)
+			}
+			if got := ResizeThresholdGiB(state, volume); got != 80 {
+				t.Errorf("ResizeThresholdGiB() = %v, want 80", got)
+			}
+			if got := IsResizeNeeded(state, volume); got != tt.expected {
+				t.Errorf("IsResizeNeeded() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestExceededRule tests that the rule reported is the one that calls for a resize, with the space rules
+// checked before MinFreeGB and inodes.
+func TestExceededRule(t *testing.T) {
+	state := runtime.EBSVolumeState{LocalDiskSizeGiB: 100, UsedSpaceGiB: 82, InodesUsedPercent: 95}
+
+	tests := []struct {
+		name     string
+		volume   runtime.EBSVolumeConfig
+		expected string
+	}{
+		{
+			name:     "percentage exceeded",
+			volume:   runtime.EBSVolumeConfig{ResizeThreshold: 80, MinFreeGB: 20},
+			expected: RuleResizeThreshold,
+		},
+		{
+			name:     "used ceiling exceeded",
+			volume:   runtime.EBSVolumeConfig{UsedCeilingGB: 80},
+			expected: RuleUsedCeiling,
+		},
+		{
+			name:     "min free exceeded before percentage",
+			volume:   runtime.EBSVolumeConfig{ResizeThreshold: 90, MinFreeGB: 20},
+			expected: RuleMinFree,
+		},
+		{
+			name:     "min free on its own",
+			volume:   runtime.EBSVolumeConfig{MinFreeGB: 20},
+			expected: RuleMinFree,
+		},
+		{
+			name:     "min free not exceeded",
+			volume:   runtime.EBSVolumeConfig{MinFreeGB: 10},
+			expected: "",
+		},
+		{
+			name:     "min free exactly reached",
+			volume:   runtime.EBSVolumeConfig{MinFreeGB: 18},
+			expected: "",
+		},
+		{
+			name:     "inodes only",
+			volume:   runtime.EBSVolumeConfig{ResizeThreshold: 90, MinFreeGB: 10, InodeResizeThreshold: 90},
+			expected: RuleInodeResizeThreshold,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExceededRule(state, tt.volume); got != tt.expected {
+				t.Errorf("ExceededRule() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestResizeThresholdGiBMinFree tests that the threshold is the lower of the used space threshold and
+// the used space leaving MinFreeGB free.
+func TestResizeThresholdGiBMinFree(t *testing.T) {
+	state := runtime.EBSVolumeState{LocalDiskSizeGiB: 100, UsedSpaceGiB: 50}
+
+	tests := []struct {
+		name     string
+		volume   runtime.EBSVolumeConfig
+		expected float64
+	}{
+		{
+			name:     "min free lower",
+			volume:   runtime.EBSVolumeConfig{ResizeThreshold: 90, MinFreeGB: 20},
+			expected: 80,
+		},
+		{
+			name:     "percentage lower",
+			volume:   runtime.EBSVolumeConfig{ResizeThreshold: 70, MinFreeGB: 20},
+			expected: 70,
+		},
+		{
+			name:     "min free only",
+			volume:   runtime.EBSVolumeConfig{MinFreeGB: 5},
+			expected: 95,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResizeThresholdGiB(state, tt.volume); got != tt.expected {
+				t.Errorf("ResizeThresholdGiB() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestSelectFilesystemState tests which filesystem of a two-partition volume decides the resize.
+func TestSelectFilesystemState(t *testing.T) {
+	small := runtime.EBSVolumeState{LocalMountPoint: "/logs", LocalDiskSizeGiB: 10, UsedSpaceGiB: 7}
+	large := runtime.EBSVolumeState{LocalMountPoint: "/data", LocalDiskSizeGiB: 200, UsedSpaceGiB: 130}
+
+	tests := []struct {
+		name     string
+		volume   runtime.EBSVolumeConfig
+		states   []runtime.EBSVolumeState
+		expected string
+	}{
+		{
+			name:     "neither exceeded picks most utilised",
+			volume:   runtime.EBSVolumeConfig{ResizeThreshold: 90},
+			states:   []runtime.EBSVolumeState{large, small},
+			expected: "/logs",
+		},
+		{
+			name:     "both exceeded picks most utilised",
+			volume:   runtime.EBSVolumeConfig{ResizeThreshold: 60},
+			states:   []runtime.EBSVolumeState{large, small},
+			expected: "/logs",
+		},
+		{
+			name:     "less utilised partition exceeded",
+			volume:   runtime.EBSVolumeConfig{UsedCeilingGB: 100},
+			states:   []runtime.EBSVolumeState{small, large},
+			expected: "/data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectFilesystemState(tt.volume, tt.states)
+			if got.LocalMountPoint != tt.expected {
+				t.Errorf("selectFilesystemState() = %v, want %v", got.LocalMountPoint, tt.expected)
+			}
+		})
+	}
+}
+
+// TestIsResizeNeededZeroSize tests that a filesystem reporting no size is never resized.
+func TestIsResizeNeededZeroSize(t *testing.T) {
+	tests := []struct {
+		name   string
+		volume runtime.EBSVolumeConfig
+	}{
+		{
+			name:   "percentage threshold",
+			volume: runtime.EBSVolumeConfig{ResizeThreshold: 80},
+		},
+		{
+			name:   "used ceiling",
+			volume: runtime.EBSVolumeConfig{UsedCeilingGB: 10},
+		},
+	}
+
+	state := runtime.EBSVolumeState{LocalDiskSizeGiB: 0, UsedSpaceGiB: 50}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if IsResizeNeeded(state, tt.volume) {
+				t.Errorf("IsResizeNeeded() = true, want false for a zero-size filesystem")
+			}
+		})
+	}
+}
+
+// TestIsResizeNeededInodes tests that inode usage above the inode threshold triggers a resize.
+func TestIsResizeNeededInodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    runtime.EBSVolumeState
+		volume   runtime.EBSVolumeConfig
+		expected bool
+	}{
+		{
+			name:     "inodes exhausted, space low",
+			state:    runtime.EBSVolumeState{LocalDiskSizeGiB: 100, UsedSpaceGiB: 10, InodesUsedPercent: 95},
+			volume:   runtime.EBSVolumeConfig{ResizeThreshold: 80, InodeResizeThreshold: 90},
+			expected: true,
+		},
+		{
+			name:     "inodes below threshold",
+			state:    runtime.EBSVolumeState{LocalDiskSizeGiB: 100, UsedSpaceGiB: 10, InodesUsedPercent: 50},
+			volume:   runtime.EBSVolumeConfig{ResizeThreshold: 80, InodeResizeThreshold: 90},
+			expected: false,
+		},
+		{
+			name:     "inode threshold disabled",
+			state:    runtime.EBSVolumeState{LocalDiskSizeGiB: 100, UsedSpaceGiB: 10, InodesUsedPercent: 95},
+			volume:   runtime.EBSVolumeConfig{ResizeThreshold: 80},
+			expected: false,
+		},
+		{
+			name:     "space exceeded, inodes low",
+			state:    runtime.EBSVolumeState{LocalDiskSizeGiB: 100, UsedSpaceGiB: 90, InodesUsedPercent: 5},
+			volume:   runtime.EBSVolumeConfig{ResizeThreshold: 80, InodeResizeThreshold: 90},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsResizeNeeded(tt.state, tt.volume); got != tt.expected {
+				t.Errorf("IsResizeNeeded() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestIsTransient tests that a TransientError is recognised even when wrapped, and other errors are not.
+func TestIsTransient(t *testing.T) {
+	transient := &TransientError{Err: errors.New("throttled")}
+	if !IsTransient(transient) {
+		t.Errorf("IsTransient(TransientError) = false, want true")
+	}
+	if !IsTransient(fmt.Errorf("check failed: %w", transient)) {
+		t.Errorf("IsTransient(wrapped TransientError) = false, want true")
+	}
+	if IsTransient(errors.New("unsupported filesystem")) {
+		t.Errorf("IsTransient(plain error) = true, want false")
+	}
+	if transient.Error() != "throttled" || !errors.Is(transient, transient.Err) {
+		t.Errorf("TransientError does not expose the wrapped error")
+	}
+}
+
+// TestIsVolumeGone tests that only a volume reported as not found by AWS is treated as gone.
+func TestIsVolumeGone(t *testing.T) {
+	if !IsVolumeGone(fmt.Errorf("failed to get device size. error: %w", aws.ErrVolumeNotFound)) {
+		t.Errorf("IsVolumeGone(wrapped ErrVolumeNotFound) = false, want true")
+	}
+	if IsVolumeGone(&TransientError{Err: aws.ErrThrottled}) {
+		t.Errorf("IsVolumeGone(ErrThrottled) = true, want false")
+	}
+}
+
+// TestGetVolumeState tests gathering a two-partition volume's state from fake AWS and host.
+func TestGetVolumeState(t *testing.T) {
+	aws.SetEC2Client(&awstest.FakeEC2{Volumes: []*ec2.Volume{awstest.NewFakeVolume("vol-1", "i-1", "/dev/sdf", 210)}})
+	fakeHost := &fstest.FakeHost{
+		MountPoints: map[string][]string{"vol-1": {"/logs", "/data"}},
+		Filesystems: map[string]*fstest.FakeFilesystem{
+			"/logs": {SizeGiB: 10, UsedGiB: 7, InodesUsedPercent: 40},
+			"/data": {SizeGiB: 200, UsedGiB: 130, InodesUsedPercent: 10},
+		},
+	}
+	host = fakeHost
+	defer func() {
+		aws.SetEC2Client(nil)
+		host = filesystem.LocalHost{}
+	}()
+
+	volume := runtime.EBSVolumeConfig{AWSVolumeID: "vol-1", AWSDeviceName: "/dev/sdf", AWSRegion: "us-east-1", UsedCeilingGB: 100}
+	state, err := GetVolumeState(volume, &runtime.EventLog{})
+	if err != nil {
+		t.Fatalf("GetVolumeState() error = %v", err)
+	}
+	if state.LocalMountPoint != "/data" || state.AWSDeviceSizeGiB != 210 || state.UsedSpaceGiB != 130 {
+		t.Errorf("GetVolumeState() = %+v, want /data on a 210 GiB volume with 130 GiB used", state)
+	}
+	if state.InodesUsedPercent != 40 {
+		t.Errorf("GetVolumeState() InodesUsedPercent = %v, want the highest of 40", state.InodesUsedPercent)
+	}
+
+	fakeHost.Filesystems["/logs"].Unmounted = true
+	if _, err := GetVolumeState(volume, &runtime.EventLog{}); !errors.Is(err, filesystem.ErrNotMounted) {
+		t.Errorf("GetVolumeState() error = %v, want ErrNotMounted", err)
+	}
+}
+
+// TestGetVolumeStateReservedSpace tests that reserved space is only read, and its failure reported, for the usable basis.
+func TestGetVolumeStateReservedSpace(t *testing.T) {
+	aws.SetEC2Client(&awstest.FakeEC2{Volumes: []*ec2.Volume{awstest.NewFakeVolume("vol-1", "i-1", "/dev/sdf", 100)}})
+	fakeHost := &fstest.FakeHost{
+		MountPoints: map[string][]string{"vol-1": {"/data"}},
+		Filesystems: map[string]*fstest.FakeFilesystem{"/data": {SizeGiB: 100, UsedGiB: 50, ReservedGiB: 5}},
+	}
+	host = fakeHost
+	defer func() {
+		aws.SetEC2Client(nil)
+		host = filesystem.LocalHost{}
+	}()
+
+	tests := []struct {
+		name         string
+		basis        string
+		reservedErr  error
+		wantReserved float64
+		wantErr      bool
+	}{
+		{name: "default basis", basis: "", wantReserved: 0},
+		{name: "total basis ignores tune2fs", basis: runtime.ThresholdBasisTotal, reservedErr: errors.New("tune2fs failed"), wantReserved: 0},
+		{name: "usable basis", basis: runtime.ThresholdBasisUsable, wantReserved: 5},
+		{name: "usable basis reports tune2fs failure", basis: runtime.ThresholdBasisUsable, reservedErr: errors.New("tune2fs failed"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeHost.Filesystems["/data"].ReservedErr = tt.reservedErr
+			volume := runtime.EBSVolumeConfig{AWSVolumeID: "vol-1", AWSDeviceName: "/dev/sdf", AWSRegion: "us-east-1", ResizeThreshold: 80, ThresholdBasis: tt.basis}
+			state, err := GetVolumeState(volume, &runtime.EventLog{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetVolumeState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && state.ReservedSpaceGiB != tt.wantReserved {
+				t.Errorf("GetVolumeState() ReservedSpaceGiB = %v, want %v", state.ReservedSpaceGiB, tt.wantReserved)
+			}
+		})
+	}
+}
+
+// TestGetVolumeStateOnlySwap tests that a volume whose partitions are all swap is reported as an error rather than
+// crashing, as it has no filesystem to measure. Listed partitions resolve without probing the host.
+func TestGetVolumeStateOnlySwap(t *testing.T) {
+	volume := runtime.EBSVolumeConfig{
+		AWSVolumeID:     "vol-1",
+		AWSDeviceName:   "/dev/sdf",
+		ResizeThreshold: 80,
+		Partitions:      []runtime.PartitionConfig{{Partition: 1, FilesystemType: "swap"}},
+	}
+	if _, err := GetVolumeState(volume, &runtime.EventLog{}); err == nil {
+		t.Error("GetVolumeState() error = nil for a volume with only swap, want an error")
+	}
+}
@@ -0,0 +1,109 @@
+package monitor
+
+import "ebs-monitor/runtime"
+
+// Threshold rules, named after the config field that sets them, reported when a rule calls for a resize.
+const (
+	RuleResizeThreshold      = "resizeThreshold"
+	RuleUsedCeiling          = "usedCeilingGB"
+	RuleMinFree              = "minFreeGB"
+	RuleInodeResizeThreshold = "inodeResizeThreshold"
+)
+
+// CapacityGiB : returns the capacity a volume's percentage threshold is measured against.
+// Usable capacity excludes the blocks the filesystem reserves for root.
+// state : runtime.EBSVolumeState state of the volume
+// thresholdBasis : string "total" or "usable"
+// returns : float64 capacity in GiB
+func CapacityGiB(state runtime.EBSVolumeState, thresholdBasis string) float64 {
+	capacityGiB := state.LocalDiskSizeGiB
+	if thresholdBasis == runtime.ThresholdBasisUsable {
+		capacityGiB -= state.ReservedSpaceGiB
+	}
+	return capacityGiB
+}
+
+// ResizeThresholdGiB : returns the used space above which a volume should be resized.
+// This is the lower of the used space threshold and, when MinFreeGB is set, the used space that leaves MinFreeGB free,
+// so whichever rule triggers first wins.
+// state : runtime.EBSVolumeState state of the volume
+// volume : runtime.EBSVolumeConfig configuration of the volume
+// returns : float64 used space threshold in GiB
+func ResizeThresholdGiB(state runtime.EBSVolumeState, volume runtime.EBSVolumeConfig) float64 {
+	threshold, set := usedThresholdGiB(state, volume)
+	if minFree, minFreeSet := minFreeThresholdGiB(state, volume); minFreeSet && (!set || minFree < threshold) {
+		return minFree
+	}
+	return threshold
+}
+
+// usedThresholdGiB : returns the used space threshold of a volume.
+// Each volume uses one used space mode: an absolute UsedCeilingGB when set, otherwise the percentage ResizeThreshold.
+// state : runtime.EBSVolumeState state of the volume
+// volume : runtime.EBSVolumeConfig configuration of the volume
+// returns : float64 used space threshold in GiB
+// returns : bool false if neither UsedCeilingGB nor ResizeThreshold is set
+func usedThresholdGiB(state runtime.EBSVolumeState, volume runtime.EBSVolumeConfig) (float64, bool) {
+	if volume.UsedCeilingGB > 0 {
+		return float64(volume.UsedCeilingGB), true
+	}
+	return CapacityGiB(state, volume.ThresholdBasis) * (float64(volume.ResizeThreshold) / 100.0), volume.ResizeThreshold > 0
+}
+
+// minFreeThresholdGiB : returns the used space above which less than MinFreeGB of the filesystem is free.
+// state : runtime.EBSVolumeState state of the volume
+// volume : runtime.EBSVolumeConfig configuration of the volume
+// returns : float64 used space threshold in GiB
+// returns : bool false if MinFreeGB is not set
+func minFreeThresholdGiB(state runtime.EBSVolumeState, volume runtime.EBSVolumeConfig) (float64, bool) {
+	return state.LocalDiskSizeGiB - float64(volume.MinFreeGB), volume.MinFreeGB > 0
+}
+
+// ExceededRule : returns the threshold rule calling for a volume to be resized.
+// Space rules are checked before inodes, so inodes are only reported when they are the sole trigger.
+// state : runtime.EBSVolumeState state of the volume
+// volume : runtime.EBSVolumeConfig configuration of the volume
+// returns : string the rule, e.g. RuleMinFree, or an empty string if no rule is exceeded
+func ExceededRule(state runtime.EBSVolumeState, volume runtime.EBSVolumeConfig) string {
+	if threshold, set := usedThresholdGiB(state, volume); set && state.UsedSpaceGiB > threshold {
+		if volume.UsedCeilingGB > 0 {
+			return RuleUsedCeiling
+		}
+		return RuleResizeThreshold
+	}
+	if threshold, set := minFreeThresholdGiB(state, volume); set && state.UsedSpaceGiB > threshold {
+		return RuleMinFree
+	}
+	if IsInodeThresholdExceeded(state, volume) {
+		return RuleInodeResizeThreshold
+	}
+	return ""
+}
+
+// IsResizeNeeded : checks if a volume's used space or inode usage is above its resize threshold.
+// A volume whose filesystem reports no size is never resized, as its usage can't be trusted.
+// state : runtime.EBSVolumeState state of the volume
+// volume : runtime.EBSVolumeConfig configuration of the volume
+// returns : bool true if the volume should be resized
+func IsResizeNeeded(state runtime.EBSVolumeState, volume runtime.EBSVolumeConfig) bool {
+	if state.LocalDiskSizeGiB <= 0 {
+		return false
+	}
+	return ExceededRule(state, volume) != ""
+}
+
+// IsSpaceThresholdExceeded : checks if a volume's used space is above its resize threshold.
+// state : runtime.EBSVolumeState state of the volume
+// volume : runtime.EBSVolumeConfig configuration of the volume
+// returns : bool true if the used space threshold is exceeded
+func IsSpaceThresholdExceeded(state runtime.EBSVolumeState, volume runtime.EBSVolumeConfig) bool {
+	return state.UsedSpaceGiB > ResizeThresholdGiB(state, volume)
+}
+
+// IsInodeThresholdExceeded : checks if a volume's inode usage is above its inode resize threshold.
+// state : runtime.EBSVolumeState state of the volume
+// volume : runtime.EBSVolumeConfig configuration of the volume
+// returns : bool true if an inode threshold is set and exceeded
+func IsInodeThresholdExceeded(state runtime.EBSVolumeState, volume runtime.EBSVolumeConfig) bool {
+	return volume.InodeResizeThreshold > 0 && state.InodesUsedPercent > float64(volume.InodeResizeThreshold)
+}
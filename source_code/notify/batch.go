@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// digestSeparator separates the alerts listed in a digest.
+const digestSeparator = "\n----\n"
+
+// Batcher coalesces notifications arriving within a short window into a single digest.
+// A batch is sent when the window elapses after its first notification, or as soon as it reaches
+// the maximum count. With a zero window every notification is sent immediately.
+type Batcher struct {
+	mu       sync.Mutex
+	send     func(n Notification)
+	window   time.Duration
+	maxCount int
+	pending  []Notification
+	timer    *time.Timer
+}
+
+// NewBatcher creates a Batcher that passes notifications and digests to send.
+// send: func(Notification) Delivers a notification, e.g. Dispatcher.Dispatch. It is called with the batcher locked,
+// so it must not block.
+// window: time.Duration How long to collect notifications before sending a digest. Zero disables batching.
+// maxCount: int Send the digest early once it holds this many notifications. Zero means no limit.
+// returns: *Batcher The batcher.
+func NewBatcher(send func(n Notification), window time.Duration, maxCount int) *Batcher {
+	return &Batcher{send: send, window: window, maxCount: maxCount}
+}
+
+// Configure changes the batch window and maximum count, sending any pending batch first.
+// window: time.Duration How long to collect notifications before sending a digest. Zero disables batching.
+// maxCount: int Send the digest early once it holds this many notifications. Zero means no limit.
+func (b *Batcher) Configure(window time.Duration, maxCount int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushLocked()
+	b.window = window
+	b.maxCount = maxCount
+}
+
+// Add queues a notification for the current batch, or sends it straight away.
+// Immediate notifications send the pending batch first so notifications stay in order.
+// n: Notification The notification.
+// immediate: bool Send without waiting for the batch window.
+func (b *Batcher) Add(n Notification, immediate bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if immediate || b.window <= 0 {
+		b.flushLocked()
+		b.send(n)
+		return
+	}
+
+	b.pending = append(b.pending, n)
+	if b.maxCount > 0 && len(b.pending) >= b.maxCount {
+		b.flushLocked()
+		return
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.window, b.Flush)
+	}
+}
+
+// Flush sends the pending batch, if any.
+func (b *Batcher) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushLocked()
+}
+
+// flushLocked sends the pending batch. The caller must hold b.mu.
+func (b *Batcher) flushLocked() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.pending) == 0 {
+		return
+	}
+	b.send(digest(b.pending))
+	b.pending = nil
+}
+
+// digest combines notifications into one, at the level of the most severe. Each notification's fields
+// are listed in the digest's message. A single notification is returned unchanged.
+// notifications: []Notification The notifications to combine.
+// returns: Notification The digest.
+func digest(notifications []Notification) Notification {
+	if len(notifications) == 1 {
+		return notifications[0]
+	}
+	level := notifications[0].Level
+	messages := make([]string, 0, len(notifications))
+	for _, n := range notifications {
+		if n.Level > level {
+			level = n.Level
+		}
+		messages = append(messages, Format(n.Message, n.Fields))
+	}
+	return Notification{
+		Level:   level,
+		Message: fmt.Sprintf("%d alerts raised together:\n%s", len(notifications), strings.Join(messages, digestSeparator)),
+	}
+}
@@ -0,0 +1,112 @@
+package notify
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector records the notifications a Batcher sends.
+type collector struct {
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+func (c *collector) send(n Notification) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notifications = append(c.notifications, n)
+}
+
+func (c *collector) sent() []Notification {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Notification(nil), c.notifications...)
+}
+
+// notifications creates an error notification for each message.
+func notifications(messages ...string) []Notification {
+	ns := make([]Notification, 0, len(messages))
+	for _, message := range messages {
+		ns = append(ns, Notification{Level: LevelError, Message: message})
+	}
+	return ns
+}
+
+// TestBatcherAdd tests how notifications are sent or batched for each batcher setting.
+func TestBatcherAdd(t *testing.T) {
+	type add struct {
+		message   string
+		immediate bool
+	}
+	tests := []struct {
+		name     string
+		window   time.Duration
+		maxCount int
+		adds     []add
+		want     []Notification
+	}{
+		{
+			name:   "batching disabled sends each notification",
+			window: 0,
+			adds:   []add{{"a", false}, {"b", false}},
+			want:   notifications("a", "b"),
+		},
+		{
+			name:     "max count sends a digest early",
+			window:   time.Hour,
+			maxCount: 2,
+			adds:     []add{{"a", false}, {"b", false}},
+			want:     []Notification{digest(notifications("a", "b"))},
+		},
+		{
+			name:   "immediate notification sends the pending batch first",
+			window: time.Hour,
+			adds:   []add{{"a", false}, {"b", false}, {"removed", true}},
+			want:   append([]Notification{digest(notifications("a", "b"))}, notifications("removed")...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &collector{}
+			b := NewBatcher(c.send, tt.window, tt.maxCount)
+			for _, a := range tt.adds {
+				b.Add(Notification{Level: LevelError, Message: a.message}, a.immediate)
+			}
+			if got := c.sent(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sent = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBatcherWindow tests that a batch is sent once the window elapses.
+func TestBatcherWindow(t *testing.T) {
+	c := &collector{}
+	b := NewBatcher(c.send, 10*time.Millisecond, 0)
+	b.Add(Notification{Level: LevelError, Message: "a"}, false)
+	b.Add(Notification{Level: LevelError, Message: "b"}, false)
+
+	deadline := time.Now().Add(time.Second)
+	for len(c.sent()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got, want := c.sent(), []Notification{digest(notifications("a", "b"))}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sent = %q, want %q", got, want)
+	}
+}
+
+// TestDigest tests that a single notification is left unchanged, and several are combined at the most severe level.
+func TestDigest(t *testing.T) {
+	single := Notification{Level: LevelWarning, Message: "a", Fields: map[string]interface{}{"VolumeID": "vol-1"}}
+	if got := digest([]Notification{single}); !reflect.DeepEqual(got, single) {
+		t.Errorf("digest() = %+v, want %+v", got, single)
+	}
+	got := digest([]Notification{single, {Level: LevelError, Message: "b"}})
+	want := Notification{Level: LevelError, Message: "2 alerts raised together:\na\nAdditional Information:\n    VolumeID: vol-1\n----\nb"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("digest() = %+v, want %+v", got, want)
+	}
+}
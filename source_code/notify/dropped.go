@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// VolumeDropped is the alert sent when a volume is dropped from monitoring after repeated errors.
+type VolumeDropped struct {
+	VolumeID      string        // Identifier of the dropped volume.
+	DeviceName    string        // Device name of the dropped volume.
+	ErrorCount    int           // Consecutive errors that reached the error threshold.
+	Reason        string        // Error that caused the volume to be dropped.
+	RecentErrors  []string      // Most recent errors recorded for the volume, newest first.
+	RetryInterval time.Duration // How often the volume is retried until it recovers.
+}
+
+// Message formats the alert, leading with a banner so it stands out from routine notifications.
+// returns: string The alert text.
+func (a VolumeDropped) Message() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[ACTION REQUIRED] Volume %s (%s) is no longer monitored\n", a.VolumeID, a.DeviceName)
+	fmt.Fprintf(&b, "It was dropped after %d consecutive errors, and won't be resized until it recovers.\n", a.ErrorCount)
+	fmt.Fprintf(&b, "It is retried every %v and monitored again once it passes its checks.\n", a.RetryInterval)
+	fmt.Fprintf(&b, "\nReason:\n    %s\n", a.Reason)
+	if len(a.RecentErrors) > 0 {
+		b.WriteString("\nRecent errors (newest first):\n")
+		for _, err := range a.RecentErrors {
+			fmt.Fprintf(&b, "    - %s\n", err)
+		}
+	}
+	b.WriteString("\nRemediation:\n")
+	for i, step := range a.remediation() {
+		fmt.Fprintf(&b, "    %d. %s\n", i+1, step)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// remediation returns the checks that resolve most dropped volumes, for this volume.
+// returns: []string The steps, in the order to try them.
+func (a VolumeDropped) remediation() []string {
+	return []string{
+		fmt.Sprintf("Check the volume is still attached to this instance: aws ec2 describe-volumes --volume-ids %s", a.VolumeID),
+		fmt.Sprintf("Check its filesystem is mounted: findmnt --source %s, or the volume's localMountPoint", a.DeviceName),
+		"Check the instance role allows ec2:DescribeVolumes, ec2:DescribeInstances and ec2:ModifyVolume",
+		"Check the volume ID and device name in the config still match the attachment",
+	}
+}
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestVolumeDroppedMessage tests that the alert names the volume and lists its errors and remediation steps.
+func TestVolumeDroppedMessage(t *testing.T) {
+	alert := VolumeDropped{
+		VolumeID:      "vol-0abcd1234efgh5678",
+		DeviceName:    "/dev/sdf",
+		ErrorCount:    5,
+		Reason:        "volume not found",
+		RecentErrors:  []string{"volume not found", "device not attached"},
+		RetryInterval: 5 * time.Minute,
+	}
+
+	message := alert.Message()
+	if !strings.HasPrefix(message, "[ACTION REQUIRED] Volume vol-0abcd1234efgh5678 (/dev/sdf)") {
+		t.Errorf("Message() doesn't start with the banner:\n%s", message)
+	}
+	for _, want := range []string{
+		"after 5 consecutive errors",
+		"retried every 5m0s",
+		"    - device not attached",
+		"Remediation:",
+		"describe-volumes --volume-ids vol-0abcd1234efgh5678",
+		"findmnt --source /dev/sdf",
+		"ec2:DescribeVolumes",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("Message() doesn't mention %q:\n%s", want, message)
+		}
+	}
+
+	alert.RecentErrors = nil
+	if strings.Contains(alert.Message(), "Recent errors") {
+		t.Errorf("Message() lists recent errors without any")
+	}
+}
@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is reported when a channel's queue is full and a notification is dropped.
+var ErrQueueFull = errors.New("notification queue full, message dropped")
+
+// Level is the severity of a notification, matching the level it was logged at.
+type Level int
+
+// Notification levels, in increasing severity.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarning
+	LevelError
+	LevelFatal
+)
+
+// Notifier is a notification channel, such as SNS or Slack.
+type Notifier interface {
+	Name() string // Name of the channel, used when reporting errors.
+	// Notify delivers a single notification, presented according to its level.
+	Notify(level Level, message string, fields map[string]interface{}) error
+}
+
+// Notification is a message waiting to be delivered, with the level and fields it was logged with.
+type Notification struct {
+	Level   Level                  // Severity of the notification.
+	Message string                 // The message.
+	Fields  map[string]interface{} // Context for the message, e.g. the volume ID. May be nil.
+}
+
+// Format renders a message and its fields as plain text, for channels without structured fields.
+// message: string The message.
+// fields: map[string]interface{} The fields, listed by name under the message. May be nil.
+// returns: string The text.
+func Format(message string, fields map[string]interface{}) string {
+	if len(fields) == 0 {
+		return message
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fieldStrs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		fieldStrs = append(fieldStrs, fmt.Sprintf("%s: %v", key, fields[key]))
+	}
+	return fmt.Sprintf("%s\nAdditional Information:\n    %s", message, strings.Join(fieldStrs, ",\n\t"))
+}
+
+// ErrorHandler is called when a notification fails to send or is dropped.
+// channel: string Name of the channel the notification was for.
+// err: error The send error, or ErrQueueFull when the notification was dropped.
+type ErrorHandler func(channel string, err error)
+
+// Dispatcher sends notifications to each channel asynchronously, so a slow channel doesn't block the caller.
+// Each channel has its own bounded queue and a single worker, so notifications are delivered in the order
+// they were dispatched.
+type Dispatcher struct {
+	mu       sync.RWMutex // Guards closed against concurrent Dispatch and Close.
+	closed   bool
+	channels []*channel
+	onError  ErrorHandler
+	wg       sync.WaitGroup
+}
+
+// channel is a notifier and the queue of notifications waiting for it.
+type channel struct {
+	notifier Notifier
+	queue    chan Notification
+}
+
+// NewDispatcher creates a Dispatcher and starts a worker for each notifier.
+// queueSize: int Number of notifications each channel can hold before new ones are dropped.
+// onError: ErrorHandler Called on send failures and dropped notifications. May be nil.
+// notifiers: ...Notifier The channels to deliver notifications to.
+// returns: *Dispatcher The running dispatcher.
+func NewDispatcher(queueSize int, onError ErrorHandler, notifiers ...Notifier) *Dispatcher {
+	d := &Dispatcher{onError: onError}
+	for _, n := range notifiers {
+		c := &channel{notifier: n, queue: make(chan Notification, queueSize)}
+		d.channels = append(d.channels, c)
+		d.wg.Add(1)
+		go d.work(c)
+	}
+	return d
+}
+
+// work delivers a channel's queued notifications in order until the queue is closed.
+// c: *channel The channel to deliver for.
+func (d *Dispatcher) work(c *channel) {
+	defer d.wg.Done()
+	for n := range c.queue {
+		if err := c.notifier.Notify(n.Level, n.Message, n.Fields); err != nil {
+			d.report(c.notifier.Name(), err)
+		}
+	}
+}
+
+// report passes an error to the dispatcher's error handler, if it has one.
+// channelName: string Name of the channel the error occurred on.
+// err: error The error to report.
+func (d *Dispatcher) report(channelName string, err error) {
+	if d.onError != nil {
+		d.onError(channelName, err)
+	}
+}
+
+// Dispatch queues a notification on every channel without blocking.
+// When a channel's queue is full the notification is dropped for that channel and ErrQueueFull reported.
+// Notifications dispatched after Close are discarded.
+// n: Notification The notification to send.
+func (d *Dispatcher) Dispatch(n Notification) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return
+	}
+	for _, c := range d.channels {
+		select {
+		case c.queue <- n:
+		default:
+			d.report(c.notifier.Name(), ErrQueueFull)
+		}
+	}
+}
+
+// Close stops accepting notifications and waits for queued ones to be delivered.
+// timeout: time.Duration Maximum time to wait for the queues to drain.
+// returns: bool True if every queued notification was delivered before the timeout.
+func (d *Dispatcher) Close(timeout time.Duration) bool {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		for _, c := range d.channels {
+			close(c.queue)
+		}
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
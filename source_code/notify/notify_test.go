@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingNotifier records the messages it is sent, optionally blocking until released.
+type recordingNotifier struct {
+	mu       sync.Mutex
+	messages []string
+	release  chan struct{}
+	err      error
+}
+
+func (n *recordingNotifier) Name() string { return "recording" }
+
+func (n *recordingNotifier) Notify(level Level, message string, fields map[string]interface{}) error {
+	if n.release != nil {
+		<-n.release
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.messages = append(n.messages, message)
+	return n.err
+}
+
+// TestDispatchOrder tests that notifications are delivered in the order they were dispatched.
+func TestDispatchOrder(t *testing.T) {
+	n := &recordingNotifier{}
+	d := NewDispatcher(10, nil, n)
+
+	want := []string{"first", "second", "third"}
+	for _, message := range want {
+		d.Dispatch(Notification{Level: LevelError, Message: message})
+	}
+	if !d.Close(time.Second) {
+		t.Fatalf("Close() timed out")
+	}
+	if !reflect.DeepEqual(n.messages, want) {
+		t.Errorf("messages = %v, want %v", n.messages, want)
+	}
+}
+
+// TestDispatchQueueFull tests that a notification is dropped and reported when the queue is full.
+func TestDispatchQueueFull(t *testing.T) {
+	n := &recordingNotifier{release: make(chan struct{})}
+	var errs []error
+	var mu sync.Mutex
+	d := NewDispatcher(1, func(channel string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}, n)
+
+	// The worker holds "first" until released, "second" fills the queue and "third" is dropped
+	d.Dispatch(Notification{Message: "first"})
+	deadline := time.Now().Add(time.Second)
+	for len(d.channels[0].queue) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	d.Dispatch(Notification{Message: "second"})
+	d.Dispatch(Notification{Message: "third"})
+	close(n.release)
+	d.Close(time.Second)
+
+	if len(errs) != 1 || !errors.Is(errs[0], ErrQueueFull) {
+		t.Errorf("errors = %v, want [%v]", errs, ErrQueueFull)
+	}
+	if want := []string{"first", "second"}; !reflect.DeepEqual(n.messages, want) {
+		t.Errorf("messages = %v, want %v", n.messages, want)
+	}
+}
+
+// TestDispatchSendError tests that send failures are passed to the error handler.
+func TestDispatchSendError(t *testing.T) {
+	sendErr := errors.New("endpoint unavailable")
+	n := &recordingNotifier{err: sendErr}
+	var got error
+	d := NewDispatcher(1, func(channel string, err error) { got = err }, n)
+
+	d.Dispatch(Notification{Message: "message"})
+	d.Close(time.Second)
+	d.Dispatch(Notification{Message: "after close"})
+
+	if !errors.Is(got, sendErr) {
+		t.Errorf("error = %v, want %v", got, sendErr)
+	}
+	if len(n.messages) != 1 {
+		t.Errorf("messages = %v, want only the message sent before Close", n.messages)
+	}
+}
+
+// TestFormat tests that fields are listed under the message in name order, and omitted when there are none.
+func TestFormat(t *testing.T) {
+	if got := Format("Volume resized", nil); got != "Volume resized" {
+		t.Errorf("Format() = %q, want the message alone", got)
+	}
+	got := Format("Volume resized", map[string]interface{}{"VolumeID": "vol-1", "New Size": 120})
+	if want := "Volume resized\nAdditional Information:\n    New Size: 120,\n\tVolumeID: vol-1"; got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+}
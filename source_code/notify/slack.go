@@ -0,0 +1,122 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+)
+
+// slackTimeout bounds each webhook post, so a hung endpoint can't stall the channel's queue.
+const slackTimeout = 30 * time.Second
+
+// SlackNotifier posts notifications to a Slack incoming webhook.
+type SlackNotifier struct {
+	webhookURL string
+	hostname   string
+	client     *http.Client
+}
+
+// slackMessage is the payload accepted by Slack incoming webhooks, posted as a single attachment
+// so the notification's level sets the colour of its sidebar.
+type slackMessage struct {
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+// slackAttachment is a Slack message attachment.
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Title  string       `json:"title"`
+	Text   string       `json:"text"`
+	Fields []slackField `json:"fields,omitempty"`
+}
+
+// slackField is a name and value shown in a table under an attachment's text.
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// slackStyle is how a notification level is presented in Slack.
+type slackStyle struct {
+	emoji string // Emoji leading the title.
+	label string // Name of the level in the title.
+	color string // Colour of the attachment sidebar.
+}
+
+// slackStyles gives the presentation of each notification level.
+var slackStyles = map[Level]slackStyle{
+	LevelDebug:   {":information_source:", "debug", "#9e9e9e"},
+	LevelInfo:    {":information_source:", "info", "#2eb67d"},
+	LevelWarning: {":warning:", "warning", "#ecb22e"},
+	LevelError:   {":no_entry:", "error", "#e01e5a"},
+	LevelFatal:   {":rotating_light:", "fatal", "#8b0000"},
+}
+
+// NewSlackNotifier creates a SlackNotifier for an incoming webhook.
+// webhookURL: string The webhook URL, e.g. https://hooks.slack.com/services/...
+// returns: *SlackNotifier The notifier.
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown host"
+	}
+	return &SlackNotifier{
+		webhookURL: webhookURL,
+		hostname:   hostname,
+		client:     &http.Client{Timeout: slackTimeout},
+	}
+}
+
+// Name returns the channel name used when reporting errors.
+func (n *SlackNotifier) Name() string {
+	return "slack"
+}
+
+// Notify posts the message to the webhook, titled with its level and the host it came from like SNS alerts,
+// and coloured by its level. The fields are shown as a table under the message.
+// level: Level The level of the notification.
+// message: string The message to post.
+// fields: map[string]interface{} Context for the message. May be nil.
+// returns: error An error if the post fails or Slack rejects it.
+func (n *SlackNotifier) Notify(level Level, message string, fields map[string]interface{}) error {
+	style, ok := slackStyles[level]
+	if !ok {
+		style = slackStyles[LevelError]
+	}
+	attachment := slackAttachment{
+		Color: style.color,
+		Title: fmt.Sprintf("%s ebsmon-alert (%s): %s", style.emoji, style.label, n.hostname),
+		Text:  message,
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		attachment.Fields = append(attachment.Fields, slackField{Title: key, Value: fmt.Sprint(fields[key]), Short: true})
+	}
+
+	payload, err := json.Marshal(slackMessage{Attachments: []slackAttachment{attachment}})
+	if err != nil {
+		return fmt.Errorf("unable to marshal Slack message, %v", err)
+	}
+
+	resp, err := n.client.Post(n.webhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("unable to post message to Slack, %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Slack rejected the message with status %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
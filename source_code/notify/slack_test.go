@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestSlackNotifierNotify tests that notifications are posted to the webhook as an attachment titled and
+// coloured by level, with the fields in name order.
+func TestSlackNotifierNotify(t *testing.T) {
+	var got slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		got = slackMessage{}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	n := NewSlackNotifier(server.URL)
+	tests := []struct {
+		level     Level
+		wantTitle string
+		wantColor string
+	}{
+		{LevelWarning, ":warning: ebsmon-alert (warning): ", slackStyles[LevelWarning].color},
+		{LevelError, ":no_entry: ebsmon-alert (error): ", slackStyles[LevelError].color},
+		{LevelFatal, ":rotating_light: ebsmon-alert (fatal): ", slackStyles[LevelFatal].color},
+	}
+	for _, tt := range tests {
+		fields := map[string]interface{}{"VolumeID": "vol-1", "New Size": 120}
+		if err := n.Notify(tt.level, "Volume resized", fields); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+		if len(got.Attachments) != 1 {
+			t.Fatalf("Notify() posted %d attachments, want 1", len(got.Attachments))
+		}
+		a := got.Attachments[0]
+		if !strings.HasPrefix(a.Title, tt.wantTitle) || a.Color != tt.wantColor || a.Text != "Volume resized" {
+			t.Errorf("Notify(%v) posted title %q, colour %q and text %q", tt.level, a.Title, a.Color, a.Text)
+		}
+		wantFields := []slackField{{Title: "New Size", Value: "120", Short: true}, {Title: "VolumeID", Value: "vol-1", Short: true}}
+		if !reflect.DeepEqual(a.Fields, wantFields) {
+			t.Errorf("Notify(%v) posted fields %+v, want %+v", tt.level, a.Fields, wantFields)
+		}
+	}
+}
+
+// TestSlackNotifierNotifyRejected tests that a non-2xx response is reported with Slack's reason.
+func TestSlackNotifierNotifyRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("invalid_token"))
+	}))
+	defer server.Close()
+
+	err := NewSlackNotifier(server.URL).Notify(LevelError, "Volume resized", nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid_token") {
+		t.Errorf("Notify() error = %v, want an error containing invalid_token", err)
+	}
+}
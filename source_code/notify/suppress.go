@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// DefaultCooldown is how long a repeated alert is suppressed for when no cooldown is configured.
+const DefaultCooldown = time.Hour
+
+// Suppressor rate-limits repeated alerts, so a wedged volume raising the same error every check
+// notifies once per cooldown rather than once per check.
+type Suppressor struct {
+	mu       sync.Mutex
+	cooldown time.Duration
+	alerts   map[uint64]*alertState
+}
+
+// alertState tracks when an alert was last sent and how often it was suppressed since.
+type alertState struct {
+	lastSent   time.Time
+	suppressed int
+}
+
+// NewSuppressor creates a Suppressor.
+// cooldown: time.Duration How long to suppress an alert after sending it. Zero uses DefaultCooldown.
+// returns: *Suppressor The suppressor.
+func NewSuppressor(cooldown time.Duration) *Suppressor {
+	s := &Suppressor{alerts: make(map[uint64]*alertState)}
+	s.SetCooldown(cooldown)
+	return s
+}
+
+// SetCooldown changes how long alerts are suppressed for.
+// cooldown: time.Duration How long to suppress an alert after sending it. Zero uses DefaultCooldown.
+func (s *Suppressor) SetCooldown(cooldown time.Duration) {
+	if cooldown <= 0 {
+		cooldown = DefaultCooldown
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cooldown = cooldown
+}
+
+// Allow reports whether an alert should be sent, recording it as sent or suppressed.
+// subject: string What the alert is about, e.g. a volume ID. May be empty.
+// message: string The alert message, without details that change between occurrences.
+// now: time.Time The time of the alert.
+// returns: bool True if the alert should be sent.
+// returns: int How many times the alert was suppressed since it was last sent, when it should be sent.
+func (s *Suppressor) Allow(subject, message string, now time.Time) (bool, int) {
+	key := alertKey(subject, message)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked(now)
+
+	state, seen := s.alerts[key]
+	if seen && now.Sub(state.lastSent) < s.cooldown {
+		state.suppressed++
+		return false, 0
+	}
+
+	suppressed := 0
+	if seen {
+		suppressed = state.suppressed
+	}
+	s.alerts[key] = &alertState{lastSent: now}
+	return true, suppressed
+}
+
+// pruneLocked forgets alerts last sent more than two cooldowns ago, which would be sent again anyway.
+// Keeping them for one extra cooldown preserves their suppressed count for the next send. The caller must hold s.mu.
+// now: time.Time The current time.
+func (s *Suppressor) pruneLocked(now time.Time) {
+	for key, state := range s.alerts {
+		if now.Sub(state.lastSent) >= 2*s.cooldown {
+			delete(s.alerts, key)
+		}
+	}
+}
+
+// alertKey hashes an alert's subject and message.
+// subject: string What the alert is about.
+// message: string The alert message.
+// returns: uint64 The hash.
+func alertKey(subject, message string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(subject))
+	h.Write([]byte{0})
+	h.Write([]byte(message))
+	return h.Sum64()
+}
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSuppressorAllow tests that repeats are suppressed within the cooldown and counted when next sent.
+func TestSuppressorAllow(t *testing.T) {
+	s := NewSuppressor(time.Hour)
+	start := time.Date(2023, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name           string
+		subject        string
+		message        string
+		offset         time.Duration
+		wantSend       bool
+		wantSuppressed int
+	}{
+		{"First alert", "vol-1", "mount point not found", 0, true, 0},
+		{"Repeat", "vol-1", "mount point not found", 10 * time.Minute, false, 0},
+		{"Repeat again", "vol-1", "mount point not found", 20 * time.Minute, false, 0},
+		{"Other volume", "vol-2", "mount point not found", 30 * time.Minute, true, 0},
+		{"Other message", "vol-1", "resize failed", 40 * time.Minute, true, 0},
+		{"After cooldown", "vol-1", "mount point not found", 61 * time.Minute, true, 2},
+		{"Repeat after resend", "vol-1", "mount point not found", 70 * time.Minute, false, 0},
+	}
+
+	for _, step := range steps {
+		send, suppressed := s.Allow(step.subject, step.message, start.Add(step.offset))
+		if send != step.wantSend || suppressed != step.wantSuppressed {
+			t.Errorf("%s: Allow() = %v, %d, want %v, %d", step.name, send, suppressed, step.wantSend, step.wantSuppressed)
+		}
+	}
+}
+
+// TestSuppressorPrune tests that long-quiet alerts are forgotten.
+func TestSuppressorPrune(t *testing.T) {
+	s := NewSuppressor(time.Minute)
+	start := time.Date(2023, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	s.Allow("vol-1", "resize failed", start)
+	s.Allow("vol-2", "resize failed", start.Add(90*time.Second))
+	if len(s.alerts) != 2 {
+		t.Fatalf("tracked %d alerts, want 2", len(s.alerts))
+	}
+
+	s.Allow("vol-2", "resize failed", start.Add(2*time.Minute))
+	if len(s.alerts) != 1 {
+		t.Errorf("tracked %d alerts after pruning, want 1", len(s.alerts))
+	}
+}
+
+// TestSuppressorDefaultCooldown tests that a zero cooldown uses DefaultCooldown.
+func TestSuppressorDefaultCooldown(t *testing.T) {
+	if s := NewSuppressor(0); s.cooldown != DefaultCooldown {
+		t.Errorf("cooldown = %v, want %v", s.cooldown, DefaultCooldown)
+	}
+}
@@ -0,0 +1,81 @@
+package resize
+
+import (
+	"ebs-monitor/aws"
+	"ebs-monitor/logger"
+	"ebs-monitor/runtime"
+	"math"
+	"sync"
+)
+
+// defaultVolumePrices are the on-demand storage prices, in USD per GiB-month, of each EBS volume type in us-east-1.
+// Provisioned IOPS and throughput are charged separately and not included.
+var defaultVolumePrices = map[string]float64{
+	"gp2": 0.10,
+	"gp3": 0.08,
+	"io1": 0.125,
+	"io2": 0.125,
+	"st1": 0.045,
+	"sc1": 0.015,
+}
+
+var (
+	// volumePricesMu guards volumePrices, which is set once config is loaded.
+	volumePricesMu sync.Mutex
+	volumePrices   map[string]float64
+)
+
+// SetVolumePrices : overrides the per GiB-month prices used to estimate resize costs, e.g. for another region.
+// prices : map[string]float64 : price in USD per GiB-month for each volume type, types not in the map use the built-in price
+func SetVolumePrices(prices map[string]float64) {
+	volumePricesMu.Lock()
+	defer volumePricesMu.Unlock()
+	volumePrices = prices
+}
+
+// volumePrice : returns the per GiB-month price of a volume type, the configured override or else the built-in price.
+// volumeType : string : the EBS volume type, e.g. "gp3"
+// returns : float64 : the price in USD per GiB-month
+// returns : bool : false if there is no price for the volume type
+func volumePrice(volumeType string) (float64, bool) {
+	volumePricesMu.Lock()
+	defer volumePricesMu.Unlock()
+	if price, ok := volumePrices[volumeType]; ok {
+		return price, true
+	}
+	price, ok := defaultVolumePrices[volumeType]
+	return price, ok
+}
+
+// EstimateMonthlyCostDelta : Estimates how much adding space to a volume adds to its monthly storage cost
+// volumeType : string : The EBS volume type, e.g. "gp3"
+// addedGB : int64 : The GiB the resize adds to the volume
+// returns : float64 : The added cost in USD per month, rounded to cents, or 0 if the volume type has no price
+func EstimateMonthlyCostDelta(volumeType string, addedGB int64) float64 {
+	price, ok := volumePrice(volumeType)
+	if !ok || addedGB <= 0 {
+		return 0
+	}
+	return math.Round(price*float64(addedGB)*100) / 100
+}
+
+// getVolumeType is replaced in tests.
+var getVolumeType = aws.GetVolumeType
+
+// estimateResizeCost : Estimates the monthly cost a resize adds, looking up the volume's type in AWS
+// A failed lookup is logged at debug, as the estimate is informational and never holds up a resize.
+// volume : runtime.EBSVolumeConfig : Configuration of the EBS volume
+// currentSize : int64 : The size of the volume before the resize in GiB
+// newSize : int64 : The size of the volume after the resize in GiB
+// returns : float64 : The added cost in USD per month, 0 if it can't be estimated
+func estimateResizeCost(volume runtime.EBSVolumeConfig, currentSize, newSize int64) float64 {
+	volumeType, err := getVolumeType(volume)
+	if err != nil {
+		l.Log(logger.LogDebug, "Failed to get the volume type to estimate the resize cost.", map[string]interface{}{
+			"AWS Volume ID": volume.AWSVolumeID,
+			"Error":         err,
+		})
+		return 0
+	}
+	return EstimateMonthlyCostDelta(volumeType, newSize-currentSize)
+}
@@ -0,0 +1,47 @@
+package resize
+
+import (
+	"ebs-monitor/aws"
+	"ebs-monitor/runtime"
+	"errors"
+	"testing"
+)
+
+// TestEstimateMonthlyCostDelta tests the monthly cost of added space, from the built-in or overridden prices.
+func TestEstimateMonthlyCostDelta(t *testing.T) {
+	tests := []struct {
+		name       string
+		prices     map[string]float64
+		volumeType string
+		addedGB    int64
+		expected   float64
+	}{
+		{name: "built-in gp3 price", volumeType: "gp3", addedGB: 100, expected: 8},
+		{name: "built-in io2 price", volumeType: "io2", addedGB: 10, expected: 1.25},
+		{name: "rounded to cents", volumeType: "sc1", addedGB: 3, expected: 0.05},
+		{name: "overridden price", prices: map[string]float64{"gp3": 0.0952}, volumeType: "gp3", addedGB: 100, expected: 9.52},
+		{name: "override leaves other types", prices: map[string]float64{"gp3": 0.0952}, volumeType: "gp2", addedGB: 100, expected: 10},
+		{name: "unknown type", volumeType: "standard", addedGB: 100, expected: 0},
+		{name: "nothing added", volumeType: "gp3", addedGB: 0, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetVolumePrices(tt.prices)
+			t.Cleanup(func() { SetVolumePrices(nil) })
+			if got := EstimateMonthlyCostDelta(tt.volumeType, tt.addedGB); got != tt.expected {
+				t.Errorf("EstimateMonthlyCostDelta() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestEstimateResizeCostLookupFails tests that a failed volume type lookup leaves the cost unestimated.
+func TestEstimateResizeCostLookupFails(t *testing.T) {
+	getVolumeType = func(runtime.EBSVolumeConfig) (string, error) { return "", errors.New("throttled") }
+	t.Cleanup(func() { getVolumeType = aws.GetVolumeType })
+
+	if got := estimateResizeCost(runtime.EBSVolumeConfig{AWSVolumeID: "vol-0abcd1234efgh5678"}, 100, 200); got != 0 {
+		t.Errorf("estimateResizeCost() = %v, want 0", got)
+	}
+}
@@ -0,0 +1,69 @@
+package resize
+
+import (
+	"ebs-monitor/filesystem"
+	"ebs-monitor/logger"
+	"ebs-monitor/runtime"
+	"fmt"
+	"strings"
+)
+
+// Names of the resize hooks, as reported in logs
+const (
+	preResizeHook  = "pre-resize"
+	postResizeHook = "post-resize"
+)
+
+// Results of a resize passed to the post-resize hook in EBS_RESIZE_RESULT
+const (
+	hookResultSuccess = "success"
+	hookResultFailure = "failure"
+)
+
+// runShellCommand is replaced in tests.
+var runShellCommand = filesystem.RunShellCommand
+
+// hookEnv : Returns the environment variables that describe a resize to its hooks
+// volume : runtime.EBSVolumeConfig : Configuration of the EBS volume
+// localMountPoint : string : Mount point of the volume's filesystem
+// originalSize : int64 : Size of the volume before the resize in GiB
+// newSize : int64 : The size the volume is resized to in GiB
+// returns : []string : The variables, as KEY=value
+func hookEnv(volume runtime.EBSVolumeConfig, localMountPoint string, originalSize, newSize int64) []string {
+	return []string{
+		"EBS_VOLUME_ID=" + volume.AWSVolumeID,
+		"EBS_DEVICE_NAME=" + volume.AWSDeviceName,
+		"EBS_REGION=" + volume.AWSRegion,
+		"EBS_MOUNT_POINT=" + localMountPoint,
+		fmt.Sprintf("EBS_OLD_SIZE_GIB=%d", originalSize),
+		fmt.Sprintf("EBS_NEW_SIZE_GIB=%d", newSize),
+	}
+}
+
+// runHook : Runs a configured resize hook and logs its output
+// Nothing is run when the command is empty.
+// name : string : Name of the hook, for the logs
+// command : string : The hook's shell command
+// volume : runtime.EBSVolumeConfig : Configuration of the EBS volume
+// env : []string : Environment variables describing the resize
+// returns : error : Any error running the hook, including a non-zero exit
+func runHook(name, command string, volume runtime.EBSVolumeConfig, env []string) error {
+	if command == "" {
+		return nil
+	}
+	fmt.Printf("Running %s hook: %s\n", name, command)
+	output, err := runShellCommand(command, env)
+	fields := map[string]interface{}{
+		"AWS Volume ID": volume.AWSVolumeID,
+		"Hook":          name,
+		"Command":       command,
+		"Output":        strings.TrimSpace(output),
+	}
+	if err != nil {
+		fields["Error"] = err
+		l.Log(logger.LogError, fmt.Sprintf("The %s hook failed.", name), fields)
+		return fmt.Errorf("%s hook failed. error: %w", name, err)
+	}
+	l.Log(logger.LogInfo, fmt.Sprintf("The %s hook completed.", name), fields)
+	return nil
+}
@@ -0,0 +1,62 @@
+package resize
+
+import (
+	"ebs-monitor/runtime"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestHookEnv tests that hooks are told the volume, mount point and sizes of the resize.
+func TestHookEnv(t *testing.T) {
+	volume := runtime.EBSVolumeConfig{AWSVolumeID: "vol-1", AWSDeviceName: "/dev/sdf", AWSRegion: "ap-southeast-2"}
+	want := []string{
+		"EBS_VOLUME_ID=vol-1",
+		"EBS_DEVICE_NAME=/dev/sdf",
+		"EBS_REGION=ap-southeast-2",
+		"EBS_MOUNT_POINT=/data",
+		"EBS_OLD_SIZE_GIB=100",
+		"EBS_NEW_SIZE_GIB=110",
+	}
+	if got := hookEnv(volume, "/data", 100, 110); !reflect.DeepEqual(got, want) {
+		t.Errorf("hookEnv() = %v, want %v", got, want)
+	}
+}
+
+// TestRunHook tests that an unset hook isn't run, and that a failing hook returns an error.
+func TestRunHook(t *testing.T) {
+	original := runShellCommand
+	defer func() { runShellCommand = original }()
+
+	tests := []struct {
+		name    string
+		command string
+		err     error
+		wantRun bool
+		wantErr bool
+	}{
+		{"unset", "", nil, false, false},
+		{"succeeds", "quiesce", nil, true, false},
+		{"fails", "quiesce", errors.New("exit status 1"), true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran := false
+			runShellCommand = func(command string, env []string) (string, error) {
+				ran = true
+				if command != tt.command || !reflect.DeepEqual(env, []string{"EBS_VOLUME_ID=vol-1"}) {
+					t.Errorf("runShellCommand(%q, %v), want %q with the hook env", command, env, tt.command)
+				}
+				return "output", tt.err
+			}
+			err := runHook(preResizeHook, tt.command, runtime.EBSVolumeConfig{AWSVolumeID: "vol-1"}, []string{"EBS_VOLUME_ID=vol-1"})
+			if ran != tt.wantRun {
+				t.Errorf("runHook() ran = %v, want %v", ran, tt.wantRun)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("runHook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
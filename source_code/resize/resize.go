@@ -5,43 +5,276 @@ import (
 	"ebs-monitor/filesystem"
 	"ebs-monitor/logger"
 	"ebs-monitor/runtime"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 )
 
 // Initialise logger
 var l = logger.NewLogger()
 
+// modificationStateModifying is the modification state during which the new size is not yet usable.
+const modificationStateModifying = "modifying"
+
+// DefaultPostAWSResizeDelaySeconds is how long to wait between the AWS resize and the filesystem resize
+// when a volume does not set postAWSResizeDelaySeconds.
+const DefaultPostAWSResizeDelaySeconds = 60
+
+// SkippedError : Reports a resize that was deliberately not attempted, as opposed to one that failed
+type SkippedError struct {
+	Reason string // Why the resize was skipped, one of the runtime SkipReason constants.
+}
+
+// Error : Returns the skip as an error message
+// returns : string : The skip reason
+func (e *SkippedError) Error() string {
+	return fmt.Sprintf("resize skipped: %s", e.Reason)
+}
+
+// DefaultResizeLockTTLSeconds is how long a resize lock not released, e.g. after a crash, is honoured
+// when resizeLockTTLSeconds is not set. It must outlast the longest resize.
+const DefaultResizeLockTTLSeconds = 3600
+
+// resizeLockSettleDelay is how long to wait after taking a resize lock before confirming it. EC2 tags are eventually
+// consistent, so another instance setting the lock at the same time may only overwrite it after it was read back.
+const resizeLockSettleDelay = 5 * time.Second
+
+// resizeLockTTL : Returns how long the volume's resize lock is held for if it isn't released
+// volume : runtime.EBSVolumeConfig : Configuration of the EBS volume
+// returns : time.Duration : The lock's time to live
+func resizeLockTTL(volume runtime.EBSVolumeConfig) time.Duration {
+	if volume.ResizeLockTTLSeconds > 0 {
+		return time.Duration(volume.ResizeLockTTLSeconds) * time.Second
+	}
+	return DefaultResizeLockTTLSeconds * time.Second
+}
+
+// confirmResizeLock : Re-reads the volume's resize lock, skipping the resize if another instance has taken it
+// volume : runtime.EBSVolumeConfig : Configuration of the EBS volume
+// returns : error : A SkippedError if the lock was lost, or an error if it can't be read
+func confirmResizeLock(volume runtime.EBSVolumeConfig) error {
+	held, err := aws.HoldsResizeLock(volume)
+	if err != nil {
+		return fmt.Errorf("failed to confirm the resize lock of volume '%v'. error: %w", volume.AWSVolumeID, err)
+	}
+	if !held {
+		return &SkippedError{Reason: runtime.SkipReasonResizeLocked}
+	}
+	return nil
+}
+
+// postAWSResizeDelay : Returns how long to wait after the AWS resize before resizing the filesystem
+// volume : runtime.EBSVolumeConfig : Configuration of the EBS volume
+// returns : time.Duration : The configured delay, or the default when not set
+func postAWSResizeDelay(volume runtime.EBSVolumeConfig) time.Duration {
+	if volume.PostAWSResizeDelaySeconds > 0 {
+		return time.Duration(volume.PostAWSResizeDelaySeconds) * time.Second
+	}
+	return DefaultPostAWSResizeDelaySeconds * time.Second
+}
+
+// modificationPollInterval is how often the modification state is polled while waiting for it to leave 'modifying'.
+const modificationPollInterval = 5 * time.Second
+
+// progressLogInterval is how often the modification progress is logged during the fixed post-resize delay.
+const progressLogInterval = 15 * time.Second
+
+// getModificationProgress, sleep and host are replaced in tests.
+var (
+	getModificationProgress                 = aws.GetModificationProgress
+	sleep                                   = time.Sleep
+	host                    filesystem.Host = filesystem.LocalHost{}
+)
+
+// logModificationProgress : Logs how far AWS has got with the volume's modification
+// volume : runtime.EBSVolumeConfig : Configuration of the EBS volume
+// progress : int64 : The modification progress as a percentage
+// state : string : The modification state, e.g. 'modifying' or 'optimizing'
+func logModificationProgress(volume runtime.EBSVolumeConfig, progress int64, state string) {
+	l.Log(logger.LogInfo, "Volume modification in progress.", map[string]interface{}{
+		"AWS Volume ID":      volume.AWSVolumeID,
+		"Modification State": state,
+		"Progress Percent":   progress,
+	})
+}
+
+// waitWithProgress : Sleeps for the delay, logging the volume's modification progress every progressLogInterval
+// Failing to fetch the progress is logged at debug and doesn't cut the delay short.
+// volume : runtime.EBSVolumeConfig : Configuration of the EBS volume
+// delay : time.Duration : How long to wait
+func waitWithProgress(volume runtime.EBSVolumeConfig, delay time.Duration) {
+	for remaining := delay; remaining > 0; remaining -= progressLogInterval {
+		progress, state, err := getModificationProgress(volume)
+		if err != nil {
+			l.Log(logger.LogDebug, "Failed to get the volume modification progress.", map[string]interface{}{
+				"AWS Volume ID": volume.AWSVolumeID,
+				"Error":         err,
+			})
+		} else if state != "" {
+			logModificationProgress(volume, progress, state)
+		}
+		if remaining < progressLogInterval {
+			sleep(remaining)
+		} else {
+			sleep(progressLogInterval)
+		}
+	}
+}
+
+// waitForModification : Polls AWS until the volume's modification leaves the 'modifying' state
+// The new size can be used by the filesystem once the modification reaches 'optimizing', so there is no
+// need to wait for optimization (which can take hours) to complete.
+// volume : runtime.EBSVolumeConfig : Configuration of the EBS volume
+// timeout : time.Duration : How long to poll before giving up
+// returns : error : An error if polling fails or the timeout is reached while still modifying
+func waitForModification(volume runtime.EBSVolumeConfig, timeout time.Duration) error {
+	for waited := time.Duration(0); ; waited += modificationPollInterval {
+		progress, state, err := getModificationProgress(volume)
+		if err != nil {
+			return err
+		}
+		logModificationProgress(volume, progress, state)
+		if state != modificationStateModifying {
+			fmt.Printf("Volume modification state is '%s', proceeding\n", state)
+			return nil
+		}
+		if waited >= timeout {
+			return fmt.Errorf("volume %s still modifying after %v", volume.AWSVolumeID, timeout)
+		}
+		sleep(modificationPollInterval)
+	}
+}
+
 // CalculateNewSize : Calculates the new size of the volume based on the given configuration
+// The increment comes from the volume's growth strategy, capped at MaxIncrementGB when set, and the result is
+// rounded up to AlignToGB and capped at MaxSizeGB.
 // config : runtime.EBSVolumeConfig : Configuration of the EBS volume
 // currentSize : int64 : The current size of the volume in GiB
-// returns : int64 : The new size of the volume in GiB
+// returns : int64 : The new size of the volume in GiB, the current size when it is already at MaxSizeGB
 func CalculateNewSize(config runtime.EBSVolumeConfig, currentSize int64) int64 {
-	// Calculate the increment size in GiB
-	incrementSize := currentSize * int64(config.IncrementSizePercent) / 100
+	return CalculateNewSizeAt(config, currentSize, runtime.Now())
+}
+
+// CalculateNewSizeAt : Calculates the new size of the volume as CalculateNewSize does, at the given time of day
+// The increment is scaled by the multiplier of the first growth window containing now.
+// config : runtime.EBSVolumeConfig : Configuration of the EBS volume
+// currentSize : int64 : The current size of the volume in GiB
+// now : time.Time : The time the resize happens at
+// returns : int64 : The new size of the volume in GiB
+func CalculateNewSizeAt(config runtime.EBSVolumeConfig, currentSize int64, now time.Time) int64 {
+	incrementSize := growthIncrement(config, currentSize)
+
+	// Scale the increment for the current time of day, rounding up to whole GiB
+	incrementSize = int64(math.Ceil(float64(incrementSize) * growthMultiplier(config.GrowthWindows, now)))
+
+	// Limit how much a single resize can add, so large volumes grow in bounded steps
+	if config.MaxIncrementGB > 0 && incrementSize > int64(config.MaxIncrementGB) {
+		l.Log(logger.LogInfo, "Resize step limited by maxIncrementGB.", map[string]interface{}{
+			"AWS Volume ID":       config.AWSVolumeID,
+			"Calculated Step GiB": incrementSize,
+			"Max Increment GiB":   config.MaxIncrementGB,
+		})
+		incrementSize = int64(config.MaxIncrementGB)
+	}
 
 	// Calculate the new size
-	newSize := currentSize + incrementSize
+	newSize := alignSize(currentSize+incrementSize, int64(config.AlignToGB))
 
+	// Never grow past the volume's size cap, or shrink a volume that is already larger
+	if maxSize := int64(config.MaxSizeGB); maxSize > 0 && newSize > maxSize {
+		if currentSize >= maxSize {
+			return currentSize
+		}
+		return maxSize
+	}
 	return newSize
 }
 
+// growthIncrement : Returns the increment, in GiB, the volume's growth strategy grows it by
+// config : runtime.EBSVolumeConfig : Configuration of the EBS volume
+// currentSize : int64 : The current size of the volume in GiB
+// returns : int64 : The increment in GiB, 0 for an unknown strategy so the volume isn't grown
+func growthIncrement(config runtime.EBSVolumeConfig, currentSize int64) int64 {
+	switch GrowthStrategy(config) {
+	case runtime.GrowthStrategyFixed:
+		return int64(config.IncrementSizeGB)
+	case runtime.GrowthStrategyPercent:
+		return currentSize * int64(config.IncrementSizePercent) / 100
+	case runtime.GrowthStrategyDoubleUntilCap:
+		return currentSize
+	default:
+		return 0
+	}
+}
+
+// GrowthStrategy : Returns the growth strategy of the volume
+// Volumes without one keep the original behaviour, growing by IncrementSizeGB when set, otherwise IncrementSizePercent.
+// config : runtime.EBSVolumeConfig : Configuration of the EBS volume
+// returns : string : One of the runtime GrowthStrategy constants
+func GrowthStrategy(config runtime.EBSVolumeConfig) string {
+	if config.GrowthStrategy != "" {
+		return config.GrowthStrategy
+	}
+	if config.IncrementSizeGB > 0 {
+		return runtime.GrowthStrategyFixed
+	}
+	return runtime.GrowthStrategyPercent
+}
+
+// growthMultiplier : Returns the increment multiplier in effect at the given time
+// windows : []runtime.GrowthWindow : The volume's growth windows
+// now : time.Time : The time to check
+// returns : float64 : The multiplier of the first window containing now, or 1.0 outside all windows
+func growthMultiplier(windows []runtime.GrowthWindow, now time.Time) float64 {
+	for _, window := range windows {
+		if window.Contains(now) {
+			return window.Multiplier
+		}
+	}
+	return 1.0
+}
+
+// alignSize : Rounds a size up to the next multiple of the alignment
+// Rounding is always upwards so alignment never reduces the size below what is needed.
+// size : int64 : The size in GiB
+// alignToGiB : int64 : The alignment in GiB, sizes are left unchanged when 0 or less
+// returns : int64 : The aligned size in GiB
+func alignSize(size, alignToGiB int64) int64 {
+	if alignToGiB <= 0 || size%alignToGiB == 0 {
+		return size
+	}
+	return (size/alignToGiB + 1) * alignToGiB
+}
+
 // PerformResize : Performs the resize operation on the volume after checking
 // the EBS volume size and comparing it with the filesystem size
 // config : runtime.EBSVolumeConfig : Configuration of the EBS volume
 // newSize : int64 : The new size of the volume in GiB
+// triggerReason : string : The threshold rule that called for the resize, recorded in the resize event and summary
 // returns : error : Any error that occurred during operation, nil if operation was successful
-func PerformResize(volume runtime.EBSVolumeConfig, newSize int64, log *runtime.EventLog) (bool, bool, error) {
+func PerformResize(volume runtime.EBSVolumeConfig, newSize int64, triggerReason string, log *runtime.EventLog, dryRun bool) (bool, bool, error) {
+	// Dry runs report what would be done without changing the volume or filesystem
+	if dryRun {
+		return false, false, simulateResize(volume, newSize, triggerReason, log)
+	}
 
 	// Tracks the success of resize actions taken
 	awsResized := false
 	fsResized := false
 
-	// Get the local mount point of the EBS volume
-	localMountPoint, err := filesystem.GetLocalMountPoint(volume.AWSVolumeID)
+	// Get the local mount point of the filesystem that grows into the new space
+	// For partitioned volumes this is the last partition in disk order, and empty when that partition is swap
+	localMountPoint, err := host.GrownMountPoint(volume)
 	if err != nil {
 		return awsResized, fsResized, fmt.Errorf("failed to get local mount point of volume '%v'. error: %w", volume.AWSDeviceName, err)
 	}
+	// The new space would go to swap and no filesystem would grow, so the volume would stay over its threshold
+	// and be resized again every cycle
+	if localMountPoint == "" {
+		return awsResized, fsResized, fmt.Errorf("the last partition of volume '%v' is swap, so no filesystem can grow into a resize", volume.AWSVolumeID)
+	}
 	fmt.Printf("Successfully fetched local mount point: %v\n", localMountPoint)
 
 	fmt.Println("STEP 1 - Attempting Filesystem Extension...")
@@ -49,7 +282,7 @@ func PerformResize(volume runtime.EBSVolumeConfig, newSize int64, log *runtime.E
 	// If successful return nil, otherwise proceed with EBS volume resize action
 	// Initialize FilesystemResize struct for logging history
 	fsAction := runtime.FilesystemResize{
-		StartTime:       time.Now(),
+		StartTime:       runtime.Now(),
 		AWSVolumeID:     volume.AWSVolumeID,
 		AWSDeviceName:   volume.AWSDeviceName,
 		LocalMountPoint: localMountPoint,
@@ -57,7 +290,7 @@ func PerformResize(volume runtime.EBSVolumeConfig, newSize int64, log *runtime.E
 	}
 
 	// Attempt extending filesystem
-	fsResizeErr := filesystem.ResizeFilesystem(volume)
+	fsResizeErr := host.ResizeFilesystem(volume)
 
 	// Add attempt to history
 	if fsResizeErr == nil {
@@ -65,17 +298,17 @@ func PerformResize(volume runtime.EBSVolumeConfig, newSize int64, log *runtime.E
 		(*log)[volume.AWSVolumeID] = append((*log)[volume.AWSVolumeID], runtime.CreateFSActionEvent(fsAction, true))
 	} else {
 		fmt.Println("Failed to resize the filesystem on the first attempt. Error: ", fsResizeErr.Error())
-		(*log)[volume.AWSVolumeID] = append((*log)[volume.AWSVolumeID], runtime.CreateFSActionEvent(fsAction, false))
+		(*log)[volume.AWSVolumeID] = append((*log)[volume.AWSVolumeID], runtime.CreateFSActionEvent(fsAction, false).WithError(fsResizeErr))
 	}
 
 	// Get the current size of the AWS EBS volume
-	currentAWSVolumeSize, err := aws.GetAWSDeviceSizeGB(volume)
+	currentAWSVolumeSize, err := aws.GetAWSDeviceSizeGiB(volume)
 	if err != nil {
 		return awsResized, fsResized, fmt.Errorf("failed to get the size of the EBS volume '%v' in AWS. error: %w", volume.AWSDeviceName, err)
 	}
 
 	// Get the current size of the local filesystem
-	currentLocalDiskSize, err := filesystem.GetLocalDiskSizeGB(localMountPoint)
+	currentLocalDiskSize, err := host.GetLocalDiskSizeGiB(localMountPoint)
 	if err != nil {
 		return awsResized, fsResized, fmt.Errorf("failed to get the size of the local filesystem for '%v'. error: %w", localMountPoint, err)
 	}
@@ -107,7 +340,41 @@ func PerformResize(volume runtime.EBSVolumeConfig, newSize int64, log *runtime.E
 	}
 	if isOptimizing {
 		fmt.Println("Volume is optimizing, aborting")
-		return awsResized, fsResized, fmt.Errorf("volume %v:%v is in optimizing state. Unable to attempt resize action", volume.AWSVolumeID, volume.AWSDeviceName)
+		return awsResized, fsResized, &SkippedError{Reason: runtime.SkipReasonOptimizing}
+	}
+
+	// A Multi-Attach volume can be monitored from each instance it's attached to, so take its resize lock
+	// and skip the resize if another instance holds it, or has just grown the volume
+	if volume.ResizeLock {
+		acquired, err := aws.AcquireResizeLock(volume, resizeLockTTL(volume))
+		if err != nil {
+			return awsResized, fsResized, fmt.Errorf("failed to acquire the resize lock of volume '%v'. error: %w", volume.AWSVolumeID, err)
+		}
+		if !acquired {
+			return awsResized, fsResized, &SkippedError{Reason: runtime.SkipReasonResizeLocked}
+		}
+		defer func() {
+			if err := aws.ReleaseResizeLock(volume); err != nil {
+				l.Log(logger.LogWarning, "Failed to release the resize lock, it is held until it expires.", map[string]interface{}{
+					"AWS Volume ID": volume.AWSVolumeID,
+					"Error":         err,
+				})
+			}
+		}()
+
+		// Confirm the lock once the tags have settled, before relying on it
+		sleep(resizeLockSettleDelay)
+		if err := confirmResizeLock(volume); err != nil {
+			return awsResized, fsResized, err
+		}
+
+		lockedSize, err := aws.GetAWSDeviceSizeGiB(volume)
+		if err != nil {
+			return awsResized, fsResized, fmt.Errorf("failed to get the size of the EBS volume '%v' in AWS. error: %w", volume.AWSDeviceName, err)
+		}
+		if lockedSize > currentAWSVolumeSize {
+			return awsResized, fsResized, &SkippedError{Reason: runtime.SkipReasonResizedElsewhere}
+		}
 	}
 
 	fmt.Println("STEP 3: Resizing AWS volume...")
@@ -120,28 +387,96 @@ func PerformResize(volume runtime.EBSVolumeConfig, newSize int64, log *runtime.E
 
 	// Initialize EBSVolumeResize struct
 	volumeAction := runtime.EBSVolumeResize{
-		StartTime:      time.Now(),
-		AWSVolumeID:    volume.AWSVolumeID,
-		AWSDeviceName:  volume.AWSDeviceName,
-		AWSRegion:      volume.AWSRegion,
-		OriginalSizeGB: float64(currentAWSVolumeSize),
-		NewSize:        float64(newSize),
+		StartTime:       runtime.Now(),
+		AWSVolumeID:     volume.AWSVolumeID,
+		AWSDeviceName:   volume.AWSDeviceName,
+		AWSRegion:       volume.AWSRegion,
+		OriginalSizeGiB: float64(currentAWSVolumeSize),
+		NewSize:         float64(newSize),
+		TriggerReason:   triggerReason,
+	}
+
+	// Run the pre-resize hook, e.g. to quiesce a database, aborting the resize if it fails
+	env := hookEnv(volume, localMountPoint, currentAWSVolumeSize, newSize)
+	if err := runHook(preResizeHook, volume.PreResizeCommand, volume, env); err != nil {
+		(*log)[volume.AWSVolumeID] = append((*log)[volume.AWSVolumeID], runtime.CreateVolumeResizeActionEvent(volumeAction, false).WithError(err))
+		return awsResized, fsResized, fmt.Errorf("resize of volume '%v' aborted. error: %w", volume.AWSVolumeID, err)
+	}
+	// Run the post-resize hook however the resize ends, so it can undo the pre-resize hook after a failure too.
+	// A failing post-resize hook is logged, but doesn't fail the resize.
+	resizeResult := hookResultFailure
+	defer func() {
+		_ = runHook(postResizeHook, volume.PostResizeCommand, volume, append(env, "EBS_RESIZE_RESULT="+resizeResult))
+	}()
+
+	// Snapshot the volume first when enabled, so the resize can be rolled back
+	// Abort the resize if the snapshot can't be taken
+	if volume.SnapshotBeforeResize {
+		fmt.Println("Creating snapshot before resizing...")
+		snapshotID, err := aws.CreateSnapshot(volume)
+		if err != nil {
+			(*log)[volume.AWSVolumeID] = append((*log)[volume.AWSVolumeID], runtime.CreateVolumeResizeActionEvent(volumeAction, false).WithError(err))
+			return awsResized, fsResized, fmt.Errorf("failed to snapshot volume '%v' before resizing, resize aborted. error: %w", volume.AWSVolumeID, err)
+		}
+		volumeAction.SnapshotID = snapshotID
+		l.Log(logger.LogInfo, "Created snapshot before resizing.", map[string]interface{}{
+			"AWS Volume ID": volume.AWSVolumeID,
+			"Snapshot ID":   snapshotID,
+		})
+	}
+
+	// The hooks and snapshot can take a while, so confirm the resize lock is still held before modifying the volume
+	if volume.ResizeLock {
+		if err := confirmResizeLock(volume); err != nil {
+			return awsResized, fsResized, err
+		}
 	}
 
+	// Log the cost the resize adds before the volume is modified, as once it grows it can't be shrunk again
+	monthlyCostDelta := estimateResizeCost(volume, currentAWSVolumeSize, newSize)
+	l.Log(logger.LogInfo, "Resizing volume in AWS.", map[string]interface{}{
+		"AWS Volume ID":          volume.AWSVolumeID,
+		"AWS Device Name":        volume.AWSDeviceName,
+		"Current Size GiB":       currentAWSVolumeSize,
+		"New Size GiB":           newSize,
+		"Monthly Cost Delta USD": monthlyCostDelta,
+	})
+
 	// Resize the EBS volume in AWS
 	// Return error if action fails
-	awsResizeErr := aws.ResizeVolume(volume, newSize)
+	awsStartTime := runtime.Now()
+	modified, awsResizeErr := aws.ResizeVolume(volume, newSize)
 	if awsResizeErr == nil {
 		(*log)[volume.AWSVolumeID] = append((*log)[volume.AWSVolumeID], runtime.CreateVolumeResizeActionEvent(volumeAction, true))
 		awsResized = true
 	} else {
-		(*log)[volume.AWSVolumeID] = append((*log)[volume.AWSVolumeID], runtime.CreateVolumeResizeActionEvent(volumeAction, false))
+		(*log)[volume.AWSVolumeID] = append((*log)[volume.AWSVolumeID], runtime.CreateVolumeResizeActionEvent(volumeAction, false).WithError(awsResizeErr))
+		// AWS refusing the modification because of an earlier one is a skip, not a failure
+		if reason := modificationSkipReason(awsResizeErr); reason != "" {
+			return awsResized, fsResized, &SkippedError{Reason: reason}
+		}
 		return awsResized, fsResized, awsResizeErr
 	}
 
 	// Adding sleep to fix issue attempting filesystem resize immediately after EBS resize action.
-	fmt.Println("Adding sleep (60s) before attempting filesystem resize...")
-	time.Sleep(time.Second * 60)
+	// Skipped when AWS reported a no-op modification as the volume did not change.
+	// When modificationWaitSeconds is set, poll the modification state instead of sleeping for a fixed delay.
+	if modified && volume.ModificationWaitSeconds > 0 {
+		fmt.Println("Waiting for the volume modification to leave the 'modifying' state...")
+		if err := waitForModification(volume, time.Duration(volume.ModificationWaitSeconds)*time.Second); err != nil {
+			// Attempt the filesystem resize regardless, it is retried on the next resize if the space isn't usable yet
+			l.Log(logger.LogWarning, "Failed to confirm the volume modification before resizing the filesystem.", map[string]interface{}{
+				"AWS Volume ID": volume.AWSVolumeID,
+				"Error":         err,
+			})
+		}
+	} else if modified {
+		delay := postAWSResizeDelay(volume)
+		fmt.Printf("Adding sleep (%v) before attempting filesystem resize...\n", delay)
+		waitWithProgress(volume, delay)
+	} else {
+		fmt.Println("AWS reported a no-op modification, proceeding straight to filesystem resize...")
+	}
 
 	fmt.Println("STEP 4: Resizing local filesystem volume...")
 
@@ -152,26 +487,170 @@ func PerformResize(volume runtime.EBSVolumeConfig, newSize int64, log *runtime.E
 	*/
 	// Initialize FilesystemResize struct
 	fsAction = runtime.FilesystemResize{
-		StartTime:       time.Now(),
+		StartTime:       runtime.Now(),
 		AWSVolumeID:     volume.AWSVolumeID,
 		AWSDeviceName:   volume.AWSDeviceName,
 		LocalMountPoint: localMountPoint,
 		AWSVolumeSize:   float64(currentAWSVolumeSize),
-		OriginalSizeGB:  currentLocalDiskSize,
+		OriginalSizeGiB: currentLocalDiskSize,
 		NewSize:         float64(newSize),
 	}
 
 	// Resize the file system on the EBS volume
 	// Return error if action fails
-	fsResizeErr = filesystem.ResizeFilesystem(volume)
+	fsResizeErr = host.ResizeFilesystem(volume)
+	if fsResizeErr == nil && modified {
+		// A resize command can exit 0 without growing anything, e.g. when the partition wasn't grown or
+		// an xfs mount is stale, so confirm the filesystem now uses the new space
+		fsResizeErr = verifyFilesystemGrowth(volume, localMountPoint, currentLocalDiskSize, newSize)
+	}
 	if fsResizeErr == nil {
 		(*log)[volume.AWSVolumeID] = append((*log)[volume.AWSVolumeID], runtime.CreateFSActionEvent(fsAction, true))
 		fsResized = true
 	} else {
-		(*log)[volume.AWSVolumeID] = append((*log)[volume.AWSVolumeID], runtime.CreateFSActionEvent(fsAction, false))
+		(*log)[volume.AWSVolumeID] = append((*log)[volume.AWSVolumeID], runtime.CreateFSActionEvent(fsAction, false).WithError(fsResizeErr))
+		logCommandFailure(volume, fsResizeErr)
 		return awsResized, fsResized, fsResizeErr
 	}
 
+	resizeResult = hookResultSuccess
+
+	// Record and report the resize as one consolidated summary
+	summary := runtime.ResizeSummary{
+		AWSVolumeID:       volume.AWSVolumeID,
+		AWSDeviceName:     volume.AWSDeviceName,
+		AWSRegion:         volume.AWSRegion,
+		LocalMountPoint:   localMountPoint,
+		TriggerReason:     triggerReason,
+		OriginalSizeGiB:   float64(currentAWSVolumeSize),
+		NewSizeGiB:        float64(newSize),
+		AWSModifyDuration: fsAction.StartTime.Sub(awsStartTime),
+		FSResizeDuration:  runtime.Now().Sub(fsAction.StartTime),
+		SnapshotID:        volumeAction.SnapshotID,
+		MonthlyCostDelta:  monthlyCostDelta,
+	}
+	(*log)[volume.AWSVolumeID] = append((*log)[volume.AWSVolumeID], runtime.CreateResizeSummaryEvent(summary))
+	l.Log(logger.LogInfo, fmt.Sprintf(":white_check_mark: Successfully resized device: %s from %s to %s.", volume.AWSDeviceName, runtime.FormatSize(float64(currentAWSVolumeSize)), runtime.FormatSize(float64(newSize))), summary.Fields())
+
 	fmt.Println("PerformResize function completed.")
 	return awsResized, fsResized, nil
 }
+
+// modificationSkipReason : Returns the skip reason for an AWS modification refused because of an earlier modification
+// err : error : The error from resizing the volume in AWS
+// returns : string : One of the runtime SkipReason constants, or empty if the error is a failure
+func modificationSkipReason(err error) string {
+	switch {
+	case errors.Is(err, aws.ErrModificationInProgress):
+		return runtime.SkipReasonOptimizing
+	case errors.Is(err, aws.ErrModificationRateLimit):
+		return runtime.SkipReasonModificationLimit
+	}
+	return ""
+}
+
+// filesystemSizeTolerance is the fraction of the volume a grown filesystem may fall short by, for the space
+// its metadata (inode tables, journal, allocation groups) takes.
+const filesystemSizeTolerance = 0.05
+
+// verifyFilesystemGrowth : Re-reads the filesystem's size after a resize and checks it grew into the resized volume
+// Partitioned volumes spread the volume over several filesystems, so only growth is checked for them.
+// volume : runtime.EBSVolumeConfig : Configuration of the EBS volume
+// localMountPoint : string : Mount point of the resized filesystem
+// originalGiB : float64 : Size of the filesystem before the resize in GiB
+// newSize : int64 : The size the volume was resized to in GiB
+// returns : error : An error if the size can't be read or the filesystem didn't grow as expected
+func verifyFilesystemGrowth(volume runtime.EBSVolumeConfig, localMountPoint string, originalGiB float64, newSize int64) error {
+	resizedGiB, err := host.GetLocalDiskSizeGiB(localMountPoint)
+	if err != nil {
+		return fmt.Errorf("failed to verify the filesystem resize of '%v'. error: %w", localMountPoint, err)
+	}
+	if resizedGiB <= originalGiB {
+		return fmt.Errorf("filesystem at '%v' did not grow after the resize command succeeded, still %.2f GiB", localMountPoint, resizedGiB)
+	}
+	if len(volume.Partitions) == 0 && resizedGiB < float64(newSize)*(1-filesystemSizeTolerance) {
+		return fmt.Errorf("filesystem at '%v' only grew to %.2f GiB of the %d GiB volume after the resize command succeeded", localMountPoint, resizedGiB, newSize)
+	}
+	fmt.Printf("Verified filesystem grew from %.2f GiB to %.2f GiB\n", originalGiB, resizedGiB)
+	return nil
+}
+
+// logCommandFailure : Logs the command line, exit code and full output of a failed filesystem resize command
+// Errors that didn't come from running a command, e.g. a failed lookup, are left to the caller to report.
+// volume : runtime.EBSVolumeConfig : Configuration of the EBS volume
+// err : error : The error from resizing the filesystem
+func logCommandFailure(volume runtime.EBSVolumeConfig, err error) {
+	var commandErr *filesystem.CommandError
+	if !errors.As(err, &commandErr) {
+		return
+	}
+	l.Log(logger.LogError, "Filesystem resize command failed.", map[string]interface{}{
+		"AWS Volume ID":   volume.AWSVolumeID,
+		"AWS Device Name": volume.AWSDeviceName,
+		"Command":         commandErr.Command,
+		"Exit Code":       commandErr.ExitCode,
+		"Output":          commandErr.Output,
+	})
+}
+
+// simulateResize : Reports the resize PerformResize would carry out, without calling AWS or resizing the filesystem
+// Only read-only lookups are made. The actions are recorded in the event log flagged as simulated.
+// volume : runtime.EBSVolumeConfig : Configuration of the EBS volume
+// newSize : int64 : The size the volume would be resized to in GiB
+// triggerReason : string : The threshold rule that called for the resize
+// log : *runtime.EventLog : Event log to record the simulated actions in
+// returns : error : A SkippedError if the resize would be skipped, or an error from the lookups
+func simulateResize(volume runtime.EBSVolumeConfig, newSize int64, triggerReason string, log *runtime.EventLog) error {
+	isOptimizing, err := aws.CheckVolumeState(volume)
+	if err != nil {
+		return err
+	}
+	if isOptimizing {
+		return &SkippedError{Reason: runtime.SkipReasonOptimizing}
+	}
+
+	currentSize, err := aws.GetAWSDeviceSizeGiB(volume)
+	if err != nil {
+		return fmt.Errorf("failed to get the size of the EBS volume '%v' in AWS. error: %w", volume.AWSDeviceName, err)
+	}
+	commands, err := host.ResizeCommands(volume)
+	if err != nil {
+		return fmt.Errorf("failed to determine the filesystem resize commands for '%v'. error: %w", volume.AWSDeviceName, err)
+	}
+
+	l.Log(logger.LogInfo, "Dry run: would resize volume.", map[string]interface{}{
+		"AWS Volume ID":          volume.AWSVolumeID,
+		"AWS Device Name":        volume.AWSDeviceName,
+		"Current Size GiB":       currentSize,
+		"New Size GiB":           newSize,
+		"Trigger Reason":         triggerReason,
+		"Monthly Cost Delta USD": estimateResizeCost(volume, currentSize, newSize),
+		"Snapshot Before Resize": volume.SnapshotBeforeResize,
+		"Pre Resize Command":     volume.PreResizeCommand,
+		"Post Resize Command":    volume.PostResizeCommand,
+		"Filesystem Commands":    strings.Join(commands, "; "),
+	})
+
+	now := runtime.Now()
+	volumeEvent := runtime.CreateVolumeResizeActionEvent(runtime.EBSVolumeResize{
+		StartTime:       now,
+		AWSVolumeID:     volume.AWSVolumeID,
+		AWSDeviceName:   volume.AWSDeviceName,
+		AWSRegion:       volume.AWSRegion,
+		OriginalSizeGiB: float64(currentSize),
+		NewSize:         float64(newSize),
+		TriggerReason:   triggerReason,
+	}, true)
+	volumeEvent.Simulated = true
+	fsEvent := runtime.CreateFSActionEvent(runtime.FilesystemResize{
+		StartTime:     now,
+		AWSVolumeID:   volume.AWSVolumeID,
+		AWSDeviceName: volume.AWSDeviceName,
+		AWSVolumeSize: float64(currentSize),
+		NewSize:       float64(newSize),
+	}, true)
+	fsEvent.Simulated = true
+	(*log)[volume.AWSVolumeID] = append((*log)[volume.AWSVolumeID], volumeEvent, fsEvent)
+
+	return nil
+}
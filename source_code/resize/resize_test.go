@@ -1,11 +1,21 @@
 package resize
 
 import (
+	"ebs-monitor/aws"
+	"ebs-monitor/aws/awstest"
+	"ebs-monitor/filesystem"
+	"ebs-monitor/filesystem/fstest"
 	"ebs-monitor/runtime"
+	"errors"
+	"fmt"
+	"reflect"
 	"testing"
-)
+	"time"
 
-// TODO: add tests - requires mocking external calls
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
 
 func TestCalculateNewSize(t *testing.T) {
 	tests := []struct {
@@ -43,3 +53,680 @@ func TestCalculateNewSize(t *testing.T) {
 		})
 	}
 }
+
+func TestAlignSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int64
+		alignToGiB int64
+		expected   int64
+	}{
+		{
+			name:       "alignment disabled",
+			size:       13,
+			alignToGiB: 0,
+			expected:   13,
+		},
+		{
+			name:       "negative alignment ignored",
+			size:       13,
+			alignToGiB: -8,
+			expected:   13,
+		},
+		{
+			name:       "already aligned",
+			size:       16,
+			alignToGiB: 8,
+			expected:   16,
+		},
+		{
+			name:       "rounds up, never down",
+			size:       17,
+			alignToGiB: 8,
+			expected:   24,
+		},
+		{
+			name:       "one below boundary",
+			size:       23,
+			alignToGiB: 8,
+			expected:   24,
+		},
+		{
+			name:       "alignment of one",
+			size:       23,
+			alignToGiB: 1,
+			expected:   23,
+		},
+		{
+			name:       "alignment larger than size",
+			size:       5,
+			alignToGiB: 64,
+			expected:   64,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := alignSize(tt.size, tt.alignToGiB)
+			if got != tt.expected {
+				t.Errorf("alignSize() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCalculateNewSizeWithAlignment(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      runtime.EBSVolumeConfig
+		currentSize int64
+		expected    int64
+	}{
+		{
+			name:        "fixed increment aligned",
+			config:      runtime.EBSVolumeConfig{IncrementSizeGB: 10, AlignToGB: 8},
+			currentSize: 100,
+			expected:    112,
+		},
+		{
+			name:        "percentage increment aligned",
+			config:      runtime.EBSVolumeConfig{IncrementSizePercent: 10, AlignToGB: 8},
+			currentSize: 100,
+			expected:    112,
+		},
+		{
+			name:        "percentage increment capped",
+			config:      runtime.EBSVolumeConfig{IncrementSizePercent: 20, MaxIncrementGB: 50},
+			currentSize: 1000,
+			expected:    1050,
+		},
+		{
+			name:        "percentage increment under cap",
+			config:      runtime.EBSVolumeConfig{IncrementSizePercent: 20, MaxIncrementGB: 50},
+			currentSize: 100,
+			expected:    120,
+		},
+		{
+			name:        "capped increment aligned",
+			config:      runtime.EBSVolumeConfig{IncrementSizePercent: 20, MaxIncrementGB: 50, AlignToGB: 8},
+			currentSize: 1000,
+			expected:    1056,
+		},
+		{
+			name:        "aligned result kept",
+			config:      runtime.EBSVolumeConfig{IncrementSizeGB: 8, AlignToGB: 8},
+			currentSize: 8,
+			expected:    16,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateNewSize(tt.config, tt.currentSize)
+			if got != tt.expected {
+				t.Errorf("CalculateNewSize() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestCalculateNewSizeStrategies tests the increment of each growth strategy, and the maxSizeGB cap.
+func TestCalculateNewSizeStrategies(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      runtime.EBSVolumeConfig
+		currentSize int64
+		expected    int64
+	}{
+		{
+			name:        "fixed",
+			config:      runtime.EBSVolumeConfig{GrowthStrategy: runtime.GrowthStrategyFixed, IncrementSizeGB: 10},
+			currentSize: 100,
+			expected:    110,
+		},
+		{
+			name:        "percent",
+			config:      runtime.EBSVolumeConfig{GrowthStrategy: runtime.GrowthStrategyPercent, IncrementSizePercent: 25},
+			currentSize: 100,
+			expected:    125,
+		},
+		{
+			name:        "double until cap doubles",
+			config:      runtime.EBSVolumeConfig{GrowthStrategy: runtime.GrowthStrategyDoubleUntilCap, MaxSizeGB: 1000},
+			currentSize: 100,
+			expected:    200,
+		},
+		{
+			name:        "double until cap stops at the cap",
+			config:      runtime.EBSVolumeConfig{GrowthStrategy: runtime.GrowthStrategyDoubleUntilCap, MaxSizeGB: 1000},
+			currentSize: 800,
+			expected:    1000,
+		},
+		{
+			name:        "double until cap at the cap",
+			config:      runtime.EBSVolumeConfig{GrowthStrategy: runtime.GrowthStrategyDoubleUntilCap, MaxSizeGB: 1000},
+			currentSize: 1000,
+			expected:    1000,
+		},
+		{
+			name:        "fixed capped at max size",
+			config:      runtime.EBSVolumeConfig{IncrementSizeGB: 50, MaxSizeGB: 120},
+			currentSize: 100,
+			expected:    120,
+		},
+		{
+			name:        "already above max size",
+			config:      runtime.EBSVolumeConfig{IncrementSizeGB: 50, MaxSizeGB: 120},
+			currentSize: 150,
+			expected:    150,
+		},
+		{
+			name:        "percent of a small volume rounds to nothing",
+			config:      runtime.EBSVolumeConfig{IncrementSizePercent: 5},
+			currentSize: 10,
+			expected:    10,
+		},
+		{
+			name:        "unknown strategy doesn't grow",
+			config:      runtime.EBSVolumeConfig{GrowthStrategy: "triple", IncrementSizeGB: 10},
+			currentSize: 100,
+			expected:    100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateNewSize(tt.config, tt.currentSize)
+			if got != tt.expected {
+				t.Errorf("CalculateNewSize() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestGrowthStrategy tests that a volume without a growth strategy keeps growing the way its increment implies.
+func TestGrowthStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   runtime.EBSVolumeConfig
+		expected string
+	}{
+		{"fixed increment", runtime.EBSVolumeConfig{IncrementSizeGB: 10}, runtime.GrowthStrategyFixed},
+		{"percent increment", runtime.EBSVolumeConfig{IncrementSizePercent: 10}, runtime.GrowthStrategyPercent},
+		{"explicit strategy", runtime.EBSVolumeConfig{GrowthStrategy: runtime.GrowthStrategyDoubleUntilCap}, runtime.GrowthStrategyDoubleUntilCap},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GrowthStrategy(tt.config); got != tt.expected {
+				t.Errorf("GrowthStrategy() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCalculateNewSizeAt(t *testing.T) {
+	day := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	windows := []runtime.GrowthWindow{
+		{WindowStart: "09:00", WindowEnd: "17:00", Multiplier: 2},
+		{WindowStart: "22:00", WindowEnd: "06:00", Multiplier: 0.5},
+	}
+
+	tests := []struct {
+		name        string
+		config      runtime.EBSVolumeConfig
+		currentSize int64
+		now         time.Time
+		expected    int64
+	}{
+		{
+			name:        "peak window doubles increment",
+			config:      runtime.EBSVolumeConfig{IncrementSizeGB: 10, GrowthWindows: windows},
+			currentSize: 100,
+			now:         day.Add(9 * time.Hour),
+			expected:    120,
+		},
+		{
+			name:        "window end is exclusive",
+			config:      runtime.EBSVolumeConfig{IncrementSizeGB: 10, GrowthWindows: windows},
+			currentSize: 100,
+			now:         day.Add(17 * time.Hour),
+			expected:    110,
+		},
+		{
+			name:        "off-hours window halves increment, rounding up",
+			config:      runtime.EBSVolumeConfig{IncrementSizeGB: 5, GrowthWindows: windows},
+			currentSize: 100,
+			now:         day.Add(2 * time.Hour),
+			expected:    103,
+		},
+		{
+			name:        "no windows",
+			config:      runtime.EBSVolumeConfig{IncrementSizePercent: 20},
+			currentSize: 100,
+			now:         day.Add(12 * time.Hour),
+			expected:    120,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateNewSizeAt(tt.config, tt.currentSize, tt.now)
+			if got != tt.expected {
+				t.Errorf("CalculateNewSizeAt() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPostAWSResizeDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   runtime.EBSVolumeConfig
+		expected time.Duration
+	}{
+		{
+			name:     "default delay",
+			config:   runtime.EBSVolumeConfig{},
+			expected: 60 * time.Second,
+		},
+		{
+			name:     "configured delay",
+			config:   runtime.EBSVolumeConfig{PostAWSResizeDelaySeconds: 15},
+			expected: 15 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := postAWSResizeDelay(tt.config)
+			if got != tt.expected {
+				t.Errorf("postAWSResizeDelay() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestWaitForModification tests that the modification state is polled until it leaves 'modifying' or the timeout is reached.
+func TestWaitForModification(t *testing.T) {
+	defer func() {
+		getModificationProgress = aws.GetModificationProgress
+		sleep = time.Sleep
+	}()
+
+	tests := []struct {
+		name      string
+		states    []string
+		timeout   time.Duration
+		wantPolls int
+		wantErr   bool
+	}{
+		{
+			name:      "already optimizing",
+			states:    []string{"optimizing"},
+			timeout:   time.Minute,
+			wantPolls: 1,
+		},
+		{
+			name:      "modifying then optimizing",
+			states:    []string{"modifying", "modifying", "optimizing"},
+			timeout:   time.Minute,
+			wantPolls: 3,
+		},
+		{
+			name:      "still modifying at timeout",
+			states:    []string{"modifying", "modifying", "modifying", "modifying"},
+			timeout:   2 * modificationPollInterval,
+			wantPolls: 3,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			polls := 0
+			getModificationProgress = func(runtime.EBSVolumeConfig) (int64, string, error) {
+				state := tt.states[polls]
+				polls++
+				return 0, state, nil
+			}
+			sleep = func(time.Duration) {}
+
+			err := waitForModification(runtime.EBSVolumeConfig{AWSVolumeID: "vol-0abcd1234efgh5678"}, tt.timeout)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("waitForModification() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if polls != tt.wantPolls {
+				t.Errorf("waitForModification() polls = %d, want %d", polls, tt.wantPolls)
+			}
+		})
+	}
+}
+
+// TestWaitWithProgress tests that the fixed post-resize delay is slept in full, polling the progress between sleeps.
+func TestWaitWithProgress(t *testing.T) {
+	defer func() {
+		getModificationProgress = aws.GetModificationProgress
+		sleep = time.Sleep
+	}()
+
+	tests := []struct {
+		name       string
+		delay      time.Duration
+		err        error
+		wantPolls  int
+		wantSleeps []time.Duration
+	}{
+		{
+			name:       "whole intervals",
+			delay:      2 * progressLogInterval,
+			wantPolls:  2,
+			wantSleeps: []time.Duration{progressLogInterval, progressLogInterval},
+		},
+		{
+			name:       "partial last interval",
+			delay:      progressLogInterval + time.Second,
+			wantPolls:  2,
+			wantSleeps: []time.Duration{progressLogInterval, time.Second},
+		},
+		{
+			name:       "progress unavailable",
+			delay:      progressLogInterval,
+			err:        errors.New("throttled"),
+			wantPolls:  1,
+			wantSleeps: []time.Duration{progressLogInterval},
+		},
+		{
+			name:  "no delay",
+			delay: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			polls := 0
+			getModificationProgress = func(runtime.EBSVolumeConfig) (int64, string, error) {
+				polls++
+				return 40, "optimizing", tt.err
+			}
+			var sleeps []time.Duration
+			sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+
+			waitWithProgress(runtime.EBSVolumeConfig{AWSVolumeID: "vol-0abcd1234efgh5678"}, tt.delay)
+			if polls != tt.wantPolls {
+				t.Errorf("waitWithProgress() polls = %d, want %d", polls, tt.wantPolls)
+			}
+			if !reflect.DeepEqual(sleeps, tt.wantSleeps) {
+				t.Errorf("waitWithProgress() sleeps = %v, want %v", sleeps, tt.wantSleeps)
+			}
+		})
+	}
+}
+
+// TestVerifyFilesystemGrowth tests that a resize is only verified once the filesystem uses the new space
+func TestVerifyFilesystemGrowth(t *testing.T) {
+	defer func() { host = filesystem.LocalHost{} }()
+
+	tests := []struct {
+		name        string
+		partitions  []runtime.PartitionConfig
+		originalGiB float64
+		resizedGiB  float64
+		err         error
+		wantErr     bool
+	}{
+		{name: "grew to the new size", originalGiB: 98, resizedGiB: 118, wantErr: false},
+		{name: "within metadata tolerance", originalGiB: 98, resizedGiB: 115, wantErr: false},
+		{name: "did not grow", originalGiB: 98, resizedGiB: 98, wantErr: true},
+		{name: "grew short of the new size", originalGiB: 98, resizedGiB: 105, wantErr: true},
+		{name: "partition only needs to grow", partitions: []runtime.PartitionConfig{{Partition: 1, MountPoint: "/logs"}, {Partition: 2, MountPoint: "/data"}}, originalGiB: 20, resizedGiB: 40, wantErr: false},
+		{name: "size unavailable", originalGiB: 98, err: errors.New("statfs failed"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host = &fstest.FakeHost{Filesystems: map[string]*fstest.FakeFilesystem{"/data": {SizeGiB: tt.resizedGiB}}, Err: tt.err}
+			volume := runtime.EBSVolumeConfig{AWSVolumeID: "vol-1", Partitions: tt.partitions}
+			err := verifyFilesystemGrowth(volume, "/data", tt.originalGiB, 120)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyFilesystemGrowth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestModificationSkipReason tests that modifications refused because of an earlier one are skips, and others failures.
+func TestModificationSkipReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"in progress", fmt.Errorf("failed. error: %w", aws.ErrModificationInProgress), runtime.SkipReasonOptimizing},
+		{"rate limit", fmt.Errorf("failed. error: %w", aws.ErrModificationRateLimit), runtime.SkipReasonModificationLimit},
+		{"access denied", fmt.Errorf("failed. error: %w", aws.ErrAccessDenied), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := modificationSkipReason(tt.err); got != tt.want {
+				t.Errorf("modificationSkipReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// useFakes : replaces AWS and the host with fakes holding one 100 GiB volume with a 98 GiB filesystem at /data
+// t : *testing.T : the test, the fakes are removed when it ends
+// returns : *awstest.FakeEC2, *fstest.FakeHost : the fakes
+func useFakes(t *testing.T) (*awstest.FakeEC2, *fstest.FakeHost) {
+	fakeEC2 := &awstest.FakeEC2{Volumes: []*ec2.Volume{awstest.NewFakeVolume("vol-1", "i-1", "/dev/sdf", 100)}}
+	fakeHost := &fstest.FakeHost{
+		MountPoints: map[string][]string{"vol-1": {"/data"}},
+		Filesystems: map[string]*fstest.FakeFilesystem{"/data": {SizeGiB: 98, UsedGiB: 90}},
+		DiskSizeGiB: aws.GetAWSDeviceSizeGiB,
+	}
+	aws.SetEC2Client(fakeEC2)
+	host = fakeHost
+	sleep = func(time.Duration) {}
+	t.Cleanup(func() {
+		aws.SetEC2Client(nil)
+		host = filesystem.LocalHost{}
+		sleep = time.Sleep
+	})
+	return fakeEC2, fakeHost
+}
+
+// TestPerformResize tests a resize against fake AWS and host, from the filesystem attempt to the summary
+func TestPerformResize(t *testing.T) {
+	fakeEC2, fakeHost := useFakes(t)
+	volume := runtime.EBSVolumeConfig{AWSVolumeID: "vol-1", AWSDeviceName: "/dev/sdf", AWSRegion: "us-east-1"}
+	log := runtime.EventLog{}
+	estimatedBeforeModify := false
+	getVolumeType = func(volume runtime.EBSVolumeConfig) (string, error) {
+		estimatedBeforeModify = *fakeEC2.Volumes[0].Size == 100
+		return aws.GetVolumeType(volume)
+	}
+	t.Cleanup(func() { getVolumeType = aws.GetVolumeType })
+
+	awsResized, fsResized, err := PerformResize(volume, 120, "threshold", &log, false)
+	if err != nil || !awsResized || !fsResized {
+		t.Fatalf("PerformResize() = (%v, %v, %v), want (true, true, nil)", awsResized, fsResized, err)
+	}
+	if !estimatedBeforeModify {
+		t.Errorf("PerformResize() estimated the cost after modifying the volume, want it logged before")
+	}
+	if got := *fakeEC2.Volumes[0].Size; got != 120 {
+		t.Errorf("volume size = %d, want 120", got)
+	}
+	if got := fakeHost.Filesystems["/data"].SizeGiB; got != 120 {
+		t.Errorf("filesystem size = %v, want 120", got)
+	}
+	if len(fakeHost.Resized) != 2 {
+		t.Errorf("ResizeFilesystem() called %d times, want 2", len(fakeHost.Resized))
+	}
+
+	events := log["vol-1"]
+	summary := events[len(events)-1].Summary
+	if summary.OriginalSizeGiB != 100 || summary.NewSizeGiB != 120 || summary.TriggerReason != "threshold" {
+		t.Errorf("summary = %+v, want a resize from 100 to 120 GiB triggered by threshold", summary)
+	}
+	if summary.MonthlyCostDelta != 1.6 {
+		t.Errorf("summary MonthlyCostDelta = %v, want 1.6", summary.MonthlyCostDelta)
+	}
+}
+
+// TestPerformResizePartitioned tests that only the last partition's filesystem is verified after a resize,
+// and that a volume whose last partition is swap, which no filesystem grows into, isn't resized
+func TestPerformResizePartitioned(t *testing.T) {
+	tests := []struct {
+		name       string
+		partitions []runtime.PartitionConfig
+		wantErr    bool
+		wantSizes  map[string]float64
+		wantAWS    int64
+	}{
+		{
+			name: "swap last",
+			partitions: []runtime.PartitionConfig{
+				{Partition: 1, MountPoint: "/boot", FilesystemType: "ext4"},
+				{Partition: 2, MountPoint: "/data", FilesystemType: "xfs"},
+				{Partition: 3, FilesystemType: "swap"},
+			},
+			wantErr:   true,
+			wantSizes: map[string]float64{"/boot": 1, "/data": 99},
+			wantAWS:   100,
+		},
+		{
+			name: "data last",
+			partitions: []runtime.PartitionConfig{
+				{Partition: 1, MountPoint: "/boot", FilesystemType: "ext4"},
+				{Partition: 2, FilesystemType: "swap"},
+				{Partition: 3, MountPoint: "/data", FilesystemType: "xfs"},
+			},
+			wantSizes: map[string]float64{"/boot": 1, "/data": 119},
+			wantAWS:   120,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeEC2, fakeHost := useFakes(t)
+			fakeHost.MountPoints["vol-1"] = []string{"/boot", "/data"}
+			fakeHost.Filesystems = map[string]*fstest.FakeFilesystem{"/boot": {SizeGiB: 1}, "/data": {SizeGiB: 99, UsedGiB: 90}}
+			volume := runtime.EBSVolumeConfig{AWSVolumeID: "vol-1", AWSDeviceName: "/dev/sdf", AWSRegion: "us-east-1", Partitions: tt.partitions}
+			log := runtime.EventLog{}
+
+			awsResized, fsResized, err := PerformResize(volume, 120, "threshold", &log, false)
+			if tt.wantErr {
+				if err == nil || awsResized || fsResized {
+					t.Fatalf("PerformResize() = (%v, %v, %v), want (false, false, error)", awsResized, fsResized, err)
+				}
+			} else if err != nil || !awsResized || !fsResized {
+				t.Fatalf("PerformResize() = (%v, %v, %v), want (true, true, nil)", awsResized, fsResized, err)
+			}
+			for mountPoint, want := range tt.wantSizes {
+				if got := fakeHost.Filesystems[mountPoint].SizeGiB; got != want {
+					t.Errorf("filesystem %s size = %v, want %v", mountPoint, got, want)
+				}
+			}
+			if got := *fakeEC2.Volumes[0].Size; got != tt.wantAWS {
+				t.Errorf("volume size = %d, want %d", got, tt.wantAWS)
+			}
+		})
+	}
+}
+
+// TestPerformResizeSkipped tests that AWS refusing or deferring the modification skips the resize
+func TestPerformResizeSkipped(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(*awstest.FakeEC2)
+		wantReason string
+	}{
+		{
+			name: "optimizing",
+			setup: func(f *awstest.FakeEC2) {
+				f.Modifications = []*ec2.VolumeModification{{VolumeId: f.Volumes[0].VolumeId, ModificationState: awssdk.String(ec2.VolumeModificationStateOptimizing)}}
+			},
+			wantReason: runtime.SkipReasonOptimizing,
+		},
+		{
+			name: "modification rate limit",
+			setup: func(f *awstest.FakeEC2) {
+				f.ModifyErr = awserr.New("VolumeModificationRateExceeded", "wait 6 hours", nil)
+			},
+			wantReason: runtime.SkipReasonModificationLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeEC2, _ := useFakes(t)
+			tt.setup(fakeEC2)
+			volume := runtime.EBSVolumeConfig{AWSVolumeID: "vol-1", AWSDeviceName: "/dev/sdf", AWSRegion: "us-east-1"}
+			log := runtime.EventLog{}
+
+			_, _, err := PerformResize(volume, 120, "threshold", &log, false)
+			var skipped *SkippedError
+			if !errors.As(err, &skipped) || skipped.Reason != tt.wantReason {
+				t.Fatalf("PerformResize() error = %v, want a skip for %q", err, tt.wantReason)
+			}
+			if got := *fakeEC2.Volumes[0].Size; got != 100 {
+				t.Errorf("volume size = %d, want 100", got)
+			}
+		})
+	}
+}
+
+// TestPerformResizeLocked tests that a Multi-Attach volume locked by another instance is not resized
+func TestPerformResizeLocked(t *testing.T) {
+	fakeEC2, _ := useFakes(t)
+	aws.SetInstanceMetadata(awstest.FakeMetadata{ID: "i-2"})
+	t.Cleanup(func() { aws.SetInstanceMetadata(nil) })
+	volume := runtime.EBSVolumeConfig{AWSVolumeID: "vol-1", AWSDeviceName: "/dev/sdf", AWSRegion: "us-east-1", ResizeLock: true}
+	fakeEC2.Volumes[0].Tags = []*ec2.Tag{{
+		Key:   awssdk.String(aws.ResizeLockTag),
+		Value: awssdk.String("i-1 " + runtime.Now().Add(time.Hour).UTC().Format(time.RFC3339)),
+	}}
+	log := runtime.EventLog{}
+
+	_, _, err := PerformResize(volume, 120, "threshold", &log, false)
+	var skipped *SkippedError
+	if !errors.As(err, &skipped) || skipped.Reason != runtime.SkipReasonResizeLocked {
+		t.Fatalf("PerformResize() error = %v, want a skip for %q", err, runtime.SkipReasonResizeLocked)
+	}
+	if got := *fakeEC2.Volumes[0].Size; got != 100 {
+		t.Errorf("volume size = %d, want 100", got)
+	}
+
+	// Once the lock is released, the volume is locked, resized and unlocked
+	fakeEC2.Volumes[0].Tags = nil
+	if _, _, err := PerformResize(volume, 120, "threshold", &log, false); err != nil {
+		t.Fatalf("PerformResize() error = %v", err)
+	}
+	if got := *fakeEC2.Volumes[0].Size; got != 120 {
+		t.Errorf("volume size = %d, want 120", got)
+	}
+	if len(fakeEC2.Volumes[0].Tags) != 0 {
+		t.Errorf("volume tags = %v, want the resize lock released", fakeEC2.Volumes[0].Tags)
+	}
+
+	// Another instance's lock that only shows once the tags settle wins, and the volume isn't resized
+	sleep = func(time.Duration) {
+		fakeEC2.Volumes[0].Tags = []*ec2.Tag{{
+			Key:   awssdk.String(aws.ResizeLockTag),
+			Value: awssdk.String("i-1 " + runtime.Now().Add(time.Hour).UTC().Format(time.RFC3339)),
+		}}
+	}
+	_, _, err = PerformResize(volume, 140, "threshold", &log, false)
+	if !errors.As(err, &skipped) || skipped.Reason != runtime.SkipReasonResizeLocked {
+		t.Fatalf("PerformResize() error = %v after losing the lock, want a skip for %q", err, runtime.SkipReasonResizeLocked)
+	}
+	if got := *fakeEC2.Volumes[0].Size; got != 120 {
+		t.Errorf("volume size = %d after losing the lock, want 120", got)
+	}
+	if got := len(fakeEC2.Volumes[0].Tags); got != 1 {
+		t.Errorf("volume has %d tags after losing the lock, want the other instance's lock kept", got)
+	}
+}
@@ -0,0 +1,80 @@
+package runtime
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time, so code that depends on it can be tested without sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock that reads the system time.
+type SystemClock struct{}
+
+// Now returns the current system time.
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// MockClock is a Clock that only moves when told to, for tests.
+type MockClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewMockClock creates a MockClock stopped at a time.
+// now : time.Time the time the clock reads until it is moved
+// returns : *MockClock the clock
+func NewMockClock(now time.Time) *MockClock {
+	return &MockClock{now: now}
+}
+
+// Now returns the time the clock is stopped at.
+func (c *MockClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to a time.
+// now : time.Time the time the clock reads from now on
+func (c *MockClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock forward.
+// d : time.Duration how far to move the clock
+func (c *MockClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+var (
+	// clockMu guards clock, which tests replace with a MockClock.
+	clockMu sync.Mutex
+	clock   Clock = SystemClock{}
+)
+
+// SetClock replaces the clock events, pruning and cooldowns are timed by.
+// c : Clock the clock to use, nil restores the system clock
+func SetClock(c Clock) {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	if c == nil {
+		c = SystemClock{}
+	}
+	clock = c
+}
+
+// Now returns the current time from the clock set with SetClock.
+// returns : time.Time the current time
+func Now() time.Time {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	return clock.Now()
+}
@@ -0,0 +1,62 @@
+package runtime
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMockClock tests that a MockClock only moves when set or advanced.
+func TestMockClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewMockClock(start)
+
+	if got := c.Now(); !got.Equal(start) {
+		t.Errorf("Now() = %v, want %v", got, start)
+	}
+	c.Advance(time.Hour)
+	if got, want := c.Now(), start.Add(time.Hour); !got.Equal(want) {
+		t.Errorf("Now() after Advance = %v, want %v", got, want)
+	}
+	c.Set(start)
+	if got := c.Now(); !got.Equal(start) {
+		t.Errorf("Now() after Set = %v, want %v", got, start)
+	}
+}
+
+// TestSetClock tests that events are timed by the clock set with SetClock, and that nil restores the system clock.
+func TestSetClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	SetClock(NewMockClock(start))
+	defer SetClock(nil)
+
+	if got := CreateVolumeStateEvent(EBSVolumeState{}, true).EventTime; !got.Equal(start) {
+		t.Errorf("CreateVolumeStateEvent() EventTime = %v, want %v", got, start)
+	}
+
+	SetClock(nil)
+	if got := Now(); got.Equal(start) {
+		t.Errorf("Now() = %v after SetClock(nil), want the system time", got)
+	}
+}
+
+// TestPruneStaleEventsClock tests that pruning measures retention from the clock, keeping events until they expire.
+func TestPruneStaleEventsClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewMockClock(start)
+	SetClock(c)
+	defer SetClock(nil)
+
+	log := EventLog{"vol-1": {CreateVolumeStateEvent(EBSVolumeState{}, true)}}
+
+	c.Advance(DefaultEventRetention - time.Minute)
+	log.PruneStaleEvents(DefaultEventRetention)
+	if len(log["vol-1"]) != 1 {
+		t.Fatalf("PruneStaleEvents() pruned an event within retention")
+	}
+
+	c.Advance(2 * time.Minute)
+	log.PruneStaleEvents(DefaultEventRetention)
+	if len(log["vol-1"]) != 0 {
+		t.Errorf("PruneStaleEvents() kept an event past retention")
+	}
+}
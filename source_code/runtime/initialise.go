@@ -1,7 +1,5 @@
 package runtime
 
-import "time"
-
 // InitialiseConfig initializes an empty Config struct.
 // return : *Config Newly created Config.
 func InitialiseConfig() *Config {
@@ -44,12 +42,22 @@ func InitialiseEBSVolumeState() EBSVolumeState {
 // returns : Event created event
 func CreateVolumeStateEvent(volumeState EBSVolumeState, success bool) Event {
 	event := InitialiseEvent()
-	event.EventTime = time.Now()
+	event.EventTime = Now()
 	event.VolumeState = volumeState
 	event.ExecutionSuccess = success
 	return event
 }
 
+// CreateResizeSkippedEvent creates an event recording that a resize was not attempted.
+// volumeState : EBSVolumeState state of the volume when the resize was skipped
+// reason : string why the resize was skipped, one of the SkipReason constants
+// returns : Event created event
+func CreateResizeSkippedEvent(volumeState EBSVolumeState, reason string) Event {
+	event := CreateVolumeStateEvent(volumeState, true)
+	event.SkipReason = reason
+	return event
+}
+
 // CreateVolumeResizeActionEvent creates an event based on a volume action.
 // volumeAction : EBSVolumeResize action taken on the volume
 // success : bool indicates if the action was successful
@@ -57,7 +65,7 @@ func CreateVolumeStateEvent(volumeState EBSVolumeState, success bool) Event {
 // returns : Event created event
 func CreateVolumeResizeActionEvent(volumeAction EBSVolumeResize, success bool) Event {
 	event := InitialiseEvent()
-	event.EventTime = time.Now()
+	event.EventTime = Now()
 	event.VolumeAction = volumeAction
 	event.ExecutionSuccess = success
 	return event
@@ -70,8 +78,29 @@ func CreateVolumeResizeActionEvent(volumeAction EBSVolumeResize, success bool) E
 // returns : Event created event
 func CreateFSActionEvent(fsAction FilesystemResize, success bool) Event {
 	event := InitialiseEvent()
-	event.EventTime = time.Now()
+	event.EventTime = Now()
 	event.FSAction = fsAction
 	event.ExecutionSuccess = success
 	return event
 }
+
+// CreateResizeSummaryEvent creates an event recording a completed resize.
+// summary : ResizeSummary consolidated record of the resize
+// returns : Event created event
+func CreateResizeSummaryEvent(summary ResizeSummary) Event {
+	event := InitialiseEvent()
+	event.EventTime = Now()
+	event.Summary = summary
+	event.ExecutionSuccess = true
+	return event
+}
+
+// WithError returns the event recording why its action failed.
+// err : error the failure, nil leaves the event unchanged
+// returns : Event the event with its error set
+func (event Event) WithError(err error) Event {
+	if err != nil {
+		event.Error = err.Error()
+	}
+	return event
+}
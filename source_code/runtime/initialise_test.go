@@ -114,24 +114,24 @@ func TestCreateVolumeStateEvent(t *testing.T) {
 	}{
 		{
 			volumeState: EBSVolumeState{
-				AWSVolumeID:     "vol-123",
-				AWSDeviceName:   "/dev/sda",
-				LocalMountPoint: "/mnt/volume",
-				AWSDeviceSizeGB: 100,
-				LocalDiskSizeGB: 100,
-				UsedSpaceGB:     50,
+				AWSVolumeID:      "vol-123",
+				AWSDeviceName:    "/dev/sda",
+				LocalMountPoint:  "/mnt/volume",
+				AWSDeviceSizeGiB: 100,
+				LocalDiskSizeGiB: 100,
+				UsedSpaceGiB:     50,
 			},
 			success: true,
 			wantErr: false,
 		},
 		{
 			volumeState: EBSVolumeState{
-				AWSVolumeID:     "",
-				AWSDeviceName:   "",
-				LocalMountPoint: "",
-				AWSDeviceSizeGB: 0,
-				LocalDiskSizeGB: 0,
-				UsedSpaceGB:     0,
+				AWSVolumeID:      "",
+				AWSDeviceName:    "",
+				LocalMountPoint:  "",
+				AWSDeviceSizeGiB: 0,
+				LocalDiskSizeGiB: 0,
+				UsedSpaceGiB:     0,
 			},
 			success: false,
 			wantErr: true,
@@ -166,23 +166,23 @@ func TestCreateVolumeResizeActionEvent(t *testing.T) {
 	}{
 		{
 			volumeAction: EBSVolumeResize{
-				StartTime:      time.Now(),
-				AWSVolumeID:    "vol-123",
-				AWSDeviceName:  "/dev/sda",
-				AWSRegion:      "us-west-2",
-				OriginalSizeGB: 100,
-				NewSize:        200,
+				StartTime:       time.Now(),
+				AWSVolumeID:     "vol-123",
+				AWSDeviceName:   "/dev/sda",
+				AWSRegion:       "us-west-2",
+				OriginalSizeGiB: 100,
+				NewSize:         200,
 			},
 			success: true,
 		},
 		{
 			volumeAction: EBSVolumeResize{
-				StartTime:      time.Now(),
-				AWSVolumeID:    "vol-456",
-				AWSDeviceName:  "/dev/sdb",
-				AWSRegion:      "eu-west-1",
-				OriginalSizeGB: 200,
-				NewSize:        300,
+				StartTime:       time.Now(),
+				AWSVolumeID:     "vol-456",
+				AWSDeviceName:   "/dev/sdb",
+				AWSRegion:       "eu-west-1",
+				OriginalSizeGiB: 200,
+				NewSize:         300,
 			},
 			success: false,
 		},
@@ -218,7 +218,7 @@ func TestCreateFSActionEvent(t *testing.T) {
 				AWSDeviceName:   "/dev/sda",
 				LocalMountPoint: "/mnt/volume",
 				AWSVolumeSize:   100,
-				OriginalSizeGB:  50,
+				OriginalSizeGiB: 50,
 				NewSize:         100,
 			},
 			success: true,
@@ -230,7 +230,7 @@ func TestCreateFSActionEvent(t *testing.T) {
 				AWSDeviceName:   "/dev/sdb",
 				LocalMountPoint: "/mnt/data",
 				AWSVolumeSize:   200,
-				OriginalSizeGB:  100,
+				OriginalSizeGiB: 100,
 				NewSize:         200,
 			},
 			success: false,
@@ -263,7 +263,7 @@ func compareVolumeActions(action1, action2 EBSVolumeResize) bool {
 		action1.AWSVolumeID == action2.AWSVolumeID &&
 		action1.AWSDeviceName == action2.AWSDeviceName &&
 		action1.AWSRegion == action2.AWSRegion &&
-		action1.OriginalSizeGB == action2.OriginalSizeGB &&
+		action1.OriginalSizeGiB == action2.OriginalSizeGiB &&
 		action1.NewSize == action2.NewSize
 }
 
@@ -274,7 +274,7 @@ func compareFSActions(action1, action2 FilesystemResize) bool {
 		action1.AWSDeviceName == action2.AWSDeviceName &&
 		action1.LocalMountPoint == action2.LocalMountPoint &&
 		action1.AWSVolumeSize == action2.AWSVolumeSize &&
-		action1.OriginalSizeGB == action2.OriginalSizeGB &&
+		action1.OriginalSizeGiB == action2.OriginalSizeGiB &&
 		action1.NewSize == action2.NewSize
 }
 
@@ -283,7 +283,69 @@ func compareVolumeStates(state1, state2 EBSVolumeState) bool {
 	return state1.AWSVolumeID == state2.AWSVolumeID &&
 		state1.AWSDeviceName == state2.AWSDeviceName &&
 		state1.LocalMountPoint == state2.LocalMountPoint &&
-		state1.AWSDeviceSizeGB == state2.AWSDeviceSizeGB &&
-		state1.LocalDiskSizeGB == state2.LocalDiskSizeGB &&
-		state1.UsedSpaceGB == state2.UsedSpaceGB
+		state1.AWSDeviceSizeGiB == state2.AWSDeviceSizeGiB &&
+		state1.LocalDiskSizeGiB == state2.LocalDiskSizeGiB &&
+		state1.UsedSpaceGiB == state2.UsedSpaceGiB
+}
+
+// TestCreateResizeSkippedEvent tests the CreateResizeSkippedEvent function.
+// It checks that the skip reason is recorded and that AddEvent does not treat the skip as a failure.
+func TestCreateResizeSkippedEvent(t *testing.T) {
+	volumeState := EBSVolumeState{
+		AWSVolumeID:      "vol-0abcd1234efgh5678",
+		AWSDeviceName:    "/dev/sdf",
+		AWSDeviceSizeGiB: 20,
+	}
+
+	event := CreateResizeSkippedEvent(volumeState, SkipReasonBelowThreshold)
+	if event.SkipReason != SkipReasonBelowThreshold {
+		t.Errorf("SkipReason = %q, want %q", event.SkipReason, SkipReasonBelowThreshold)
+	}
+	if !event.ExecutionSuccess {
+		t.Errorf("ExecutionSuccess = false, want true")
+	}
+
+	eventLog := make(EventLog)
+	fields, err := eventLog.AddEvent(volumeState.AWSVolumeID, event)
+	if err != nil {
+		t.Fatalf("AddEvent() error = %v", err)
+	}
+	if fields["SkipReason"] != SkipReasonBelowThreshold {
+		t.Errorf("AddEvent() SkipReason field = %v, want %q", fields["SkipReason"], SkipReasonBelowThreshold)
+	}
+}
+
+// TestCreateResizeSummaryEvent tests the CreateResizeSummaryEvent function.
+// It checks that the summary is recorded, reported as fields, and not counted as another resize.
+func TestCreateResizeSummaryEvent(t *testing.T) {
+	summary := ResizeSummary{
+		AWSVolumeID:       "vol-0abcd1234efgh5678",
+		AWSDeviceName:     "/dev/sdf",
+		TriggerReason:     "minFreeGB",
+		OriginalSizeGiB:   100,
+		NewSizeGiB:        120,
+		AWSModifyDuration: 61500 * time.Millisecond,
+		FSResizeDuration:  2 * time.Second,
+	}
+
+	event := CreateResizeSummaryEvent(summary)
+	if !reflect.DeepEqual(event.Summary, summary) {
+		t.Errorf("Summary = %+v, want %+v", event.Summary, summary)
+	}
+	if !event.ExecutionSuccess {
+		t.Errorf("ExecutionSuccess = false, want true")
+	}
+
+	fields := event.Summary.Fields()
+	if fields["AWS Modify Duration"] != "1m2s" || fields["Trigger Reason"] != "minFreeGB" {
+		t.Errorf("Fields() = %v", fields)
+	}
+	if _, ok := fields["Snapshot ID"]; ok {
+		t.Errorf("Fields() has a Snapshot ID without a snapshot")
+	}
+
+	eventLog := EventLog{summary.AWSVolumeID: {event}}
+	if _, ok := eventLog.LastResizeTime(summary.AWSVolumeID); ok {
+		t.Errorf("LastResizeTime() found a resize in a summary event")
+	}
 }
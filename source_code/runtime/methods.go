@@ -1,10 +1,204 @@
 package runtime
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"time"
 )
 
+/*
+-------------------------
+Methods for Runtime Struct
+-------------------------
+*/
+
+// MarkChecked records the time a volume was checked and returns the gap since its previous check.
+// volumeID : string AWS Volume ID of the checked volume.
+// checkTime : time.Time Time of the check.
+// returns : time.Duration Time since the previous check, or 0 if the volume had not been checked before.
+func (rt *Runtime) MarkChecked(volumeID string, checkTime time.Time) time.Duration {
+	if rt.LastChecked == nil {
+		rt.LastChecked = make(map[string]time.Time)
+	}
+
+	var gap time.Duration
+	if previous, exists := rt.LastChecked[volumeID]; exists {
+		gap = checkTime.Sub(previous)
+	}
+	rt.LastChecked[volumeID] = checkTime
+
+	return gap
+}
+
+// ScheduleCheck records when a volume is next due a check.
+// volumeID : string AWS Volume ID of the volume.
+// next : time.Time Time the volume is next due a check.
+func (rt *Runtime) ScheduleCheck(volumeID string, next time.Time) {
+	if rt.NextCheck == nil {
+		rt.NextCheck = make(map[string]time.Time)
+	}
+	rt.NextCheck[volumeID] = next
+}
+
+// DueVolumes returns the monitored volumes due a check, in config order.
+// Volumes that have never been scheduled are due straight away.
+// now : time.Time Current time.
+// returns : []EBSVolumeConfig Volumes to check.
+func (rt *Runtime) DueVolumes(now time.Time) []EBSVolumeConfig {
+	due := make([]EBSVolumeConfig, 0)
+	for _, volume := range rt.Configuration.Volumes {
+		if next, scheduled := rt.NextCheck[volume.AWSVolumeID]; !scheduled || !next.After(now) {
+			due = append(due, volume)
+		}
+	}
+	return due
+}
+
+// NextCheckDue returns when the next monitored volume is due a check.
+// returns : time.Time Earliest time a volume is due, the zero time if one has never been scheduled.
+// returns : bool False if there are no monitored volumes.
+func (rt *Runtime) NextCheckDue() (time.Time, bool) {
+	var earliest time.Time
+	for i, volume := range rt.Configuration.Volumes {
+		next := rt.NextCheck[volume.AWSVolumeID]
+		if i == 0 || next.Before(earliest) {
+			earliest = next
+		}
+	}
+	return earliest, len(rt.Configuration.Volumes) > 0
+}
+
+// SetMounted records whether a volume was found mounted and reports whether that changed.
+// volumeID : string AWS Volume ID of the checked volume.
+// mounted : bool Whether the volume's filesystems are mounted.
+// returns : bool True if the volume was previously recorded in the other state. Volumes start as mounted.
+func (rt *Runtime) SetMounted(volumeID string, mounted bool) bool {
+	if rt.Unmounted == nil {
+		rt.Unmounted = make(map[string]bool)
+	}
+
+	wasUnmounted := rt.Unmounted[volumeID]
+	if mounted {
+		delete(rt.Unmounted, volumeID)
+	} else {
+		rt.Unmounted[volumeID] = true
+	}
+
+	return wasUnmounted == mounted
+}
+
+// SetTransient records whether a volume's last check hit a transient error, which isn't counted towards its removal.
+// volumeID : string AWS Volume ID of the volume.
+// transient : bool Whether the check hit a transient error.
+func (rt *Runtime) SetTransient(volumeID string, transient bool) {
+	if rt.Transient == nil {
+		rt.Transient = make(map[string]bool)
+	}
+
+	if transient {
+		rt.Transient[volumeID] = true
+	} else {
+		delete(rt.Transient, volumeID)
+	}
+}
+
+// Quarantine records a volume dropped from monitoring, to be retried once interval has passed.
+// volume : EBSVolumeConfig Configuration of the dropped volume.
+// reason : string Error that caused the volume to be dropped.
+// dropTime : time.Time Time the volume was dropped.
+func (rt *Runtime) Quarantine(volume EBSVolumeConfig, reason string, dropTime time.Time) {
+	if rt.Quarantined == nil {
+		rt.Quarantined = make(map[string]QuarantinedVolume)
+	}
+	rt.Quarantined[volume.AWSVolumeID] = QuarantinedVolume{Volume: volume, Reason: reason, LastRetry: dropTime}
+}
+
+// DueForRetry returns the quarantined volumes not retried within interval, ordered by ID, and marks them retried.
+// now : time.Time Current time.
+// interval : time.Duration Minimum time between retries of a volume.
+// returns : []EBSVolumeConfig Volumes to retry.
+func (rt *Runtime) DueForRetry(now time.Time, interval time.Duration) []EBSVolumeConfig {
+	due := make([]EBSVolumeConfig, 0)
+	for volumeID, quarantined := range rt.Quarantined {
+		if now.Sub(quarantined.LastRetry) < interval {
+			continue
+		}
+		quarantined.LastRetry = now
+		rt.Quarantined[volumeID] = quarantined
+		due = append(due, quarantined.Volume)
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].AWSVolumeID < due[j].AWSVolumeID })
+	return due
+}
+
+// Complete records that a monitoring cycle completed.
+// completed : time.Time Time the cycle completed.
+// interval : time.Duration Check interval, the expected time until the next cycle completes.
+func (c *CycleTracker) Complete(completed time.Time, interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.last = completed
+	c.interval = interval
+}
+
+// Overdue reports whether the next cycle is late, i.e. more than twice the check interval has passed since the last one completed.
+// now : time.Time Current time.
+// returns : bool True if the next cycle is overdue, false if no cycle has been recorded yet.
+// returns : time.Duration Time since the last cycle completed.
+func (c *CycleTracker) Overdue(now time.Time) (bool, time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last.IsZero() {
+		return false, 0
+	}
+	since := now.Sub(c.last)
+	return since > 2*c.interval, since
+}
+
+// redacted replaces secrets in a Dump.
+const redacted = "REDACTED"
+
+// Dump takes a snapshot of the runtime state for debugging, redacting secrets from the configuration.
+// eventLog : EventLog The log of events.
+// errorLog : map[string]int The error count of each volume.
+// now : time.Time Time of the snapshot.
+// returns : Dump The snapshot. It shares the runtime's maps, so encode it before the runtime changes.
+func (rt *Runtime) Dump(eventLog EventLog, errorLog map[string]int, now time.Time) Dump {
+	config := rt.Configuration
+	if config.SlackWebhookURL != "" {
+		config.SlackWebhookURL = redacted
+	}
+	return Dump{
+		Time:        now,
+		Config:      config,
+		EventLog:    eventLog,
+		ErrorLog:    errorLog,
+		LastChecked: rt.LastChecked,
+		NextCheck:   rt.NextCheck,
+		Unmounted:   rt.Unmounted,
+		Quarantined: rt.Quarantined,
+	}
+}
+
+// WriteJSON writes the snapshot as indented JSON.
+// w : io.Writer Where to write the snapshot.
+// returns : error An error if the snapshot can't be encoded or written.
+func (d Dump) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(d); err != nil {
+		return fmt.Errorf("failed to write runtime dump. error: %w", err)
+	}
+	return nil
+}
+
 /*
 -------------------------
 Methods for Config Struct
@@ -23,6 +217,124 @@ func (cfg *Config) SetCheckInterval(interval int) {
 	cfg.CheckIntervalSeconds = interval
 }
 
+// CheckInterval returns how often a volume is checked, its own checkIntervalSeconds or else the Config's.
+// volume : EBSVolumeConfig Volume to get the interval of.
+// returns : time.Duration Check interval of the volume.
+func (cfg *Config) CheckInterval(volume EBSVolumeConfig) time.Duration {
+	if volume.CheckIntervalSeconds > 0 {
+		return time.Duration(volume.CheckIntervalSeconds) * time.Second
+	}
+	return time.Duration(cfg.CheckIntervalSeconds) * time.Second
+}
+
+// EventRetention returns how long events are kept in the event log, the Config's eventRetentionHours or else DefaultEventRetention.
+// returns : time.Duration Retention of the event log.
+func (cfg *Config) EventRetention() time.Duration {
+	if cfg.EventRetentionHours > 0 {
+		return time.Duration(cfg.EventRetentionHours) * time.Hour
+	}
+	return DefaultEventRetention
+}
+
+// RemoveEBSVolumeConfig removes the EBS volume with the given ID from the Config's list of volumes.
+// volumeID : string AWS Volume ID of the volume to remove.
+// returns : bool True if a volume was removed.
+func (cfg *Config) RemoveEBSVolumeConfig(volumeID string) bool {
+	for i, volume := range cfg.Volumes {
+		if volume.AWSVolumeID == volumeID {
+			cfg.Volumes = append(cfg.Volumes[:i], cfg.Volumes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ReplaceEBSVolumeConfig replaces the EBS volume sharing the given volume's ID, keeping its position in the list.
+// volume : EBSVolumeConfig Updated volume configuration.
+// returns : bool True if a volume was replaced.
+func (cfg *Config) ReplaceEBSVolumeConfig(volume EBSVolumeConfig) bool {
+	for i := range cfg.Volumes {
+		if cfg.Volumes[i].AWSVolumeID == volume.AWSVolumeID {
+			cfg.Volumes[i] = volume
+			return true
+		}
+	}
+	return false
+}
+
+// DiffVolumes compares the Config's volumes with a desired set of volumes, matched by AWS Volume ID.
+// desired : []EBSVolumeConfig The volumes that should be monitored.
+// returns : added []EBSVolumeConfig Volumes in desired but not in the Config.
+// returns : removed []EBSVolumeConfig Volumes in the Config but not in desired.
+// returns : changed []EBSVolumeConfig Volumes in both whose settings differ, as given in desired.
+func (cfg *Config) DiffVolumes(desired []EBSVolumeConfig) (added, removed, changed []EBSVolumeConfig) {
+	current := make(map[string]EBSVolumeConfig, len(cfg.Volumes))
+	for _, volume := range cfg.Volumes {
+		current[volume.AWSVolumeID] = volume
+	}
+
+	wanted := make(map[string]bool, len(desired))
+	for _, volume := range desired {
+		wanted[volume.AWSVolumeID] = true
+		existing, exists := current[volume.AWSVolumeID]
+		if !exists {
+			added = append(added, volume)
+		} else if !reflect.DeepEqual(existing, volume) {
+			changed = append(changed, volume)
+		}
+	}
+
+	for _, volume := range cfg.Volumes {
+		if !wanted[volume.AWSVolumeID] {
+			removed = append(removed, volume)
+		}
+	}
+
+	return added, removed, changed
+}
+
+/*
+-------------------------
+Methods for GrowthWindow Struct
+-------------------------
+*/
+
+// Bounds parses the window's start and end times.
+// returns : start time.Duration Offset of the window start from midnight.
+// returns : end time.Duration Offset of the window end from midnight.
+// returns : error An error if either time is not in "HH:MM" format.
+func (w GrowthWindow) Bounds() (start, end time.Duration, err error) {
+	startTime, err := time.Parse("15:04", w.WindowStart)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid window start %q, expected HH:MM. error: %w", w.WindowStart, err)
+	}
+	endTime, err := time.Parse("15:04", w.WindowEnd)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid window end %q, expected HH:MM. error: %w", w.WindowEnd, err)
+	}
+
+	start = time.Duration(startTime.Hour())*time.Hour + time.Duration(startTime.Minute())*time.Minute
+	end = time.Duration(endTime.Hour())*time.Hour + time.Duration(endTime.Minute())*time.Minute
+	return start, end, nil
+}
+
+// Contains checks if the given time falls within the window.
+// t : time.Time The time to check, compared using its own location's time of day.
+// returns : bool True if t is within the window. Windows with invalid times contain nothing.
+func (w GrowthWindow) Contains(t time.Time) bool {
+	start, end, err := w.Bounds()
+	if err != nil {
+		return false
+	}
+
+	timeOfDay := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if start <= end {
+		return timeOfDay >= start && timeOfDay < end
+	}
+	// The window crosses midnight
+	return timeOfDay >= start || timeOfDay < end
+}
+
 /*
 -------------------------
 Methods for EventLog type (map[string][]VolumeHistory)
@@ -74,14 +386,20 @@ func (eventLog EventLog) AddEvent(volumeID string, event Event) (map[string]inte
 	fields := map[string]interface{}{
 		"AWSVolumeID":      volumeID,
 		"EventTime":        event.EventTime,
-		"VolumeState":      event.VolumeState.AWSDeviceSizeGB,
+		"VolumeState":      event.VolumeState.AWSDeviceSizeGiB,
 		"VolumeAction":     event.VolumeAction.AWSDeviceName,
 		"FSAction":         event.FSAction.AWSDeviceName,
 		"ExecutionSuccess": event.ExecutionSuccess,
 	}
+	if event.SkipReason != "" {
+		fields["SkipReason"] = event.SkipReason
+	}
+	if event.VolumeAction.TriggerReason != "" {
+		fields["TriggerReason"] = event.VolumeAction.TriggerReason
+	}
 
 	failedAction := ""
-	if event.VolumeState.AWSDeviceSizeGB <= 0 {
+	if event.VolumeState.AWSDeviceSizeGiB <= 0 {
 		failedAction = "Get volume state"
 	} else if event.VolumeAction.AWSDeviceName != "" {
 		failedAction = "Perform AWS device resize"
@@ -97,6 +415,29 @@ func (eventLog EventLog) AddEvent(volumeID string, event Event) (map[string]inte
 
 }
 
+// Fields returns the summary as log fields, for logging and notifying it as one message.
+// returns : map[string]interface{} The summary's fields, without the snapshot ID when no snapshot was taken, or the cost when it wasn't estimated.
+func (s ResizeSummary) Fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"AWS Volume ID":       s.AWSVolumeID,
+		"AWS Device Name":     s.AWSDeviceName,
+		"AWS Region":          s.AWSRegion,
+		"Local Mount Point":   s.LocalMountPoint,
+		"Trigger Reason":      s.TriggerReason,
+		"Original Size":       FormatSize(s.OriginalSizeGiB),
+		"New Size":            FormatSize(s.NewSizeGiB),
+		"AWS Modify Duration": s.AWSModifyDuration.Round(time.Second).String(),
+		"FS Resize Duration":  s.FSResizeDuration.Round(time.Second).String(),
+	}
+	if s.SnapshotID != "" {
+		fields["Snapshot ID"] = s.SnapshotID
+	}
+	if s.MonthlyCostDelta > 0 {
+		fields["Estimated Monthly Cost Delta (USD)"] = s.MonthlyCostDelta
+	}
+	return fields
+}
+
 // Equals checks if the calling Event is the same as the provided Event.
 // otherEvent : Event - The Event to compare with the calling Event.
 // returns : bool - True if the Events are the same, otherwise false.
@@ -104,20 +445,170 @@ func (e Event) Equals(otherEvent Event) bool {
 	// Here we assume two events are considered "the same" if they share the same EventTime,
 	// VolumeState, and ExecutionSuccess values. Adjust this logic if your definition of
 	// "same" is different.
-	return e.EventTime == otherEvent.EventTime && e.VolumeState == otherEvent.VolumeState && e.ExecutionSuccess == otherEvent.ExecutionSuccess
+	return e.EventTime == otherEvent.EventTime && e.VolumeState == otherEvent.VolumeState && e.ExecutionSuccess == otherEvent.ExecutionSuccess && e.SkipReason == otherEvent.SkipReason
 }
 
-// PruneStaleEvents removes all VolumeHistory entries older than 1 day from the VolumeHistories.
-func (histories EventLog) PruneStaleEvents() {
-	oneDayAgo := time.Now().Add(-24 * time.Hour)
+// LastResizeTime returns when a volume was last successfully resized in AWS, including simulated resizes.
+// volumeID : string Identifier of the volume.
+// returns : time.Time StartTime of the most recent successful resize.
+// returns : bool False if the log holds no successful resize for the volume.
+func (eventLog EventLog) LastResizeTime(volumeID string) (time.Time, bool) {
+	var last time.Time
+	for _, event := range eventLog[volumeID] {
+		if event.ExecutionSuccess && !event.VolumeAction.StartTime.IsZero() && event.VolumeAction.StartTime.After(last) {
+			last = event.VolumeAction.StartTime
+		}
+	}
+	return last, !last.IsZero()
+}
+
+// ResizesSince counts a volume's successful AWS resizes started after a time, including simulated resizes.
+// volumeID : string Identifier of the volume.
+// since : time.Time Resizes started at or before this time aren't counted.
+// returns : int The number of resizes.
+func (eventLog EventLog) ResizesSince(volumeID string, since time.Time) int {
+	count := 0
+	for _, event := range eventLog[volumeID] {
+		if event.ExecutionSuccess && event.VolumeAction.StartTime.After(since) {
+			count++
+		}
+	}
+	return count
+}
+
+// LatestStates returns the most recently recorded state of each volume.
+// returns : map[string]EBSVolumeState The states, keyed by AWS Volume ID, without volumes that have no recorded state yet.
+func (eventLog EventLog) LatestStates() map[string]EBSVolumeState {
+	states := make(map[string]EBSVolumeState)
+	for volumeID, events := range eventLog {
+		var latest time.Time
+		for _, event := range events {
+			if event.VolumeState.AWSVolumeID == "" || event.EventTime.Before(latest) {
+				continue
+			}
+			latest = event.EventTime
+			states[volumeID] = event.VolumeState
+		}
+	}
+	return states
+}
+
+// Snapshot takes the last-known state of each volume, for local tooling.
+// now : time.Time Time of the snapshot.
+// returns : StateSnapshot The snapshot. It shares the event log, so encode it before the log changes.
+func (eventLog EventLog) Snapshot(now time.Time) StateSnapshot {
+	return StateSnapshot{
+		Time:     now,
+		Volumes:  eventLog.LatestStates(),
+		EventLog: eventLog,
+	}
+}
+
+// RecentErrors returns the errors of a volume's most recent failed events, newest first.
+// volumeID : string Identifier of the volume.
+// n : int Most errors to return.
+// returns : []string The errors, fewer than n if the log holds fewer failures with a recorded error.
+func (eventLog EventLog) RecentErrors(volumeID string, n int) []string {
+	events := eventLog[volumeID]
+	errs := make([]string, 0, n)
+	for i := len(events) - 1; i >= 0 && len(errs) < n; i-- {
+		if !events[i].ExecutionSuccess && events[i].Error != "" {
+			errs = append(errs, events[i].Error)
+		}
+	}
+	return errs
+}
+
+// ConsecutiveStates counts the volume's most recent successful state checks that match a condition, newest first.
+// Skips and failed checks are passed over, and counting stops at the first state that doesn't match or at a resize,
+// as states from before a resize describe the old size.
+// volumeID : string Identifier of the volume.
+// match : func(EBSVolumeState) bool The condition each state must meet, e.g. being above the resize threshold.
+// returns : int The number of consecutive matching states.
+func (eventLog EventLog) ConsecutiveStates(volumeID string, match func(EBSVolumeState) bool) int {
+	events := eventLog[volumeID]
+	count := 0
+	for i := len(events) - 1; i >= 0; i-- {
+		event := events[i]
+		if event.VolumeAction.AWSVolumeID != "" {
+			break
+		}
+		if !event.ExecutionSuccess || event.SkipReason != "" || event.VolumeState.AWSVolumeID == "" {
+			continue
+		}
+		if !match(event.VolumeState) {
+			break
+		}
+		count++
+	}
+	return count
+}
+
+// PruneStaleEvents removes all VolumeHistory entries older than the retention from the VolumeHistories.
+// retention : time.Duration How long events are kept, see Config.EventRetention.
+func (histories EventLog) PruneStaleEvents(retention time.Duration) {
+	cutoff := Now().Add(-retention)
 
 	for volumeID, volumeHistories := range histories {
 		var prunedVolumeHistories []Event
 		for _, history := range volumeHistories {
-			if history.EventTime.After(oneDayAgo) {
+			if history.EventTime.After(cutoff) {
 				prunedVolumeHistories = append(prunedVolumeHistories, history)
 			}
 		}
 		histories[volumeID] = prunedVolumeHistories
 	}
 }
+
+// SaveToFile writes the event log to a JSON file, so history survives a restart.
+// The file is written to a temporary file first and renamed into place, so a crash never leaves it half written.
+// path : string Path of the file to write.
+// returns : error An error if the log can't be encoded or written.
+func (eventLog EventLog) SaveToFile(path string) error {
+	data, err := json.Marshal(eventLog)
+	if err != nil {
+		return fmt.Errorf("failed to encode event log. error: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create event log file. error: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write event log file. error: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write event log file. error: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace event log file. error: %w", err)
+	}
+	return nil
+}
+
+// LoadEventLog reads an event log saved by SaveToFile, dropping events older than the retention.
+// A missing file is not an error and returns an empty log, as on first start.
+// path : string Path of the file to read.
+// retention : time.Duration How long events are kept, see Config.EventRetention.
+// returns : EventLog The loaded event log.
+// returns : error An error if the file exists but can't be read or decoded.
+func LoadEventLog(path string, retention time.Duration) (EventLog, error) {
+	eventLog := make(EventLog)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return eventLog, nil
+	}
+	if err != nil {
+		return eventLog, fmt.Errorf("failed to read event log file. error: %w", err)
+	}
+	if err := json.Unmarshal(data, &eventLog); err != nil {
+		return make(EventLog), fmt.Errorf("failed to decode event log file. error: %w", err)
+	}
+
+	eventLog.PruneStaleEvents(retention)
+	return eventLog, nil
+}
@@ -1,11 +1,168 @@
 package runtime
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
 
+// TestMarkChecked tests the MarkChecked method of the Runtime struct.
+// It checks that the gap since the previous check is returned and the last check time updated.
+func TestMarkChecked(t *testing.T) {
+	rt := InitialiseRuntime()
+	first := time.Now()
+	second := first.Add(90 * time.Second)
+
+	if gap := rt.MarkChecked("vol-0abcd1234efgh5678", first); gap != 0 {
+		t.Errorf("MarkChecked() first check = %v, want 0", gap)
+	}
+	if gap := rt.MarkChecked("vol-0abcd1234efgh5678", second); gap != 90*time.Second {
+		t.Errorf("MarkChecked() second check = %v, want %v", gap, 90*time.Second)
+	}
+	if got := rt.LastChecked["vol-0abcd1234efgh5678"]; !got.Equal(second) {
+		t.Errorf("LastChecked = %v, want %v", got, second)
+	}
+}
+
+// TestSetMounted tests the SetMounted method of the Runtime struct.
+// It checks that only changes between the mounted and unmounted states are reported.
+func TestSetMounted(t *testing.T) {
+	rt := InitialiseRuntime()
+	steps := []struct {
+		mounted bool
+		want    bool
+	}{
+		{true, false},
+		{false, true},
+		{false, false},
+		{true, true},
+		{true, false},
+	}
+
+	for i, step := range steps {
+		if got := rt.SetMounted("vol-0abcd1234efgh5678", step.mounted); got != step.want {
+			t.Errorf("step %d: SetMounted(%v) = %v, want %v", i, step.mounted, got, step.want)
+		}
+	}
+	if len(rt.Unmounted) != 0 {
+		t.Errorf("Unmounted = %v, want empty once mounted again", rt.Unmounted)
+	}
+}
+
+// TestDueForRetry tests the Quarantine and DueForRetry methods of the Runtime struct.
+// It checks that a quarantined volume is only retried once the interval has passed since its last retry.
+func TestDueForRetry(t *testing.T) {
+	rt := InitialiseRuntime()
+	dropped := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	interval := 5 * time.Minute
+	rt.Quarantine(EBSVolumeConfig{AWSVolumeID: "vol-0bbbb"}, "volume not found", dropped)
+	rt.Quarantine(EBSVolumeConfig{AWSVolumeID: "vol-0aaaa"}, "volume not found", dropped)
+
+	if due := rt.DueForRetry(dropped.Add(time.Minute), interval); len(due) != 0 {
+		t.Errorf("DueForRetry() before the interval = %v, want none", due)
+	}
+
+	due := rt.DueForRetry(dropped.Add(interval), interval)
+	if len(due) != 2 || due[0].AWSVolumeID != "vol-0aaaa" || due[1].AWSVolumeID != "vol-0bbbb" {
+		t.Fatalf("DueForRetry() after the interval = %v, want both volumes ordered by ID", due)
+	}
+	if rt.Quarantined["vol-0aaaa"].Reason != "volume not found" {
+		t.Errorf("Reason = %q, want it kept across retries", rt.Quarantined["vol-0aaaa"].Reason)
+	}
+
+	if due := rt.DueForRetry(dropped.Add(interval+time.Minute), interval); len(due) != 0 {
+		t.Errorf("DueForRetry() just after a retry = %v, want none", due)
+	}
+}
+
+// TestDueVolumes tests the ScheduleCheck, DueVolumes and NextCheckDue methods of the Runtime struct.
+// It checks that unscheduled volumes are due straight away and scheduled ones once their time comes.
+func TestDueVolumes(t *testing.T) {
+	rt := InitialiseRuntime()
+	if _, ok := rt.NextCheckDue(); ok {
+		t.Errorf("NextCheckDue() with no volumes ok = true, want false")
+	}
+
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	rt.Configuration.Volumes = []EBSVolumeConfig{{AWSVolumeID: "vol-0aaaa"}, {AWSVolumeID: "vol-0bbbb"}}
+	if due := rt.DueVolumes(now); len(due) != 2 {
+		t.Errorf("DueVolumes() before scheduling = %v, want both volumes", due)
+	}
+	if next, ok := rt.NextCheckDue(); !ok || !next.IsZero() {
+		t.Errorf("NextCheckDue() before scheduling = %v, %v, want the zero time", next, ok)
+	}
+
+	rt.ScheduleCheck("vol-0aaaa", now.Add(30*time.Second))
+	rt.ScheduleCheck("vol-0bbbb", now.Add(10*time.Minute))
+	if due := rt.DueVolumes(now); len(due) != 0 {
+		t.Errorf("DueVolumes() before either is due = %v, want none", due)
+	}
+	if next, _ := rt.NextCheckDue(); !next.Equal(now.Add(30 * time.Second)) {
+		t.Errorf("NextCheckDue() = %v, want the earlier volume's check", next)
+	}
+	due := rt.DueVolumes(now.Add(30 * time.Second))
+	if len(due) != 1 || due[0].AWSVolumeID != "vol-0aaaa" {
+		t.Errorf("DueVolumes() at the first check = %v, want only vol-0aaaa", due)
+	}
+}
+
+// TestDumpWriteJSON tests the Dump method of the Runtime struct and WriteJSON.
+// It checks that the snapshot round-trips through JSON and that the Slack webhook URL is redacted.
+func TestDumpWriteJSON(t *testing.T) {
+	rt := InitialiseRuntime()
+	rt.Configuration.SlackWebhookURL = "https://hooks.slack.com/services/secret"
+	rt.Configuration.Volumes = []EBSVolumeConfig{{AWSVolumeID: "vol-0abcd1234efgh5678"}}
+	checked := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	rt.MarkChecked("vol-0abcd1234efgh5678", checked)
+	eventLog := EventLog{"vol-0abcd1234efgh5678": {{EventTime: checked, ExecutionSuccess: true}}}
+	errorLog := map[string]int{"vol-0abcd1234efgh5678": 2}
+
+	var buf bytes.Buffer
+	if err := rt.Dump(eventLog, errorLog, checked).WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("WriteJSON() output contains the Slack webhook URL: %s", buf.String())
+	}
+
+	var got Dump
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode dump: %v", err)
+	}
+	if got.Config.SlackWebhookURL != redacted || got.ErrorLog["vol-0abcd1234efgh5678"] != 2 ||
+		!got.LastChecked["vol-0abcd1234efgh5678"].Equal(checked) || len(got.EventLog["vol-0abcd1234efgh5678"]) != 1 {
+		t.Errorf("decoded dump = %+v, want the runtime state", got)
+	}
+	if rt.Configuration.SlackWebhookURL == redacted {
+		t.Errorf("Dump() redacted the runtime's own configuration")
+	}
+}
+
+// TestCycleTrackerOverdue tests the Complete and Overdue methods of the CycleTracker struct.
+// It checks that a cycle is overdue only once twice the interval has passed, and never before the first cycle.
+func TestCycleTrackerOverdue(t *testing.T) {
+	var tracker CycleTracker
+	completed := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	if overdue, _ := tracker.Overdue(completed); overdue {
+		t.Errorf("Overdue() before any cycle = true, want false")
+	}
+
+	tracker.Complete(completed, time.Minute)
+	if overdue, since := tracker.Overdue(completed.Add(2 * time.Minute)); overdue || since != 2*time.Minute {
+		t.Errorf("Overdue() at twice the interval = (%v, %v), want (false, 2m0s)", overdue, since)
+	}
+	if overdue, _ := tracker.Overdue(completed.Add(2*time.Minute + time.Second)); !overdue {
+		t.Errorf("Overdue() past twice the interval = false, want true")
+	}
+}
+
 // TestAddEBSVolumeConfigs tests the AddEBSVolumeConfigs method of the Config struct.
 // It checks if the EBS volumes have been correctly added to the Config's list of volumes.
 func TestAddEBSVolumeConfigs(t *testing.T) {
@@ -50,6 +207,108 @@ func TestSetCheckInterval(t *testing.T) {
 	}
 }
 
+// TestCheckInterval tests the CheckInterval method of the Config struct.
+// It checks that a volume's own interval overrides the Config's.
+func TestCheckInterval(t *testing.T) {
+	cfg := Config{CheckIntervalSeconds: 60}
+	if got := cfg.CheckInterval(EBSVolumeConfig{}); got != time.Minute {
+		t.Errorf("CheckInterval() without an override = %v, want %v", got, time.Minute)
+	}
+	if got := cfg.CheckInterval(EBSVolumeConfig{CheckIntervalSeconds: 30}); got != 30*time.Second {
+		t.Errorf("CheckInterval() with an override = %v, want %v", got, 30*time.Second)
+	}
+}
+
+// TestDiffVolumes tests the DiffVolumes method of the Config struct.
+// It checks that added, removed and changed volumes are identified by AWS Volume ID.
+func TestDiffVolumes(t *testing.T) {
+	cfg := InitialiseConfig()
+	cfg.AddEBSVolumeConfigs(
+		EBSVolumeConfig{AWSVolumeID: "vol-keep", ResizeThreshold: 80},
+		EBSVolumeConfig{AWSVolumeID: "vol-change", ResizeThreshold: 80},
+		EBSVolumeConfig{AWSVolumeID: "vol-remove", ResizeThreshold: 80},
+	)
+	desired := []EBSVolumeConfig{
+		{AWSVolumeID: "vol-keep", ResizeThreshold: 80},
+		{AWSVolumeID: "vol-change", ResizeThreshold: 90},
+		{AWSVolumeID: "vol-add", ResizeThreshold: 70},
+	}
+
+	// "want" represents the expected outcome
+	wantAdded := []EBSVolumeConfig{{AWSVolumeID: "vol-add", ResizeThreshold: 70}}
+	wantRemoved := []EBSVolumeConfig{{AWSVolumeID: "vol-remove", ResizeThreshold: 80}}
+	wantChanged := []EBSVolumeConfig{{AWSVolumeID: "vol-change", ResizeThreshold: 90}}
+
+	// "got" represents the actual outcome
+	gotAdded, gotRemoved, gotChanged := cfg.DiffVolumes(desired)
+
+	if !reflect.DeepEqual(gotAdded, wantAdded) || !reflect.DeepEqual(gotRemoved, wantRemoved) || !reflect.DeepEqual(gotChanged, wantChanged) {
+		t.Errorf("DiffVolumes() = (%v, %v, %v), want (%v, %v, %v)", gotAdded, gotRemoved, gotChanged, wantAdded, wantRemoved, wantChanged)
+	}
+}
+
+// TestRemoveAndReplaceEBSVolumeConfig tests the RemoveEBSVolumeConfig and ReplaceEBSVolumeConfig methods of the Config struct.
+func TestRemoveAndReplaceEBSVolumeConfig(t *testing.T) {
+	cfg := InitialiseConfig()
+	cfg.AddEBSVolumeConfigs(
+		EBSVolumeConfig{AWSVolumeID: "vol-a", ResizeThreshold: 80},
+		EBSVolumeConfig{AWSVolumeID: "vol-b", ResizeThreshold: 80},
+	)
+
+	// "want" represents the expected outcome
+	want := []EBSVolumeConfig{{AWSVolumeID: "vol-b", ResizeThreshold: 90}}
+
+	if !cfg.RemoveEBSVolumeConfig("vol-a") {
+		t.Errorf("RemoveEBSVolumeConfig() = false, want true")
+	}
+	if !cfg.ReplaceEBSVolumeConfig(EBSVolumeConfig{AWSVolumeID: "vol-b", ResizeThreshold: 90}) {
+		t.Errorf("ReplaceEBSVolumeConfig() = false, want true")
+	}
+	if cfg.RemoveEBSVolumeConfig("vol-missing") {
+		t.Errorf("RemoveEBSVolumeConfig() = true for unknown volume, want false")
+	}
+
+	// "got" represents the actual outcome
+	got := cfg.Volumes
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Volumes = %v, want %v", got, want)
+	}
+}
+
+// TestGrowthWindowContains tests the Contains method of the GrowthWindow struct at window boundaries.
+func TestGrowthWindowContains(t *testing.T) {
+	day := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	business := GrowthWindow{WindowStart: "09:00", WindowEnd: "17:00", Multiplier: 2}
+	overnight := GrowthWindow{WindowStart: "22:00", WindowEnd: "06:00", Multiplier: 0.5}
+
+	tests := []struct {
+		name   string
+		window GrowthWindow
+		at     time.Time
+		want   bool
+	}{
+		{"before start", business, day.Add(8*time.Hour + 59*time.Minute + 59*time.Second), false},
+		{"at start", business, day.Add(9 * time.Hour), true},
+		{"inside", business, day.Add(12 * time.Hour), true},
+		{"just before end", business, day.Add(16*time.Hour + 59*time.Minute + 59*time.Second), true},
+		{"at end", business, day.Add(17 * time.Hour), false},
+		{"overnight before midnight", overnight, day.Add(23 * time.Hour), true},
+		{"overnight after midnight", overnight, day.Add(5 * time.Hour), true},
+		{"overnight at end", overnight, day.Add(6 * time.Hour), false},
+		{"overnight outside", overnight, day.Add(12 * time.Hour), false},
+		{"invalid window", GrowthWindow{WindowStart: "9am", WindowEnd: "17:00"}, day.Add(12 * time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Contains(tt.at); got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 // TestAddEBSVolumeStateExecution tests the AddEBSVolumeStateExecution method of the VolumeHistory struct.
 // It checks if the volume state and execution success flag have been correctly added.
 func TestAddEBSVolumeStateExecution(t *testing.T) {
@@ -80,9 +339,9 @@ func TestAddEBSVolumeStateExecution(t *testing.T) {
 func TestAddEBSVolumeResizeExecution(t *testing.T) {
 	history := InitialiseEvent()
 	action := EBSVolumeResize{
-		AWSVolumeID:    "vol-0abcd1234efgh5678",
-		OriginalSizeGB: 10,
-		NewSize:        20,
+		AWSVolumeID:     "vol-0abcd1234efgh5678",
+		OriginalSizeGiB: 10,
+		NewSize:         20,
 	}
 	success := true
 
@@ -102,14 +361,32 @@ func TestAddEBSVolumeResizeExecution(t *testing.T) {
 	}
 }
 
+// TestAddEventTriggerReason tests that a failed resize is logged with the rule that triggered it.
+func TestAddEventTriggerReason(t *testing.T) {
+	eventLog := EventLog{}
+	event := CreateVolumeResizeActionEvent(EBSVolumeResize{
+		AWSVolumeID:   "vol-0abcd1234efgh5678",
+		AWSDeviceName: "/dev/sdf",
+		TriggerReason: "minFreeGB",
+	}, false)
+
+	fields, err := eventLog.AddEvent("vol-0abcd1234efgh5678", event)
+	if err == nil {
+		t.Fatal("AddEvent() error = nil, want the failed action")
+	}
+	if got := fields["TriggerReason"]; got != "minFreeGB" {
+		t.Errorf("AddEvent() TriggerReason = %v, want minFreeGB", got)
+	}
+}
+
 // TestAddFilesystemResizeExecution tests the AddFilesystemResizeExecution method of the VolumeHistory struct.
 // It checks if the filesystem resize action and execution success flag have been correctly added.
 func TestAddFilesystemResizeExecution(t *testing.T) {
 	history := InitialiseEvent()
 	action := FilesystemResize{
-		AWSVolumeID:    "vol-0abcd1234efgh5678",
-		OriginalSizeGB: 10,
-		NewSize:        20,
+		AWSVolumeID:     "vol-0abcd1234efgh5678",
+		OriginalSizeGiB: 10,
+		NewSize:         20,
 	}
 	success := true
 
@@ -141,7 +418,7 @@ func TestPrune(t *testing.T) {
 	want := EventLog{}
 
 	// Pruning histories
-	histories.PruneStaleEvents()
+	histories.PruneStaleEvents(DefaultEventRetention)
 
 	// "got" represents the actual outcome
 	got := histories
@@ -150,3 +427,201 @@ func TestPrune(t *testing.T) {
 		t.Errorf("Prune() = %v, want %v", got, want)
 	}
 }
+
+// TestSaveAndLoadEventLog tests that an event log survives a save and load, with stale events pruned on load.
+func TestSaveAndLoadEventLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eventlog.json")
+	recent := Event{
+		EventTime:        time.Now().Add(-time.Hour).Round(0),
+		VolumeState:      EBSVolumeState{AWSVolumeID: "vol-0abcd1234efgh5678", AWSDeviceSizeGiB: 20},
+		ExecutionSuccess: true,
+		SkipReason:       SkipReasonBelowThreshold,
+	}
+	stale := Event{EventTime: time.Now().Add(-48 * time.Hour).Round(0), ExecutionSuccess: true}
+	eventLog := EventLog{"vol-0abcd1234efgh5678": {stale, recent}}
+
+	if err := eventLog.SaveToFile(path); err != nil {
+		t.Fatalf("SaveToFile() error = %v", err)
+	}
+	loaded, err := LoadEventLog(path, DefaultEventRetention)
+	if err != nil {
+		t.Fatalf("LoadEventLog() error = %v", err)
+	}
+
+	events := loaded["vol-0abcd1234efgh5678"]
+	if len(events) != 1 {
+		t.Fatalf("LoadEventLog() loaded %d events, want 1 after pruning", len(events))
+	}
+	if !events[0].EventTime.Equal(recent.EventTime) || events[0].VolumeState != recent.VolumeState || events[0].SkipReason != recent.SkipReason {
+		t.Errorf("LoadEventLog() event = %+v, want %+v", events[0], recent)
+	}
+}
+
+// TestLoadEventLogMissingOrInvalid tests that a missing file loads an empty log and an invalid file is an error.
+func TestLoadEventLogMissingOrInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	loaded, err := LoadEventLog(filepath.Join(dir, "missing.json"), DefaultEventRetention)
+	if err != nil || len(loaded) != 0 {
+		t.Errorf("LoadEventLog() missing file = %v, %v, want empty log and nil error", loaded, err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte("{not json"), 0o600); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	if _, err := LoadEventLog(invalid, DefaultEventRetention); err == nil {
+		t.Errorf("LoadEventLog() invalid file error = nil, want error")
+	}
+}
+
+// TestLastResizeTime tests that the most recent successful resize is found.
+func TestLastResizeTime(t *testing.T) {
+	older := time.Now().Add(-2 * time.Hour)
+	newer := time.Now().Add(-time.Hour)
+	failed := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name     string
+		events   []Event
+		expected time.Time
+		found    bool
+	}{
+		{
+			name:   "no resizes",
+			events: []Event{CreateVolumeStateEvent(EBSVolumeState{AWSDeviceSizeGiB: 10}, true)},
+			found:  false,
+		},
+		{
+			name: "latest successful resize",
+			events: []Event{
+				CreateVolumeResizeActionEvent(EBSVolumeResize{StartTime: older}, true),
+				CreateVolumeResizeActionEvent(EBSVolumeResize{StartTime: newer}, true),
+				CreateVolumeResizeActionEvent(EBSVolumeResize{StartTime: failed}, false),
+			},
+			expected: newer,
+			found:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventLog := EventLog{"vol-1": tt.events}
+			got, found := eventLog.LastResizeTime("vol-1")
+			if found != tt.found || !got.Equal(tt.expected) {
+				t.Errorf("LastResizeTime() = %v, %v, want %v, %v", got, found, tt.expected, tt.found)
+			}
+		})
+	}
+}
+
+// TestConsecutiveStates tests that only the newest unbroken run of matching states since the last resize is counted.
+func TestConsecutiveStates(t *testing.T) {
+	state := func(usedGiB float64) Event {
+		return CreateVolumeStateEvent(EBSVolumeState{AWSVolumeID: "vol-1", UsedSpaceGiB: usedGiB}, true)
+	}
+	overThreshold := func(s EBSVolumeState) bool { return s.UsedSpaceGiB > 80 }
+
+	tests := []struct {
+		name     string
+		events   []Event
+		expected int
+	}{
+		{name: "no events", events: nil, expected: 0},
+		{name: "all above", events: []Event{state(85), state(90), state(95)}, expected: 3},
+		{name: "run broken by a state below", events: []Event{state(85), state(70), state(90), state(95)}, expected: 2},
+		{name: "newest below", events: []Event{state(90), state(70)}, expected: 0},
+		{
+			name: "skips and failed checks passed over",
+			events: []Event{
+				state(85),
+				CreateVolumeStateEvent(EBSVolumeState{AWSVolumeID: "vol-1"}, false),
+				CreateResizeSkippedEvent(EBSVolumeState{AWSVolumeID: "vol-1", UsedSpaceGiB: 90}, SkipReasonObserving),
+				state(90),
+			},
+			expected: 2,
+		},
+		{
+			name: "stops at a resize",
+			events: []Event{
+				state(85),
+				CreateVolumeResizeActionEvent(EBSVolumeResize{AWSVolumeID: "vol-1"}, true),
+				CreateFSActionEvent(FilesystemResize{AWSVolumeID: "vol-1"}, true),
+				state(90),
+			},
+			expected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventLog := EventLog{"vol-1": tt.events}
+			if got := eventLog.ConsecutiveStates("vol-1", overThreshold); got != tt.expected {
+				t.Errorf("ConsecutiveStates() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestResizesSince tests that only successful resizes after the cutoff are counted.
+func TestResizesSince(t *testing.T) {
+	now := time.Now()
+	eventLog := EventLog{"vol-1": {
+		CreateVolumeResizeActionEvent(EBSVolumeResize{StartTime: now.Add(-25 * time.Hour)}, true),
+		CreateVolumeResizeActionEvent(EBSVolumeResize{StartTime: now.Add(-3 * time.Hour)}, true),
+		CreateVolumeResizeActionEvent(EBSVolumeResize{StartTime: now.Add(-2 * time.Hour)}, false),
+		CreateFSActionEvent(FilesystemResize{StartTime: now.Add(-2 * time.Hour)}, true),
+		CreateVolumeResizeActionEvent(EBSVolumeResize{StartTime: now.Add(-time.Hour)}, true),
+	}}
+
+	if got := eventLog.ResizesSince("vol-1", now.Add(-24*time.Hour)); got != 2 {
+		t.Errorf("ResizesSince() = %v, want 2", got)
+	}
+	if got := eventLog.ResizesSince("vol-2", now.Add(-24*time.Hour)); got != 0 {
+		t.Errorf("ResizesSince() for an unknown volume = %v, want 0", got)
+	}
+}
+
+// TestLatestStates tests that each volume's most recently recorded state is returned, ignoring events without one.
+func TestLatestStates(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(event Event, minutes int) Event {
+		event.EventTime = start.Add(time.Duration(minutes) * time.Minute)
+		return event
+	}
+	eventLog := EventLog{
+		"vol-1": {
+			at(CreateVolumeStateEvent(EBSVolumeState{AWSVolumeID: "vol-1", UsedSpaceGiB: 10}, true), 0),
+			at(CreateVolumeStateEvent(EBSVolumeState{AWSVolumeID: "vol-1", UsedSpaceGiB: 20}, true), 5),
+			at(CreateVolumeResizeActionEvent(EBSVolumeResize{AWSVolumeID: "vol-1"}, true), 6),
+		},
+		"vol-2": {at(CreateVolumeResizeActionEvent(EBSVolumeResize{AWSVolumeID: "vol-2"}, false), 0)},
+	}
+
+	want := map[string]EBSVolumeState{"vol-1": {AWSVolumeID: "vol-1", UsedSpaceGiB: 20}}
+	if got := eventLog.LatestStates(); !reflect.DeepEqual(got, want) {
+		t.Errorf("LatestStates() = %v, want %v", got, want)
+	}
+}
+
+// TestRecentErrors tests that a volume's recorded errors are returned newest first, up to the limit.
+func TestRecentErrors(t *testing.T) {
+	volumeID := "vol-0abcd1234efgh5678"
+	eventLog := EventLog{volumeID: {
+		CreateVolumeStateEvent(EBSVolumeState{}, false).WithError(errors.New("first")),
+		CreateVolumeStateEvent(EBSVolumeState{}, true),
+		CreateVolumeStateEvent(EBSVolumeState{}, false),
+		CreateVolumeResizeActionEvent(EBSVolumeResize{}, false).WithError(errors.New("second")),
+		CreateFSActionEvent(FilesystemResize{}, false).WithError(errors.New("third")),
+	}}
+
+	if got, want := eventLog.RecentErrors(volumeID, 2), []string{"third", "second"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RecentErrors(2) = %v, want %v", got, want)
+	}
+	if got, want := eventLog.RecentErrors(volumeID, 5), []string{"third", "second", "first"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RecentErrors(5) = %v, want %v", got, want)
+	}
+	if got := eventLog.RecentErrors("vol-unknown", 5); len(got) != 0 {
+		t.Errorf("RecentErrors() of an unknown volume = %v, want none", got)
+	}
+}
@@ -1,35 +1,218 @@
 package runtime
 
-import "time"
+import (
+	"sync"
+	"time"
+)
+
+// Threshold bases control which capacity a volume's resize threshold is measured against.
+const (
+	ThresholdBasisTotal  = "total"  // Measure against the total filesystem size (default).
+	ThresholdBasisUsable = "usable" // Measure against the filesystem size minus root-reserved blocks.
+)
+
+// Growth strategies control how much a volume grows by with each resize.
+const (
+	GrowthStrategyFixed          = "fixed"            // Grow by incrementSizeGB.
+	GrowthStrategyPercent        = "percent"          // Grow by incrementSizePercent of the current size.
+	GrowthStrategyDoubleUntilCap = "double-until-cap" // Double the current size each resize, up to maxSizeGB.
+)
+
+// Skip reasons explain why a volume that was checked was not resized.
+const (
+	SkipReasonBelowThreshold    = "below threshold"                 // Usage has not reached the resize threshold.
+	SkipReasonOptimizing        = "volume modification in progress" // AWS is still optimizing a previous modification.
+	SkipReasonNotMounted        = "not mounted"                     // The volume, or one of its partitions, has no filesystem mounted.
+	SkipReasonCooldown          = "resize cooldown"                 // The volume was resized within resizeCooldownSeconds.
+	SkipReasonObserving         = "observing"                       // Usage hasn't been above the threshold for minObservationCycles checks yet.
+	SkipReasonDailyLimit        = "daily resize limit"              // The volume was resized maxResizesPerDay times in the last 24 hours.
+	SkipReasonMonitorOnly       = "monitor only"                    // The volume is configured with monitorOnly, so it is never resized.
+	SkipReasonModificationLimit = "volume modification limit"       // AWS refused the modification as the volume was modified too recently.
+	SkipReasonMaxSize           = "max size reached"                // The volume is already maxSizeGB, so it can't grow further.
+	SkipReasonNoIncrement       = "no increment"                    // The increment works out to nothing, e.g. a percentage of a small volume.
+	SkipReasonResizeLocked      = "resize locked"                   // Another instance holds the Multi-Attach volume's resize lock.
+	SkipReasonResizedElsewhere  = "resized by another instance"     // Another instance sharing the Multi-Attach volume already grew it.
+)
+
+// Display units control how sizes are shown in logs, notifications and debug output.
+const (
+	DisplayUnitGiB  = "GiB"  // Show sizes in GiB (default).
+	DisplayUnitGB   = "GB"   // Show sizes in decimal GB.
+	DisplayUnitTiB  = "TiB"  // Show sizes in TiB.
+	DisplayUnitAuto = "auto" // Show each size in GiB, or TiB once it reaches 1 TiB.
+)
+
+// Unmounted actions control how a monitored volume found unmounted is handled.
+const (
+	UnmountedActionAlert = "alert" // Alert once and skip the volume until it is mounted again (default).
+	UnmountedActionError = "error" // Treat it as a failed check, counting towards the volume's removal.
+)
 
 // Runtime represents the runtime state of the application, including the loaded configuration and
 // a debug mode toggle for verbose output.
 type Runtime struct {
-	Configuration Config // Configuration loaded from the config.yaml file.
-	DebugMode     bool   // Indicates if the application is running in debug mode.
+	Configuration Config                       // Configuration loaded from the config.yaml file.
+	DebugMode     bool                         // Indicates if the application is running in debug mode.
+	DryRun        bool                         // Indicates resizes are simulated rather than performed.
+	LastChecked   map[string]time.Time         // Time each volume was last checked, keyed by AWS Volume ID.
+	NextCheck     map[string]time.Time         // Time each volume is next due a check, keyed by AWS Volume ID. Volumes without one are due now.
+	Unmounted     map[string]bool              // Volumes currently found unmounted, keyed by AWS Volume ID.
+	Transient     map[string]bool              // Volumes whose last check hit a transient error, e.g. AWS throttling, keyed by AWS Volume ID.
+	Quarantined   map[string]QuarantinedVolume // Volumes dropped after repeated errors and retried until they recover, keyed by AWS Volume ID.
+	LastCycle     CycleTracker                 // When the last monitoring cycle completed, read by the health check.
+}
+
+// CycleTracker records when the last monitoring cycle completed and the check interval at the time.
+// It is safe to read from other goroutines, e.g. an HTTP health check.
+type CycleTracker struct {
+	mu       sync.Mutex
+	last     time.Time
+	interval time.Duration
+}
+
+// QuarantinedVolume represents a volume dropped from monitoring after repeated errors.
+// It is retried every requarantineRetrySeconds and monitored again once it is healthy.
+type QuarantinedVolume struct {
+	Volume    EBSVolumeConfig // Configuration of the dropped volume.
+	Reason    string          // Error that caused the volume to be dropped.
+	LastRetry time.Time       // When the volume was dropped or last retried.
 }
 
 // Config represents the runtime configuration of the system.
 // It includes the list of EBS volumes to be monitored and the frequency of checks.
 type Config struct {
-	Volumes              []EBSVolumeConfig // List of EBS volumes to be managed.
-	CheckIntervalSeconds int               `yaml:"checkIntervalSeconds"` // Frequency of checking volume state in seconds.
+	Volumes                     []EBSVolumeConfig  // List of EBS volumes to be managed.
+	CheckIntervalSeconds        int                `yaml:"checkIntervalSeconds"`        // Frequency of checking volume state in seconds.
+	CheckIntervalJitterPercent  int                `yaml:"checkIntervalJitterPercent"`  // Vary the time between each volume's checks randomly by up to this percentage of its interval, 0 disables.
+	RemoteConfig                RemoteConfigSource `yaml:"remoteConfig"`                // Optional HTTP endpoint polled for the desired config.
+	LateCheckMarginSeconds      int                `yaml:"lateCheckMarginSeconds"`      // Alert when a volume goes unchecked this long past its interval, 0 disables.
+	LogTarget                   string             `yaml:"logTarget"`                   // Where logs are sent, "syslog" (default), "journald" or "stdout".
+	LogLevel                    string             `yaml:"logLevel"`                    // Lowest level written to the logs, "debug", "info" (default), "warn" or "error".
+	LogFormat                   string             `yaml:"logFormat"`                   // Format log entries are written in, "text" (default) or "json".
+	LogFilePath                 string             `yaml:"logFilePath"`                 // Also write logs to this file, rotated by size. Disabled when empty.
+	LogFileMaxSizeMB            int                `yaml:"logFileMaxSizeMB"`            // Size in megabytes at which the log file is rotated, 0 uses the default of 100.
+	LogFileMaxBackups           int                `yaml:"logFileMaxBackups"`           // Number of rotated log files to keep, 0 uses the default of 5.
+	FailOnRegionMismatch        bool               `yaml:"failOnRegionMismatch"`        // Reject, rather than warn about, volumes configured outside the instance's region.
+	FailOnMissingResizeTools    bool               `yaml:"failOnMissingResizeTools"`    // Refuse to start, rather than log an error, when a tool needed to grow a volume isn't installed.
+	RecordSkippedResizes        bool               `yaml:"recordSkippedResizes"`        // Record an event each time a volume is checked but not resized.
+	NotificationBatch           NotificationBatch  `yaml:"notificationBatch"`           // Coalesce alerts raised close together into a digest.
+	UnmountedAction             string             `yaml:"unmountedAction"`             // How to handle a monitored volume found unmounted, "alert" (default) or "error".
+	MaxUptimeHours              int                `yaml:"maxUptimeHours"`              // Exit cleanly between cycles after running this long, for systemd to restart. 0 is unlimited.
+	MaxRetries                  int                `yaml:"maxRetries"`                  // Retries for throttled or failed EC2 calls, 0 uses the default of 3 and -1 disables retries.
+	AssumeRoleARN               string             `yaml:"assumeRoleARN"`               // IAM role assumed for AWS calls, empty uses the default credential chain.
+	VolumeTagFilters            map[string]string  `yaml:"volumeTagFilters"`            // Monitor attached volumes carrying all of these tags, in addition to Volumes.
+	VolumeTemplate              EBSVolumeConfig    `yaml:"volumeTemplate"`              // Settings for volumes discovered by VolumeTagFilters.
+	MaxConcurrentChecks         int                `yaml:"maxConcurrentChecks"`         // How many volumes are checked at once, 0 uses the default of 4.
+	NotificationChannels        []string           `yaml:"notificationChannels"`        // Where alerts are sent, any of "sns" (default) and "slack".
+	SlackWebhookURL             string             `yaml:"slackWebhookURL"`             // Slack incoming webhook URL, required for the slack channel.
+	SNSTopicARN                 string             `yaml:"snsTopicARN"`                 // SNS topic for the sns channel, SNS notifications are skipped when empty.
+	SNSRegion                   string             `yaml:"snsRegion"`                   // Region of the SNS topic, defaults to the region in SNSTopicARN.
+	EnableSNS                   bool               `yaml:"enableSNS"`                   // Publish notifications to SNSTopicARN, off by default.
+	CheckAptVersions            bool               `yaml:"checkAptVersions"`            // Look up the installed and latest ebs-monitor versions with apt for notifications, off by default.
+	NotificationLevel           string             `yaml:"notificationLevel"`           // Lowest log level sent as a notification, defaults to "error".
+	AlertCooldownSeconds        int                `yaml:"alertCooldownSeconds"`        // Suppress repeats of an alert for this long after sending it, 0 uses the default of 1 hour.
+	ResizeCooldownSeconds       int                `yaml:"resizeCooldownSeconds"`       // Minimum time between successful resizes of the same volume, 0 disables.
+	CommandTimeoutSeconds       int                `yaml:"commandTimeoutSeconds"`       // How long commands inspecting the host (lsblk, df) may run, 0 uses the default of 30.
+	ResizeCommandTimeoutSeconds int                `yaml:"resizeCommandTimeoutSeconds"` // How long partition and filesystem resize commands may run, 0 uses the default of 600.
+	ResizeCommands              map[string]string  `yaml:"resizeCommands"`              // Binary used to grow each filesystem type, e.g. "ext4": "/sbin/resize2fs". Unset types use the default on the PATH.
+	VolumePrices                map[string]float64 `yaml:"volumePrices"`                // Price in USD per GiB-month of each volume type for resize cost estimates, e.g. "gp3": 0.08. Unset types use built-in us-east-1 prices.
+	RequarantineRetrySeconds    int                `yaml:"requarantineRetrySeconds"`    // How often volumes dropped after repeated errors are retried, 0 uses the default of 300.
+	ErrorThreshold              int                `yaml:"errorThreshold"`              // Consecutive errors before a volume is dropped from monitoring, 0 uses the default of 5.
+	EventRetentionHours         int                `yaml:"eventRetentionHours"`         // How long events are kept in the event log, 0 uses the default of 24.
+	DisplayUnit                 string             `yaml:"displayUnit"`                 // Unit sizes are shown in, "GiB" (default), "GB", "TiB" or "auto".
+}
+
+// NotificationBatch represents how alerts raised close together are coalesced into one digest notification.
+type NotificationBatch struct {
+	WindowSeconds   int      `yaml:"windowSeconds"`   // How long to collect alerts before sending a digest, 0 disables batching.
+	MaxCount        int      `yaml:"maxCount"`        // Send the digest early once it holds this many alerts, 0 means no limit.
+	ImmediateLevels []string `yaml:"immediateLevels"` // Log levels sent straight away, defaults to error and fatal.
+}
+
+// RemoteConfigSource represents an HTTP endpoint serving the desired configuration.
+// The endpoint returns the same YAML/JSON format as config.yaml.
+type RemoteConfigSource struct {
+	URL                 string `yaml:"url"`                 // Endpoint to poll. Remote config is disabled when empty.
+	PollIntervalSeconds int    `yaml:"pollIntervalSeconds"` // Frequency of polling the endpoint in seconds.
 }
 
 // EBSVolumeConfig represents the configuration for an EBS volume.
 type EBSVolumeConfig struct {
-	AWSVolumeID          string `yaml:"awsVolumeID"`          // Identifier for the EBS volume.
-	AWSDeviceName        string `yaml:"awsDeviceName"`        // Name of the EBS device.
-	AWSRegion            string `yaml:"awsRegion"`            // AWS region where the EBS volume is located.
-	IncrementSizeGB      int    `yaml:"incrementSizeGB"`      // Size to increase volume by (in GB), when required.
-	IncrementSizePercent int    `yaml:"incrementSizePercent"` // Percentage to increase volume size, when required.
-	ResizeThreshold      int    `yaml:"resizeThreshold"`      // Threshold percentage at which to resize the volume.
+	AWSVolumeID               string            `yaml:"awsVolumeID"`               // Identifier for the EBS volume.
+	AWSDeviceName             string            `yaml:"awsDeviceName"`             // Name of the EBS device.
+	AWSRegion                 string            `yaml:"awsRegion"`                 // AWS region where the EBS volume is located.
+	IncrementSizeGB           int               `yaml:"incrementSizeGB"`           // Size to increase volume by (in GiB, the unit AWS sizes volumes in), when required.
+	IncrementSizePercent      int               `yaml:"incrementSizePercent"`      // Percentage to increase volume size, when required.
+	MaxIncrementGB            int               `yaml:"maxIncrementGB"`            // Most a single resize may add (in GiB), capping percentage or scaled increments. 0 is unlimited.
+	GrowthStrategy            string            `yaml:"growthStrategy"`            // How the volume grows, "fixed", "percent" or "double-until-cap". Inferred from the increment set when empty.
+	MaxSizeGB                 int               `yaml:"maxSizeGB"`                 // Largest size (in GiB) a resize may grow the volume to. Required by double-until-cap, 0 is unlimited otherwise.
+	ResizeThreshold           int               `yaml:"resizeThreshold"`           // Threshold percentage at which to resize the volume.
+	UsedCeilingGB             int               `yaml:"usedCeilingGB"`             // Used space (in GiB) at which to resize the volume, instead of ResizeThreshold.
+	MinFreeGB                 int               `yaml:"minFreeGB"`                 // Resize the volume when less than this much space (in GiB) is free, as well as ResizeThreshold or UsedCeilingGB. 0 disables.
+	InodeResizeThreshold      int               `yaml:"inodeResizeThreshold"`      // Inode utilisation percentage at which to resize the volume, as well as ResizeThreshold. 0 disables.
+	ThresholdBasis            string            `yaml:"thresholdBasis"`            // Capacity the threshold is measured against, "total" or "usable".
+	WaitOnNoopModification    bool              `yaml:"waitOnNoopModification"`    // Wait for 'optimizing' even when AWS reports the resize as a no-op.
+	PostAWSResizeDelaySeconds int               `yaml:"postAWSResizeDelaySeconds"` // Wait between the AWS resize and the filesystem resize, default 60.
+	ModificationWaitSeconds   int               `yaml:"modificationWaitSeconds"`   // Poll AWS until the modification leaves 'modifying', for up to this long, instead of the fixed delay.
+	CheckIntervalSeconds      int               `yaml:"checkIntervalSeconds"`      // Frequency of checking this volume in seconds, defaults to the top-level checkIntervalSeconds.
+	LocalMountPoint           string            `yaml:"localMountPoint"`           // Mount point of the volume's filesystem, skipping the lookup by volume serial when set.
+	Partitions                []PartitionConfig `yaml:"partitions"`                // Filesystems on a partitioned volume. The last partition is grown and each filesystem resized, and a resize triggers when any exceeds its threshold. The whole volume is one filesystem when empty.
+	AlignToGB                 int               `yaml:"alignToGB"`                 // Round the new volume size up to a multiple of this many GiB, when set.
+	GrowthWindows             []GrowthWindow    `yaml:"growthWindows"`             // Times of day when the increment is scaled by a multiplier.
+	TargetIOPS                int               `yaml:"targetIOPS"`                // Provisioned IOPS to set with each resize (gp3/io1/io2), 0 leaves IOPS unchanged.
+	TargetThroughput          int               `yaml:"targetThroughput"`          // Throughput in MiB/s to set with each resize (gp3), 0 leaves throughput unchanged.
+	SnapshotBeforeResize      bool              `yaml:"snapshotBeforeResize"`      // Snapshot the volume before each resize, aborting the resize if the snapshot fails.
+	ResizeLock                bool              `yaml:"resizeLock"`                // Take a lock tag on the volume before resizing it, for Multi-Attach volumes monitored from several instances.
+	ResizeLockTTLSeconds      int               `yaml:"resizeLockTTLSeconds"`      // How long a resize lock not released, e.g. after a crash, is honoured, default 3600.
+	AssumeRoleARN             string            `yaml:"assumeRoleARN"`             // IAM role assumed for this volume's AWS calls, defaults to the top-level assumeRoleARN.
+	MinObservationCycles      int               `yaml:"minObservationCycles"`      // Consecutive checks usage must be above the threshold before resizing, 0 resizes on the first.
+	MaxResizesPerDay          int               `yaml:"maxResizesPerDay"`          // Most successful resizes of the volume in any 24 hours, 0 is unlimited.
+	MonitorOnly               bool              `yaml:"monitorOnly"`               // Alert when the threshold is exceeded, but never resize the volume.
+	PreResizeCommand          string            `yaml:"preResizeCommand"`          // Shell command run before the volume is resized, a non-zero exit aborts the resize.
+	PostResizeCommand         string            `yaml:"postResizeCommand"`         // Shell command run after a resize attempt, a failure is logged but doesn't fail the resize.
+}
+
+// GrowthWindow represents a daily time window during which a volume's resize increment is scaled.
+// Windows are in the host's local time, include their start and exclude their end, and may cross midnight.
+type GrowthWindow struct {
+	WindowStart string  `yaml:"windowStart"` // Start of the window, "HH:MM".
+	WindowEnd   string  `yaml:"windowEnd"`   // End of the window, "HH:MM".
+	Multiplier  float64 `yaml:"multiplier"`  // Factor the increment is multiplied by inside the window.
+}
+
+// PartitionConfig represents one partition of a partitioned EBS volume and the filesystem on it.
+type PartitionConfig struct {
+	Partition      int    `yaml:"partition"`      // Partition number on the volume, e.g. 2 for nvme1n1p2.
+	MountPoint     string `yaml:"mountPoint"`     // Where the partition's filesystem is mounted. Not used for swap.
+	FilesystemType string `yaml:"filesystemType"` // Filesystem on the partition, "ext4", "xfs" or "swap".
+}
+
+// Dump represents a snapshot of the runtime state, exported as JSON for debugging.
+type Dump struct {
+	Time        time.Time                    `json:"time"`                  // When the snapshot was taken.
+	Config      Config                       `json:"config"`                // Active configuration, with secrets redacted.
+	EventLog    EventLog                     `json:"eventLog"`              // Recent events of each volume.
+	ErrorLog    map[string]int               `json:"errorLog"`              // Error count of each volume.
+	LastChecked map[string]time.Time         `json:"lastChecked"`           // Time each volume was last checked.
+	NextCheck   map[string]time.Time         `json:"nextCheck,omitempty"`   // Time each volume is next due a check.
+	Unmounted   map[string]bool              `json:"unmounted,omitempty"`   // Volumes currently found unmounted.
+	Quarantined map[string]QuarantinedVolume `json:"quarantined,omitempty"` // Volumes dropped after repeated errors.
+}
+
+// StateSnapshot represents the last-known state of each volume, served as JSON to local tooling.
+type StateSnapshot struct {
+	Time     time.Time                 `json:"time"`     // When the snapshot was taken.
+	Volumes  map[string]EBSVolumeState `json:"volumes"`  // Latest recorded state of each volume, keyed by AWS Volume ID.
+	EventLog EventLog                  `json:"eventLog"` // Recent events of each volume.
 }
 
 // EventLog represents a map of volume histories.
 // It maps AWS Volume IDs to slices of VolumeHistory.
 type EventLog map[string][]Event
 
+// DefaultEventRetention is how long events are kept in the EventLog before they are pruned, when eventRetentionHours is not set.
+const DefaultEventRetention = 24 * time.Hour
+
 // Event represents the history of actions taken on a specific EBS volume.
 // It includes timestamps, volume states, actions, and success flags.
 type Event struct {
@@ -38,28 +221,36 @@ type Event struct {
 	VolumeAction     EBSVolumeResize  // Resize action taken on the EBS volume.
 	FSAction         FilesystemResize // Filesystem resize action.
 	ExecutionSuccess bool             // Indicates if the action executed successfully.
+	SkipReason       string           // Why a resize was not attempted, empty unless the event records a skip.
+	Simulated        bool             // Indicates the action was simulated by a dry run and nothing was changed.
+	Summary          ResizeSummary    // Consolidated record of a completed resize, set only on resize summary events.
+	Error            string           // Why the action failed, empty on success or when the failure wasn't recorded.
 }
 
 // EBSVolumeState represents a snapshot of an EBS volume at a point in time.
 // It includes various size and space measurements, as well as identifiers.
 type EBSVolumeState struct {
-	AWSVolumeID     string  // Identifier for the EBS volume.
-	AWSDeviceName   string  // Name of the EBS device.
-	LocalMountPoint string  // Local device name where the EBS volume is attached.
-	AWSDeviceSizeGB float64 // Size of the EBS volume in gigabytes.
-	LocalDiskSizeGB float64 // Size of the local disk in gigabytes.
-	UsedSpaceGB     float64 // Amount of disk space used, in gigabytes.
+	AWSVolumeID       string  // Identifier for the EBS volume.
+	AWSDeviceName     string  // Name of the EBS device.
+	LocalMountPoint   string  // Local device name where the EBS volume is attached.
+	AWSDeviceSizeGiB  float64 // Size of the EBS volume in GiB.
+	LocalDiskSizeGiB  float64 // Size of the local disk in GiB.
+	UsedSpaceGiB      float64 // Amount of disk space used, in GiB.
+	ReservedSpaceGiB  float64 // Space reserved by the filesystem for root, in GiB.
+	InodesUsedPercent float64 // Percentage of the filesystem's inodes in use, 0 when the filesystem has no fixed inode count.
 }
 
 // EBSVolumeResize represents a resize action on an EBS volume.
 // It includes timestamps, identifiers, and the original and new sizes of the volume.
 type EBSVolumeResize struct {
-	StartTime      time.Time // Time when resize API request was sent.
-	AWSVolumeID    string    // Identifier for the EBS volume.
-	AWSDeviceName  string    // Name of the EBS device.
-	AWSRegion      string    // AWS region where the EBS volume is located.
-	OriginalSizeGB float64   // Original size of the EBS volume, in gigabytes.
-	NewSize        float64   // New size of the EBS volume, in gigabytes.
+	StartTime       time.Time // Time when resize API request was sent.
+	AWSVolumeID     string    // Identifier for the EBS volume.
+	AWSDeviceName   string    // Name of the EBS device.
+	AWSRegion       string    // AWS region where the EBS volume is located.
+	OriginalSizeGiB float64   // Original size of the EBS volume, in GiB.
+	NewSize         float64   // New size of the EBS volume, in GiB.
+	SnapshotID      string    // Snapshot taken before the resize, when snapshotBeforeResize is enabled.
+	TriggerReason   string    // Threshold rule that called for the resize, one of the monitor Rule constants.
 }
 
 // FilesystemResize represents a resize action on the local filesystem.
@@ -69,7 +260,23 @@ type FilesystemResize struct {
 	AWSVolumeID     string    // Identifier for the EBS volume.
 	AWSDeviceName   string    // Name of the EBS device.
 	LocalMountPoint string    // Local device name where the EBS volume is attached.
-	AWSVolumeSize   float64   // Current size of the EBS volume, in gigabytes.
-	OriginalSizeGB  float64   // Original size of the filesystem, in gigabytes.
-	NewSize         float64   // New size of the filesystem, in gigabytes.
+	AWSVolumeSize   float64   // Current size of the EBS volume, in GiB.
+	OriginalSizeGiB float64   // Original size of the filesystem, in GiB.
+	NewSize         float64   // New size of the filesystem, in GiB.
+}
+
+// ResizeSummary represents a completed resize of a volume and its filesystem as a single record.
+// It consolidates the EBSVolumeResize and FilesystemResize of the resize for reporting.
+type ResizeSummary struct {
+	AWSVolumeID       string        // Identifier for the EBS volume.
+	AWSDeviceName     string        // Name of the EBS device.
+	AWSRegion         string        // AWS region where the EBS volume is located.
+	LocalMountPoint   string        // Local mount point of the resized filesystem.
+	TriggerReason     string        // Threshold rule that called for the resize, one of the monitor Rule constants.
+	OriginalSizeGiB   float64       // Size of the EBS volume before the resize, in GiB.
+	NewSizeGiB        float64       // Size of the EBS volume after the resize, in GiB.
+	AWSModifyDuration time.Duration // Time from the ModifyVolume request until the filesystem resize started.
+	FSResizeDuration  time.Duration // Time taken to resize and verify the filesystem.
+	SnapshotID        string        // Snapshot taken before the resize, when snapshotBeforeResize is enabled.
+	MonthlyCostDelta  float64       // Estimated monthly storage cost the resize adds, in USD. 0 when it can't be estimated.
 }
@@ -0,0 +1,56 @@
+package runtime
+
+import (
+	"fmt"
+	"sync"
+)
+
+// bytesPerGiB is the number of bytes in a GiB, used to convert to decimal GB.
+const bytesPerGiB = 1 << 30
+
+var (
+	// displayUnitMu guards displayUnit, which is set from the config when it is loaded.
+	displayUnitMu sync.Mutex
+	displayUnit   = DisplayUnitGiB
+)
+
+// SetDisplayUnit sets the unit FormatSize shows sizes in.
+// unit : string the unit, one of the DisplayUnit constants, empty restores the default of GiB
+func SetDisplayUnit(unit string) {
+	displayUnitMu.Lock()
+	defer displayUnitMu.Unlock()
+	if unit == "" {
+		unit = DisplayUnitGiB
+	}
+	displayUnit = unit
+}
+
+// FormatSize formats a size for people to read, in the unit set with SetDisplayUnit.
+// Only output is affected, sizes are still calculated in GiB.
+// gib : float64 the size in GiB
+// returns : string the size with its unit, e.g. "1.50 TiB"
+func FormatSize(gib float64) string {
+	displayUnitMu.Lock()
+	unit := displayUnit
+	displayUnitMu.Unlock()
+	return formatSizeIn(gib, unit)
+}
+
+// formatSizeIn formats a size in a unit.
+// gib : float64 the size in GiB
+// unit : string the unit, one of the DisplayUnit constants, anything else shows GiB
+// returns : string the size with its unit
+func formatSizeIn(gib float64, unit string) string {
+	switch unit {
+	case DisplayUnitGB:
+		return fmt.Sprintf("%.2f GB", gib*bytesPerGiB/1e9)
+	case DisplayUnitTiB:
+		return fmt.Sprintf("%.2f TiB", gib/1024)
+	case DisplayUnitAuto:
+		// Negative sizes, such as an overrun, are scaled by their magnitude
+		if gib >= 1024 || gib <= -1024 {
+			return fmt.Sprintf("%.2f TiB", gib/1024)
+		}
+	}
+	return fmt.Sprintf("%.2f GiB", gib)
+}
@@ -0,0 +1,41 @@
+package runtime
+
+import "testing"
+
+func TestFormatSizeIn(t *testing.T) {
+	tests := []struct {
+		name string
+		gib  float64
+		unit string
+		want string
+	}{
+		{"gib", 1536, DisplayUnitGiB, "1536.00 GiB"},
+		{"unset defaults to gib", 20.5, "", "20.50 GiB"},
+		{"gb", 100, DisplayUnitGB, "107.37 GB"},
+		{"tib", 512, DisplayUnitTiB, "0.50 TiB"},
+		{"auto below 1 TiB", 1023.5, DisplayUnitAuto, "1023.50 GiB"},
+		{"auto at 1 TiB", 1024, DisplayUnitAuto, "1.00 TiB"},
+		{"auto multi-TiB", 5632, DisplayUnitAuto, "5.50 TiB"},
+		{"auto negative", -2048, DisplayUnitAuto, "-2.00 TiB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatSizeIn(tt.gib, tt.unit); got != tt.want {
+				t.Errorf("formatSizeIn(%v, %q) = %q, want %q", tt.gib, tt.unit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetDisplayUnit(t *testing.T) {
+	t.Cleanup(func() { SetDisplayUnit("") })
+
+	SetDisplayUnit(DisplayUnitTiB)
+	if got := FormatSize(2048); got != "2.00 TiB" {
+		t.Errorf("FormatSize(2048) = %q, want %q", got, "2.00 TiB")
+	}
+	SetDisplayUnit("")
+	if got := FormatSize(2048); got != "2048.00 GiB" {
+		t.Errorf("FormatSize(2048) after reset = %q, want %q", got, "2048.00 GiB")
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"ebs-monitor/aws"
+	"ebs-monitor/monitor"
+	"ebs-monitor/runtime"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// statusJSON : bool A flag indicating the status subcommand should print JSON instead of a table
+var statusJSON bool
+
+// statusCmd : Prints the current utilisation of every configured volume once and exits
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Print the current utilisation of each configured volume and exit",
+	Long:  `Gathers the state of every volume in the config file once, prints it, and exits without resizing anything. Exits with status 1 if any volume's state could not be gathered.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		status(cmd, args)
+	},
+}
+
+// init : Registers the status subcommand
+func init() {
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Print machine-readable JSON instead of a table")
+	rootCmd.AddCommand(statusCmd)
+}
+
+// VolumeStatus : The current utilisation of a volume, as printed by the status subcommand
+type VolumeStatus struct {
+	AWSVolumeID      string  `json:"awsVolumeId"`
+	AWSDeviceName    string  `json:"awsDeviceName"`
+	LocalMountPoint  string  `json:"localMountPoint,omitempty"`
+	UsedGiB          float64 `json:"usedGiB"`
+	SizeGiB          float64 `json:"sizeGiB"`
+	UsedPercent      float64 `json:"usedPercent"`
+	ThresholdPercent float64 `json:"thresholdPercent"`
+	Error            string  `json:"error,omitempty"`
+}
+
+// status : The function that runs the status subcommand
+// cmd : *cobra.Command The status command
+// args : []string The arguments passed to the status command
+func status(cmd *cobra.Command, args []string) {
+	if err := ResolveConfigSource(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if configFile == "" {
+		fmt.Fprintln(os.Stderr, "Config file path is missing")
+		os.Exit(1)
+	}
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	aws.SetMaxRetries(cfg.MaxRetries)
+	aws.SetAssumeRoleARN(cfg.AssumeRoleARN)
+
+	statuses := GetVolumeStatuses(cfg.Volumes)
+
+	if statusJSON {
+		err = PrintStatusJSON(os.Stdout, statuses)
+	} else {
+		err = PrintStatusTable(os.Stdout, statuses)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to print status: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, s := range statuses {
+		if s.Error != "" {
+			os.Exit(1)
+		}
+	}
+}
+
+// GetVolumeStatuses : Gathers the state of each volume once.
+// A volume whose state can't be gathered is reported with its error rather than stopping the others.
+// volumes : []runtime.EBSVolumeConfig The volumes to check.
+// Returns: []VolumeStatus The status of each volume, in config order.
+func GetVolumeStatuses(volumes []runtime.EBSVolumeConfig) []VolumeStatus {
+	eventLog := runtime.EventLog{}
+	statuses := make([]VolumeStatus, 0, len(volumes))
+	for _, volume := range volumes {
+		volumeState, err := monitor.GetVolumeState(volume, &eventLog)
+		statuses = append(statuses, NewVolumeStatus(volume, volumeState, err))
+	}
+	return statuses
+}
+
+// NewVolumeStatus : Builds the status of a volume from its gathered state.
+// volume : runtime.EBSVolumeConfig The configuration of the volume.
+// volumeState : runtime.EBSVolumeState The gathered state of the volume.
+// err : error The error gathering the state, if any.
+// Returns: VolumeStatus The status of the volume. Percentages are measured against the volume's threshold basis.
+func NewVolumeStatus(volume runtime.EBSVolumeConfig, volumeState runtime.EBSVolumeState, err error) VolumeStatus {
+	s := VolumeStatus{
+		AWSVolumeID:   volume.AWSVolumeID,
+		AWSDeviceName: volume.AWSDeviceName,
+	}
+	if err != nil {
+		s.Error = err.Error()
+		return s
+	}
+
+	s.LocalMountPoint = volumeState.LocalMountPoint
+	s.UsedGiB = volumeState.UsedSpaceGiB
+	s.SizeGiB = volumeState.LocalDiskSizeGiB
+	if capacityGiB := monitor.CapacityGiB(volumeState, volume.ThresholdBasis); capacityGiB > 0 {
+		s.UsedPercent = volumeState.UsedSpaceGiB / capacityGiB * 100
+		s.ThresholdPercent = monitor.ResizeThresholdGiB(volumeState, volume) / capacityGiB * 100
+	}
+	return s
+}
+
+// PrintStatusTable : Prints volume statuses as an aligned table.
+// w : io.Writer Where to print the table.
+// statuses : []VolumeStatus The statuses to print.
+// Returns: error Any error writing the table.
+func PrintStatusTable(w io.Writer, statuses []VolumeStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VOLUME ID\tDEVICE\tMOUNT POINT\tUSED GiB\tSIZE GiB\tUSED %\tTHRESHOLD %")
+	for _, s := range statuses {
+		if s.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\terror: %s\n", s.AWSVolumeID, s.AWSDeviceName, s.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.2f\t%.2f\t%.1f\t%.1f\n",
+			s.AWSVolumeID, s.AWSDeviceName, s.LocalMountPoint, s.UsedGiB, s.SizeGiB, s.UsedPercent, s.ThresholdPercent)
+	}
+	return tw.Flush()
+}
+
+// PrintStatusJSON : Prints volume statuses as an indented JSON array.
+// w : io.Writer Where to print the JSON.
+// statuses : []VolumeStatus The statuses to print.
+// Returns: error Any error encoding the statuses.
+func PrintStatusJSON(w io.Writer, statuses []VolumeStatus) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(statuses)
+}